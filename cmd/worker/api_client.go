@@ -17,6 +17,7 @@ type InstanceInfo struct {
 
 type SudevwaClient struct {
 	BaseURL      string
+	APIKey       string // kalau diisi, dipakai lewat header X-API-Key (tanpa login / refresh JWT)
 	Username     string
 	Password     string
 	AccessToken  string
@@ -48,6 +49,7 @@ type APIResponse struct {
 			Circle      string `json:"circle"`
 			Status      string `json:"status"`
 		} `json:"instances"`
+		MessageID string `json:"messageId"` // WhatsApp message ID dari endpoint send
 	} `json:"data"`
 }
 
@@ -59,7 +61,18 @@ func NewSudevwaClient(baseURL, username, password string) *SudevwaClient {
 	}
 }
 
+// NewSudevwaClientWithAPIKey membuat client yang autentikasi dengan API key (scope send + read:instances)
+func NewSudevwaClientWithAPIKey(baseURL, apiKey string) *SudevwaClient {
+	return &SudevwaClient{
+		BaseURL: baseURL,
+		APIKey:  apiKey,
+	}
+}
+
 func (c *SudevwaClient) EnsureAuth() error {
+	if c.APIKey != "" {
+		return nil
+	}
 	if c.AccessToken == "" || time.Now().After(c.ExpiresAt) {
 		if c.RefreshToken != "" {
 			err := c.Refresh()
@@ -126,6 +139,15 @@ func (c *SudevwaClient) Refresh() error {
 	return nil
 }
 
+// setAuthHeader memasang X-API-Key atau Bearer token ke request
+func (c *SudevwaClient) setAuthHeader(req *http.Request) {
+	if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+}
+
 func (c *SudevwaClient) GetInstances(circle string) ([]InstanceInfo, error) {
 	c.mu.RLock()
 	if c.allInstancesCache != nil && time.Now().Before(c.cacheExpiry) {
@@ -150,7 +172,7 @@ func (c *SudevwaClient) GetInstances(circle string) ([]InstanceInfo, error) {
 	}
 
 	req, _ := http.NewRequest("GET", c.BaseURL+"/api/instances?all=true", nil)
-	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+	c.setAuthHeader(req)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -184,9 +206,9 @@ func (c *SudevwaClient) GetInstances(circle string) ([]InstanceInfo, error) {
 	return instances, nil
 }
 
-func (c *SudevwaClient) SendMessage(instanceID, to, message string) (bool, string, error) {
+func (c *SudevwaClient) SendMessage(instanceID, to, message string) (bool, string, string, error) {
 	if err := c.EnsureAuth(); err != nil {
-		return false, "", err
+		return false, "", "", err
 	}
 
 	payload, _ := json.Marshal(map[string]string{
@@ -196,28 +218,28 @@ func (c *SudevwaClient) SendMessage(instanceID, to, message string) (bool, strin
 
 	url := fmt.Sprintf("%s/api/send/%s", c.BaseURL, instanceID)
 	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(payload))
-	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+	c.setAuthHeader(req)
 	req.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return false, "", err
+		return false, "", "", err
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 	var res APIResponse
 	if err := json.Unmarshal(body, &res); err != nil {
-		return false, string(body), err
+		return false, string(body), "", err
 	}
 
-	return res.Success, res.Message, nil
+	return res.Success, res.Message, res.Data.MessageID, nil
 }
 
-func (c *SudevwaClient) SendGroupMessage(instanceID, groupID, message string) (bool, string, error) {
+func (c *SudevwaClient) SendGroupMessage(instanceID, groupID, message string) (bool, string, string, error) {
 	if err := c.EnsureAuth(); err != nil {
-		return false, "", err
+		return false, "", "", err
 	}
 
 	payload, _ := json.Marshal(map[string]string{
@@ -227,28 +249,28 @@ func (c *SudevwaClient) SendGroupMessage(instanceID, groupID, message string) (b
 
 	url := fmt.Sprintf("%s/api/send-group/%s", c.BaseURL, instanceID)
 	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(payload))
-	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+	c.setAuthHeader(req)
 	req.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return false, "", err
+		return false, "", "", err
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 	var res APIResponse
 	if err := json.Unmarshal(body, &res); err != nil {
-		return false, string(body), err
+		return false, string(body), "", err
 	}
 
-	return res.Success, res.Message, nil
+	return res.Success, res.Message, res.Data.MessageID, nil
 }
 
-func (c *SudevwaClient) SendMediaURL(instanceID, to, mediaURL, caption string) (bool, string, error) {
+func (c *SudevwaClient) SendMediaURL(instanceID, to, mediaURL, caption string) (bool, string, string, error) {
 	if err := c.EnsureAuth(); err != nil {
-		return false, "", err
+		return false, "", "", err
 	}
 
 	payload, _ := json.Marshal(map[string]string{
@@ -259,28 +281,28 @@ func (c *SudevwaClient) SendMediaURL(instanceID, to, mediaURL, caption string) (
 
 	url := fmt.Sprintf("%s/api/send/%s/media-url", c.BaseURL, instanceID)
 	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(payload))
-	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+	c.setAuthHeader(req)
 	req.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return false, "", err
+		return false, "", "", err
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 	var res APIResponse
 	if err := json.Unmarshal(body, &res); err != nil {
-		return false, string(body), err
+		return false, string(body), "", err
 	}
 
-	return res.Success, res.Message, nil
+	return res.Success, res.Message, res.Data.MessageID, nil
 }
 
-func (c *SudevwaClient) SendGroupMediaURL(instanceID, groupID, mediaURL, caption string) (bool, string, error) {
+func (c *SudevwaClient) SendGroupMediaURL(instanceID, groupID, mediaURL, caption string) (bool, string, string, error) {
 	if err := c.EnsureAuth(); err != nil {
-		return false, "", err
+		return false, "", "", err
 	}
 
 	payload, _ := json.Marshal(map[string]string{
@@ -291,21 +313,21 @@ func (c *SudevwaClient) SendGroupMediaURL(instanceID, groupID, mediaURL, caption
 
 	url := fmt.Sprintf("%s/api/send-group/%s/media-url", c.BaseURL, instanceID)
 	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(payload))
-	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+	c.setAuthHeader(req)
 	req.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return false, "", err
+		return false, "", "", err
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 	var res APIResponse
 	if err := json.Unmarshal(body, &res); err != nil {
-		return false, string(body), err
+		return false, string(body), "", err
 	}
 
-	return res.Success, res.Message, nil
+	return res.Success, res.Message, res.Data.MessageID, nil
 }
@@ -93,15 +93,19 @@ func main() {
 		apiBaseURL = "http://localhost:" + port
 	}
 
-	apiUser := os.Getenv("OUTBOX_API_USER")
-	apiPass := os.Getenv("OUTBOX_API_PASS")
-	if apiUser == "" || apiPass == "" {
-		log.Fatal("OUTBOX_API_USER or OUTBOX_API_PASS is not set")
+	// 4. Initialize API Client (API key diutamakan, fallback ke login username / password)
+	var client *SudevwaClient
+	if apiKey := os.Getenv("OUTBOX_API_KEY"); apiKey != "" {
+		client = NewSudevwaClientWithAPIKey(apiBaseURL, apiKey)
+	} else {
+		apiUser := os.Getenv("OUTBOX_API_USER")
+		apiPass := os.Getenv("OUTBOX_API_PASS")
+		if apiUser == "" || apiPass == "" {
+			log.Fatal("OUTBOX_API_KEY or OUTBOX_API_USER / OUTBOX_API_PASS must be set")
+		}
+		client = NewSudevwaClient(apiBaseURL, apiUser, apiPass)
 	}
 
-	// 4. Initialize API Client
-	client := NewSudevwaClient(apiBaseURL, apiUser, apiPass)
-
 	// 5. Start Worker Manager
 	manager := NewWorkerManager(client)
 	manager.Start()
@@ -5,8 +5,12 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"os"
+	"strconv"
 	"strings"
 	"time"
+
+	"gowa-yourself/internal/phone"
 )
 
 // ConfigSQL returns correct placeholders for ConfigDB (always postgres)
@@ -54,6 +58,7 @@ type WorkerConfig struct {
 	Enabled            bool           `json:"enabled"`
 	AllowMedia         bool           `json:"allow_media"`
 	ReplacePending     bool           `json:"replace_pending"`
+	DefaultCountry     string         `json:"default_country"` // dari circle_settings, kosong = DEFAULT_PHONE_COUNTRY
 	WebhookURL         sql.NullString `json:"webhook_url"`
 	WebhookSecret      sql.NullString `json:"webhook_secret"`
 	CreatedAt          time.Time      `json:"created_at"`
@@ -62,10 +67,12 @@ type WorkerConfig struct {
 
 func FetchWorkerConfigs(ctx context.Context) ([]WorkerConfig, error) {
 	query := `
-		SELECT id, user_id, worker_name, circle, application, message_type,
-		       interval_seconds, interval_max_seconds, enabled, allow_media, replace_pending, webhook_url, webhook_secret, created_at, updated_at
-		FROM outbox_worker_config
-		WHERE enabled = true
+		SELECT w.id, w.user_id, w.worker_name, w.circle, w.application, w.message_type,
+		       w.interval_seconds, w.interval_max_seconds, w.enabled, w.allow_media, w.replace_pending,
+		       COALESCE(cs.default_country, ''), w.webhook_url, w.webhook_secret, w.created_at, w.updated_at
+		FROM outbox_worker_config w
+		LEFT JOIN circle_settings cs ON cs.circle = w.circle
+		WHERE w.enabled = true
 	`
 
 	rows, err := ConfigDB.QueryContext(ctx, query)
@@ -89,6 +96,7 @@ func FetchWorkerConfigs(ctx context.Context) ([]WorkerConfig, error) {
 			&config.Enabled,
 			&config.AllowMedia,
 			&config.ReplacePending,
+			&config.DefaultCountry,
 			&config.WebhookURL,
 			&config.WebhookSecret,
 			&config.CreatedAt,
@@ -218,13 +226,18 @@ func FetchPendingOutbox(ctx context.Context, filter string) (*OutboxMessage, err
 	return &msg, nil
 }
 
-func UpdateOutboxSuccess(ctx context.Context, id int64, fromNumber string) error {
+// UpdateOutboxSuccess menandai outbox terkirim dan menyimpan WhatsApp message ID
+// supaya receipt (delivered/read) dari API bisa di-update ke baris ini.
+func UpdateOutboxSuccess(ctx context.Context, id int64, fromNumber string, waMessageID string) error {
 	query := `
 		UPDATE outbox 
-		SET status = 1, sendingDateTime = NOW(), from_number = $1, msg_error = NULL 
-		WHERE id_outbox = $2
+		SET status = 1, sendingDateTime = NOW(), from_number = $1, msg_error = NULL,
+		    wa_message_id = $2, receipt_status = $3
+		WHERE id_outbox = $4
 	`
-	res, err := OutboxDB.ExecContext(ctx, OutboxSQL(query), fromNumber, id)
+	waID := sql.NullString{String: waMessageID, Valid: waMessageID != ""}
+	receipt := sql.NullString{String: "server_ack", Valid: waMessageID != ""}
+	res, err := OutboxDB.ExecContext(ctx, OutboxSQL(query), fromNumber, waID, receipt, id)
 	if err != nil {
 		return err
 	}
@@ -284,6 +297,31 @@ func LogWorkerEvent(workerID int, workerName, level, message string) {
 	}
 }
 
+// EnqueueWebhookDelivery menyimpan webhook ke tabel webhook_deliveries (ConfigDB / APP_DATABASE_URL).
+// Pengiriman, retry dan dead-letter ditangani oleh webhook dispatcher di API server.
+// instanceID (instance pengirim) membuat delivery bisa dilihat / di-replay lewat
+// /api/instances/:instanceId/webhook-deliveries.
+func EnqueueWebhookDelivery(workerID int, instanceID, application, event, url, payload, signature string) (int64, error) {
+	query := `
+		INSERT INTO webhook_deliveries (worker_id, instance_id, application, event, url, payload, signature, max_attempts)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	maxAttempts := 8
+	if v, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS")); err == nil && v > 0 {
+		maxAttempts = v
+	}
+
+	sig := sql.NullString{String: signature, Valid: signature != ""}
+	inst := sql.NullString{String: instanceID, Valid: instanceID != ""}
+	app := sql.NullString{String: application, Valid: application != ""}
+
+	var id int64
+	err := ConfigDB.QueryRow(ConfigSQL(query), workerID, inst, app, event, url, payload, sig, maxAttempts).Scan(&id)
+	return id, err
+}
+
 // CleanupStaleProcessingOutbox resets any outbox items stuck in status 3 (processing) for more than 5 minutes to status 2 (failed)
 func CleanupStaleProcessingOutbox(ctx context.Context) {
 	query := `
@@ -311,57 +349,99 @@ func CleanupStaleProcessingOutbox(ctx context.Context) {
 	}
 }
 
-// PurgeSupersededPendingOutbox cancels older pending messages (status 0) for the same destination & application, leaving only the latest message
-func PurgeSupersededPendingOutbox(ctx context.Context, filter string) (int64, error) {
+// normalizeBatchSize batas pesan pending tanpa destination_normalized yang dinormalkan per siklus
+const normalizeBatchSize = 500
+
+// NormalizePendingOutboxDestinations mengisi destination_normalized untuk pesan pending yang belum punya
+// (mis. di-insert langsung oleh aplikasi lain, bukan lewat API). Tiap pesan hanya dinormalkan sekali,
+// maksimal normalizeBatchSize per siklus. Nomor yang tidak valid disimpan apa adanya (di-trim).
+func NormalizePendingOutboxDestinations(ctx context.Context, filter string, country string) error {
+	query := "SELECT id_outbox, destination FROM outbox WHERE status = 0 AND destination_normalized IS NULL"
+	if filter != "" {
+		query += fmt.Sprintf(" AND (%s) ", filter)
+	}
+	query += fmt.Sprintf(" ORDER BY id_outbox LIMIT %d", normalizeBatchSize)
+
+	rows, err := OutboxDB.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	normalized := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var destination string
+		if err := rows.Scan(&id, &destination); err != nil {
+			rows.Close()
+			return err
+		}
+		value, err := phone.Normalize(destination, country)
+		if err != nil {
+			value = strings.TrimSpace(destination)
+		}
+		normalized[id] = value
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	update := OutboxSQL("UPDATE outbox SET destination_normalized = $1 WHERE id_outbox = $2 AND destination_normalized IS NULL")
+	for id, value := range normalized {
+		if _, err := OutboxDB.ExecContext(ctx, update, value, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PurgeSupersededPendingOutbox membatalkan pesan pending lama untuk nomor + application yang sama,
+// hanya pesan pending terbaru yang dikirim. Nomor dibandingkan lewat destination_normalized (E.164,
+// diisi API saat enqueue / import atau oleh NormalizePendingOutboxDestinations), jadi 08xx, 628xx
+// dan +628xx dianggap sama. Dedup dijalankan sebagai satu UPDATE di database.
+func PurgeSupersededPendingOutbox(ctx context.Context, filter string, country string) (int64, error) {
+	if err := NormalizePendingOutboxDestinations(ctx, filter, country); err != nil {
+		log.Printf("⚠️ [replace_pending] Worker normalize error: %v", err)
+		return 0, err
+	}
+
+	// Subquery o2 hanya punya kolom dest, app dan max_id, jadi "application" di filter selalu merujuk o1
+	latest := `
+		SELECT COALESCE(destination_normalized, destination) AS dest,
+		       LOWER(COALESCE(application, '')) AS app,
+		       MAX(id_outbox) AS max_id
+		FROM outbox
+		WHERE status = 0
+	`
+	if filter != "" {
+		latest += fmt.Sprintf(" AND (%s) ", filter)
+	}
+	latest += " GROUP BY COALESCE(destination_normalized, destination), LOWER(COALESCE(application, ''))"
+
+	match := `
+		COALESCE(o1.destination_normalized, o1.destination) = o2.dest
+		AND LOWER(COALESCE(o1.application, '')) = o2.app
+	`
+
 	var query string
 	if OutboxDriver == "postgres" {
 		query = `
-			UPDATE outbox
+			UPDATE outbox o1
 			SET status = 2, msg_error = 'Superseded by newer pending message (replace_pending)'
-			WHERE status = 0
-		`
-		if filter != "" {
-			query += fmt.Sprintf(" AND (%s) ", filter)
-		}
-		query += `
-			  AND id_outbox NOT IN (
-				  SELECT MAX(id_outbox)
-				  FROM outbox
-				  WHERE status = 0
-		`
-		if filter != "" {
-			query += fmt.Sprintf(" AND (%s) ", filter)
-		}
-		query += `
-				  GROUP BY destination, application
-			  )
+			FROM (` + latest + `) o2
+			WHERE ` + match + ` AND o1.status = 0 AND o1.id_outbox < o2.max_id
 		`
 	} else {
-		// MySQL query
-		o1Filter := strings.ReplaceAll(filter, "application", "o1.application")
+		// MySQL: derived table o2 di-materialize, sehingga UPDATE ke tabel yang sama diizinkan
 		query = `
 			UPDATE outbox o1
-			JOIN (
-				SELECT destination, application, MAX(id_outbox) AS max_id
-				FROM outbox
-				WHERE status = 0
-		`
-		if filter != "" {
-			query += fmt.Sprintf(" AND (%s) ", filter)
-		}
-		query += `
-				GROUP BY destination, application
-			) o2 ON (
-				o1.destination = o2.destination OR
-				(o1.destination LIKE '0%' AND o2.destination = CONCAT('62', SUBSTRING(o1.destination, 2))) OR
-				(o1.destination LIKE '62%' AND o2.destination = CONCAT('0', SUBSTRING(o1.destination, 3)))
-			) AND LOWER(COALESCE(o1.application, '')) = LOWER(COALESCE(o2.application, ''))
+			JOIN (` + latest + `) o2 ON ` + match + `
 			SET o1.status = 2, o1.msg_error = 'Superseded by newer pending message (replace_pending)'
 			WHERE o1.status = 0 AND o1.id_outbox < o2.max_id
 		`
-		if o1Filter != "" {
-			query += fmt.Sprintf(" AND (%s) ", o1Filter)
-		}
+	}
+	if filter != "" {
+		query += fmt.Sprintf(" AND (%s) ", filter)
 	}
 
 	res, err := OutboxDB.ExecContext(ctx, query)
@@ -369,10 +449,10 @@ func PurgeSupersededPendingOutbox(ctx context.Context, filter string) (int64, er
 		log.Printf("⚠️ [replace_pending] Worker purge error: %v", err)
 		return 0, err
 	}
+	purged, _ := res.RowsAffected()
 
-	rows, _ := res.RowsAffected()
-	if rows > 0 {
-		log.Printf("🧹 [replace_pending] Worker purged %d superseded pending outbox messages (set to status 2)", rows)
+	if purged > 0 {
+		log.Printf("🧹 [replace_pending] Worker purged %d superseded pending outbox messages (set to status 2)", purged)
 	}
-	return rows, nil
+	return purged, nil
 }
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"crypto/hmac"
 	"crypto/sha256"
@@ -9,12 +8,12 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"math/rand"
-	"net/http"
 	"strings"
 	"time"
+
+	"gowa-yourself/internal/phone"
 )
 
 type WorkerInstance struct {
@@ -95,7 +94,7 @@ func (w *WorkerInstance) runCycle() {
 
 	// 1b. If replace_pending is enabled for this worker, purge older superseded pending messages
 	if w.config.ReplacePending {
-		_, _ = PurgeSupersededPendingOutbox(w.ctx, filter)
+		_, _ = PurgeSupersededPendingOutbox(w.ctx, filter, w.config.DefaultCountry)
 	}
 
 	// 2. Claim a pending message (atomicly sets status to 3)
@@ -119,24 +118,14 @@ func (w *WorkerInstance) runCycle() {
 			log.Printf("[%s] Normalized Group ID: %s", w.config.WorkerName, destination)
 		}
 	} else {
-		// Direct Message normalization
-		cleaned := strings.Map(func(r rune) rune {
-			if r >= '0' && r <= '9' {
-				return r
-			}
-			return -1
-		}, destination)
-
-		if strings.HasPrefix(cleaned, "0") {
-			cleaned = "62" + cleaned[1:]
-		}
-
-		if !strings.HasPrefix(cleaned, "62") || len(cleaned) < 10 {
-			log.Printf("[%s] Invalid phone number format: %s", w.config.WorkerName, destination)
-			UpdateOutboxFailed(w.ctx, msg.ID, "Invalid phone number format")
+		// Direct Message normalization (E.164, default country dari circle_settings)
+		normalized, err := phone.Normalize(destination, w.config.DefaultCountry)
+		if err != nil {
+			log.Printf("[%s] Invalid phone number format: %s (%v)", w.config.WorkerName, destination, err)
+			UpdateOutboxFailed(w.ctx, msg.ID, fmt.Sprintf("Invalid phone number format: %v", err))
 			return
 		}
-		destination = cleaned
+		destination = normalized
 	}
 
 	// 3. Get Instances for this Circle
@@ -164,20 +153,21 @@ func (w *WorkerInstance) runCycle() {
 	// 5. Send Message
 	var success bool
 	var apiMsg string
+	var waMessageID string
 
 	if w.config.AllowMedia && msg.File.Valid && msg.File.String != "" {
 		// Media Message (File with Caption from Messages)
 		if w.config.MessageType == "group" {
-			success, apiMsg, err = w.client.SendGroupMediaURL(selectedInstance.InstanceID, destination, msg.File.String, msg.Messages)
+			success, apiMsg, waMessageID, err = w.client.SendGroupMediaURL(selectedInstance.InstanceID, destination, msg.File.String, msg.Messages)
 		} else {
-			success, apiMsg, err = w.client.SendMediaURL(selectedInstance.InstanceID, destination, msg.File.String, msg.Messages)
+			success, apiMsg, waMessageID, err = w.client.SendMediaURL(selectedInstance.InstanceID, destination, msg.File.String, msg.Messages)
 		}
 	} else {
 		// Text Message
 		if w.config.MessageType == "group" {
-			success, apiMsg, err = w.client.SendGroupMessage(selectedInstance.InstanceID, destination, msg.Messages)
+			success, apiMsg, waMessageID, err = w.client.SendGroupMessage(selectedInstance.InstanceID, destination, msg.Messages)
 		} else {
-			success, apiMsg, err = w.client.SendMessage(selectedInstance.InstanceID, destination, msg.Messages)
+			success, apiMsg, waMessageID, err = w.client.SendMessage(selectedInstance.InstanceID, destination, msg.Messages)
 		}
 	}
 
@@ -191,12 +181,12 @@ func (w *WorkerInstance) runCycle() {
 
 	if success {
 		log.Printf("[%s] Success! Sent ID %d via instance %s (%s)", w.config.WorkerName, msg.ID, selectedInstance.InstanceID, selectedInstance.PhoneNumber)
-		if err := UpdateOutboxSuccess(w.ctx, msg.ID, selectedInstance.PhoneNumber); err != nil {
+		if err := UpdateOutboxSuccess(w.ctx, msg.ID, selectedInstance.PhoneNumber, waMessageID); err != nil {
 			log.Printf("[%s] CRITICAL: Failed to update status to success for ID %d: %v", w.config.WorkerName, msg.ID, err)
 		}
 
 		// Trigger Webhook
-		go w.sendWebhook(msg, 1, "success", selectedInstance.PhoneNumber, "")
+		go w.sendWebhook(msg, 1, "success", selectedInstance.InstanceID, selectedInstance.PhoneNumber, "")
 
 		// Optional: delay after success to prevent mass-ban
 		time.Sleep(time.Duration(rand.Intn(2)+1) * time.Second)
@@ -212,11 +202,11 @@ func (w *WorkerInstance) runCycle() {
 		}
 
 		// Trigger Webhook
-		go w.sendWebhook(msg, 2, "failed", "", apiMsg)
+		go w.sendWebhook(msg, 2, "failed", selectedInstance.InstanceID, "", apiMsg)
 	}
 }
 
-func (w *WorkerInstance) sendWebhook(msg *OutboxMessage, status int, statusText string, fromNumber string, errorMsg string) {
+func (w *WorkerInstance) sendWebhook(msg *OutboxMessage, status int, statusText string, instanceID, fromNumber string, errorMsg string) {
 	webhookURL := strings.TrimSpace(w.config.WebhookURL.String)
 	if webhookURL == "" {
 		return
@@ -243,39 +233,26 @@ func (w *WorkerInstance) sendWebhook(msg *OutboxMessage, status int, statusText
 		return
 	}
 
-	// Log webhook details for debugging
-	log.Printf("[%s] Preparing webhook to URL: %s", w.config.WorkerName, webhookURL)
-	log.Printf("[%s] Webhook payload: %s", w.config.WorkerName, string(body))
-
-	req, err := http.NewRequest("POST", webhookURL, bytes.NewReader(body))
-	if err != nil {
-		log.Printf("[%s] Webhook request error: %v", w.config.WorkerName, err)
-		return
-	}
-	req.Header.Set("Content-Type", "application/json")
-
 	// Add HMAC signature if secret is provided
-	secret := w.config.WebhookSecret.String
-	if secret != "" {
+	var signature string
+	if secret := w.config.WebhookSecret.String; secret != "" {
 		mac := hmac.New(sha256.New, []byte(secret))
 		mac.Write(body)
-		signature := hex.EncodeToString(mac.Sum(nil))
-		req.Header.Set("X-SUDEVWA-Signature", signature)
-		log.Printf("[%s] Webhook signature generated: %s", w.config.WorkerName, signature)
+		signature = hex.EncodeToString(mac.Sum(nil))
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	// Webhook tidak dikirim langsung dari worker: masuk ke webhook_deliveries dan dikirim
+	// oleh webhook dispatcher di API server (retry + dead-letter + replay).
+	application := msg.Application
+	if application == "" {
+		application = w.config.Application
+	}
+	id, err := EnqueueWebhookDelivery(w.config.ID, instanceID, application, "outbox.processed", webhookURL, string(body), signature)
 	if err != nil {
-		log.Printf("[%s] Webhook send error: %v", w.config.WorkerName, err)
+		log.Printf("[%s] Failed to enqueue webhook for outbox ID %d: %v", w.config.WorkerName, msg.ID, err)
+		LogWorkerEvent(w.config.ID, w.config.WorkerName, "ERROR", fmt.Sprintf("Failed to enqueue webhook: %v", err))
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(resp.Body)
-		log.Printf("[%s] Webhook returned error status %d: %s", w.config.WorkerName, resp.StatusCode, string(respBody))
-	} else {
-		log.Printf("[%s] Webhook sent successfully to %s", w.config.WorkerName, webhookURL)
-	}
+	log.Printf("[%s] Webhook for outbox ID %d queued (delivery %d)", w.config.WorkerName, msg.ID, id)
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"time"
 )
 
 var EnableWebsocketIncomingMessage bool
@@ -19,6 +20,15 @@ var AIConversationHistoryLimit int
 var AIDefaultTemperature float64
 var AIDefaultMaxTokens int
 
+// Cluster (beberapa replica API berbagi instance lewat lease di APP DB)
+var ClusterEnabled bool
+var ClusterNodeID string
+var ClusterAdvertiseURL string // base URL node ini untuk proxy / redirect dari replica lain
+var ClusterLeaseTTL time.Duration
+var ClusterHeartbeatInterval time.Duration
+var ClusterForwardMode string // "proxy" atau "redirect"
+var ClusterSecret string      // key HMAC untuk request yang diteruskan antar replica (kosong = turunan JWT_SECRET)
+
 type Config struct {
 	Port               string
 	DBConnectionString string
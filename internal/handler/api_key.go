@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// APIKeyRequest is the body for POST /api/api-keys
+type APIKeyRequest struct {
+	Name          string     `json:"name"`
+	Scopes        []string   `json:"scopes"`
+	InstanceIDs   []string   `json:"instanceIds"` // kosong = semua instance milik user
+	ExpiresAt     *time.Time `json:"expiresAt"`
+	ExpiresInDays int        `json:"expiresInDays"`
+}
+
+// validateAPIKeyRequest memvalidasi scope, allow-list instance (harus instance milik user) dan expiry
+func validateAPIKeyRequest(claims *service.Claims, req *APIKeyRequest) *apiError {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return &apiError{http.StatusBadRequest, "Field 'name' is required", "VALIDATION_ERROR", ""}
+	}
+
+	if len(req.Scopes) == 0 {
+		return &apiError{http.StatusBadRequest, "At least one scope is required", "VALIDATION_ERROR",
+			"Allowed scopes: " + strings.Join(model.APIKeyScopes, ", ")}
+	}
+	for _, s := range req.Scopes {
+		if !model.IsValidAPIKeyScope(s) {
+			return &apiError{http.StatusBadRequest, "Unknown scope: " + s, "INVALID_SCOPE",
+				"Allowed scopes: " + strings.Join(model.APIKeyScopes, ", ")}
+		}
+	}
+
+	for _, id := range req.InstanceIDs {
+		if _, err := model.GetInstanceByInstanceID(id); err != nil {
+			return &apiError{http.StatusNotFound, "Instance not found: " + id, "INSTANCE_NOT_FOUND", ""}
+		}
+		if claims.Role != "admin" {
+			if _, err := model.CheckUserInstancePermission(claims.UserID, id); err != nil {
+				return &apiError{http.StatusForbidden, "You do not have access to instance " + id, "FORBIDDEN", ""}
+			}
+		}
+	}
+
+	if req.ExpiresAt == nil && req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		req.ExpiresAt = &expiresAt
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return &apiError{http.StatusBadRequest, "expiresAt must be in the future", "VALIDATION_ERROR", ""}
+	}
+
+	return nil
+}
+
+// logAPIKeyAction mencatat pembuatan / pencabutan API key ke audit_logs
+func logAPIKeyAction(c echo.Context, claims *service.Claims, action string, key *model.APIKey) {
+	_ = model.LogAction(&model.AuditLog{
+		UserID:       sql.NullInt64{Int64: claims.UserID, Valid: true},
+		Action:       action,
+		ResourceType: sql.NullString{String: "api_key", Valid: true},
+		ResourceID:   sql.NullString{String: strconv.FormatInt(key.ID, 10), Valid: true},
+		Details: map[string]interface{}{
+			"prefix":       key.Prefix,
+			"name":         key.Name,
+			"owner_id":     key.UserID,
+			"scopes":       key.Scopes,
+			"instance_ids": key.InstanceIDs,
+			"performed_by": claims.Username,
+		},
+		IPAddress: sql.NullString{String: c.RealIP(), Valid: true},
+		UserAgent: sql.NullString{String: c.Request().UserAgent(), Valid: true},
+	})
+}
+
+// GET /api/api-keys (admin: ?all=true untuk semua user)
+func GetAPIKeys(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	userID := claims.UserID
+	if claims.Role == "admin" && c.QueryParam("all") == "true" {
+		userID = 0
+	}
+
+	keys, err := model.GetAPIKeys(userID)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve API keys", "INTERNAL_ERROR", err.Error())
+	}
+
+	responses := make([]model.APIKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		responses = append(responses, model.ToAPIKeyResponse(k))
+	}
+
+	return SuccessResponse(c, http.StatusOK, "API keys retrieved successfully", responses)
+}
+
+// POST /api/api-keys
+// Key plaintext hanya dikembalikan sekali di response ini.
+func CreateAPIKey(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	var req APIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+
+	if verr := validateAPIKeyRequest(claims, &req); verr != nil {
+		return verr.send(c)
+	}
+
+	key := model.APIKey{
+		UserID:      claims.UserID,
+		Name:        req.Name,
+		Scopes:      req.Scopes,
+		InstanceIDs: req.InstanceIDs,
+	}
+	if req.ExpiresAt != nil {
+		key.ExpiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	plaintext, err := service.GenerateAPIKey(&key)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to create API key", "INTERNAL_ERROR", err.Error())
+	}
+
+	logAPIKeyAction(c, claims, "api_key.create", &key)
+
+	return SuccessResponse(c, http.StatusCreated, "API key created successfully. Store it now, it will not be shown again.", map[string]interface{}{
+		"key":    plaintext,
+		"apiKey": model.ToAPIKeyResponse(key),
+	})
+}
+
+// DELETE /api/api-keys/:id (revoke, pemilik atau admin)
+func RevokeAPIKey(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid API key ID", "BAD_REQUEST", "")
+	}
+
+	key, err := model.GetAPIKeyByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrorResponse(c, http.StatusNotFound, "API key not found", "NOT_FOUND", "")
+		}
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve API key", "INTERNAL_ERROR", err.Error())
+	}
+
+	if claims.Role != "admin" && key.UserID != claims.UserID {
+		return ErrorResponse(c, http.StatusForbidden, "Access denied", "FORBIDDEN", "")
+	}
+
+	if err := model.RevokeAPIKey(key.ID); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke API key", "INTERNAL_ERROR", err.Error())
+	}
+
+	logAPIKeyAction(c, claims, "api_key.revoke", key)
+
+	return SuccessResponse(c, http.StatusOK, "API key revoked successfully", nil)
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"gowa-yourself/internal/helper"
+
+	"github.com/labstack/echo/v4"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+// Audio dikirim via multipart form:
+//   file      : mp3 / wav / m4a / ogg / opus (atau 'audioUrl' untuk ambil dari URL)
+//   to        : nomor tujuan (route personal) / groupJid (route grup)
+//   ptt       : true = kirim sebagai voice note (transcode ke OGG/Opus + waveform)
+
+// POST /send/:instanceId/audio
+func SendAudio(c echo.Context) error {
+	return sendAudioMessage(c, scopeInstance)
+}
+
+// POST /by-number/:phoneNumber/audio
+func SendAudioByNumber(c echo.Context) error {
+	return sendAudioMessage(c, scopeNumber)
+}
+
+// POST /send-group/:instanceId/audio
+func SendGroupAudio(c echo.Context) error {
+	return sendAudioMessage(c, scopeGroup)
+}
+
+// POST /send-group/by-number/:phoneNumber/audio
+func SendGroupAudioByNumber(c echo.Context) error {
+	return sendAudioMessage(c, scopeGroupByNumber)
+}
+
+func sendAudioMessage(c echo.Context, scope richScope) error {
+	target := RichTarget{To: c.FormValue("to"), GroupJID: c.FormValue("groupJid")}
+
+	ptt := false
+	if v := c.FormValue("ptt"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return ErrorResponse(c, 400, "Field 'ptt' must be true or false", "VALIDATION_ERROR", "")
+		}
+		ptt = parsed
+	}
+
+	chat, apiErr := resolveRichChat(c, scope, target)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+	chat.recording = ptt
+
+	data, filename, apiErr := readAudioInput(c)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	audio, err := helper.ProcessAudio(data, filename, ptt)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to process audio", "TRANSCODE_FAILED", err.Error())
+	}
+
+	uploaded, err := chat.Session.Client.Upload(context.Background(), audio.Data, whatsmeow.MediaAudio)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to upload media", "UPLOAD_FAILED",
+			fmt.Sprintf("Type: audio, Size: %d bytes, Error: %v", len(audio.Data), err))
+	}
+
+	audioMsg := &waE2E.AudioMessage{
+		URL:           proto.String(uploaded.URL),
+		DirectPath:    proto.String(uploaded.DirectPath),
+		MediaKey:      uploaded.MediaKey,
+		Mimetype:      proto.String(audio.Mimetype),
+		FileEncSHA256: uploaded.FileEncSHA256,
+		FileSHA256:    uploaded.FileSHA256,
+		FileLength:    proto.Uint64(uploaded.FileLength),
+		PTT:           proto.Bool(ptt),
+		Waveform:      audio.Waveform,
+	}
+	if audio.Seconds > 0 {
+		audioMsg.Seconds = proto.Uint32(audio.Seconds)
+	}
+
+	return deliverRichMessage(c, scope, target, chat, &waE2E.Message{AudioMessage: audioMsg}, map[string]interface{}{
+		"mediaType": "audio",
+		"ptt":       ptt,
+		"seconds":   audio.Seconds,
+		"fileName":  filename,
+		"fileSize":  len(audio.Data),
+	})
+}
+
+// readAudioInput membaca audio dari field 'file' (upload) atau 'audioUrl', lalu validasi format & ukuran
+func readAudioInput(c echo.Context) ([]byte, string, *apiError) {
+	var data []byte
+	var filename string
+
+	if audioURL := c.FormValue("audioUrl"); audioURL != "" {
+		downloaded, name, err := helper.DownloadFile(audioURL)
+		if err != nil {
+			return nil, "", &apiError{400, "Failed to download audio", "DOWNLOAD_FAILED", err.Error()}
+		}
+		data, filename = downloaded, name
+	} else {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", &apiError{400, "File is required", "FILE_REQUIRED", "Upload 'file' or set 'audioUrl'"}
+		}
+
+		src, err := file.Open()
+		if err != nil {
+			return nil, "", &apiError{500, "Failed to open file", "FILE_OPEN_FAILED", err.Error()}
+		}
+		defer src.Close()
+
+		data, err = io.ReadAll(src)
+		if err != nil {
+			return nil, "", &apiError{500, "Failed to read file", "FILE_READ_FAILED", err.Error()}
+		}
+		filename = file.Filename
+	}
+
+	if !helper.IsSupportedAudio(filename) {
+		return nil, "", &apiError{400, "Unsupported audio format", "INVALID_AUDIO_FORMAT", "Allowed: mp3, wav, m4a, ogg, opus"}
+	}
+
+	maxSize := getMaxFileSize("audio")
+	if len(data) > maxSize {
+		return nil, "", &apiError{400, "File too large", "FILE_TOO_LARGE",
+			fmt.Sprintf("File size: %d bytes, Max: %d bytes (audio)", len(data), maxSize)}
+	}
+
+	return data, filename, nil
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/phone"
 	"gowa-yourself/internal/service"
 	"gowa-yourself/internal/ws"
 
@@ -98,7 +99,7 @@ func Login(c echo.Context) error {
 
 	// Assign initial access if user is logged in
 	if createdBy.Valid {
-		err = model.AssignInstanceToUser(createdBy.Int64, instanceID, "access")
+		err = model.AssignInstanceToUser(createdBy.Int64, instanceID, model.PermissionOwner)
 		if err != nil {
 			log.Printf("⚠️ Warning: Failed to assign access for instance %s to user %d: %v", instanceID, createdBy.Int64, err)
 		}
@@ -116,12 +117,8 @@ func GetQR(c echo.Context) error {
 
 	instanceID := c.Param("instanceId")
 
-	// Cek apakah sudah ada QR generation yang sedang berjalan
-	qrCancelMutex.RLock()
-	_, exists := qrCancelFuncs[instanceID]
-	qrCancelMutex.RUnlock()
-
-	if exists {
+	// Cek apakah sudah ada QR generation / pairing code yang sedang berjalan
+	if loginInProgress(instanceID) {
 		return ErrorResponse(c, 409, "QR generation already in progress, please wait", "QR_IN_PROGRESS", "Please wait or cancel the current QR generation first.")
 	}
 
@@ -145,23 +142,16 @@ func GetQR(c echo.Context) error {
 		})
 	}
 
-	// Buat context dengan timeout 3 menit
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
-
-	// Simpan cancel function
-	qrCancelMutex.Lock()
-	qrCancelFuncs[instanceID] = cancel
-	qrCancelMutex.Unlock()
+	// Buat context dengan timeout 3 menit dan simpan cancel function
+	ctx, done, ok := beginLoginAttempt(instanceID)
+	if !ok {
+		return ErrorResponse(c, 409, "QR generation already in progress, please wait", "QR_IN_PROGRESS", "Please wait or cancel the current QR generation first.")
+	}
 
 	// Jalankan QR generation di goroutine (background process)
 	go func() {
 		// Cleanup setelah selesai
-		defer func() {
-			qrCancelMutex.Lock()
-			delete(qrCancelFuncs, instanceID)
-			qrCancelMutex.Unlock()
-			cancel()
-		}()
+		defer done()
 
 		// Get QR channel dengan context
 		qrChan, err := session.Client.GetQRChannel(ctx)
@@ -336,6 +326,36 @@ func GetQR(c echo.Context) error {
 	})
 }
 
+// loginInProgress mengecek apakah instance sedang generate QR / pairing code
+func loginInProgress(instanceID string) bool {
+	qrCancelMutex.RLock()
+	defer qrCancelMutex.RUnlock()
+	_, exists := qrCancelFuncs[instanceID]
+	return exists
+}
+
+// beginLoginAttempt mendaftarkan proses login (QR atau pairing code) dengan timeout 3 menit.
+// ok=false jika sudah ada proses login lain untuk instance ini. done wajib dipanggil setelah selesai.
+func beginLoginAttempt(instanceID string) (ctx context.Context, done func(), ok bool) {
+	qrCancelMutex.Lock()
+	defer qrCancelMutex.Unlock()
+
+	if _, exists := qrCancelFuncs[instanceID]; exists {
+		return nil, nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	qrCancelFuncs[instanceID] = cancel
+
+	done = func() {
+		qrCancelMutex.Lock()
+		delete(qrCancelFuncs, instanceID)
+		qrCancelMutex.Unlock()
+		cancel()
+	}
+	return ctx, done, true
+}
+
 // DELETE /qr/:instanceId - Cancel QR generation
 func CancelQR(c echo.Context) error {
 	instanceID := c.Param("instanceId")
@@ -525,8 +545,16 @@ func UpdateInstanceFields(c echo.Context) error {
 	}
 
 	// Validate at least one field is provided
-	if req.Used == nil && req.Keterangan == nil && req.Circle == nil {
-		return ErrorResponse(c, http.StatusBadRequest, "At least one field (used, keterangan, or circle) must be provided", "NO_FIELDS", "")
+	if req.Used == nil && req.Keterangan == nil && req.Circle == nil && req.DefaultCountry == nil {
+		return ErrorResponse(c, http.StatusBadRequest, "At least one field (used, keterangan, circle, or defaultCountry) must be provided", "NO_FIELDS", "")
+	}
+
+	if req.DefaultCountry != nil && *req.DefaultCountry != "" {
+		country := strings.ToUpper(*req.DefaultCountry)
+		if !phone.IsSupportedRegion(country) {
+			return ErrorResponse(c, http.StatusBadRequest, "Unsupported defaultCountry", "INVALID_COUNTRY", "Use an ISO 3166-1 alpha-2 code, e.g. ID, MY, SG")
+		}
+		req.DefaultCountry = &country
 	}
 
 	err := model.UpdateInstanceFields(instanceID, &req)
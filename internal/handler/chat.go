@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gowa-yourself/internal/model"
+
+	"github.com/labstack/echo/v4"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// GetChats handles GET /api/chats/:instanceId
+// Query: limit (default 50, max 200), cursor (nextCursor dari response sebelumnya)
+func GetChats(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	limit := parseChatLimit(c.QueryParam("limit"), 50)
+
+	var before *time.Time
+	var beforeJID string
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		t, jid, err := decodeChatCursor(cursor)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "Invalid cursor", "INVALID_CURSOR", err.Error())
+		}
+		before = &t
+		beforeJID = jid
+	}
+
+	chats, err := model.GetChatList(instanceID, before, beforeJID, limit)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to get chats", "GET_FAILED", err.Error())
+	}
+
+	nextCursor := ""
+	if len(chats) == limit {
+		last := chats[len(chats)-1]
+		nextCursor = fmt.Sprintf("%d_%s", last.LastTimestamp.UnixNano(), last.ChatJID)
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Chats retrieved successfully", map[string]interface{}{
+		"instanceId": instanceID,
+		"total":      len(chats),
+		"chats":      chats,
+		"nextCursor": nextCursor,
+	})
+}
+
+// GetChatMessages handles GET /api/chats/:instanceId/:jid/messages
+// Query: limit (default 50, max 200), cursor (nextCursor dari response sebelumnya)
+func GetChatMessages(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	chatJID, err := types.ParseJID(c.Param("jid"))
+	if err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid JID format", "INVALID_JID", err.Error())
+	}
+
+	limit := parseChatLimit(c.QueryParam("limit"), 50)
+
+	var beforeTime *time.Time
+	var beforeID int64
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		t, id, err := decodeMessageCursor(cursor)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "Invalid cursor", "INVALID_CURSOR", err.Error())
+		}
+		beforeTime = &t
+		beforeID = id
+	}
+
+	messages, err := model.GetChatMessages(instanceID, chatJID.ToNonAD().String(), beforeTime, beforeID, limit)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to get messages", "GET_FAILED", err.Error())
+	}
+
+	responses := make([]model.MessageResponse, 0, len(messages))
+	for _, m := range messages {
+		responses = append(responses, model.ToMessageResponse(m))
+	}
+
+	nextCursor := ""
+	if len(messages) == limit {
+		last := messages[len(messages)-1]
+		nextCursor = fmt.Sprintf("%d_%d", last.MessageTimestamp.UnixNano(), last.ID)
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Messages retrieved successfully", map[string]interface{}{
+		"instanceId": instanceID,
+		"chatJid":    chatJID.ToNonAD().String(),
+		"total":      len(responses),
+		"messages":   responses,
+		"nextCursor": nextCursor,
+	})
+}
+
+// parseChatLimit membaca query limit dengan default dan batas maksimum 200
+func parseChatLimit(raw string, fallback int) int {
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 {
+		return fallback
+	}
+	if limit > 200 {
+		return 200
+	}
+	return limit
+}
+
+// decodeChatCursor memecah cursor "<unixNano>_<chatJid>".
+// Cursor lama tanpa chatJid ditolak: tanpa jid, chat lain dengan timestamp yang sama akan terlewat.
+func decodeChatCursor(cursor string) (time.Time, string, error) {
+	raw, jid, ok := strings.Cut(cursor, "_")
+	if !ok || jid == "" {
+		return time.Time{}, "", fmt.Errorf("cursor must be in format <timestamp>_<chatJid>")
+	}
+
+	nanos, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("invalid cursor timestamp: %w", err)
+	}
+
+	return time.Unix(0, nanos), jid, nil
+}
+
+// decodeMessageCursor memecah cursor "<unixNano>_<id>"
+func decodeMessageCursor(cursor string) (time.Time, int64, error) {
+	parts := strings.SplitN(cursor, "_", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, fmt.Errorf("cursor must be in format <timestamp>_<id>")
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor timestamp: %w", err)
+	}
+
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor id: %w", err)
+	}
+
+	return time.Unix(0, nanos), id, nil
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/phone"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CircleSettingRequest is the body of PUT /circles/:circle/settings
+type CircleSettingRequest struct {
+	DefaultCountry string `json:"defaultCountry"`
+}
+
+// GET /circles/settings
+func GetCircleSettings(c echo.Context) error {
+	settings, err := model.GetCircleSettings()
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve circle settings", "INTERNAL_ERROR", err.Error())
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Circle settings retrieved successfully", map[string]interface{}{
+		"globalDefaultCountry": phone.DefaultRegion(),
+		"circles":              settings,
+	})
+}
+
+// PUT /circles/:circle/settings (admin only)
+func UpdateCircleSetting(c echo.Context) error {
+	circle := c.Param("circle")
+
+	var req CircleSettingRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+
+	country := strings.ToUpper(strings.TrimSpace(req.DefaultCountry))
+	if !phone.IsSupportedRegion(country) {
+		return ErrorResponse(c, http.StatusBadRequest, "Unsupported defaultCountry", "INVALID_COUNTRY", "Use an ISO 3166-1 alpha-2 code, e.g. ID, MY, SG")
+	}
+
+	setting, err := model.UpsertCircleSetting(circle, country)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to save circle setting", "INTERNAL_ERROR", err.Error())
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Circle setting saved successfully", setting)
+}
+
+// DELETE /circles/:circle/settings (admin only)
+func DeleteCircleSetting(c echo.Context) error {
+	if err := model.DeleteCircleSetting(c.Param("circle")); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrorResponse(c, http.StatusNotFound, "Circle setting not found", "NOT_FOUND", "")
+		}
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to delete circle setting", "INTERNAL_ERROR", err.Error())
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Circle setting deleted successfully", nil)
+}
+
+// GET /phone/countries - negara yang dikenal untuk normalisasi nomor
+func GetPhoneCountries(c echo.Context) error {
+	list := []map[string]interface{}{}
+	for _, country := range phone.Countries() {
+		list = append(list, map[string]interface{}{
+			"region":      country.Region,
+			"name":        country.Name,
+			"callingCode": country.CallingCode,
+		})
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Phone countries retrieved successfully", list)
+}
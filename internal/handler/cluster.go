@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+
+	"gowa-yourself/config"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GET /cluster (admin only)
+// Status cluster: node yang hidup, jumlah instance per node, dan lease yang berlaku
+func GetClusterStatus(c echo.Context) error {
+	if !config.ClusterEnabled {
+		return SuccessResponse(c, http.StatusOK, "Cluster mode disabled", map[string]interface{}{
+			"enabled":       false,
+			"nodeId":        config.ClusterNodeID,
+			"localSessions": len(service.GetAllSessions()),
+		})
+	}
+
+	nodes, err := model.GetLiveClusterNodes(config.ClusterLeaseTTL)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve cluster nodes", "INTERNAL_ERROR", err.Error())
+	}
+
+	leases, err := model.GetAllInstanceLeases()
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve instance leases", "INTERNAL_ERROR", err.Error())
+	}
+
+	perNode := make(map[string]int, len(nodes))
+	for _, l := range leases {
+		perNode[l.NodeID]++
+	}
+
+	nodeList := make([]map[string]interface{}, 0, len(nodes))
+	for _, n := range nodes {
+		nodeList = append(nodeList, map[string]interface{}{
+			"nodeId":        n.NodeID,
+			"advertiseUrl":  n.AdvertiseURL,
+			"startedAt":     n.StartedAt,
+			"heartbeatAt":   n.HeartbeatAt,
+			"instanceCount": perNode[n.NodeID],
+			"self":          n.NodeID == config.ClusterNodeID,
+		})
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Cluster status retrieved successfully", map[string]interface{}{
+		"enabled":       true,
+		"nodeId":        config.ClusterNodeID,
+		"forwardMode":   config.ClusterForwardMode,
+		"leaseTtl":      config.ClusterLeaseTTL.String(),
+		"localSessions": len(service.GetAllSessions()),
+		"nodes":         nodeList,
+		"leases":        leases,
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
 	"gowa-yourself/internal/service"
 
 	"github.com/labstack/echo/v4"
@@ -58,7 +59,7 @@ func CheckNumber(c echo.Context) error {
 	}
 
 	// Import helper package for phone number formatting
-	recipient, err := helper.FormatPhoneNumber(req.Phone)
+	recipient, err := helper.FormatPhoneNumber(req.Phone, model.GetInstanceDefaultCountry(instanceID))
 	if err != nil {
 		return ErrorResponse(c, 400, "Invalid phone number", "INVALID_PHONE", err.Error())
 	}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+	"gowa-yourself/internal/ws"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Heartbeat SSE: komentar kosong supaya proxy tidak menutup koneksi yang idle
+const (
+	sseHeartbeatInterval = 25 * time.Second
+	sseRetryMillis       = 3000
+)
+
+// EventStream mengirim event Hub sebagai Server-Sent Events (alternatif WebSocket di belakang proxy
+// yang tidak mendukung upgrade).
+// GET /api/events/stream?topics=instance:<id>,warming:<roomId>,outbox:<app>
+// GET /api/events/stream/:instanceId  (event instance tsb, termasuk incoming_message)
+// Filter akses sama dengan /ws; id setiap event adalah seq, jadi Last-Event-ID dipakai untuk resume.
+func EventStream(hub *ws.Hub) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		claims, ok := c.Get("user_claims").(*service.Claims)
+		if !ok || claims == nil {
+			return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+		}
+
+		instanceID := c.Param("instanceId")
+
+		client := ws.NewStreamClient(hub)
+		client.UserID = claims.UserID
+		client.Role = claims.Role
+		client.InstanceID = instanceID
+
+		if err := hub.LoadAccess(client); err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "Failed to load access", "ACCESS_LOAD_FAILED", err.Error())
+		}
+		if key, ok := c.Get("api_key").(*model.APIKey); ok && len(key.InstanceIDs) > 0 {
+			client.RestrictInstances(key.InstanceIDs)
+		}
+
+		var topics []string
+		if instanceID != "" {
+			topics = append(topics, ws.TopicInstancePrefix+instanceID)
+		}
+		for _, topic := range strings.Split(c.QueryParam("topics"), ",") {
+			if topic = strings.TrimSpace(topic); topic != "" {
+				topics = append(topics, topic)
+			}
+		}
+		if rejected := client.SetTopics(topics); len(rejected) > 0 {
+			return ErrorResponse(c, http.StatusForbidden, "Invalid or forbidden topics", "TOPIC_FORBIDDEN", strings.Join(rejected, ", "))
+		}
+
+		// Browser mengirim Last-Event-ID otomatis saat reconnect; ?last_event_id= untuk koneksi pertama
+		lastEventID := c.Request().Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = c.QueryParam("last_event_id")
+		}
+		if lastEventID != "" {
+			seq, err := strconv.ParseUint(strings.TrimSpace(lastEventID), 10, 64)
+			if err != nil {
+				return ErrorResponse(c, http.StatusBadRequest, "Invalid Last-Event-ID", "INVALID_LAST_EVENT_ID", "Last-Event-ID must be the seq of the last received event")
+			}
+			client.ResumeFrom(seq)
+		}
+
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set("Cache-Control", "no-cache")
+		res.Header().Set("Connection", "keep-alive")
+		res.Header().Set("X-Accel-Buffering", "no") // nginx: jangan buffer stream
+		res.WriteHeader(http.StatusOK)
+		fmt.Fprintf(res, "retry: %d\n\n", sseRetryMillis)
+		res.Flush()
+
+		hub.Register(client)
+		defer hub.Unregister(client)
+
+		heartbeat := time.NewTicker(sseHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		ctx := c.Request().Context()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+
+			case event, ok := <-client.Events():
+				if !ok {
+					// Diputus hub (buffer penuh); client reconnect dengan Last-Event-ID
+					return nil
+				}
+				if err := writeServerSentEvent(res, event); err != nil {
+					log.Printf("sse: failed to write event for user %d: %v", claims.UserID, err)
+					return nil
+				}
+
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+					return nil
+				}
+				res.Flush()
+			}
+		}
+	}
+}
+
+// writeServerSentEvent menulis satu event: id = seq (kosong untuk WS_ACK / WS_ERROR), event = nama event,
+// data = envelope JSON yang sama dengan WebSocket
+func writeServerSentEvent(res *echo.Response, event ws.WsEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("sse: failed to marshal event: %v", err)
+		return nil
+	}
+
+	var b strings.Builder
+	if event.Seq > 0 {
+		b.WriteString("id: " + strconv.FormatUint(event.Seq, 10) + "\n")
+	}
+	b.WriteString("event: " + event.Event + "\n")
+	b.WriteString("data: " + string(payload) + "\n\n")
+
+	if _, err := res.Write([]byte(b.String())); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
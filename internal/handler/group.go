@@ -146,6 +146,7 @@ func SendGroupMessage(c echo.Context) error {
 	}
 	// Increment daily message count
 	_ = model.IncrementMessageCount(instanceID)
+	service.RecordOutgoingMessage(instanceID, groupJID, msg, resp)
 
 
 	return SuccessResponse(c, 200, "Message sent to group", map[string]interface{}{
@@ -269,6 +270,7 @@ func SendGroupMedia(c echo.Context) error {
 	}
 	// Increment daily message count
 	_ = model.IncrementMessageCount(instanceID)
+	service.RecordOutgoingMessage(instanceID, groupJID, msg, resp)
 
 
 	return SuccessResponse(c, 200, "Media sent to group", map[string]interface{}{
@@ -393,6 +395,7 @@ func SendGroupMediaURL(c echo.Context) error {
 	}
 	// Increment daily message count
 	_ = model.IncrementMessageCount(instanceID)
+	service.RecordOutgoingMessage(instanceID, groupJID, msg, resp)
 
 
 	return SuccessResponse(c, 200, "Media sent to group", map[string]interface{}{
@@ -420,7 +423,7 @@ func GetGroupsByNumber(c echo.Context) error {
 	// Permission Check
 	userClaims, _ := c.Get("user_claims").(*service.Claims)
 	if userClaims != nil && userClaims.Role != "admin" {
-		_, err := model.CheckUserInstancePermission(userClaims.UserID, inst.InstanceID)
+		err := model.CheckUserInstanceLevel(userClaims.UserID, userClaims.Role, inst.InstanceID, model.PermissionRead)
 		if err != nil {
 			return ErrorResponse(c, 403, "Insufficient permission to use this phone number", "FORBIDDEN", "")
 		}
@@ -488,7 +491,7 @@ func SendGroupMessageByNumber(c echo.Context) error {
 	// Permission Check
 	userClaims, _ := c.Get("user_claims").(*service.Claims)
 	if userClaims != nil && userClaims.Role != "admin" {
-		_, err := model.CheckUserInstancePermission(userClaims.UserID, inst.InstanceID)
+		err := model.CheckUserInstanceLevel(userClaims.UserID, userClaims.Role, inst.InstanceID, model.PermissionSend)
 		if err != nil {
 			return ErrorResponse(c, 403, "Insufficient permission to use this phone number", "FORBIDDEN", "")
 		}
@@ -561,6 +564,7 @@ func SendGroupMessageByNumber(c echo.Context) error {
 	}
 	// Increment daily message count
 	_ = model.IncrementMessageCount(inst.InstanceID)
+	service.RecordOutgoingMessage(inst.InstanceID, groupJID, msg, resp)
 
 
 	return SuccessResponse(c, 200, "Message sent to group", map[string]interface{}{
@@ -594,7 +598,7 @@ func SendGroupMediaByNumber(c echo.Context) error {
 	// Permission Check
 	userClaims, _ := c.Get("user_claims").(*service.Claims)
 	if userClaims != nil && userClaims.Role != "admin" {
-		_, err := model.CheckUserInstancePermission(userClaims.UserID, inst.InstanceID)
+		err := model.CheckUserInstanceLevel(userClaims.UserID, userClaims.Role, inst.InstanceID, model.PermissionSend)
 		if err != nil {
 			return ErrorResponse(c, 403, "Insufficient permission to use this phone number", "FORBIDDEN", "")
 		}
@@ -703,6 +707,7 @@ func SendGroupMediaByNumber(c echo.Context) error {
 	}
 	// Increment daily message count
 	_ = model.IncrementMessageCount(inst.InstanceID)
+	service.RecordOutgoingMessage(inst.InstanceID, groupJID, msg, resp)
 
 
 	return SuccessResponse(c, 200, "Media sent to group", map[string]interface{}{
@@ -747,7 +752,7 @@ func SendGroupMediaURLByNumber(c echo.Context) error {
 	// Permission Check
 	userClaims, _ := c.Get("user_claims").(*service.Claims)
 	if userClaims != nil && userClaims.Role != "admin" {
-		_, err := model.CheckUserInstancePermission(userClaims.UserID, inst.InstanceID)
+		err := model.CheckUserInstanceLevel(userClaims.UserID, userClaims.Role, inst.InstanceID, model.PermissionSend)
 		if err != nil {
 			return ErrorResponse(c, 403, "Insufficient permission to use this phone number", "FORBIDDEN", "")
 		}
@@ -846,6 +851,7 @@ func SendGroupMediaURLByNumber(c echo.Context) error {
 	}
 	// Increment daily message count
 	_ = model.IncrementMessageCount(inst.InstanceID)
+	service.RecordOutgoingMessage(inst.InstanceID, groupJID, msg, resp)
 
 
 	return SuccessResponse(c, 200, "Media sent to group", map[string]interface{}{
@@ -0,0 +1,789 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+	"gowa-yourself/internal/ws"
+
+	"github.com/labstack/echo/v4"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Group administration: buat grup, kelola peserta, ubah subject / deskripsi / foto,
+// announce / locked mode, invite link dan join request. Setiap route punya varian
+// by-number (/groups/by-number/:phoneNumber/...) dan semua aksi dicatat ke audit_logs.
+
+// Request untuk membuat grup baru
+type CreateGroupRequest struct {
+	Name         string   `json:"name"`
+	Participants []string `json:"participants"` // nomor telepon atau JID
+}
+
+// Request untuk add / remove / promote / demote peserta dan approve / reject join request
+type GroupParticipantsRequest struct {
+	Action       string   `json:"action"`
+	Participants []string `json:"participants"`
+}
+
+// Request untuk ubah subject grup
+type GroupSubjectRequest struct {
+	Subject string `json:"subject"`
+}
+
+// Request untuk ubah deskripsi grup (kosong = hapus deskripsi)
+type GroupDescriptionRequest struct {
+	Description string `json:"description"`
+}
+
+// Request untuk ubah setting grup, field yang nil tidak diubah
+type GroupSettingsRequest struct {
+	Announce *bool `json:"announce"` // true = hanya admin yang bisa kirim pesan
+	Locked   *bool `json:"locked"`   // true = hanya admin yang bisa ubah info grup
+}
+
+// Request untuk join grup via invite link
+type JoinGroupRequest struct {
+	InviteLink string `json:"inviteLink"` // https://chat.whatsapp.com/XXXX atau kodenya saja
+}
+
+// Batas nama grup dari WhatsApp (lebih panjang ditolak server dengan 406)
+const maxGroupNameLength = 25
+
+var participantActions = map[string]whatsmeow.ParticipantChange{
+	"add":     whatsmeow.ParticipantChangeAdd,
+	"remove":  whatsmeow.ParticipantChangeRemove,
+	"promote": whatsmeow.ParticipantChangePromote,
+	"demote":  whatsmeow.ParticipantChangeDemote,
+}
+
+var joinRequestActions = map[string]whatsmeow.ParticipantRequestChange{
+	"approve": whatsmeow.ParticipantChangeApprove,
+	"reject":  whatsmeow.ParticipantChangeReject,
+}
+
+// groupAdmin adalah hasil resolve instance + session (+ JID grup dari path)
+type groupAdmin struct {
+	InstanceID string
+	Session    *model.Session
+	GroupJID   types.JID
+}
+
+// resolveGroupAdmin memastikan session siap dan, jika withGroup, memvalidasi :groupJid dari path
+func resolveGroupAdmin(c echo.Context, byNumber, withGroup bool) (*groupAdmin, *apiError) {
+	instanceID, session, apiErr := resolveSession(c, byNumber)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	ga := &groupAdmin{InstanceID: instanceID, Session: session}
+	if !withGroup {
+		return ga, nil
+	}
+
+	groupJID, apiErr := parseGroupJID(c.Param("groupJid"))
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	ga.GroupJID = groupJID
+
+	return ga, nil
+}
+
+// parseGroupJID menerima "120363xxx@g.us" atau tanpa suffix "@g.us"
+func parseGroupJID(raw string) (types.JID, *apiError) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return types.EmptyJID, &apiError{400, "Group JID is required", "VALIDATION_ERROR", ""}
+	}
+	if !strings.Contains(raw, "@") {
+		raw += "@" + types.GroupServer
+	}
+
+	groupJID, err := types.ParseJID(raw)
+	if err != nil {
+		return types.EmptyJID, &apiError{400, "Invalid group JID", "INVALID_GROUP_JID", err.Error()}
+	}
+	if groupJID.Server != types.GroupServer {
+		return types.EmptyJID, &apiError{400, "Not a group JID", "NOT_GROUP_JID", "Group JID must end with @g.us"}
+	}
+
+	return groupJID, nil
+}
+
+// parseParticipants mengubah daftar nomor / JID peserta ke JID WhatsApp
+func parseParticipants(instanceID string, raw []string) ([]types.JID, *apiError) {
+	if len(raw) == 0 {
+		return nil, &apiError{400, "Field 'participants' is required", "VALIDATION_ERROR", ""}
+	}
+
+	country := model.GetInstanceDefaultCountry(instanceID)
+	jids := make([]types.JID, 0, len(raw))
+	for _, p := range raw {
+		p = strings.TrimSpace(p)
+
+		// JID lengkap (termasuk @lid dari daftar join request) dipakai apa adanya
+		if strings.Contains(p, "@") {
+			jid, err := types.ParseJID(p)
+			if err != nil {
+				return nil, &apiError{400, "Invalid participant JID", "INVALID_JID", fmt.Sprintf("%s: %v", p, err)}
+			}
+			jids = append(jids, jid)
+			continue
+		}
+
+		jid, err := helper.FormatPhoneNumber(p, country)
+		if err != nil {
+			return nil, &apiError{400, "Invalid participant phone number", "INVALID_PHONE", fmt.Sprintf("%s: %v", p, err)}
+		}
+		jids = append(jids, jid)
+	}
+
+	return jids, nil
+}
+
+// groupError memetakan error whatsmeow untuk operasi grup ke response
+func groupError(err error, message string) *apiError {
+	switch {
+	case errors.Is(err, whatsmeow.ErrGroupNotFound):
+		return &apiError{404, "Group not found", "GROUP_NOT_FOUND", err.Error()}
+	case errors.Is(err, whatsmeow.ErrNotInGroup):
+		return &apiError{403, "Instance is not a participant of this group", "NOT_IN_GROUP", err.Error()}
+	case errors.Is(err, whatsmeow.ErrGroupInviteLinkUnauthorized), errors.Is(err, whatsmeow.ErrIQNotAuthorized), errors.Is(err, whatsmeow.ErrIQForbidden):
+		return &apiError{403, "Instance is not an admin of this group", "NOT_GROUP_ADMIN", err.Error()}
+	case errors.Is(err, whatsmeow.ErrInviteLinkRevoked):
+		return &apiError{410, "Invite link has been revoked", "INVITE_LINK_REVOKED", err.Error()}
+	case errors.Is(err, whatsmeow.ErrInviteLinkInvalid):
+		return &apiError{400, "Invalid invite link", "INVITE_LINK_INVALID", err.Error()}
+	default:
+		return &apiError{500, message, "GROUP_ACTION_FAILED", err.Error()}
+	}
+}
+
+// logGroupAction mencatat aksi admin grup ke audit_logs
+func logGroupAction(c echo.Context, action, instanceID string, groupJID types.JID, details map[string]interface{}) {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["instance_id"] = instanceID
+	if phoneNumber := c.Param("phoneNumber"); phoneNumber != "" {
+		details["from"] = phoneNumber
+	}
+
+	auditLog := &model.AuditLog{
+		Action:       action,
+		ResourceType: sql.NullString{String: "group", Valid: true},
+		ResourceID:   sql.NullString{String: groupJID.String(), Valid: !groupJID.IsEmpty()},
+		Details:      details,
+		IPAddress:    sql.NullString{String: c.RealIP(), Valid: true},
+		UserAgent:    sql.NullString{String: c.Request().UserAgent(), Valid: true},
+	}
+	if userClaims, _ := c.Get("user_claims").(*service.Claims); userClaims != nil {
+		auditLog.UserID = sql.NullInt64{Int64: userClaims.UserID, Valid: true}
+		details["performed_by"] = userClaims.Username
+	}
+
+	_ = model.LogAction(auditLog)
+}
+
+// groupInfoResponse memformat detail grup untuk response API
+func groupInfoResponse(info *types.GroupInfo) map[string]interface{} {
+	participants := make([]map[string]interface{}, 0, len(info.Participants))
+	for _, p := range info.Participants {
+		participants = append(participants, map[string]interface{}{
+			"jid":          p.JID.String(),
+			"phoneNumber":  p.PhoneNumber.User,
+			"isAdmin":      p.IsAdmin,
+			"isSuperAdmin": p.IsSuperAdmin,
+		})
+	}
+
+	return map[string]interface{}{
+		"jid":                  info.JID.String(),
+		"name":                 info.Name,
+		"topic":                info.Topic,
+		"ownerJid":             info.OwnerJID.String(),
+		"announce":             info.IsAnnounce,
+		"locked":               info.IsLocked,
+		"joinApprovalRequired": info.IsJoinApprovalRequired,
+		"createdAt":            info.GroupCreated.Unix(),
+		"participantCount":     len(info.Participants),
+		"participants":         participants,
+	}
+}
+
+// participantResults memformat hasil per peserta (WhatsApp mengembalikan error per nomor,
+// mis. 403 = privasi tidak mengizinkan add, 408 = baru keluar dari grup, 409 = sudah jadi peserta)
+func participantResults(results []types.GroupParticipant) []map[string]interface{} {
+	list := make([]map[string]interface{}, 0, len(results))
+	for _, p := range results {
+		item := map[string]interface{}{
+			"jid":     p.JID.String(),
+			"success": p.Error == 0,
+		}
+		if p.Error != 0 {
+			item["error"] = p.Error
+		}
+		if p.AddRequest != nil {
+			// Peserta harus di-invite manual (kode undangan berlaku sampai expiration)
+			item["inviteRequired"] = true
+			item["inviteExpiration"] = p.AddRequest.Expiration.Unix()
+		}
+		list = append(list, item)
+	}
+	return list
+}
+
+// POST /groups/:instanceId - Create group
+func CreateGroup(c echo.Context) error {
+	return createGroup(c, false)
+}
+
+// POST /groups/by-number/:phoneNumber - Create group
+func CreateGroupByNumber(c echo.Context) error {
+	return createGroup(c, true)
+}
+
+func createGroup(c echo.Context, byNumber bool) error {
+	var req CreateGroupRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return ErrorResponse(c, 400, "Field 'name' is required", "VALIDATION_ERROR", "")
+	}
+	if len([]rune(req.Name)) > maxGroupNameLength {
+		return ErrorResponse(c, 400, fmt.Sprintf("Group name is limited to %d characters", maxGroupNameLength), "VALIDATION_ERROR", "")
+	}
+
+	ga, apiErr := resolveGroupAdmin(c, byNumber, false)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	participants, apiErr := parseParticipants(ga.InstanceID, req.Participants)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	info, err := ga.Session.Client.CreateGroup(context.Background(), whatsmeow.ReqCreateGroup{
+		Name:         req.Name,
+		Participants: participants,
+	})
+	if err != nil {
+		return groupError(err, "Failed to create group").send(c)
+	}
+
+	logGroupAction(c, "group.create", ga.InstanceID, info.JID, map[string]interface{}{
+		"name":         req.Name,
+		"participants": req.Participants,
+	})
+
+	return SuccessResponse(c, 201, "Group created successfully", groupInfoResponse(info))
+}
+
+// GET /groups/:instanceId/:groupJid - Group detail & participants
+func GetGroupInfo(c echo.Context) error {
+	return getGroupInfo(c, false)
+}
+
+// GET /groups/by-number/:phoneNumber/:groupJid
+func GetGroupInfoByNumber(c echo.Context) error {
+	return getGroupInfo(c, true)
+}
+
+func getGroupInfo(c echo.Context, byNumber bool) error {
+	ga, apiErr := resolveGroupAdmin(c, byNumber, true)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	info, err := ga.Session.Client.GetGroupInfo(context.Background(), ga.GroupJID)
+	if err != nil {
+		return groupError(err, "Failed to get group info").send(c)
+	}
+
+	return SuccessResponse(c, 200, "Group info retrieved", groupInfoResponse(info))
+}
+
+// POST /groups/:instanceId/:groupJid/participants - add / remove / promote / demote
+func UpdateGroupParticipants(c echo.Context) error {
+	return updateGroupParticipants(c, false)
+}
+
+// POST /groups/by-number/:phoneNumber/:groupJid/participants
+func UpdateGroupParticipantsByNumber(c echo.Context) error {
+	return updateGroupParticipants(c, true)
+}
+
+func updateGroupParticipants(c echo.Context, byNumber bool) error {
+	var req GroupParticipantsRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	action, ok := participantActions[strings.ToLower(req.Action)]
+	if !ok {
+		return ErrorResponse(c, 400, "Invalid action", "VALIDATION_ERROR", "Allowed: add, remove, promote, demote")
+	}
+
+	ga, apiErr := resolveGroupAdmin(c, byNumber, true)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	participants, apiErr := parseParticipants(ga.InstanceID, req.Participants)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	results, err := ga.Session.Client.UpdateGroupParticipants(context.Background(), ga.GroupJID, participants, action)
+	if err != nil {
+		return groupError(err, "Failed to update group participants").send(c)
+	}
+
+	logGroupAction(c, "group.participants."+string(action), ga.InstanceID, ga.GroupJID, map[string]interface{}{
+		"participants": req.Participants,
+	})
+
+	return SuccessResponse(c, 200, "Group participants updated", map[string]interface{}{
+		"groupJid":     ga.GroupJID.String(),
+		"action":       string(action),
+		"participants": participantResults(results),
+	})
+}
+
+// PUT /groups/:instanceId/:groupJid/subject
+func SetGroupSubject(c echo.Context) error {
+	return setGroupSubject(c, false)
+}
+
+// PUT /groups/by-number/:phoneNumber/:groupJid/subject
+func SetGroupSubjectByNumber(c echo.Context) error {
+	return setGroupSubject(c, true)
+}
+
+func setGroupSubject(c echo.Context, byNumber bool) error {
+	var req GroupSubjectRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	req.Subject = strings.TrimSpace(req.Subject)
+	if req.Subject == "" {
+		return ErrorResponse(c, 400, "Field 'subject' is required", "VALIDATION_ERROR", "")
+	}
+	if len([]rune(req.Subject)) > maxGroupNameLength {
+		return ErrorResponse(c, 400, fmt.Sprintf("Group subject is limited to %d characters", maxGroupNameLength), "VALIDATION_ERROR", "")
+	}
+
+	ga, apiErr := resolveGroupAdmin(c, byNumber, true)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	if err := ga.Session.Client.SetGroupName(context.Background(), ga.GroupJID, req.Subject); err != nil {
+		return groupError(err, "Failed to change group subject").send(c)
+	}
+
+	logGroupAction(c, "group.subject.update", ga.InstanceID, ga.GroupJID, map[string]interface{}{
+		"subject": req.Subject,
+	})
+
+	return SuccessResponse(c, 200, "Group subject updated", map[string]interface{}{
+		"groupJid": ga.GroupJID.String(),
+		"subject":  req.Subject,
+	})
+}
+
+// PUT /groups/:instanceId/:groupJid/description
+func SetGroupDescription(c echo.Context) error {
+	return setGroupDescription(c, false)
+}
+
+// PUT /groups/by-number/:phoneNumber/:groupJid/description
+func SetGroupDescriptionByNumber(c echo.Context) error {
+	return setGroupDescription(c, true)
+}
+
+func setGroupDescription(c echo.Context, byNumber bool) error {
+	var req GroupDescriptionRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	ga, apiErr := resolveGroupAdmin(c, byNumber, true)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	if err := ga.Session.Client.SetGroupDescription(context.Background(), ga.GroupJID, req.Description); err != nil {
+		return groupError(err, "Failed to change group description").send(c)
+	}
+
+	logGroupAction(c, "group.description.update", ga.InstanceID, ga.GroupJID, map[string]interface{}{
+		"description": req.Description,
+	})
+
+	return SuccessResponse(c, 200, "Group description updated", map[string]interface{}{
+		"groupJid":    ga.GroupJID.String(),
+		"description": req.Description,
+	})
+}
+
+// PUT /groups/:instanceId/:groupJid/photo - multipart 'file' (jpg / png / webp)
+func SetGroupPhoto(c echo.Context) error {
+	return setGroupPhoto(c, false)
+}
+
+// PUT /groups/by-number/:phoneNumber/:groupJid/photo
+func SetGroupPhotoByNumber(c echo.Context) error {
+	return setGroupPhoto(c, true)
+}
+
+func setGroupPhoto(c echo.Context, byNumber bool) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return ErrorResponse(c, 400, "File is required", "FILE_REQUIRED", "Upload image as 'file'")
+	}
+
+	maxSize := getMaxFileSize("image")
+	if file.Size > int64(maxSize) {
+		return ErrorResponse(c, 400, "File too large", "FILE_TOO_LARGE",
+			fmt.Sprintf("File size: %d bytes, Max: %d bytes (image)", file.Size, maxSize))
+	}
+
+	ga, apiErr := resolveGroupAdmin(c, byNumber, true)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to open file", "FILE_OPEN_FAILED", err.Error())
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to read file", "FILE_READ_FAILED", err.Error())
+	}
+
+	photo, err := helper.PrepareGroupPhoto(data)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid image", "INVALID_IMAGE", err.Error())
+	}
+
+	pictureID, err := ga.Session.Client.SetGroupPhoto(context.Background(), ga.GroupJID, photo)
+	if err != nil {
+		return groupError(err, "Failed to change group photo").send(c)
+	}
+
+	logGroupAction(c, "group.photo.update", ga.InstanceID, ga.GroupJID, map[string]interface{}{
+		"picture_id": pictureID,
+		"file_name":  file.Filename,
+	})
+
+	return SuccessResponse(c, 200, "Group photo updated", map[string]interface{}{
+		"groupJid":  ga.GroupJID.String(),
+		"pictureId": pictureID,
+	})
+}
+
+// PUT /groups/:instanceId/:groupJid/settings - announce / locked mode
+func SetGroupSettings(c echo.Context) error {
+	return setGroupSettings(c, false)
+}
+
+// PUT /groups/by-number/:phoneNumber/:groupJid/settings
+func SetGroupSettingsByNumber(c echo.Context) error {
+	return setGroupSettings(c, true)
+}
+
+func setGroupSettings(c echo.Context, byNumber bool) error {
+	var req GroupSettingsRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	if req.Announce == nil && req.Locked == nil {
+		return ErrorResponse(c, 400, "At least one field (announce or locked) must be provided", "NO_FIELDS", "")
+	}
+
+	ga, apiErr := resolveGroupAdmin(c, byNumber, true)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	changes := map[string]interface{}{}
+	if req.Announce != nil {
+		if err := ga.Session.Client.SetGroupAnnounce(context.Background(), ga.GroupJID, *req.Announce); err != nil {
+			return groupError(err, "Failed to change announce mode").send(c)
+		}
+		changes["announce"] = *req.Announce
+	}
+	if req.Locked != nil {
+		if err := ga.Session.Client.SetGroupLocked(context.Background(), ga.GroupJID, *req.Locked); err != nil {
+			return groupError(err, "Failed to change locked mode").send(c)
+		}
+		changes["locked"] = *req.Locked
+	}
+
+	logGroupAction(c, "group.settings.update", ga.InstanceID, ga.GroupJID, map[string]interface{}{
+		"changes": changes,
+	})
+
+	return SuccessResponse(c, 200, "Group settings updated", map[string]interface{}{
+		"groupJid": ga.GroupJID.String(),
+		"changes":  changes,
+	})
+}
+
+// GET /groups/:instanceId/:groupJid/invite-link
+func GetGroupInviteLink(c echo.Context) error {
+	return groupInviteLink(c, false, false)
+}
+
+// GET /groups/by-number/:phoneNumber/:groupJid/invite-link
+func GetGroupInviteLinkByNumber(c echo.Context) error {
+	return groupInviteLink(c, true, false)
+}
+
+// POST /groups/:instanceId/:groupJid/invite-link/revoke - revoke link lama & buat link baru
+func RevokeGroupInviteLink(c echo.Context) error {
+	return groupInviteLink(c, false, true)
+}
+
+// POST /groups/by-number/:phoneNumber/:groupJid/invite-link/revoke
+func RevokeGroupInviteLinkByNumber(c echo.Context) error {
+	return groupInviteLink(c, true, true)
+}
+
+func groupInviteLink(c echo.Context, byNumber, reset bool) error {
+	ga, apiErr := resolveGroupAdmin(c, byNumber, true)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	link, err := ga.Session.Client.GetGroupInviteLink(context.Background(), ga.GroupJID, reset)
+	if err != nil {
+		return groupError(err, "Failed to get group invite link").send(c)
+	}
+
+	message := "Group invite link retrieved"
+	if reset {
+		message = "Group invite link revoked"
+		logGroupAction(c, "group.invite_link.revoke", ga.InstanceID, ga.GroupJID, nil)
+	}
+
+	return SuccessResponse(c, 200, message, map[string]interface{}{
+		"groupJid":   ga.GroupJID.String(),
+		"inviteLink": link,
+	})
+}
+
+// POST /groups/:instanceId/join - join grup via invite link
+func JoinGroupWithLink(c echo.Context) error {
+	return joinGroupWithLink(c, false)
+}
+
+// POST /groups/by-number/:phoneNumber/join
+func JoinGroupWithLinkByNumber(c echo.Context) error {
+	return joinGroupWithLink(c, true)
+}
+
+func joinGroupWithLink(c echo.Context, byNumber bool) error {
+	var req JoinGroupRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	code := strings.TrimPrefix(strings.TrimSpace(req.InviteLink), whatsmeow.InviteLinkPrefix)
+	if code == "" {
+		return ErrorResponse(c, 400, "Field 'inviteLink' is required", "VALIDATION_ERROR", "")
+	}
+
+	ga, apiErr := resolveGroupAdmin(c, byNumber, false)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	ctx := context.Background()
+
+	// Cek dulu info grup supaya tahu apakah join perlu approval admin
+	info, err := ga.Session.Client.GetGroupInfoFromLink(ctx, code)
+	if err != nil {
+		return groupError(err, "Failed to resolve invite link").send(c)
+	}
+
+	groupJID, err := ga.Session.Client.JoinGroupWithLink(ctx, code)
+	if err != nil {
+		return groupError(err, "Failed to join group").send(c)
+	}
+
+	logGroupAction(c, "group.join", ga.InstanceID, groupJID, map[string]interface{}{
+		"name":             info.Name,
+		"approval_pending": info.IsJoinApprovalRequired,
+	})
+
+	message := "Joined group successfully"
+	if info.IsJoinApprovalRequired {
+		message = "Join request sent, waiting for admin approval"
+	}
+
+	return SuccessResponse(c, 200, message, map[string]interface{}{
+		"groupJid":        groupJID.String(),
+		"name":            info.Name,
+		"approvalPending": info.IsJoinApprovalRequired,
+	})
+}
+
+// GET /groups/:instanceId/:groupJid/requests - daftar join request yang menunggu approval
+func GetGroupJoinRequests(c echo.Context) error {
+	return getGroupJoinRequests(c, false)
+}
+
+// GET /groups/by-number/:phoneNumber/:groupJid/requests
+func GetGroupJoinRequestsByNumber(c echo.Context) error {
+	return getGroupJoinRequests(c, true)
+}
+
+func getGroupJoinRequests(c echo.Context, byNumber bool) error {
+	ga, apiErr := resolveGroupAdmin(c, byNumber, true)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	requests, err := ga.Session.Client.GetGroupRequestParticipants(context.Background(), ga.GroupJID)
+	if err != nil {
+		return groupError(err, "Failed to get join requests").send(c)
+	}
+
+	list := make([]map[string]interface{}, 0, len(requests))
+	for _, r := range requests {
+		list = append(list, map[string]interface{}{
+			"jid":         r.JID.String(),
+			"requestedAt": r.RequestedAt.Unix(),
+		})
+	}
+
+	return SuccessResponse(c, 200, "Join requests retrieved", map[string]interface{}{
+		"groupJid": ga.GroupJID.String(),
+		"total":    len(list),
+		"requests": list,
+	})
+}
+
+// POST /groups/:instanceId/:groupJid/requests - approve / reject join request
+func UpdateGroupJoinRequests(c echo.Context) error {
+	return updateGroupJoinRequests(c, false)
+}
+
+// POST /groups/by-number/:phoneNumber/:groupJid/requests
+func UpdateGroupJoinRequestsByNumber(c echo.Context) error {
+	return updateGroupJoinRequests(c, true)
+}
+
+func updateGroupJoinRequests(c echo.Context, byNumber bool) error {
+	var req GroupParticipantsRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	action, ok := joinRequestActions[strings.ToLower(req.Action)]
+	if !ok {
+		return ErrorResponse(c, 400, "Invalid action", "VALIDATION_ERROR", "Allowed: approve, reject")
+	}
+
+	ga, apiErr := resolveGroupAdmin(c, byNumber, true)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	participants, apiErr := parseParticipants(ga.InstanceID, req.Participants)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	results, err := ga.Session.Client.UpdateGroupRequestParticipants(context.Background(), ga.GroupJID, participants, action)
+	if err != nil {
+		return groupError(err, "Failed to update join requests").send(c)
+	}
+
+	logGroupAction(c, "group.requests."+string(action), ga.InstanceID, ga.GroupJID, map[string]interface{}{
+		"participants": req.Participants,
+	})
+
+	return SuccessResponse(c, 200, "Join requests updated", map[string]interface{}{
+		"groupJid":     ga.GroupJID.String(),
+		"action":       string(action),
+		"participants": participantResults(results),
+	})
+}
+
+// GET /groups/:instanceId/:groupJid/events - riwayat event grup (join / leave / promote / demote / update)
+// Query: type (GROUP_PARTICIPANTS_CHANGED, GROUP_UPDATED, GROUP_JOINED), limit (default 50, max 200), cursor
+func GetGroupEvents(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	groupJID, apiErr := parseGroupJID(c.Param("groupJid"))
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	eventType := strings.ToUpper(c.QueryParam("type"))
+	switch eventType {
+	case "", ws.EventGroupParticipantsChanged, ws.EventGroupUpdated, ws.EventGroupJoined:
+	default:
+		return ErrorResponse(c, 400, "Invalid event type", "VALIDATION_ERROR",
+			fmt.Sprintf("Allowed: %s, %s, %s", ws.EventGroupParticipantsChanged, ws.EventGroupUpdated, ws.EventGroupJoined))
+	}
+
+	limit := parseChatLimit(c.QueryParam("limit"), 50)
+
+	var beforeTime *time.Time
+	var beforeID int64
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		t, id, err := decodeMessageCursor(cursor)
+		if err != nil {
+			return ErrorResponse(c, 400, "Invalid cursor", "INVALID_CURSOR", err.Error())
+		}
+		beforeTime = &t
+		beforeID = id
+	}
+
+	events, err := model.GetGroupEvents(instanceID, groupJID.String(), eventType, beforeTime, beforeID, limit)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get group events", "GET_FAILED", err.Error())
+	}
+
+	responses := make([]model.GroupEventResponse, 0, len(events))
+	for _, e := range events {
+		responses = append(responses, model.ToGroupEventResponse(e))
+	}
+
+	nextCursor := ""
+	if len(events) == limit {
+		last := events[len(events)-1]
+		nextCursor = fmt.Sprintf("%d_%d", last.EventTimestamp.UnixNano(), last.ID)
+	}
+
+	return SuccessResponse(c, 200, "Group events retrieved", map[string]interface{}{
+		"instanceId": instanceID,
+		"groupJid":   groupJID.String(),
+		"total":      len(responses),
+		"events":     responses,
+		"nextCursor": nextCursor,
+	})
+}
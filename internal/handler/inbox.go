@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"gowa-yourself/internal/model"
 	"gowa-yourself/internal/service"
 	"gowa-yourself/internal/ws"
 	"log"
@@ -45,6 +46,16 @@ func ListenMessages(hub *ws.Hub) echo.HandlerFunc {
 		// Buat client dan register
 		client := ws.NewClient(hub, conn)
 		client.InstanceID = instanceID
+		if claims, ok := c.Get("user_claims").(*service.Claims); ok && claims != nil {
+			client.UserID = claims.UserID
+			client.Role = claims.Role
+		}
+		if err := hub.LoadAccess(client); err != nil {
+			log.Printf("ws: failed to load access for listener %s: %v", instanceID, err)
+		}
+		if key, ok := c.Get("api_key").(*model.APIKey); ok && len(key.InstanceIDs) > 0 {
+			client.RestrictInstances(key.InstanceIDs)
+		}
 
 		hub.Register(client)
 
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"path"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GET /api/media/:instanceId/:messageId
+// Ambil media dari pesan masuk yang sudah di-download. ?download=true untuk Content-Disposition attachment.
+func GetMessageMedia(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+	messageID := c.Param("messageId")
+
+	msg, err := model.GetMessageByMessageID(instanceID, messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrorResponse(c, 404, "Message not found", "MESSAGE_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to get message", "DB_ERROR", err.Error())
+	}
+
+	if !msg.MediaPath.Valid {
+		return ErrorResponse(c, 404, "Media is not available for this message", "MEDIA_NOT_AVAILABLE",
+			"Only incoming media received while the instance is connected is downloaded")
+	}
+
+	key := helper.StorageKey(msg.MediaPath.String)
+
+	fileName := msg.MediaFileName.String
+	if fileName == "" {
+		fileName = path.Base(key)
+	}
+
+	return streamStorageObject(c, key, msg.MediaMime.String, fileName, c.QueryParam("download") == "true")
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// InstanceUserRequest is the body for PUT /api/instances/:instanceId/users/:userId
+type InstanceUserRequest struct {
+	Permission string `json:"permission"` // kosong = default dari role user (lihat model.RoleDefaultPermission)
+}
+
+// InstanceUserResponse adalah satu assignment user pada instance
+type InstanceUserResponse struct {
+	UserID     int64     `json:"userId"`
+	Username   string    `json:"username"`
+	Permission string    `json:"permission"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// logInstanceUserAction mencatat assign / revoke akses instance ke audit_logs
+func logInstanceUserAction(c echo.Context, claims *service.Claims, action, instanceID string, target *model.User, permission string) {
+	details := map[string]interface{}{
+		"user_id":      target.ID,
+		"username":     target.Username,
+		"performed_by": claims.Username,
+	}
+	if permission != "" {
+		details["permission"] = permission
+	}
+
+	_ = model.LogAction(&model.AuditLog{
+		UserID:       sql.NullInt64{Int64: claims.UserID, Valid: true},
+		Action:       action,
+		ResourceType: sql.NullString{String: "instance", Valid: true},
+		ResourceID:   sql.NullString{String: instanceID, Valid: true},
+		Details:      details,
+		IPAddress:    sql.NullString{String: c.RealIP(), Valid: true},
+		UserAgent:    sql.NullString{String: c.Request().UserAgent(), Valid: true},
+	})
+}
+
+// resolveInstanceUser memvalidasi instance dan user target dari path param
+func resolveInstanceUser(c echo.Context) (string, *model.User, *apiError) {
+	instanceID := c.Param("instanceId")
+	if _, err := model.GetInstanceByInstanceID(instanceID); err != nil {
+		return "", nil, &apiError{http.StatusNotFound, "Instance not found", "INSTANCE_NOT_FOUND", ""}
+	}
+
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		return "", nil, &apiError{http.StatusBadRequest, "Invalid user ID", "BAD_REQUEST", ""}
+	}
+
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, model.ErrUserNotFound) {
+			return "", nil, &apiError{http.StatusNotFound, "User not found", "USER_NOT_FOUND", ""}
+		}
+		return "", nil, &apiError{http.StatusInternalServerError, "Failed to retrieve user", "INTERNAL_ERROR", err.Error()}
+	}
+
+	return instanceID, user, nil
+}
+
+// GET /api/instances/:instanceId/users (admin)
+func GetInstanceUsers(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+	if _, err := model.GetInstanceByInstanceID(instanceID); err != nil {
+		return ErrorResponse(c, http.StatusNotFound, "Instance not found", "INSTANCE_NOT_FOUND", "")
+	}
+
+	users, err := model.GetInstanceUsers(instanceID)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve instance users", "INTERNAL_ERROR", err.Error())
+	}
+
+	responses := make([]InstanceUserResponse, 0, len(users))
+	for _, u := range users {
+		responses = append(responses, InstanceUserResponse{
+			UserID:     u.UserID,
+			Username:   u.Username,
+			Permission: u.PermissionLevel,
+			CreatedAt:  u.CreatedAt,
+		})
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Instance users retrieved successfully", responses)
+}
+
+// PUT /api/instances/:instanceId/users/:userId (admin)
+// Assign user ke instance atau ubah level-nya.
+func AssignInstanceUser(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	var req InstanceUserRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+
+	instanceID, user, aerr := resolveInstanceUser(c)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+
+	permission := strings.ToLower(strings.TrimSpace(req.Permission))
+	if permission == "" {
+		permission = model.RoleDefaultPermission(user.Role)
+	}
+	if !model.IsValidPermissionLevel(permission) {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid permission level", "INVALID_PERMISSION",
+			"Allowed levels: "+strings.Join(model.PermissionLevels, ", "))
+	}
+
+	if err := model.AssignInstanceToUser(user.ID, instanceID, permission); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to assign instance", "INTERNAL_ERROR", err.Error())
+	}
+
+	logInstanceUserAction(c, claims, "instance.assign_user", instanceID, user, permission)
+
+	return SuccessResponse(c, http.StatusOK, "Instance access updated successfully", map[string]interface{}{
+		"userId":     user.ID,
+		"username":   user.Username,
+		"permission": permission,
+		// Level yang benar-benar berlaku setelah batas role (viewer maksimal read)
+		"effectivePermission": model.EffectivePermission(user.Role, permission),
+	})
+}
+
+// DELETE /api/instances/:instanceId/users/:userId (admin)
+func RevokeInstanceUser(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	instanceID, user, aerr := resolveInstanceUser(c)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+
+	if err := model.RemoveUserInstance(user.ID, instanceID); err != nil {
+		if errors.Is(err, model.ErrNoPermission) {
+			return ErrorResponse(c, http.StatusNotFound, "User has no access to this instance", "NOT_FOUND", "")
+		}
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke instance access", "INTERNAL_ERROR", err.Error())
+	}
+
+	logInstanceUserAction(c, claims, "instance.revoke_user", instanceID, user, "")
+
+	return SuccessResponse(c, http.StatusOK, "Instance access revoked successfully", nil)
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Masa berlaku default undangan jika expiresInDays tidak diisi
+const defaultInvitationDays = 7
+
+// InvitationRequest is the body for POST /api/invitations
+type InvitationRequest struct {
+	Email         string `json:"email,omitempty"` // opsional: hanya email ini yang bisa memakai undangan
+	Role          string `json:"role,omitempty"`  // default: user
+	ExpiresInDays int    `json:"expiresInDays,omitempty"`
+}
+
+// logInvitationAction mencatat pembuatan / pencabutan undangan ke audit_logs
+func logInvitationAction(c echo.Context, claims *service.Claims, action string, inv *model.UserInvitation) {
+	_ = model.LogAction(&model.AuditLog{
+		UserID:       sql.NullInt64{Int64: claims.UserID, Valid: true},
+		Action:       action,
+		ResourceType: sql.NullString{String: "invitation", Valid: true},
+		ResourceID:   sql.NullString{String: strconv.FormatInt(inv.ID, 10), Valid: true},
+		Details: map[string]interface{}{
+			"email":        inv.Email.String,
+			"role":         inv.Role,
+			"expires_at":   inv.ExpiresAt,
+			"performed_by": claims.Username,
+		},
+		IPAddress: sql.NullString{String: c.RealIP(), Valid: true},
+		UserAgent: sql.NullString{String: c.Request().UserAgent(), Valid: true},
+	})
+}
+
+// GET /register (public) - mode registrasi untuk halaman sign up
+func GetRegistrationMode(c echo.Context) error {
+	return SuccessResponse(c, http.StatusOK, "Registration mode retrieved", map[string]interface{}{
+		"mode": service.RegistrationMode(),
+	})
+}
+
+// GET /api/invitations (admin, ?pending=true hanya yang masih bisa dipakai)
+func GetInvitations(c echo.Context) error {
+	invitations, err := model.GetUserInvitations(c.QueryParam("pending") == "true")
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve invitations", "DATABASE_ERROR", err.Error())
+	}
+
+	responses := make([]model.UserInvitationResponse, 0, len(invitations))
+	for _, inv := range invitations {
+		responses = append(responses, model.ToUserInvitationResponse(inv))
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Invitations retrieved successfully", map[string]interface{}{
+		"mode":        service.RegistrationMode(),
+		"invitations": responses,
+	})
+}
+
+// POST /api/invitations (admin)
+// Token plaintext hanya dikembalikan sekali di response ini.
+func CreateInvitation(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	var req InvitationRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+
+	if req.Role == "" {
+		req.Role = "user"
+	}
+	if !service.IsValidRole(req.Role) {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid role", "INVALID_ROLE", "Allowed roles: admin, user, viewer")
+	}
+	if req.ExpiresInDays <= 0 {
+		req.ExpiresInDays = defaultInvitationDays
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	inv := model.UserInvitation{
+		Email:     sql.NullString{String: req.Email, Valid: req.Email != ""},
+		Role:      req.Role,
+		CreatedBy: sql.NullInt64{Int64: claims.UserID, Valid: true},
+		ExpiresAt: time.Now().AddDate(0, 0, req.ExpiresInDays),
+	}
+
+	token, err := service.CreateInvitation(&inv)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to create invitation", "INTERNAL_ERROR", err.Error())
+	}
+
+	logInvitationAction(c, claims, "user.invite", &inv)
+
+	return SuccessResponse(c, http.StatusCreated, "Invitation created successfully. Share the token now, it will not be shown again.", map[string]interface{}{
+		"token":      token,
+		"invitation": model.ToUserInvitationResponse(inv),
+	})
+}
+
+// DELETE /api/invitations/:id (admin)
+func RevokeInvitation(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid invitation ID", "BAD_REQUEST", "")
+	}
+
+	inv, err := model.GetUserInvitationByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrorResponse(c, http.StatusNotFound, "Invitation not found", "NOT_FOUND", "")
+		}
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve invitation", "INTERNAL_ERROR", err.Error())
+	}
+
+	if err := model.RevokeUserInvitation(inv.ID); err != nil {
+		if errors.Is(err, model.ErrInvitationUnavailable) {
+			return ErrorResponse(c, http.StatusConflict, "Invitation is already used or revoked", "INVITATION_UNAVAILABLE", "")
+		}
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke invitation", "INTERNAL_ERROR", err.Error())
+	}
+
+	logInvitationAction(c, claims, "user.invite_revoke", inv)
+
+	return SuccessResponse(c, http.StatusOK, "Invitation revoked successfully", nil)
+}
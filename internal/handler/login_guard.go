@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// attemptBlocked returns 429 + Retry-After ketika username / IP sedang dikunci atau harus menunggu
+func attemptBlocked(c echo.Context, block *service.AttemptBlock) error {
+	seconds := int(math.Ceil(block.RetryAfter.Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	details := fmt.Sprintf("Retry after %d seconds", seconds)
+
+	if !block.Locked {
+		return ErrorResponse(c, http.StatusTooManyRequests, "Too many failed attempts, please wait before trying again", "TOO_MANY_ATTEMPTS", details)
+	}
+	if block.Scope == service.AttemptScopeUsername {
+		return ErrorResponse(c, http.StatusTooManyRequests, "Account is temporarily locked after too many failed login attempts", "ACCOUNT_LOCKED", details)
+	}
+	return ErrorResponse(c, http.StatusTooManyRequests, "Too many failed attempts from your IP address", "IP_LOCKED", details)
+}
+
+// countFailedAttempts menambah counter setiap key, returns ringkasan untuk audit log
+func countFailedAttempts(c echo.Context, keys []service.AttemptKey) map[string]interface{} {
+	details := map[string]interface{}{}
+	for _, k := range keys {
+		f := service.RecordFailedAttempt(k, c.RealIP())
+		if f == nil {
+			continue
+		}
+		details[k.Scope+"_failures"] = f.Failures
+		if f.LockedUntil.Valid {
+			details["locked_until"] = f.LockedUntil.Time
+		}
+	}
+	return details
+}
+
+// recordLoginFailure menghitung kegagalan login dan mencatat user.login_failed ke audit_logs
+func recordLoginFailure(c echo.Context, username, reason string, keys []service.AttemptKey) {
+	details := countFailedAttempts(c, keys)
+	details["reason"] = reason
+	details["username"] = keys[0].Key
+
+	// user_id hanya diisi jika username memang ada (username tidak dikenal tetap dicatat)
+	var userID sql.NullInt64
+	if user, err := model.GetUserByUsername(username); err == nil {
+		userID = sql.NullInt64{Int64: user.ID, Valid: true}
+	}
+
+	_ = model.LogAction(&model.AuditLog{
+		UserID:       userID,
+		Action:       "user.login_failed",
+		ResourceType: sql.NullString{String: "user", Valid: true},
+		ResourceID:   sql.NullString{String: keys[0].Key, Valid: true},
+		Details:      details,
+		IPAddress:    sql.NullString{String: c.RealIP(), Valid: true},
+		UserAgent:    sql.NullString{String: c.Request().UserAgent(), Valid: true},
+	})
+}
+
+// GetLoginLockouts returns usernames / IPs that are locked or have recent failed attempts
+// GET /api/security/lockouts
+func GetLoginLockouts(c echo.Context) error {
+	failures, err := service.ListFailedAttempts()
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve lockouts", "INTERNAL_ERROR", err.Error())
+	}
+
+	resp := make([]model.AuthFailureResponse, 0, len(failures))
+	for i := range failures {
+		resp = append(resp, failures[i].ToResponse())
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Lockouts retrieved", resp)
+}
+
+// ClearLoginLockout removes the counter of a username or IP
+// DELETE /api/security/lockouts?scope=login_ip&key=1.2.3.4
+func ClearLoginLockout(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	scope := c.QueryParam("scope")
+	key := c.QueryParam("key")
+	if !service.IsValidAttemptScope(scope) || key == "" {
+		return ErrorResponse(c, http.StatusBadRequest, "Valid scope and key are required", "BAD_REQUEST",
+			"scope: username, login_ip, refresh_ip or api_key_ip")
+	}
+
+	cleared, err := service.ClearFailedAttempts(service.AttemptKey{Scope: scope, Key: key})
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to clear lockout", "INTERNAL_ERROR", err.Error())
+	}
+	if !cleared {
+		return ErrorResponse(c, http.StatusNotFound, "No failed attempts recorded for this key", "NOT_FOUND", "")
+	}
+
+	_ = model.LogAction(&model.AuditLog{
+		UserID:       sql.NullInt64{Int64: claims.UserID, Valid: true},
+		Action:       "security.lockout_clear",
+		ResourceType: sql.NullString{String: "auth_failure", Valid: true},
+		ResourceID:   sql.NullString{String: scope + ":" + key, Valid: true},
+		Details: map[string]interface{}{
+			"scope":        scope,
+			"key":          key,
+			"performed_by": claims.Username,
+		},
+		IPAddress: sql.NullString{String: c.RealIP(), Valid: true},
+		UserAgent: sql.NullString{String: c.Request().UserAgent(), Valid: true},
+	})
+
+	return SuccessResponse(c, http.StatusOK, "Lockout cleared", nil)
+}
+
+// UnlockUser clears the failed login counter of a user
+// POST /api/users/:id/unlock
+func UnlockUser(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	user, aerr := resolveTargetUser(c)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+
+	key := service.LoginAttemptKeys(user.Username, "")[0]
+	cleared, err := service.ClearFailedAttempts(key)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to unlock user", "INTERNAL_ERROR", err.Error())
+	}
+
+	logUserAdminAction(c, claims, "user.unlock", user, map[string]interface{}{"had_failures": cleared})
+
+	return SuccessResponse(c, http.StatusOK, "User unlocked", nil)
+}
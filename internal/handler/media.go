@@ -61,7 +61,7 @@ func SendMediaFile(c echo.Context) error {
 	}
 
 	// 5. FORMAT & VALIDATE PHONE NUMBER
-	recipient, err := helper.FormatPhoneNumber(to)
+	recipient, err := helper.FormatPhoneNumber(to, model.GetInstanceDefaultCountry(instanceID))
 	if err != nil {
 		return ErrorResponse(c, 400, "Invalid phone number", "INVALID_PHONE", err.Error())
 	}
@@ -161,6 +161,7 @@ func SendMediaFile(c echo.Context) error {
 	}
 	// Increment daily message count
 	_ = model.IncrementMessageCount(instanceID)
+	service.RecordOutgoingMessage(instanceID, recipient, msg, resp)
 
 
 	// 14. SUCCESS RESPONSE
@@ -211,7 +212,7 @@ func SendMediaURL(c echo.Context) error {
 	}
 
 	// 5. FORMAT & VALIDATE PHONE NUMBER
-	recipient, err := helper.FormatPhoneNumber(req.To)
+	recipient, err := helper.FormatPhoneNumber(req.To, model.GetInstanceDefaultCountry(instanceID))
 	if err != nil {
 		return ErrorResponse(c, 400, "Invalid phone number", "INVALID_PHONE", err.Error())
 	}
@@ -307,6 +308,7 @@ func SendMediaURL(c echo.Context) error {
 	}
 	// Increment daily message count
 	_ = model.IncrementMessageCount(instanceID)
+	service.RecordOutgoingMessage(instanceID, recipient, msg, resp)
 
 
 	// 14. SUCCESS RESPONSE
@@ -346,7 +348,7 @@ func SendMediaURLByNumber(c echo.Context) error {
 	// 1.5) Check Permission
 	userClaims, _ := c.Get("user_claims").(*service.Claims)
 	if userClaims != nil && userClaims.Role != "admin" {
-		_, err := model.CheckUserInstancePermission(userClaims.UserID, inst.InstanceID)
+		err := model.CheckUserInstanceLevel(userClaims.UserID, userClaims.Role, inst.InstanceID, model.PermissionSend)
 		if err != nil {
 			return ErrorResponse(c, 403, "Insufficient permission to use this phone number", "FORBIDDEN", "")
 		}
@@ -369,7 +371,7 @@ func SendMediaURLByNumber(c echo.Context) error {
 		return ErrorResponse(c, 400, "Not logged in", "NOT_LOGGED_IN", "Please scan QR code first")
 	}
 
-	recipient, err := helper.FormatPhoneNumber(req.To)
+	recipient, err := helper.FormatPhoneNumber(req.To, model.GetInstanceDefaultCountry(inst.InstanceID))
 	if err != nil {
 		return ErrorResponse(c, 400, "Invalid phone number", "INVALID_PHONE", err.Error())
 	}
@@ -455,6 +457,7 @@ func SendMediaURLByNumber(c echo.Context) error {
 	}
 	// Increment daily message count
 	_ = model.IncrementMessageCount(inst.InstanceID)
+	service.RecordOutgoingMessage(inst.InstanceID, recipient, msg, resp)
 
 	return SuccessResponse(c, 200, "Media sent successfully", map[string]interface{}{
 		"from":      phoneNumber,
@@ -492,7 +495,7 @@ func SendMediaFileByNumber(c echo.Context) error {
 	// 1.5) Check Permission
 	userClaims, _ := c.Get("user_claims").(*service.Claims)
 	if userClaims != nil && userClaims.Role != "admin" {
-		_, err := model.CheckUserInstancePermission(userClaims.UserID, inst.InstanceID)
+		err := model.CheckUserInstanceLevel(userClaims.UserID, userClaims.Role, inst.InstanceID, model.PermissionSend)
 		if err != nil {
 			return ErrorResponse(c, 403, "Insufficient permission to use this phone number", "FORBIDDEN", "")
 		}
@@ -520,7 +523,7 @@ func SendMediaFileByNumber(c echo.Context) error {
 	}
 
 	// 6. FORMAT & VALIDATE PHONE NUMBER TUJUAN
-	recipient, err := helper.FormatPhoneNumber(to)
+	recipient, err := helper.FormatPhoneNumber(to, model.GetInstanceDefaultCountry(inst.InstanceID))
 	if err != nil {
 		return ErrorResponse(c, 400, "Invalid phone number", "INVALID_PHONE", err.Error())
 	}
@@ -617,6 +620,7 @@ func SendMediaFileByNumber(c echo.Context) error {
 	}
 	// Increment daily message count
 	_ = model.IncrementMessageCount(inst.InstanceID)
+	service.RecordOutgoingMessage(inst.InstanceID, recipient, msg, resp)
 
 	// 15. SUCCESS RESPONSE
 	return SuccessResponse(c, 200, "Media sent successfully", map[string]interface{}{
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"math/rand"
 	"os"
@@ -54,7 +55,7 @@ func SendMessage(c echo.Context) error {
 		return ErrorResponse(c, 400, "Not logged in", "NOT_LOGGED_IN", "Please scan QR code first")
 	}
 
-	recipient, err := helper.FormatPhoneNumber(req.To)
+	recipient, err := helper.FormatPhoneNumber(req.To, model.GetInstanceDefaultCountry(instanceID))
 	if err != nil {
 		return ErrorResponse(c, 400, "Invalid phone number", "INVALID_PHONE", err.Error())
 	}
@@ -134,6 +135,7 @@ func SendMessage(c echo.Context) error {
 
 	// Increment daily message count
 	_ = model.IncrementMessageCount(instanceID)
+	service.RecordOutgoingMessage(instanceID, recipient, msg, resp)
 
 	return SuccessResponse(c, 200, "Message sent successfully", map[string]interface{}{
 		"messageId": resp.ID,
@@ -176,7 +178,7 @@ func SendMessageByNumber(c echo.Context) error {
 	// 1.5) Check Permission
 	userClaims, _ := c.Get("user_claims").(*service.Claims)
 	if userClaims != nil && userClaims.Role != "admin" {
-		_, err := model.CheckUserInstancePermission(userClaims.UserID, inst.InstanceID)
+		err := model.CheckUserInstanceLevel(userClaims.UserID, userClaims.Role, inst.InstanceID, model.PermissionSend)
 		if err != nil {
 			return ErrorResponse(c, 403, "Insufficient permission to use this phone number", "FORBIDDEN", "")
 		}
@@ -194,7 +196,7 @@ func SendMessageByNumber(c echo.Context) error {
 	}
 
 	// 4) Format recipient & cek registered
-	recipient, err := helper.FormatPhoneNumber(req.To)
+	recipient, err := helper.FormatPhoneNumber(req.To, model.GetInstanceDefaultCountry(inst.InstanceID))
 	if err != nil {
 		return ErrorResponse(c, 400, "Invalid phone number", "INVALID_PHONE", err.Error())
 	}
@@ -273,6 +275,7 @@ func SendMessageByNumber(c echo.Context) error {
 
 	// Increment daily message count
 	_ = model.IncrementMessageCount(inst.InstanceID)
+	service.RecordOutgoingMessage(inst.InstanceID, recipient, msg, resp)
 
 	return SuccessResponse(c, 200, "Message sent successfully", map[string]interface{}{
 		"messageId": resp.ID,
@@ -282,3 +285,49 @@ func SendMessageByNumber(c echo.Context) error {
 		"verified":  true,
 	})
 }
+
+// GET /messages/:instanceId/:messageId/status
+func GetMessageStatus(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+	messageID := c.Param("messageId")
+
+	msg, err := model.GetMessageByMessageID(instanceID, messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrorResponse(c, 404, "Message not found", "MESSAGE_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to get message", "DB_ERROR", err.Error())
+	}
+
+	if !msg.FromMe {
+		return ErrorResponse(c, 400, "Status is only tracked for outgoing messages", "NOT_OUTGOING", "")
+	}
+
+	data := map[string]interface{}{
+		"instanceId": instanceID,
+		"messageId":  msg.MessageID,
+		"chatJid":    msg.ChatJID,
+		"status":     msg.Status.String,
+		"sentAt":     msg.MessageTimestamp,
+	}
+	if msg.StatusUpdatedAt.Valid {
+		data["statusUpdatedAt"] = msg.StatusUpdatedAt.Time
+	}
+
+	// Grup: status di atas = status terjauh dari peserta mana pun, detail per peserta ada di receipts
+	if msg.IsGroup {
+		receipts, err := model.GetMessageReceipts(instanceID, msg.MessageID)
+		if err != nil {
+			return ErrorResponse(c, 500, "Failed to get message receipts", "DB_ERROR", err.Error())
+		}
+
+		counts := map[string]int{}
+		for _, r := range receipts {
+			counts[r.Status]++
+		}
+		data["receipts"] = receipts
+		data["receiptCounts"] = counts
+	}
+
+	return SuccessResponse(c, 200, "Message status retrieved successfully", data)
+}
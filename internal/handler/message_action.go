@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Request body untuk edit message
+type EditMessageRequest struct {
+	Message string `json:"message"`
+}
+
+// Request body untuk pin message
+type PinMessageRequest struct {
+	Duration string `json:"duration"` // 24h (default), 7d, 30d
+}
+
+// Request body untuk bulk revoke pesan blast outbox
+type BulkRevokeRequest struct {
+	Application string `json:"application"`
+	TableID     string `json:"table_id"`
+	IDs         []int  `json:"ids"`
+}
+
+// messageActionError memetakan error dari service edit/revoke/pin ke response
+func messageActionError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrSessionNotReady):
+		return ErrorResponse(c, 400, "Session is not connected", "NOT_CONNECTED", "Please check /status endpoint")
+	case errors.Is(err, service.ErrMessageNotFound):
+		return ErrorResponse(c, 404, "Message not found", "MESSAGE_NOT_FOUND", "")
+	case errors.Is(err, service.ErrNotOwnMessage):
+		return ErrorResponse(c, 403, err.Error(), "NOT_OWN_MESSAGE", "")
+	case errors.Is(err, service.ErrMessageNotEditable):
+		return ErrorResponse(c, 400, err.Error(), "NOT_EDITABLE", "")
+	case errors.Is(err, service.ErrEditWindowExpired):
+		return ErrorResponse(c, 400, err.Error(), "EDIT_WINDOW_EXPIRED", "Messages can only be edited within 20 minutes")
+	case errors.Is(err, service.ErrMessageRevoked):
+		return ErrorResponse(c, 409, err.Error(), "MESSAGE_REVOKED", "")
+	default:
+		return ErrorResponse(c, 500, "Failed to update message", "SEND_FAILED", err.Error())
+	}
+}
+
+// PATCH /messages/:instanceId/:messageId - edit teks pesan
+func EditMessage(c echo.Context) error {
+	var req EditMessageRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	if strings.TrimSpace(req.Message) == "" {
+		return ErrorResponse(c, 400, "Field 'message' is required", "VALIDATION_ERROR", "")
+	}
+
+	msg, err := service.EditMessage(c.Param("instanceId"), c.Param("messageId"), req.Message)
+	if err != nil {
+		return messageActionError(c, err)
+	}
+
+	return SuccessResponse(c, 200, "Message edited successfully", model.ToMessageResponse(*msg))
+}
+
+// DELETE /messages/:instanceId/:messageId - revoke (delete for everyone)
+func RevokeMessage(c echo.Context) error {
+	msg, err := service.RevokeMessage(c.Param("instanceId"), c.Param("messageId"))
+	if err != nil {
+		return messageActionError(c, err)
+	}
+
+	return SuccessResponse(c, 200, "Message revoked successfully", model.ToMessageResponse(*msg))
+}
+
+// POST /messages/:instanceId/:messageId/pin
+func PinMessage(c echo.Context) error {
+	var req PinMessageRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	if req.Duration == "" {
+		req.Duration = "24h"
+	}
+
+	duration, ok := service.PinDurations[req.Duration]
+	if !ok {
+		return ErrorResponse(c, 400, "Invalid pin duration", "VALIDATION_ERROR", "Allowed: 24h, 7d, 30d")
+	}
+
+	msg, err := service.PinMessage(c.Param("instanceId"), c.Param("messageId"), duration)
+	if err != nil {
+		return messageActionError(c, err)
+	}
+
+	return SuccessResponse(c, 200, "Message pinned successfully", map[string]interface{}{
+		"messageId": msg.MessageID,
+		"chatJid":   msg.ChatJID,
+		"duration":  req.Duration,
+	})
+}
+
+// DELETE /messages/:instanceId/:messageId/pin
+func UnpinMessage(c echo.Context) error {
+	msg, err := service.PinMessage(c.Param("instanceId"), c.Param("messageId"), 0)
+	if err != nil {
+		return messageActionError(c, err)
+	}
+
+	return SuccessResponse(c, 200, "Message unpinned successfully", map[string]interface{}{
+		"messageId": msg.MessageID,
+		"chatJid":   msg.ChatJID,
+	})
+}
+
+// POST /blast-outbox/revoke - antrikan revoke semua pesan terkirim dari satu application / table_id / daftar ID outbox.
+// Hanya pesan dari instance yang boleh dipakai kirim oleh user (dan API key) yang masuk ke job;
+// revoke dijalankan bertahap oleh revoke worker di node pemilik instance.
+func BulkRevokeOutbox(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	var req BulkRevokeRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid JSON payload", "INVALID_PAYLOAD", err.Error())
+	}
+
+	if req.Application == "" && req.TableID == "" && len(req.IDs) == 0 {
+		return ErrorResponse(c, http.StatusBadRequest, "At least one of application, table_id or ids is required", "VALIDATION_ERROR", "")
+	}
+
+	messageIDs, err := model.GetSentOutboxMessageIDs(c.Request().Context(), req.Application, req.TableID, req.IDs)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to load sent outbox messages", "DATABASE_ERROR", err.Error())
+	}
+
+	apiKey, _ := c.Get("api_key").(*model.APIKey)
+
+	// Cache hasil cek akses per instance
+	allowed := map[string]bool{}
+	canRevoke := func(instanceID string) bool {
+		if ok, cached := allowed[instanceID]; cached {
+			return ok
+		}
+		ok := model.CheckUserInstanceLevel(claims.UserID, claims.Role, instanceID, model.PermissionSend) == nil &&
+			(apiKey == nil || service.APIKeyAllowsInstance(apiKey, instanceID))
+		allowed[instanceID] = ok
+		return ok
+	}
+
+	messages, err := model.GetOutgoingMessages(c.Request().Context(), messageIDs)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to load sent messages", "DATABASE_ERROR", err.Error())
+	}
+
+	var items []model.RevokeJobItem
+	notFound, alreadyRevoked, forbidden := 0, 0, 0
+	for _, messageID := range messageIDs {
+		msg, ok := messages[messageID]
+		if !ok {
+			notFound++
+			continue
+		}
+		if !canRevoke(msg.InstanceID) {
+			forbidden++
+			continue
+		}
+		if msg.RevokedAt.Valid {
+			alreadyRevoked++
+			continue
+		}
+		items = append(items, model.RevokeJobItem{InstanceID: msg.InstanceID, WAMessageID: messageID})
+	}
+
+	if len(items) == 0 && forbidden > 0 {
+		return ErrorResponse(c, http.StatusForbidden, "You do not have send access to the instances of these messages", "FORBIDDEN", "")
+	}
+
+	job := &model.RevokeJob{
+		UserID:      sql.NullInt64{Int64: claims.UserID, Valid: true},
+		Application: sql.NullString{String: req.Application, Valid: req.Application != ""},
+		TableID:     sql.NullString{String: req.TableID, Valid: req.TableID != ""},
+		Skipped:     notFound + alreadyRevoked + forbidden,
+	}
+	if err := model.CreateRevokeJob(job, items); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to queue bulk revoke", "DATABASE_ERROR", err.Error())
+	}
+
+	return SuccessResponse(c, http.StatusAccepted, "Bulk revoke queued", map[string]interface{}{
+		"job_id":          job.ID,
+		"queued":          len(items),
+		"not_found":       notFound,
+		"already_revoked": alreadyRevoked,
+		"forbidden":       forbidden,
+	})
+}
+
+// GET /blast-outbox/revoke/:jobId - progress bulk revoke
+func GetBulkRevokeJob(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	jobID, err := strconv.ParseInt(c.Param("jobId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid job ID", "INVALID_ID", "")
+	}
+
+	job, resp, err := model.GetRevokeJob(jobID)
+	if err == sql.ErrNoRows {
+		return ErrorResponse(c, http.StatusNotFound, "Revoke job not found", "NOT_FOUND", "")
+	}
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve revoke job", "DATABASE_ERROR", err.Error())
+	}
+
+	// Job milik user lain disembunyikan (kecuali admin)
+	if claims.Role != "admin" && (!job.UserID.Valid || job.UserID.Int64 != claims.UserID) {
+		return ErrorResponse(c, http.StatusNotFound, "Revoke job not found", "NOT_FOUND", "")
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Revoke job retrieved", resp)
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Cookie berisi state, nonce dan PKCE verifier selama user berada di halaman provider
+const oidcFlowCookie = "oidc_flow"
+
+// GetOAuthProviders returns the enabled SSO providers for the login page
+// GET /auth/providers
+func GetOAuthProviders(c echo.Context) error {
+	return SuccessResponse(c, http.StatusOK, "SSO providers retrieved", service.ListOIDCProviders())
+}
+
+// OAuthLogin redirects the browser to the provider's authorization page
+// GET /auth/:provider/login
+func OAuthLogin(c echo.Context) error {
+	authURL, flowToken, err := service.StartOIDCLogin(c.Param("provider"))
+	if err != nil {
+		if errors.Is(err, service.ErrOIDCUnknownProvider) {
+			return ErrorResponse(c, http.StatusNotFound, "SSO provider not found or not enabled", "UNKNOWN_PROVIDER", "")
+		}
+		return ErrorResponse(c, http.StatusBadGateway, "SSO provider is unavailable", "PROVIDER_UNAVAILABLE", err.Error())
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    flowToken,
+		Path:     "/auth/",
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   service.OAuthSecureCookie(),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return c.Redirect(http.StatusFound, authURL)
+}
+
+// OAuthCallback handles the redirect back from the provider and issues the same tokens as /login
+// GET /auth/:provider/callback
+func OAuthCallback(c echo.Context) error {
+	provider := c.Param("provider")
+
+	flowToken := ""
+	if cookie, err := c.Cookie(oidcFlowCookie); err == nil {
+		flowToken = cookie.Value
+	}
+	// State hanya boleh dipakai sekali
+	c.SetCookie(&http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    "",
+		Path:     "/auth/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   service.OAuthSecureCookie(),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	if c.QueryParam("error") != "" {
+		return oauthFailure(c, http.StatusUnauthorized, "Login was cancelled or denied by the provider", "OAUTH_DENIED")
+	}
+
+	user, action, err := service.CompleteOIDCLogin(provider, c.QueryParam("code"), c.QueryParam("state"), flowToken)
+	if err != nil {
+		return oauthError(c, provider, err)
+	}
+
+	switch action {
+	case "provision":
+		logUserAction(c, user.ID, user.Username, "user.register", map[string]interface{}{
+			"provider": provider,
+			"role":     user.Role,
+		})
+	case "link":
+		logUserAction(c, user.ID, user.Username, "user.oauth_link", map[string]interface{}{
+			"provider": provider,
+		})
+	}
+
+	// User dengan 2FA tetap melewati POST /login/2fa seperti login password
+	needed, enroll, err := service.TwoFactorStep(user)
+	if err != nil {
+		return oauthFailure(c, http.StatusInternalServerError, "Failed to retrieve security policy", "INTERNAL_ERROR")
+	}
+	if needed {
+		message, data, aerr := twoFactorChallenge(user, enroll)
+		if aerr != nil {
+			return oauthFailure(c, aerr.status, aerr.message, aerr.code)
+		}
+		return oauthSuccess(c, message, data, url.Values{
+			"two_factor_required": {"true"},
+			"enrollment_required": {strconv.FormatBool(enroll)},
+			"challenge_token":     {data["challenge_token"].(string)},
+		})
+	}
+
+	resp, aerr := loginTokens(c, user, map[string]interface{}{"provider": provider})
+	if aerr != nil {
+		return oauthFailure(c, aerr.status, aerr.message, aerr.code)
+	}
+
+	return oauthSuccess(c, "Login successful", resp, url.Values{
+		"access_token":  {resp.AccessToken},
+		"refresh_token": {resp.RefreshToken},
+	})
+}
+
+// oauthSuccess mengirim hasil ke frontend (OAUTH_SUCCESS_REDIRECT_URL, di URL fragment) atau sebagai JSON
+func oauthSuccess(c echo.Context, message string, data interface{}, fragment url.Values) error {
+	if redirect := service.OAuthSuccessRedirectURL(); redirect != "" {
+		return c.Redirect(http.StatusFound, redirect+"#"+fragment.Encode())
+	}
+	return SuccessResponse(c, http.StatusOK, message, data)
+}
+
+// oauthFailure mengirim error ke frontend (fragment error / error_description) atau sebagai JSON
+func oauthFailure(c echo.Context, status int, message, code string) error {
+	if redirect := service.OAuthSuccessRedirectURL(); redirect != "" {
+		fragment := url.Values{"error": {code}, "error_description": {message}}
+		return c.Redirect(http.StatusFound, redirect+"#"+fragment.Encode())
+	}
+	return ErrorResponse(c, status, message, code, "")
+}
+
+// oauthError maps SSO service errors to responses
+func oauthError(c echo.Context, provider string, err error) error {
+	switch {
+	case errors.Is(err, service.ErrOIDCUnknownProvider):
+		return oauthFailure(c, http.StatusNotFound, "SSO provider not found or not enabled", "UNKNOWN_PROVIDER")
+	case errors.Is(err, service.ErrOIDCInvalidState):
+		return oauthFailure(c, http.StatusBadRequest, "Login session expired or invalid, please try again", "INVALID_STATE")
+	case errors.Is(err, service.ErrOIDCExchangeFailed), errors.Is(err, service.ErrOIDCInvalidIDToken):
+		return oauthFailure(c, http.StatusUnauthorized, "Could not verify the login with the provider", "OAUTH_FAILED")
+	case errors.Is(err, service.ErrOIDCProviderMisconfig):
+		return oauthFailure(c, http.StatusBadGateway, "SSO provider is unavailable", "PROVIDER_UNAVAILABLE")
+	case errors.Is(err, service.ErrOIDCEmailNotVerified):
+		return oauthFailure(c, http.StatusForbidden, "Your email is not verified by the provider", "EMAIL_NOT_VERIFIED")
+	case errors.Is(err, service.ErrOIDCDomainNotAllowed):
+		return oauthFailure(c, http.StatusForbidden, "Your email domain is not allowed to sign in", "DOMAIN_NOT_ALLOWED")
+	case errors.Is(err, service.ErrOIDCNoAccount):
+		return oauthFailure(c, http.StatusForbidden, "No account exists for this identity. Ask an administrator for access.", "ACCOUNT_NOT_FOUND")
+	case errors.Is(err, service.ErrOIDCAccountLinked):
+		return oauthFailure(c, http.StatusConflict, "An account with this email is already linked to another sign-in method", "ACCOUNT_ALREADY_LINKED")
+	case errors.Is(err, service.ErrOIDCAccountNotLinked):
+		return oauthFailure(c, http.StatusConflict, "An account with this email already exists. Sign in with your password.", "ACCOUNT_EXISTS")
+	case errors.Is(err, service.ErrOIDCAdminNotLinked):
+		return oauthFailure(c, http.StatusConflict, "Admin accounts cannot be linked to SSO. Sign in with your password.", "ADMIN_LINK_NOT_ALLOWED")
+	case errors.Is(err, service.ErrOIDCAccountDisabled):
+		return oauthFailure(c, http.StatusForbidden, "User account is disabled", "ACCOUNT_DISABLED")
+	default:
+		log.Printf("❌ SSO login via %s failed: %v", provider, err)
+		return oauthFailure(c, http.StatusInternalServerError, "SSO login failed", "INTERNAL_ERROR")
+	}
+}
@@ -1,10 +1,12 @@
 package handler
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/phone"
 	"io"
 	"net/http"
 	"strconv"
@@ -28,6 +30,7 @@ type OutboxRequest struct {
 	SendingDateTime *time.Time `json:"sending_datetime"`
 	TableID         string     `json:"table_id"`
 	File            string     `json:"file"`
+	Country         string     `json:"country"` // default country untuk normalisasi destination (opsional)
 }
 
 // OutboxResponse represents the clean JSON response structure
@@ -47,6 +50,8 @@ type OutboxResponse struct {
 	File            *string    `json:"file"`
 	ErrorCount      int        `json:"error_count"`
 	MsgError        *string    `json:"msg_error"`
+	WaMessageID     *string    `json:"wa_message_id"`
+	ReceiptStatus   *string    `json:"receipt_status"`
 }
 
 func ToResponse(m model.Outbox) OutboxResponse {
@@ -83,6 +88,12 @@ func ToResponse(m model.Outbox) OutboxResponse {
 	if m.MsgError.Valid {
 		resp.MsgError = &m.MsgError.String
 	}
+	if m.WaMessageID.Valid {
+		resp.WaMessageID = &m.WaMessageID.String
+	}
+	if m.ReceiptStatus.Valid {
+		resp.ReceiptStatus = &m.ReceiptStatus.String
+	}
 
 	return resp
 }
@@ -127,6 +138,40 @@ func (req *OutboxRequest) ToModel() model.Outbox {
 	return m
 }
 
+// normalizeDestination menormalkan nomor tujuan outbox ke E.164. Group ID (mengandung '@'
+// atau lebih dari 15 digit) dibiarkan apa adanya karena dinormalkan oleh worker grup.
+func normalizeDestination(destination, country string) (string, error) {
+	destination = strings.TrimSpace(destination)
+	if strings.Contains(destination, "@") {
+		return destination, nil
+	}
+
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, destination)
+	if len(digits) > 15 {
+		return destination, nil
+	}
+
+	return phone.Normalize(destination, country)
+}
+
+// outboxCountry returns default country untuk request outbox:
+// field 'country' -> circle worker yang memproses application -> DEFAULT_PHONE_COUNTRY
+func outboxCountry(ctx context.Context, country, application string) (string, error) {
+	if country != "" {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if !phone.IsSupportedRegion(country) {
+			return "", fmt.Errorf("unsupported country %q", country)
+		}
+		return country, nil
+	}
+	return model.GetApplicationDefaultCountry(ctx, application), nil
+}
+
 // CreateOutboxQueue handles requests to add message(s) to the outbox queue
 func CreateOutboxQueue(c echo.Context) error {
 	body := c.Request().Body
@@ -170,12 +215,21 @@ func CreateOutboxQueue(c echo.Context) error {
 
 		// Validation & conversion
 		var models []model.Outbox
-		for _, req := range reqs {
+		for i, req := range reqs {
 			if req.Destination == "" || req.Messages == "" {
 				return ErrorResponse(c, http.StatusBadRequest, "destination and messages are required for all records", "VALIDATION_ERROR", "")
 			}
+			country, err := outboxCountry(ctx, req.Country, req.Application)
+			if err != nil {
+				return ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid country at index %d", i), "INVALID_COUNTRY", err.Error())
+			}
+			destination, err := normalizeDestination(req.Destination, country)
+			if err != nil {
+				return ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid destination at index %d", i), "INVALID_PHONE", err.Error())
+			}
+			req.Destination = destination
 			if shouldReplace, _ := model.ShouldReplacePendingForApp(ctx, req.Application); shouldReplace {
-				_, _ = model.CancelPendingOutboxForApp(ctx, req.Destination, req.Application)
+				_, _ = model.CancelPendingOutboxForApp(ctx, req.Destination, req.Application, country)
 			}
 			models = append(models, req.ToModel())
 		}
@@ -197,8 +251,18 @@ func CreateOutboxQueue(c echo.Context) error {
 			return ErrorResponse(c, http.StatusBadRequest, "destination and messages are required", "VALIDATION_ERROR", "")
 		}
 
+		country, err := outboxCountry(ctx, req.Country, req.Application)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "Invalid country", "INVALID_COUNTRY", err.Error())
+		}
+		destination, err := normalizeDestination(req.Destination, country)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "Invalid destination", "INVALID_PHONE", err.Error())
+		}
+		req.Destination = destination
+
 		if shouldReplace, _ := model.ShouldReplacePendingForApp(ctx, req.Application); shouldReplace {
-			_, _ = model.CancelPendingOutboxForApp(ctx, req.Destination, req.Application)
+			_, _ = model.CancelPendingOutboxForApp(ctx, req.Destination, req.Application, country)
 		}
 
 		outboxModel := req.ToModel()
@@ -322,6 +386,13 @@ func ImportOutboxExcel(c echo.Context) error {
 	}
 
 	defaultApp := c.FormValue("application")
+	ctx := c.Request().Context()
+
+	// Default country untuk nomor nasional (mis. 08xx); kosong = ikut circle worker / DEFAULT_PHONE_COUNTRY
+	formCountry := c.FormValue("country")
+	if _, err := outboxCountry(ctx, formCountry, ""); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid country", "INVALID_COUNTRY", err.Error())
+	}
 
 	destIdx := 0
 	msgIdx := 1
@@ -348,6 +419,8 @@ func ImportOutboxExcel(c echo.Context) error {
 
 	var models []model.Outbox
 	skippedCount := 0
+	invalidRows := []map[string]interface{}{}
+	appCountries := map[string]string{}
 
 	for i := startRow; i < len(rows); i++ {
 		row := rows[i]
@@ -376,6 +449,23 @@ func ImportOutboxExcel(c echo.Context) error {
 			continue
 		}
 
+		country, ok := appCountries[appStr]
+		if !ok {
+			country, _ = outboxCountry(ctx, formCountry, appStr)
+			appCountries[appStr] = country
+		}
+
+		normalized, err := normalizeDestination(dest, country)
+		if err != nil {
+			invalidRows = append(invalidRows, map[string]interface{}{
+				"row":         i + 1,
+				"destination": dest,
+				"error":       err.Error(),
+			})
+			continue
+		}
+		dest = normalized
+
 		m := model.Outbox{
 			Type:        1,
 			Destination: dest,
@@ -391,10 +481,9 @@ func ImportOutboxExcel(c echo.Context) error {
 	}
 
 	if len(models) == 0 {
-		return ErrorResponse(c, http.StatusBadRequest, "No valid message rows found in Excel file", "NO_VALID_DATA", fmt.Sprintf("Skipped rows: %d", skippedCount))
+		return ErrorResponse(c, http.StatusBadRequest, "No valid message rows found in Excel file", "NO_VALID_DATA", fmt.Sprintf("Skipped rows: %d, invalid destinations: %d", skippedCount, len(invalidRows)))
 	}
 
-	ctx := c.Request().Context()
 	if err := model.CreateOutboxBatch(ctx, models); err != nil {
 		return ErrorResponse(c, http.StatusInternalServerError, "Failed to save imported outbox records", "DATABASE_ERROR", err.Error())
 	}
@@ -402,6 +491,8 @@ func ImportOutboxExcel(c echo.Context) error {
 	return SuccessResponse(c, http.StatusCreated, "Excel import completed successfully", map[string]interface{}{
 		"imported_count": len(models),
 		"skipped_count":  skippedCount,
+		"invalid_count":  len(invalidRows),
+		"invalid_rows":   invalidRows,
 		"total_rows":     len(rows) - startRow,
 	})
 }
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/phone"
+	"gowa-yourself/internal/service"
+	"gowa-yourself/internal/ws"
+
+	"github.com/labstack/echo/v4"
+	"go.mau.fi/whatsmeow"
+)
+
+// pairCodeDisplayName harus berformat "Browser (OS)" dan hanya browser/OS umum yang diterima server WA
+const pairCodeDisplayName = "Chrome (Linux)"
+
+// pairCodeLifetime: login websocket ditutup setelah QR habis (~160 detik), linking code ikut tidak berlaku
+const pairCodeLifetime = 160 * time.Second
+
+// pairCodeStartTimeout adalah batas menunggu koneksi login siap sebelum PairPhone dipanggil
+const pairCodeStartTimeout = 30 * time.Second
+
+type PairCodeRequest struct {
+	PhoneNumber string `json:"phoneNumber"`
+}
+
+// POST /pair-code/:instanceId
+// Alternatif QR: generate linking code 8 karakter yang dimasukkan di HP
+// (WhatsApp > Perangkat tertaut > Tautkan dengan nomor telepon).
+// Berbagi qrCancelFuncs dengan GetQR, jadi bisa dibatalkan via DELETE /qr-cancel/:instanceId.
+func PairCode(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	var req PairCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+	if strings.TrimSpace(req.PhoneNumber) == "" {
+		return ErrorResponse(c, 400, "phoneNumber is required", "VALIDATION_ERROR", "")
+	}
+
+	phoneNumber, err := phone.Normalize(req.PhoneNumber, model.GetInstanceDefaultCountry(instanceID))
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid phone number", "INVALID_PHONE", err.Error())
+	}
+
+	// Cek apakah sudah ada QR generation / pairing code yang sedang berjalan
+	if loginInProgress(instanceID) {
+		return ErrorResponse(c, 409, "QR generation already in progress, please wait", "QR_IN_PROGRESS", "Please wait or cancel the current QR generation first.")
+	}
+
+	session, err := service.GetSession(instanceID)
+	if err != nil || session == nil {
+		fmt.Println("⚠ Session not found in memory, creating new session for instance:", instanceID)
+		session, err = service.CreateSession(instanceID)
+		if err != nil {
+			return ErrorResponse(c, 500, "Failed to create session", "CREATE_SESSION_FAILED", err.Error())
+		}
+		fmt.Println("✓ New session created for existing instance:", instanceID)
+	}
+
+	if session.IsConnected {
+		return SuccessResponse(c, 200, "Already connected", map[string]interface{}{
+			"status": "already_connected",
+			"jid":    session.Client.Store.ID.String(),
+		})
+	}
+
+	ctx, done, ok := beginLoginAttempt(instanceID)
+	if !ok {
+		return ErrorResponse(c, 409, "QR generation already in progress, please wait", "QR_IN_PROGRESS", "Please wait or cancel the current QR generation first.")
+	}
+
+	qrChan, err := session.Client.GetQRChannel(ctx)
+	if err != nil {
+		done()
+		return ErrorResponse(c, 500, "Failed to start pairing", "QR_CHANNEL_FAILED", err.Error())
+	}
+
+	if err := session.Client.Connect(); err != nil {
+		done()
+		return ErrorResponse(c, 500, "Failed to connect", "CONNECT_FAILED", err.Error())
+	}
+
+	// PairPhone baru boleh dipanggil setelah item pertama QR channel (koneksi login sudah siap)
+	startedAt := time.Now()
+	select {
+	case evt, open := <-qrChan:
+		if !open || evt.Event != "code" {
+			done()
+			reason := "QR channel closed"
+			if open {
+				reason = evt.Event
+			}
+			return ErrorResponse(c, 502, "Failed to start pairing", "PAIR_CODE_FAILED", reason)
+		}
+	case <-time.After(pairCodeStartTimeout):
+		done()
+		return ErrorResponse(c, 504, "Timed out waiting for WhatsApp login connection", "PAIR_CODE_TIMEOUT", "")
+	case <-ctx.Done():
+		done()
+		return ErrorResponse(c, 409, "Pairing cancelled", "PAIR_CODE_CANCELLED", ctx.Err().Error())
+	}
+
+	code, err := session.Client.PairPhone(ctx, phoneNumber, true, whatsmeow.PairClientChrome, pairCodeDisplayName)
+	if err != nil {
+		done()
+		return ErrorResponse(c, 502, "Failed to generate pairing code", "PAIR_CODE_FAILED", err.Error())
+	}
+
+	expiresAt := startedAt.Add(pairCodeLifetime)
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(expiresAt) {
+		expiresAt = deadline
+	}
+
+	log.Printf("🔗 Pairing code generated for instance %s (%s)", instanceID, phoneNumber)
+
+	if service.Realtime != nil {
+		service.Realtime.Publish(ws.WsEvent{
+			Event:     ws.EventPairCodeGenerated,
+			Timestamp: time.Now().UTC(),
+			Data: ws.PairCodeGeneratedData{
+				InstanceID:  instanceID,
+				PhoneNumber: phoneNumber,
+				PairCode:    code,
+				ExpiresAt:   expiresAt,
+			},
+		})
+	}
+
+	// Tunggu hasil pairing di background (success / expired / cancel)
+	go watchPairCode(ctx, done, instanceID, phoneNumber, qrChan)
+
+	return SuccessResponse(c, 200, "Pairing code generated", map[string]interface{}{
+		"status":      "pair_code_generated",
+		"instance_id": instanceID,
+		"phoneNumber": phoneNumber,
+		"pairCode":    code,
+		"expiresAt":   expiresAt,
+		"message":     "Enter this code on the phone: WhatsApp > Linked devices > Link with phone number. Listen to PAIR_CODE_SUCCESS event.",
+	})
+}
+
+// watchPairCode membaca sisa QR channel setelah linking code dibuat. QR code baru diabaikan
+// (linking code tetap berlaku selama login websocket terbuka).
+func watchPairCode(ctx context.Context, done func(), instanceID, phoneNumber string, qrChan <-chan whatsmeow.QRChannelItem) {
+	defer done()
+
+	for evt := range qrChan {
+		select {
+		case <-ctx.Done():
+			println("\n✗ Pairing code cancelled or timeout for instance:", instanceID)
+			publishPairCodeExpired(instanceID, phoneNumber, "cancelled", ctx.Err().Error())
+			return
+		default:
+		}
+
+		switch {
+		case evt.Event == "code":
+			// QR refresh, tidak relevan untuk login via linking code
+
+		case evt.Event == "success":
+			println("\n✓ Pairing code accepted for instance:", instanceID)
+			if service.Realtime != nil {
+				service.Realtime.Publish(ws.WsEvent{
+					Event:     ws.EventPairCodeSuccess,
+					Timestamp: time.Now().UTC(),
+					Data: map[string]interface{}{
+						"instance_id":  instanceID,
+						"phone_number": phoneNumber,
+						"status":       "connected",
+					},
+				})
+			}
+			return
+
+		case evt.Event == "timeout":
+			println("\n✗ Pairing code expired for instance:", instanceID)
+			publishPairCodeExpired(instanceID, phoneNumber, "timeout", "")
+			return
+
+		case strings.HasPrefix(evt.Event, "err-"):
+			println("\n✗ Pairing error for instance:", instanceID, "->", evt.Event)
+			if service.Realtime != nil {
+				service.Realtime.Publish(ws.WsEvent{
+					Event:     ws.EventInstanceError,
+					Timestamp: time.Now().UTC(),
+					Data: map[string]interface{}{
+						"instance_id": instanceID,
+						"error":       evt.Event,
+					},
+				})
+			}
+			return
+		}
+	}
+
+	// Channel ditutup karena context selesai (cancel via /qr-cancel atau timeout 3 menit)
+	if ctx.Err() != nil {
+		publishPairCodeExpired(instanceID, phoneNumber, "cancelled", ctx.Err().Error())
+		return
+	}
+	publishPairCodeExpired(instanceID, phoneNumber, "closed", "QR channel closed unexpectedly")
+}
+
+func publishPairCodeExpired(instanceID, phoneNumber, status, reason string) {
+	if service.Realtime == nil {
+		return
+	}
+
+	data := map[string]interface{}{
+		"instance_id":  instanceID,
+		"phone_number": phoneNumber,
+		"status":       status,
+	}
+	if reason != "" {
+		data["reason"] = reason
+	}
+
+	service.Realtime.Publish(ws.WsEvent{
+		Event:     ws.EventPairCodeExpired,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	})
+}
@@ -45,3 +45,15 @@ func ErrorResponse(c echo.Context, statusCode int, message string, errorCode str
 	log.Println("Error:", message, "| Code:", errorCode, "| Details:", details)
 	return c.JSON(statusCode, response)
 }
+
+// apiError membawa info error dari helper validasi sampai ke handler (dikirim via ErrorResponse)
+type apiError struct {
+	status  int
+	message string
+	code    string
+	details string
+}
+
+func (e *apiError) send(c echo.Context) error {
+	return ErrorResponse(c, e.status, e.message, e.code, e.details)
+}
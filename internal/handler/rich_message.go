@@ -0,0 +1,611 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// richScope menentukan cara handler mencari instance (by instance ID / by nomor pengirim)
+// dan jenis tujuan (nomor personal / grup), sama seperti route text yang sudah ada.
+type richScope struct {
+	byNumber bool
+	group    bool
+}
+
+var (
+	scopeInstance      = richScope{}
+	scopeNumber        = richScope{byNumber: true}
+	scopeGroup         = richScope{group: true}
+	scopeGroupByNumber = richScope{byNumber: true, group: true}
+)
+
+// RichTarget adalah tujuan pesan: 'to' untuk route personal, 'groupJid' untuk route grup
+type RichTarget struct {
+	To       string `json:"to"`
+	GroupJID string `json:"groupJid"`
+}
+
+func (t RichTarget) target() RichTarget { return t }
+
+type richRequest interface {
+	target() RichTarget
+}
+
+// richChat adalah instance, session dan JID tujuan yang sudah tervalidasi
+type richChat struct {
+	InstanceID string
+	Session    *model.Session
+	JID        types.JID
+	IsGroup    bool
+	recording  bool // tampilkan "recording audio" alih-alih "typing" (voice note)
+}
+
+// Request body untuk send location
+type SendLocationRequest struct {
+	RichTarget
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Name      string   `json:"name"`
+	Address   string   `json:"address"`
+}
+
+// ContactCard adalah satu kontak untuk send contact (vCard)
+type ContactCard struct {
+	Name         string `json:"name"`
+	Phone        string `json:"phone"`
+	Organization string `json:"organization"`
+	Email        string `json:"email"`
+	VCard        string `json:"vcard"` // opsional: vCard mentah, field lain diabaikan kecuali name
+}
+
+// Request body untuk send contact
+type SendContactRequest struct {
+	RichTarget
+	Contacts []ContactCard `json:"contacts"`
+}
+
+// Request body untuk send poll
+type SendPollRequest struct {
+	RichTarget
+	Question        string   `json:"question"`
+	Options         []string `json:"options"`
+	SelectableCount int      `json:"selectableCount"` // 0 = boleh pilih banyak, 1 = single choice
+}
+
+// Request body untuk send reaction
+type SendReactionRequest struct {
+	RichTarget
+	MessageID   string `json:"messageId"`
+	Emoji       string `json:"emoji"`       // kosong = hapus reaction
+	Participant string `json:"participant"` // pengirim pesan asli, opsional kalau pesan ada di inbox
+}
+
+// Request body untuk send reply (quoted message)
+type SendReplyRequest struct {
+	RichTarget
+	Message           string `json:"message"`
+	QuotedMessageID   string `json:"quotedMessageId"`
+	QuotedParticipant string `json:"quotedParticipant"` // opsional kalau pesan ada di inbox
+}
+
+// Batas opsi poll dari WhatsApp
+const (
+	minPollOptions = 2
+	maxPollOptions = 12
+)
+
+// =====================================================
+// LOCATION
+// =====================================================
+
+// POST /send/:instanceId/location
+func SendLocation(c echo.Context) error {
+	return sendRichMessage(c, scopeInstance, buildLocationMessage)
+}
+
+// POST /by-number/:phoneNumber/location
+func SendLocationByNumber(c echo.Context) error {
+	return sendRichMessage(c, scopeNumber, buildLocationMessage)
+}
+
+// POST /send-group/:instanceId/location
+func SendGroupLocation(c echo.Context) error {
+	return sendRichMessage(c, scopeGroup, buildLocationMessage)
+}
+
+// POST /send-group/by-number/:phoneNumber/location
+func SendGroupLocationByNumber(c echo.Context) error {
+	return sendRichMessage(c, scopeGroupByNumber, buildLocationMessage)
+}
+
+func buildLocationMessage(chat *richChat, req *SendLocationRequest) (*waE2E.Message, *apiError) {
+	if req.Latitude == nil || req.Longitude == nil {
+		return nil, &apiError{400, "Fields 'latitude' and 'longitude' are required", "VALIDATION_ERROR", ""}
+	}
+	if *req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180 {
+		return nil, &apiError{400, "Invalid coordinates", "VALIDATION_ERROR", "latitude must be -90..90 and longitude -180..180"}
+	}
+
+	location := &waE2E.LocationMessage{
+		DegreesLatitude:  proto.Float64(*req.Latitude),
+		DegreesLongitude: proto.Float64(*req.Longitude),
+	}
+	if req.Name != "" {
+		location.Name = proto.String(req.Name)
+	}
+	if req.Address != "" {
+		location.Address = proto.String(req.Address)
+	}
+
+	return &waE2E.Message{LocationMessage: location}, nil
+}
+
+// =====================================================
+// CONTACT (vCard)
+// =====================================================
+
+// POST /send/:instanceId/contact
+func SendContact(c echo.Context) error {
+	return sendRichMessage(c, scopeInstance, buildContactMessage)
+}
+
+// POST /by-number/:phoneNumber/contact
+func SendContactByNumber(c echo.Context) error {
+	return sendRichMessage(c, scopeNumber, buildContactMessage)
+}
+
+// POST /send-group/:instanceId/contact
+func SendGroupContact(c echo.Context) error {
+	return sendRichMessage(c, scopeGroup, buildContactMessage)
+}
+
+// POST /send-group/by-number/:phoneNumber/contact
+func SendGroupContactByNumber(c echo.Context) error {
+	return sendRichMessage(c, scopeGroupByNumber, buildContactMessage)
+}
+
+func buildContactMessage(chat *richChat, req *SendContactRequest) (*waE2E.Message, *apiError) {
+	if len(req.Contacts) == 0 {
+		return nil, &apiError{400, "Field 'contacts' must contain at least one contact", "VALIDATION_ERROR", ""}
+	}
+
+	contacts := make([]*waE2E.ContactMessage, 0, len(req.Contacts))
+	for i, card := range req.Contacts {
+		if card.Name == "" || (card.Phone == "" && card.VCard == "") {
+			return nil, &apiError{400, fmt.Sprintf("Contact #%d requires 'name' and 'phone' (or 'vcard')", i+1), "VALIDATION_ERROR", ""}
+		}
+
+		vcard := card.VCard
+		if vcard == "" {
+			vcard = buildVCard(card)
+		}
+
+		contacts = append(contacts, &waE2E.ContactMessage{
+			DisplayName: proto.String(card.Name),
+			Vcard:       proto.String(vcard),
+		})
+	}
+
+	if len(contacts) == 1 {
+		return &waE2E.Message{ContactMessage: contacts[0]}, nil
+	}
+
+	return &waE2E.Message{
+		ContactsArrayMessage: &waE2E.ContactsArrayMessage{
+			DisplayName: proto.String(fmt.Sprintf("%d contacts", len(contacts))),
+			Contacts:    contacts,
+		},
+	}, nil
+}
+
+// nonDigit dipakai untuk membuat waid dari nomor telepon
+var nonDigit = regexp.MustCompile(`[^\d]`)
+
+// vCardEscaper escape nilai teks vCard (RFC 2426 bagian 4): backslash, koma, titik koma dan newline,
+// supaya input seperti "Budi\nTEL:..." tidak bisa menyisipkan property baru
+var vCardEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	",", `\,`,
+	";", `\;`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// vCardText escape s untuk dipakai sebagai nilai vCard; karakter kontrol lain dibuang
+func vCardText(s string) string {
+	s = vCardEscaper.Replace(s)
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// buildVCard membuat vCard 3.0; waid membuat tombol "Message" di WhatsApp langsung ke nomor tsb
+func buildVCard(card ContactCard) string {
+	waID := nonDigit.ReplaceAllString(card.Phone, "")
+
+	var b strings.Builder
+	b.WriteString("BEGIN:VCARD\nVERSION:3.0\n")
+	b.WriteString("FN:" + vCardText(card.Name) + "\n")
+	if card.Organization != "" {
+		b.WriteString("ORG:" + vCardText(card.Organization) + ";\n")
+	}
+	b.WriteString("TEL;type=CELL;type=VOICE;waid=" + waID + ":+" + waID + "\n")
+	if card.Email != "" {
+		b.WriteString("EMAIL:" + vCardText(card.Email) + "\n")
+	}
+	b.WriteString("END:VCARD")
+
+	return b.String()
+}
+
+// =====================================================
+// POLL
+// =====================================================
+
+// POST /send/:instanceId/poll
+func SendPoll(c echo.Context) error {
+	return sendRichMessage(c, scopeInstance, buildPollMessage)
+}
+
+// POST /by-number/:phoneNumber/poll
+func SendPollByNumber(c echo.Context) error {
+	return sendRichMessage(c, scopeNumber, buildPollMessage)
+}
+
+// POST /send-group/:instanceId/poll
+func SendGroupPoll(c echo.Context) error {
+	return sendRichMessage(c, scopeGroup, buildPollMessage)
+}
+
+// POST /send-group/by-number/:phoneNumber/poll
+func SendGroupPollByNumber(c echo.Context) error {
+	return sendRichMessage(c, scopeGroupByNumber, buildPollMessage)
+}
+
+func buildPollMessage(chat *richChat, req *SendPollRequest) (*waE2E.Message, *apiError) {
+	if req.Question == "" {
+		return nil, &apiError{400, "Field 'question' is required", "VALIDATION_ERROR", ""}
+	}
+
+	if len(req.Options) < minPollOptions || len(req.Options) > maxPollOptions {
+		return nil, &apiError{400, fmt.Sprintf("Poll must have %d-%d options", minPollOptions, maxPollOptions), "VALIDATION_ERROR", ""}
+	}
+
+	// Vote dikirim sebagai hash nama opsi, jadi opsi harus unik
+	seen := make(map[string]bool, len(req.Options))
+	for _, opt := range req.Options {
+		if opt == "" || seen[opt] {
+			return nil, &apiError{400, "Poll options must be unique and not empty", "VALIDATION_ERROR", ""}
+		}
+		seen[opt] = true
+	}
+
+	if req.SelectableCount < 0 || req.SelectableCount > len(req.Options) {
+		return nil, &apiError{400, "Field 'selectableCount' must be between 0 and the number of options", "VALIDATION_ERROR", ""}
+	}
+
+	return chat.Session.Client.BuildPollCreation(req.Question, req.Options, req.SelectableCount), nil
+}
+
+// GET /polls/:instanceId/:messageId - hasil vote poll
+func GetPollResults(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+	messageID := c.Param("messageId")
+
+	poll, err := model.GetPoll(instanceID, messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrorResponse(c, 404, "Poll not found", "POLL_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to get poll", "DB_ERROR", err.Error())
+	}
+
+	votes, err := model.GetPollVotes(instanceID, messageID)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get poll votes", "DB_ERROR", err.Error())
+	}
+
+	return SuccessResponse(c, 200, "Poll results retrieved successfully", model.ToPollResultResponse(*poll, votes))
+}
+
+// =====================================================
+// REACTION
+// =====================================================
+
+// POST /send/:instanceId/reaction
+func SendReaction(c echo.Context) error {
+	return sendRichMessage(c, scopeInstance, buildReactionMessage)
+}
+
+// POST /by-number/:phoneNumber/reaction
+func SendReactionByNumber(c echo.Context) error {
+	return sendRichMessage(c, scopeNumber, buildReactionMessage)
+}
+
+// POST /send-group/:instanceId/reaction
+func SendGroupReaction(c echo.Context) error {
+	return sendRichMessage(c, scopeGroup, buildReactionMessage)
+}
+
+// POST /send-group/by-number/:phoneNumber/reaction
+func SendGroupReactionByNumber(c echo.Context) error {
+	return sendRichMessage(c, scopeGroupByNumber, buildReactionMessage)
+}
+
+func buildReactionMessage(chat *richChat, req *SendReactionRequest) (*waE2E.Message, *apiError) {
+	if req.MessageID == "" {
+		return nil, &apiError{400, "Field 'messageId' is required", "VALIDATION_ERROR", ""}
+	}
+
+	sender, _, apiErr := resolveQuotedMessage(chat, req.MessageID, req.Participant)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return chat.Session.Client.BuildReaction(chat.JID, sender, req.MessageID, req.Emoji), nil
+}
+
+// =====================================================
+// REPLY (quoted message)
+// =====================================================
+
+// POST /send/:instanceId/reply
+func SendReply(c echo.Context) error {
+	return sendRichMessage(c, scopeInstance, buildReplyMessage)
+}
+
+// POST /by-number/:phoneNumber/reply
+func SendReplyByNumber(c echo.Context) error {
+	return sendRichMessage(c, scopeNumber, buildReplyMessage)
+}
+
+// POST /send-group/:instanceId/reply
+func SendGroupReply(c echo.Context) error {
+	return sendRichMessage(c, scopeGroup, buildReplyMessage)
+}
+
+// POST /send-group/by-number/:phoneNumber/reply
+func SendGroupReplyByNumber(c echo.Context) error {
+	return sendRichMessage(c, scopeGroupByNumber, buildReplyMessage)
+}
+
+func buildReplyMessage(chat *richChat, req *SendReplyRequest) (*waE2E.Message, *apiError) {
+	if req.Message == "" || req.QuotedMessageID == "" {
+		return nil, &apiError{400, "Fields 'message' and 'quotedMessageId' are required", "VALIDATION_ERROR", ""}
+	}
+
+	sender, quotedBody, apiErr := resolveQuotedMessage(chat, req.QuotedMessageID, req.QuotedParticipant)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return &waE2E.Message{
+		ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+			Text: proto.String(req.Message),
+			ContextInfo: &waE2E.ContextInfo{
+				StanzaID:      proto.String(req.QuotedMessageID),
+				Participant:   proto.String(sender.ToNonAD().String()),
+				QuotedMessage: &waE2E.Message{Conversation: proto.String(quotedBody)},
+			},
+		},
+	}, nil
+}
+
+// resolveQuotedMessage mencari pengirim (participant) dan isi pesan yang di-reply / di-react.
+// Urutan: inbox (tabel messages) -> field participant dari request -> lawan chat (khusus chat personal).
+func resolveQuotedMessage(chat *richChat, messageID, participant string) (types.JID, string, *apiError) {
+	if msg, err := model.GetMessageByMessageID(chat.InstanceID, messageID); err == nil {
+		if msg.FromMe {
+			return chat.Session.Client.Store.ID.ToNonAD(), msg.Body.String, nil
+		}
+		if sender, err := types.ParseJID(msg.SenderJID.String); err == nil && msg.SenderJID.Valid {
+			return sender, msg.Body.String, nil
+		}
+	}
+
+	if participant != "" {
+		sender, err := types.ParseJID(participant)
+		if err != nil {
+			return types.JID{}, "", &apiError{400, "Invalid participant JID", "INVALID_JID", err.Error()}
+		}
+		return sender, "", nil
+	}
+
+	if chat.IsGroup {
+		return types.JID{}, "", &apiError{400, "Message not found in inbox, 'participant' is required for group chats",
+			"PARTICIPANT_REQUIRED", "Set the JID of the original sender"}
+	}
+
+	return chat.JID, "", nil
+}
+
+// =====================================================
+// SHARED
+// =====================================================
+
+// sendRichMessage: bind body -> resolve instance & tujuan -> build pesan -> kirim -> catat ke inbox
+func sendRichMessage[T richRequest](c echo.Context, scope richScope, build func(*richChat, *T) (*waE2E.Message, *apiError)) error {
+	var req T
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	target := req.target()
+	chat, apiErr := resolveRichChat(c, scope, target)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	msg, apiErr := build(chat, &req)
+	if apiErr != nil {
+		return apiErr.send(c)
+	}
+
+	return deliverRichMessage(c, scope, target, chat, msg, nil)
+}
+
+// deliverRichMessage mengirim pesan yang sudah di-build, mencatatnya ke inbox,
+// lalu membalas dengan messageId + tujuan (extra ditambahkan ke data response).
+func deliverRichMessage(c echo.Context, scope richScope, target RichTarget, chat *richChat, msg *waE2E.Message, extra map[string]interface{}) error {
+	// Reaction tidak perlu simulasi typing
+	if msg.ReactionMessage == nil {
+		simulateTyping(chat)
+	}
+
+	resp, err := chat.Session.Client.SendMessage(context.Background(), chat.JID, msg)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to send message", "SEND_FAILED", err.Error())
+	}
+
+	// Increment daily message count
+	_ = model.IncrementMessageCount(chat.InstanceID)
+	service.RecordOutgoingMessage(chat.InstanceID, chat.JID, msg, resp)
+
+	data := map[string]interface{}{
+		"messageId": resp.ID,
+		"timestamp": resp.Timestamp.Unix(),
+	}
+	if scope.group {
+		data["groupJid"] = target.GroupJID
+	} else {
+		data["to"] = target.To
+	}
+	if scope.byNumber {
+		data["from"] = c.Param("phoneNumber")
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+
+	return SuccessResponse(c, 200, "Message sent successfully", data)
+}
+
+// resolveSession mencari instance (by instanceId atau nomor pengirim, dengan cek permission)
+// dan memastikan session WhatsApp-nya tersambung dan sudah login.
+func resolveSession(c echo.Context, byNumber bool) (string, *model.Session, *apiError) {
+	instanceID := c.Param("instanceId")
+
+	if byNumber {
+		inst, err := model.GetActiveInstanceByPhoneNumber(c.Param("phoneNumber"))
+		if err != nil {
+			if errors.Is(err, model.ErrNoActiveInstance) {
+				return "", nil, &apiError{404, "No active instance for this phone number", "NO_ACTIVE_INSTANCE", "Please login / scan QR for this number"}
+			}
+			return "", nil, &apiError{500, "Failed to get instance for this phone number", "DB_ERROR", err.Error()}
+		}
+
+		userClaims, _ := c.Get("user_claims").(*service.Claims)
+		if userClaims != nil && userClaims.Role != "admin" {
+			if err := model.CheckUserInstanceLevel(userClaims.UserID, userClaims.Role, inst.InstanceID, model.PermissionSend); err != nil {
+				return "", nil, &apiError{403, "Insufficient permission to use this phone number", "FORBIDDEN", ""}
+			}
+		}
+
+		instanceID = inst.InstanceID
+	}
+
+	session, err := service.GetSession(instanceID)
+	if err != nil {
+		return "", nil, &apiError{404, "Session not found", "SESSION_NOT_FOUND", "Please login first"}
+	}
+	if !session.IsConnected {
+		return "", nil, &apiError{400, "Session is not connected", "NOT_CONNECTED", "Please check /status endpoint"}
+	}
+	if !session.Client.IsConnected() {
+		return "", nil, &apiError{400, "WhatsApp connection lost", "CONNECTION_LOST", "Please reconnect"}
+	}
+	if session.Client.Store.ID == nil {
+		return "", nil, &apiError{400, "Not logged in", "NOT_LOGGED_IN", "Please scan QR code first"}
+	}
+
+	return instanceID, session, nil
+}
+
+// resolveRichChat mencari instance (by instanceId atau nomor pengirim), memastikan session
+// tersambung, lalu memvalidasi tujuan (nomor terdaftar di WhatsApp / JID grup).
+func resolveRichChat(c echo.Context, scope richScope, target RichTarget) (*richChat, *apiError) {
+	instanceID, session, apiErr := resolveSession(c, scope.byNumber)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	chat := &richChat{InstanceID: instanceID, Session: session, IsGroup: scope.group}
+
+	if scope.group {
+		if target.GroupJID == "" {
+			return nil, &apiError{400, "Field 'groupJid' is required", "VALIDATION_ERROR", ""}
+		}
+
+		groupJID, err := types.ParseJID(target.GroupJID)
+		if err != nil {
+			return nil, &apiError{400, "Invalid group JID", "INVALID_GROUP_JID", err.Error()}
+		}
+		if groupJID.Server != types.GroupServer {
+			return nil, &apiError{400, "Not a group JID", "NOT_GROUP_JID", "Group JID must end with @g.us"}
+		}
+
+		chat.JID = groupJID
+		return chat, nil
+	}
+
+	if target.To == "" {
+		return nil, &apiError{400, "Field 'to' is required", "VALIDATION_ERROR", ""}
+	}
+
+	recipient, err := helper.FormatPhoneNumber(target.To, model.GetInstanceDefaultCountry(instanceID))
+	if err != nil {
+		return nil, &apiError{400, "Invalid phone number", "INVALID_PHONE", err.Error()}
+	}
+
+	if !helper.ShouldSkipValidation(target.To) {
+		isRegistered, err := session.Client.IsOnWhatsApp(context.Background(), []string{recipient.User})
+		if err != nil {
+			return nil, &apiError{500, "Failed to verify phone number", "VERIFICATION_FAILED", err.Error()}
+		}
+		if len(isRegistered) == 0 || !isRegistered[0].IsIn {
+			return nil, &apiError{400, "Phone number is not registered on WhatsApp", "PHONE_NOT_REGISTERED",
+				"Please check the number or ask recipient to install WhatsApp"}
+		}
+	}
+
+	chat.JID = recipient
+	return chat, nil
+}
+
+// simulateTyping mengirim status typing selama SUDEVWA_TYPING_DELAY_MIN..MAX detik (jika diset)
+func simulateTyping(chat *richChat) {
+	min, _ := strconv.Atoi(os.Getenv("SUDEVWA_TYPING_DELAY_MIN"))
+	max, _ := strconv.Atoi(os.Getenv("SUDEVWA_TYPING_DELAY_MAX"))
+	if min <= 0 || max < min {
+		return
+	}
+
+	delaySeconds := rand.Intn(max-min+1) + min
+	media := types.ChatPresenceMediaText
+	if chat.recording {
+		media = types.ChatPresenceMediaAudio
+	}
+	_ = chat.Session.Client.SendChatPresence(context.Background(), chat.JID, types.ChatPresenceComposing, media)
+	time.Sleep(time.Duration(delaySeconds) * time.Second)
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ErrNoSendAccess dikembalikan jika user (atau API key) tidak boleh mengirim lewat instance / circle
+var ErrNoSendAccess = errors.New("no send access to")
+
+// CheckSendAccess memastikan user punya level send pada setiap instance, dan instance diizinkan
+// API key jika request memakai API key. Instance kosong dilewati.
+func CheckSendAccess(c echo.Context, instanceIDs ...string) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return fmt.Errorf("%w instance", ErrNoSendAccess)
+	}
+	apiKey, _ := c.Get("api_key").(*model.APIKey)
+
+	for _, instanceID := range instanceIDs {
+		if instanceID == "" {
+			continue
+		}
+		err := model.CheckUserInstanceLevel(claims.UserID, claims.Role, instanceID, model.PermissionSend)
+		if errors.Is(err, model.ErrNoPermission) || errors.Is(err, model.ErrInsufficientPermission) {
+			return fmt.Errorf("%w instance %s", ErrNoSendAccess, instanceID)
+		}
+		if err != nil {
+			return err
+		}
+		if apiKey != nil && !service.APIKeyAllowsInstance(apiKey, instanceID) {
+			return fmt.Errorf("%w instance %s", ErrNoSendAccess, instanceID)
+		}
+	}
+
+	return nil
+}
+
+// CheckCircleSendAccess memastikan user boleh mengirim lewat semua instance di circle,
+// karena worker blast bisa memilih instance mana pun di circle tersebut
+func CheckCircleSendAccess(c echo.Context, circle string) error {
+	instanceIDs, err := model.GetCircleInstanceIDs(c.Request().Context(), circle)
+	if err != nil {
+		return err
+	}
+
+	// Circle tanpa instance hanya boleh dipakai admin (belum ada instance untuk dicek)
+	if claims := getClaims(c); len(instanceIDs) == 0 && (claims == nil || claims.Role != "admin") {
+		return fmt.Errorf("%w circle %s", ErrNoSendAccess, circle)
+	}
+
+	if err := CheckSendAccess(c, instanceIDs...); err != nil {
+		if errors.Is(err, ErrNoSendAccess) {
+			return fmt.Errorf("%w circle %s (%v)", ErrNoSendAccess, circle, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// SendAccessErrorResponse memetakan error CheckSendAccess / CheckCircleSendAccess ke response
+func SendAccessErrorResponse(c echo.Context, err error) error {
+	if errors.Is(err, ErrNoSendAccess) {
+		return ErrorResponse(c, http.StatusForbidden, "You do not have send permission on this instance or circle", "INSUFFICIENT_PERMISSION", err.Error())
+	}
+	return ErrorResponse(c, http.StatusInternalServerError, "Failed to verify instance access", "INTERNAL_ERROR", err.Error())
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+	"net/url"
+	"path"
+
+	"gowa-yourself/internal/helper"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GET /files/*?expires=&signature=
+// Melayani signed URL dari storage lokal (pengganti /uploads statis). Tanpa JWT, dijaga signature.
+func ServeStorageFile(c echo.Context) error {
+	key, err := url.PathUnescape(c.Param("*"))
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid file path", "BAD_REQUEST", "")
+	}
+
+	if !helper.VerifyFileSignature(key, c.QueryParam("expires"), c.QueryParam("signature")) {
+		return ErrorResponse(c, 403, "Invalid or expired file URL", "INVALID_SIGNATURE", "")
+	}
+
+	return streamStorageObject(c, key, "", "", false)
+}
+
+// streamStorageObject mengirim isi object storage ke client.
+// contentType/fileName opsional (kalau kosong pakai dari storage / nama key).
+// Isi file bisa berasal dari pengirim WhatsApp, jadi hanya gambar / audio / video (helper.IsInlineContentType)
+// yang boleh inline; sisanya (html, svg, ...) selalu attachment, ditambah nosniff dan CSP sandbox.
+func streamStorageObject(c echo.Context, key, contentType, fileName string, attachment bool) error {
+	obj, err := helper.GetStorage().Get(c.Request().Context(), key)
+	if err != nil {
+		if errors.Is(err, helper.ErrObjectNotFound) {
+			return ErrorResponse(c, 404, "File not found", "FILE_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to read file", "STORAGE_ERROR", err.Error())
+	}
+	defer obj.Body.Close()
+
+	if contentType == "" {
+		contentType = obj.ContentType
+	}
+	if contentType == "" {
+		contentType = echo.MIMEOctetStream
+	}
+
+	if !helper.IsInlineContentType(contentType) {
+		attachment = true
+	}
+	if fileName == "" {
+		fileName = path.Base(key)
+	}
+
+	disposition := "inline"
+	if attachment {
+		disposition = "attachment"
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentDisposition, disposition+"; filename*=UTF-8''"+url.PathEscape(fileName))
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	header.Set(echo.HeaderContentSecurityPolicy, "default-src 'none'; sandbox")
+
+	return c.Stream(200, contentType, obj.Body)
+}
@@ -5,8 +5,6 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
-	"os"
-	"path/filepath"
 
 	"gowa-yourself/internal/helper"
 	"gowa-yourself/internal/model"
@@ -15,9 +13,6 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-// SystemDir is the directory for system-wide images
-const SystemDir = "./uploads/system"
-
 // UpdateSystemIdentityFull handles both text settings and file uploads in one request (Admin Only)
 // POST /api/system/identity
 func UpdateSystemIdentityFull(c echo.Context) error {
@@ -108,34 +103,29 @@ func UpdateSystemIdentityFull(c echo.Context) error {
 			})
 		}
 
-		// Create system directory
-		_ = os.MkdirAll(SystemDir, 0755)
-
-		// Use fixed filename (will overwrite old file)
-		filename := fmt.Sprintf("%s.webp", key)
-		filePath := filepath.Join(SystemDir, filename)
-		imageURL := fmt.Sprintf("/uploads/system/%s", filename)
+		// Use fixed key (will overwrite old file)
+		imageKey := fmt.Sprintf("system/%s.webp", key)
 
-		// Delete old file if exists (will be overwritten anyway)
 		var oldPath string
 		switch key {
 		case "logo":
 			oldPath = identity.LogoURL
-			identity.LogoURL = imageURL
+			identity.LogoURL = imageKey
 		case "ico":
 			oldPath = identity.IcoURL
-			identity.IcoURL = imageURL
+			identity.IcoURL = imageKey
 		case "second_logo":
 			oldPath = identity.SecondLogoURL
-			identity.SecondLogoURL = imageURL
+			identity.SecondLogoURL = imageKey
 		}
 
-		if oldPath != "" {
-			helper.DeleteFile(filepath.Join(".", oldPath))
+		// Delete old file if stored under a different key (data lama: /uploads/system/...)
+		if oldPath != "" && helper.StorageKey(oldPath) != imageKey {
+			helper.GetStorage().Delete(c.Request().Context(), helper.StorageKey(oldPath))
 		}
 
 		// Save new file
-		if err := os.WriteFile(filePath, compressedData, 0644); err != nil {
+		if err := helper.GetStorage().Put(c.Request().Context(), imageKey, compressedData, "image/webp"); err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 				"success": false,
 				"message": fmt.Sprintf("Failed to save file %s", key),
@@ -168,7 +158,7 @@ func UpdateSystemIdentityFull(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
 		"message": "System identity updated successfully",
-		"data":    identity,
+		"data":    signedSystemIdentity(identity),
 	})
 }
 
@@ -185,6 +175,15 @@ func GetSystemIdentityHandler(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
-		"data":    identity,
+		"data":    signedSystemIdentity(identity),
 	})
 }
+
+// signedSystemIdentity returns a copy with logo/ico keys replaced by signed URLs
+func signedSystemIdentity(identity *model.SystemIdentity) model.SystemIdentity {
+	signed := *identity
+	signed.LogoURL = helper.StorageURL(identity.LogoURL)
+	signed.IcoURL = helper.StorageURL(identity.IcoURL)
+	signed.SecondLogoURL = helper.StorageURL(identity.SecondLogoURL)
+	return signed
+}
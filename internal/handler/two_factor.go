@@ -0,0 +1,490 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TwoFactorLoginRequest is the payload for the second login step
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"` // kode TOTP 6 digit atau recovery code
+}
+
+// TwoFactorCodeRequest is the payload for enabling 2FA / regenerating recovery codes
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorDisableRequest is the payload for disabling 2FA (password + kode)
+type TwoFactorDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// loginTokens membuat access + refresh token dan mencatat user.login (dipakai /login, /login/2fa dan SSO)
+func loginTokens(c echo.Context, user *model.User, details map[string]interface{}) (*AuthResponse, *apiError) {
+	accessToken, err := service.GenerateAccessToken(user)
+	if err != nil {
+		return nil, &apiError{http.StatusInternalServerError, "Failed to generate access token", "TOKEN_GENERATION_FAILED", err.Error()}
+	}
+
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+	refreshToken, err := service.GenerateRefreshTokenForUser(user, ipAddress, userAgent)
+	if err != nil {
+		return nil, &apiError{http.StatusInternalServerError, "Failed to generate refresh token", "TOKEN_GENERATION_FAILED", err.Error()}
+	}
+
+	// Log login
+	err = model.LogAction(&model.AuditLog{
+		UserID:       sql.NullInt64{Int64: user.ID, Valid: true},
+		Action:       "user.login",
+		ResourceType: sql.NullString{String: "user", Valid: true},
+		ResourceID:   sql.NullString{String: user.Username, Valid: true},
+		Details:      details,
+		IPAddress:    sql.NullString{String: ipAddress, Valid: true},
+		UserAgent:    sql.NullString{String: userAgent, Valid: true},
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to log audit: %v", err)
+	}
+
+	return &AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		User:         user.ToResponse(),
+	}, nil
+}
+
+// issueLogin returns the login tokens as JSON
+func issueLogin(c echo.Context, user *model.User, details map[string]interface{}, recoveryCodes []string) error {
+	resp, aerr := loginTokens(c, user, details)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+	resp.RecoveryCodes = recoveryCodes
+
+	return SuccessResponse(c, http.StatusOK, "Login successful", resp)
+}
+
+// logUserAction mencatat aksi atas akun user sendiri (2FA, SSO) ke audit_logs
+func logUserAction(c echo.Context, userID int64, username, action string, details map[string]interface{}) {
+	_ = model.LogAction(&model.AuditLog{
+		UserID:       sql.NullInt64{Int64: userID, Valid: true},
+		Action:       action,
+		ResourceType: sql.NullString{String: "user", Valid: true},
+		ResourceID:   sql.NullString{String: username, Valid: true},
+		Details:      details,
+		IPAddress:    sql.NullString{String: c.RealIP(), Valid: true},
+		UserAgent:    sql.NullString{String: c.Request().UserAgent(), Valid: true},
+	})
+}
+
+// challengeUser memvalidasi challenge token dan mengambil user-nya
+func challengeUser(token string) (*service.ChallengeClaims, *model.User, *apiError) {
+	if token == "" {
+		return nil, nil, &apiError{http.StatusBadRequest, "Challenge token is required", "MISSING_TOKEN", ""}
+	}
+
+	claims, err := service.ValidateTwoFactorChallenge(token)
+	if err != nil {
+		return nil, nil, &apiError{http.StatusUnauthorized, "Invalid or expired challenge, please login again", "INVALID_CHALLENGE", ""}
+	}
+
+	user, err := model.GetUserByID(claims.UserID)
+	if err != nil || !user.IsActive {
+		return nil, nil, &apiError{http.StatusUnauthorized, "Invalid or expired challenge, please login again", "INVALID_CHALLENGE", ""}
+	}
+
+	return claims, user, nil
+}
+
+// LoginTwoFactorSetup returns secret + QR untuk user yang wajib 2FA tapi belum setup
+// POST /login/2fa/setup
+func LoginTwoFactorSetup(c echo.Context) error {
+	var req TwoFactorLoginRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+
+	claims, user, aerr := challengeUser(req.ChallengeToken)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+	if !claims.Enroll {
+		return ErrorResponse(c, http.StatusBadRequest, "Two-factor authentication is already enabled", "TWO_FACTOR_ALREADY_ENABLED", "")
+	}
+
+	setup, err := service.SetupTwoFactor(user)
+	if err != nil {
+		return twoFactorError(c, err)
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Scan the QR code with your authenticator app, then submit a code to /login/2fa", setup)
+}
+
+// LoginTwoFactor menukar challenge token + kode 2FA dengan access & refresh token
+// POST /login/2fa
+func LoginTwoFactor(c echo.Context) error {
+	var req TwoFactorLoginRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+	if req.Code == "" {
+		return ErrorResponse(c, http.StatusBadRequest, "Code is required", "MISSING_FIELDS", "")
+	}
+
+	claims, user, aerr := challengeUser(req.ChallengeToken)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+
+	// Tebakan kode 2FA ikut dihitung ke counter username / IP yang sama dengan /login
+	attemptKeys := service.LoginAttemptKeys(user.Username, c.RealIP())
+	if block := service.CheckAttempts(attemptKeys...); block != nil {
+		return attemptBlocked(c, block)
+	}
+
+	// Enrollment paksa: kode pertama mengaktifkan 2FA dan recovery code ikut dikembalikan
+	if claims.Enroll {
+		recoveryCodes, err := service.EnableTwoFactor(user.ID, req.Code)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+				details := countFailedAttempts(c, attemptKeys)
+				details["stage"] = "enrollment"
+				logUserAction(c, user.ID, user.Username, "user.2fa_failed", details)
+			}
+			return twoFactorError(c, err)
+		}
+
+		if err := service.ConsumeTwoFactorChallenge(claims); err != nil {
+			return twoFactorError(c, err)
+		}
+
+		logUserAction(c, user.ID, user.Username, "user.2fa_enable", map[string]interface{}{"enforced": true})
+		user.TwoFactorEnabled = true
+		_, _ = service.ClearFailedAttempts(attemptKeys[0])
+		return issueLogin(c, user, map[string]interface{}{"two_factor": "totp"}, recoveryCodes)
+	}
+
+	method, err := service.VerifyTwoFactorCode(user.ID, req.Code)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			details := countFailedAttempts(c, attemptKeys)
+			details["stage"] = "login"
+			logUserAction(c, user.ID, user.Username, "user.2fa_failed", details)
+		}
+		return twoFactorError(c, err)
+	}
+
+	// Challenge token hanya bisa ditukar sekali
+	if err := service.ConsumeTwoFactorChallenge(claims); err != nil {
+		return twoFactorError(c, err)
+	}
+	_, _ = service.ClearFailedAttempts(attemptKeys[0])
+
+	return issueLogin(c, user, map[string]interface{}{"two_factor": method}, nil)
+}
+
+// GetTwoFactorStatus returns the 2FA status of the current user
+// GET /api/me/2fa
+func GetTwoFactorStatus(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	user, err := model.GetUserByID(claims.UserID)
+	if err != nil {
+		return ErrorResponse(c, http.StatusNotFound, "User not found", "USER_NOT_FOUND", err.Error())
+	}
+
+	totp, err := model.GetUserTOTP(user.ID)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve 2FA status", "INTERNAL_ERROR", err.Error())
+	}
+
+	required, err := service.TwoFactorRequired(user)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve security policy", "INTERNAL_ERROR", err.Error())
+	}
+
+	status := map[string]interface{}{
+		"enabled":  totp.Enabled,
+		"required": required,
+	}
+	if totp.Enabled {
+		remaining, err := model.CountUnusedRecoveryCodes(user.ID)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "Failed to count recovery codes", "INTERNAL_ERROR", err.Error())
+		}
+		status["enabled_at"] = totp.EnabledAt.Time
+		status["recovery_codes_remaining"] = remaining
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Two-factor status retrieved", status)
+}
+
+// SetupTwoFactor starts enrollment for the current user (secret + otpauth URI + QR)
+// POST /api/me/2fa/setup
+func SetupTwoFactor(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	user, err := model.GetUserByID(claims.UserID)
+	if err != nil {
+		return ErrorResponse(c, http.StatusNotFound, "User not found", "USER_NOT_FOUND", err.Error())
+	}
+
+	setup, err := service.SetupTwoFactor(user)
+	if err != nil {
+		return twoFactorError(c, err)
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Scan the QR code with your authenticator app, then confirm with /api/me/2fa/enable", setup)
+}
+
+// EnableTwoFactor confirms enrollment with the first code and returns recovery codes
+// POST /api/me/2fa/enable
+func EnableTwoFactor(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+	if req.Code == "" {
+		return ErrorResponse(c, http.StatusBadRequest, "Code is required", "MISSING_FIELDS", "")
+	}
+
+	recoveryCodes, err := service.EnableTwoFactor(claims.UserID, req.Code)
+	if err != nil {
+		return twoFactorError(c, err)
+	}
+
+	logUserAction(c, claims.UserID, claims.Username, "user.2fa_enable", nil)
+
+	return SuccessResponse(c, http.StatusOK, "Two-factor authentication enabled. Store the recovery codes in a safe place, they are shown only once.", map[string]interface{}{
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// DisableTwoFactor disables 2FA for the current user (butuh password dan kode 2FA)
+// POST /api/me/2fa/disable
+func DisableTwoFactor(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	var req TwoFactorDisableRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+	if req.Code == "" {
+		return ErrorResponse(c, http.StatusBadRequest, "Code is required", "MISSING_FIELDS", "")
+	}
+
+	user, err := model.GetUserByID(claims.UserID)
+	if err != nil {
+		return ErrorResponse(c, http.StatusNotFound, "User not found", "USER_NOT_FOUND", err.Error())
+	}
+
+	required, err := service.TwoFactorRequired(user)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve security policy", "INTERNAL_ERROR", err.Error())
+	}
+	if required {
+		return twoFactorError(c, service.ErrTwoFactorRequired)
+	}
+
+	// Akun lokal wajib konfirmasi password
+	if user.AuthProvider == "local" {
+		if req.Password == "" {
+			return ErrorResponse(c, http.StatusBadRequest, "Password is required", "MISSING_FIELDS", "")
+		}
+		if _, err := service.AuthenticateUser(user.Username, req.Password); err != nil {
+			return ErrorResponse(c, http.StatusUnauthorized, "Invalid password", "INVALID_PASSWORD", "")
+		}
+	}
+
+	if _, err := service.VerifyTwoFactorCode(user.ID, req.Code); err != nil {
+		return twoFactorError(c, err)
+	}
+
+	if err := service.DisableTwoFactor(user.ID); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to disable two-factor authentication", "INTERNAL_ERROR", err.Error())
+	}
+
+	logUserAction(c, user.ID, user.Username, "user.2fa_disable", nil)
+
+	return SuccessResponse(c, http.StatusOK, "Two-factor authentication disabled", nil)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the current user
+// POST /api/me/2fa/recovery-codes
+func RegenerateRecoveryCodes(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+	if req.Code == "" {
+		return ErrorResponse(c, http.StatusBadRequest, "Code is required", "MISSING_FIELDS", "")
+	}
+
+	if _, err := service.VerifyTwoFactorCode(claims.UserID, req.Code); err != nil {
+		return twoFactorError(c, err)
+	}
+
+	recoveryCodes, err := service.RegenerateRecoveryCodes(claims.UserID)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to generate recovery codes", "INTERNAL_ERROR", err.Error())
+	}
+
+	logUserAction(c, claims.UserID, claims.Username, "user.2fa_recovery_regenerate", nil)
+
+	return SuccessResponse(c, http.StatusOK, "Recovery codes regenerated. Old codes are no longer valid.", map[string]interface{}{
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// ResetUserTwoFactor menghapus 2FA user (misal HP hilang) dan memutus semua sesinya
+// DELETE /api/users/:id/2fa
+func ResetUserTwoFactor(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	user, aerr := resolveTargetUser(c)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+	if !user.TwoFactorEnabled {
+		return twoFactorError(c, service.ErrTwoFactorNotEnabled)
+	}
+
+	if err := service.DisableTwoFactor(user.ID); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to reset two-factor authentication", "INTERNAL_ERROR", err.Error())
+	}
+	if err := service.RevokeAllUserSessions(user.ID); err != nil {
+		log.Printf("⚠️ Failed to revoke sessions of user %d after 2FA reset: %v", user.ID, err)
+	}
+
+	logUserAdminAction(c, claims, "user.2fa_reset", user, nil)
+
+	return SuccessResponse(c, http.StatusOK, "Two-factor authentication reset. The user must login again.", nil)
+}
+
+// GetSecurityPolicy returns the system security policy
+// GET /api/system/security-policy
+func GetSecurityPolicy(c echo.Context) error {
+	policy, err := model.GetSecurityPolicy()
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve security policy", "INTERNAL_ERROR", err.Error())
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Security policy retrieved", policy)
+}
+
+// UpdateSecurityPolicy updates the system security policy
+// PUT /api/system/security-policy
+func UpdateSecurityPolicy(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	var policy model.SecurityPolicy
+	if err := c.Bind(&policy); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+
+	if err := model.UpdateSecurityPolicy(&policy); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to update security policy", "INTERNAL_ERROR", err.Error())
+	}
+
+	_ = model.LogAction(&model.AuditLog{
+		UserID:       sql.NullInt64{Int64: claims.UserID, Valid: true},
+		Action:       "system.security_policy_update",
+		ResourceType: sql.NullString{String: "system_setting", Valid: true},
+		ResourceID:   sql.NullString{String: model.KeySecurityPolicy, Valid: true},
+		Details: map[string]interface{}{
+			"require_2fa_for_admins": policy.RequireTwoFactorForAdmins,
+			"performed_by":           claims.Username,
+		},
+		IPAddress: sql.NullString{String: c.RealIP(), Valid: true},
+		UserAgent: sql.NullString{String: c.Request().UserAgent(), Valid: true},
+	})
+
+	return SuccessResponse(c, http.StatusOK, "Security policy updated", policy)
+}
+
+// twoFactorError maps 2FA service errors to responses
+func twoFactorError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		return ErrorResponse(c, http.StatusUnauthorized, "Invalid two-factor code", "INVALID_2FA_CODE", "")
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		return ErrorResponse(c, http.StatusConflict, "Two-factor authentication is already enabled", "TWO_FACTOR_ALREADY_ENABLED", "")
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		return ErrorResponse(c, http.StatusBadRequest, "Two-factor authentication is not enabled", "TWO_FACTOR_NOT_ENABLED", "")
+	case errors.Is(err, service.ErrTwoFactorNotSetUp):
+		return ErrorResponse(c, http.StatusBadRequest, "Start the setup first", "TWO_FACTOR_NOT_SET_UP", "")
+	case errors.Is(err, service.ErrTwoFactorRequired):
+		return ErrorResponse(c, http.StatusForbidden, "Two-factor authentication is required for your role", "TWO_FACTOR_REQUIRED", "")
+	case errors.Is(err, service.ErrInvalidChallenge):
+		return ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired challenge, please login again", "INVALID_CHALLENGE", "")
+	case errors.Is(err, model.ErrUserNotFound):
+		return ErrorResponse(c, http.StatusNotFound, "User not found", "USER_NOT_FOUND", "")
+	default:
+		return ErrorResponse(c, http.StatusInternalServerError, "Two-factor operation failed", "INTERNAL_ERROR", err.Error())
+	}
+}
+
+// twoFactorChallenge membuat data challenge untuk login tahap kedua (tanpa token)
+func twoFactorChallenge(user *model.User, enroll bool) (string, map[string]interface{}, *apiError) {
+	token, expiresAt, err := service.GenerateTwoFactorChallenge(user, enroll)
+	if err != nil {
+		return "", nil, &apiError{http.StatusInternalServerError, "Failed to generate challenge", "TOKEN_GENERATION_FAILED", err.Error()}
+	}
+
+	message := "Two-factor authentication required"
+	if enroll {
+		message = "Two-factor authentication must be set up before login"
+	}
+
+	return message, map[string]interface{}{
+		"two_factor_required": true,
+		"enrollment_required": enroll,
+		"challenge_token":     token,
+		"expires_at":          expiresAt,
+	}, nil
+}
+
+// challengeResponse adalah respons /login saat 2FA dibutuhkan
+func challengeResponse(c echo.Context, user *model.User, enroll bool) error {
+	message, data, aerr := twoFactorChallenge(user, enroll)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+	return SuccessResponse(c, http.StatusOK, message, data)
+}
@@ -0,0 +1,484 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AdminCreateUserRequest is the body for POST /api/users
+type AdminCreateUserRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	FullName string `json:"full_name,omitempty"`
+	Role     string `json:"role,omitempty"` // default: user
+	// true = user wajib ganti password saat login pertama
+	MustChangePassword bool `json:"must_change_password,omitempty"`
+}
+
+// UpdateUserRoleRequest is the body for PUT /api/users/:id/role
+type UpdateUserRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// ResetUserPasswordRequest is the body for POST /api/users/:id/reset-password
+type ResetUserPasswordRequest struct {
+	Password string `json:"password,omitempty"` // kosong = dibuat acak
+}
+
+// ImpersonateRequest is the body for POST /api/users/:id/impersonate
+type ImpersonateRequest struct {
+	Reason string `json:"reason"`
+}
+
+// UserSessionResponse adalah satu refresh token aktif (token-nya sendiri tidak pernah dikirim)
+type UserSessionResponse struct {
+	ID        int64     `json:"id"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// logUserAdminAction mencatat aksi admin terhadap user ke audit_logs
+func logUserAdminAction(c echo.Context, claims *service.Claims, action string, target *model.User, details map[string]interface{}) {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["username"] = target.Username
+	details["performed_by"] = claims.Username
+
+	_ = model.LogAction(&model.AuditLog{
+		UserID:       sql.NullInt64{Int64: claims.UserID, Valid: true},
+		Action:       action,
+		ResourceType: sql.NullString{String: "user", Valid: true},
+		ResourceID:   sql.NullString{String: strconv.FormatInt(target.ID, 10), Valid: true},
+		Details:      details,
+		IPAddress:    sql.NullString{String: c.RealIP(), Valid: true},
+		UserAgent:    sql.NullString{String: c.Request().UserAgent(), Valid: true},
+	})
+}
+
+// resolveTargetUser mengambil user dari path param :id
+func resolveTargetUser(c echo.Context) (*model.User, *apiError) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, "Invalid user ID", "BAD_REQUEST", ""}
+	}
+
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, model.ErrUserNotFound) {
+			return nil, &apiError{http.StatusNotFound, "User not found", "USER_NOT_FOUND", ""}
+		}
+		return nil, &apiError{http.StatusInternalServerError, "Failed to retrieve user", "INTERNAL_ERROR", err.Error()}
+	}
+
+	return user, nil
+}
+
+// GET /api/users?search=&role=&active=&page=&limit= (admin)
+func ListUsers(c echo.Context) error {
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := model.UserFilter{
+		Search: strings.TrimSpace(c.QueryParam("search")),
+		Role:   c.QueryParam("role"),
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	}
+	if active := c.QueryParam("active"); active != "" {
+		val, err := strconv.ParseBool(active)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "Invalid 'active' filter", "BAD_REQUEST", "Use true or false")
+		}
+		filter.Active = &val
+	}
+
+	users, total, err := model.SearchUsers(filter)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve users", "DATABASE_ERROR", err.Error())
+	}
+
+	responses := make([]model.UserResponse, 0, len(users))
+	for i := range users {
+		responses = append(responses, users[i].ToResponse())
+	}
+
+	totalPages := 0
+	if total > 0 {
+		totalPages = (total + limit - 1) / limit
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Users retrieved successfully", map[string]interface{}{
+		"data": responses,
+		"pagination": map[string]interface{}{
+			"total_data":   total,
+			"total_pages":  totalPages,
+			"current_page": page,
+			"limit":        limit,
+		},
+	})
+}
+
+// GET /api/users/:id (admin)
+func GetUser(c echo.Context) error {
+	user, aerr := resolveTargetUser(c)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+
+	instances, err := model.GetUserInstances(user.ID)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve user instances", "DATABASE_ERROR", err.Error())
+	}
+	if instances == nil {
+		instances = []string{}
+	}
+
+	sessions, err := model.GetUserTokenCount(user.ID)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve user sessions", "DATABASE_ERROR", err.Error())
+	}
+
+	return SuccessResponse(c, http.StatusOK, "User retrieved successfully", map[string]interface{}{
+		"user":            user.ToResponse(),
+		"instances":       instances,
+		"active_sessions": sessions,
+	})
+}
+
+// POST /api/users (admin)
+func AdminCreateUser(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	var req AdminCreateUserRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+
+	if req.Username == "" || req.Email == "" || req.Password == "" {
+		return ErrorResponse(c, http.StatusBadRequest, "Username, email, and password are required", "MISSING_FIELDS", "")
+	}
+	if req.Role != "" && !service.IsValidRole(req.Role) {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid role", "INVALID_ROLE", "Allowed roles: admin, user, viewer")
+	}
+
+	user, err := service.RegisterUser(model.CreateUserRequest{
+		Username: req.Username,
+		Email:    req.Email,
+		Password: req.Password,
+		FullName: req.FullName,
+		Role:     req.Role,
+	})
+	if err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, err.Error(), "USER_CREATE_FAILED", "")
+	}
+
+	if req.MustChangePassword {
+		if err := model.SetTemporaryPassword(user.ID, user.PasswordHash.String); err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "User created but failed to require password change", "INTERNAL_ERROR", err.Error())
+		}
+		user.MustChangePassword = true
+	}
+
+	logUserAdminAction(c, claims, "user.create", user, map[string]interface{}{
+		"role":                 user.Role,
+		"must_change_password": req.MustChangePassword,
+	})
+
+	return SuccessResponse(c, http.StatusCreated, "User created successfully", user.ToResponse())
+}
+
+// PUT /api/users/:id/role (admin)
+func UpdateUserRole(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	var req UpdateUserRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+	if !service.IsValidRole(req.Role) {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid role", "INVALID_ROLE", "Allowed roles: admin, user, viewer")
+	}
+
+	user, aerr := resolveTargetUser(c)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+	if user.Role == req.Role {
+		return SuccessResponse(c, http.StatusOK, "Role unchanged", user.ToResponse())
+	}
+	if user.ID == claims.UserID {
+		return ErrorResponse(c, http.StatusBadRequest, "You cannot change your own role", "SELF_ROLE_CHANGE", "")
+	}
+	if err := service.EnsureNotLastAdmin(user); err != nil {
+		return userAdminError(c, err)
+	}
+
+	if err := model.UpdateUserRole(user.ID, req.Role); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to update role", "UPDATE_FAILED", err.Error())
+	}
+
+	// Access token lama membawa role lama; refresh token tetap berlaku sehingga client cukup refresh
+	if err := model.RevokeUserAccessTokens(user.ID); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Role updated but failed to revoke access tokens", "INTERNAL_ERROR", err.Error())
+	}
+
+	logUserAdminAction(c, claims, "user.role_change", user, map[string]interface{}{
+		"old_role": user.Role,
+		"new_role": req.Role,
+	})
+
+	user.Role = req.Role
+	return SuccessResponse(c, http.StatusOK, "Role updated successfully", user.ToResponse())
+}
+
+// POST /api/users/:id/deactivate (admin)
+// Nonaktifkan akun dan cabut semua sesi; API key user ikut tidak berlaku.
+func DeactivateUser(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	user, aerr := resolveTargetUser(c)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+	if user.ID == claims.UserID {
+		return ErrorResponse(c, http.StatusBadRequest, "You cannot deactivate your own account", "SELF_DEACTIVATE", "")
+	}
+	if !user.IsActive {
+		return SuccessResponse(c, http.StatusOK, "User is already inactive", user.ToResponse())
+	}
+	if err := service.EnsureNotLastAdmin(user); err != nil {
+		return userAdminError(c, err)
+	}
+
+	if err := model.SetUserActive(user.ID, false); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to deactivate user", "UPDATE_FAILED", err.Error())
+	}
+	if err := service.RevokeAllUserSessions(user.ID); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "User deactivated but failed to revoke sessions", "INTERNAL_ERROR", err.Error())
+	}
+
+	logUserAdminAction(c, claims, "user.deactivate", user, nil)
+
+	user.IsActive = false
+	return SuccessResponse(c, http.StatusOK, "User deactivated successfully", user.ToResponse())
+}
+
+// POST /api/users/:id/reactivate (admin)
+func ReactivateUser(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	user, aerr := resolveTargetUser(c)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+	if user.IsActive {
+		return SuccessResponse(c, http.StatusOK, "User is already active", user.ToResponse())
+	}
+
+	if err := model.SetUserActive(user.ID, true); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to reactivate user", "UPDATE_FAILED", err.Error())
+	}
+
+	logUserAdminAction(c, claims, "user.reactivate", user, nil)
+
+	user.IsActive = true
+	return SuccessResponse(c, http.StatusOK, "User reactivated successfully", user.ToResponse())
+}
+
+// POST /api/users/:id/reset-password (admin)
+// Password sementara dikembalikan sekali; user wajib menggantinya saat login berikutnya.
+func ResetUserPassword(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	var req ResetUserPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+
+	user, aerr := resolveTargetUser(c)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+
+	password, err := service.ResetUserPassword(user, req.Password)
+	if err != nil {
+		return userAdminError(c, err)
+	}
+
+	logUserAdminAction(c, claims, "user.password_reset", user, map[string]interface{}{
+		"generated": req.Password == "",
+	})
+
+	return SuccessResponse(c, http.StatusOK, "Password reset successfully. The user must change it at next login.", map[string]interface{}{
+		"temporary_password": password,
+	})
+}
+
+// GET /api/users/:id/sessions (admin)
+func GetUserSessions(c echo.Context) error {
+	user, aerr := resolveTargetUser(c)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+
+	tokens, err := model.GetUserActiveTokens(user.ID)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve sessions", "DATABASE_ERROR", err.Error())
+	}
+
+	sessions := make([]UserSessionResponse, 0, len(tokens))
+	for _, t := range tokens {
+		sessions = append(sessions, UserSessionResponse{
+			ID:        t.ID,
+			IPAddress: t.IPAddress.String,
+			UserAgent: t.UserAgent.String,
+			CreatedAt: t.CreatedAt,
+			ExpiresAt: t.ExpiresAt,
+		})
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Sessions retrieved successfully", sessions)
+}
+
+// DELETE /api/users/:id/sessions (admin)
+// Cabut semua refresh token dan access token yang sudah terbit.
+func RevokeUserSessions(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	user, aerr := resolveTargetUser(c)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+
+	if err := service.RevokeAllUserSessions(user.ID); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke sessions", "INTERNAL_ERROR", err.Error())
+	}
+
+	logUserAdminAction(c, claims, "user.sessions_revoke", user, map[string]interface{}{"all": true})
+
+	return SuccessResponse(c, http.StatusOK, "All sessions revoked successfully", nil)
+}
+
+// DELETE /api/users/:id/sessions/:sessionId (admin)
+// Hanya refresh token tsb; access token yang sudah terbit tetap berlaku sampai expired.
+func RevokeUserSession(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	user, aerr := resolveTargetUser(c)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+
+	sessionID, err := strconv.ParseInt(c.Param("sessionId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid session ID", "BAD_REQUEST", "")
+	}
+
+	if err := model.RevokeUserTokenByID(user.ID, sessionID); err != nil {
+		if errors.Is(err, model.ErrTokenNotFound) {
+			return ErrorResponse(c, http.StatusNotFound, "Session not found", "NOT_FOUND", "")
+		}
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke session", "INTERNAL_ERROR", err.Error())
+	}
+
+	logUserAdminAction(c, claims, "user.sessions_revoke", user, map[string]interface{}{"session_id": sessionID})
+
+	return SuccessResponse(c, http.StatusOK, "Session revoked successfully", nil)
+}
+
+// POST /api/users/:id/impersonate (admin)
+// Token berumur pendek atas nama user untuk support; setiap request dengan token ini diaudit.
+func ImpersonateUser(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	var req ImpersonateRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return ErrorResponse(c, http.StatusBadRequest, "Field 'reason' is required", "VALIDATION_ERROR", "")
+	}
+
+	user, aerr := resolveTargetUser(c)
+	if aerr != nil {
+		return aerr.send(c)
+	}
+
+	token, expiresAt, err := service.GenerateImpersonationToken(user, claims)
+	if err != nil {
+		return userAdminError(c, err)
+	}
+
+	logUserAdminAction(c, claims, "user.impersonate", user, map[string]interface{}{
+		"reason":     req.Reason,
+		"expires_at": expiresAt,
+	})
+
+	return SuccessResponse(c, http.StatusOK, "Impersonation token issued", map[string]interface{}{
+		"access_token": token,
+		"expires_at":   expiresAt,
+		"user":         user.ToResponse(),
+	})
+}
+
+// userAdminError memetakan error service ke response
+func userAdminError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrLastActiveAdmin):
+		return ErrorResponse(c, http.StatusConflict, "Cannot remove the last active admin", "LAST_ADMIN", "")
+	case errors.Is(err, service.ErrCannotImpersonate):
+		return ErrorResponse(c, http.StatusForbidden, "This user cannot be impersonated", "IMPERSONATION_NOT_ALLOWED",
+			"Admins, inactive users and your own account cannot be impersonated")
+	case errors.Is(err, model.ErrUserNotFound):
+		return ErrorResponse(c, http.StatusNotFound, "User not found", "USER_NOT_FOUND", "")
+	case errors.Is(err, service.ErrPasswordResetNotLocal):
+		return ErrorResponse(c, http.StatusBadRequest, "Password can only be reset for local accounts", "OAUTH_USER", "")
+	default:
+		return ErrorResponse(c, http.StatusInternalServerError, "Operation failed", "INTERNAL_ERROR", err.Error())
+	}
+}
@@ -3,6 +3,7 @@ package handler
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
@@ -21,6 +22,8 @@ type RegisterRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	FullName string `json:"full_name,omitempty"`
+	// Token undangan, wajib jika REGISTRATION_MODE=invite
+	InviteToken string `json:"invite_token,omitempty"`
 }
 
 // LoginRequest represents the login request payload
@@ -39,10 +42,12 @@ type AuthResponse struct {
 	AccessToken  string             `json:"access_token"`
 	RefreshToken string             `json:"refresh_token"`
 	User         model.UserResponse `json:"user"`
+	// Hanya terisi saat login pertama yang sekaligus mengaktifkan 2FA (enrollment paksa)
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
 }
 
 // Register handles user registration
-// POST /register
+// POST /register (mengikuti REGISTRATION_MODE: open, invite atau disabled)
 func Register(c echo.Context) error {
 	var req RegisterRequest
 	if err := c.Bind(&req); err != nil {
@@ -54,17 +59,26 @@ func Register(c echo.Context) error {
 		return ErrorResponse(c, http.StatusBadRequest, "Username, email, and password are required", "MISSING_FIELDS", "")
 	}
 
-	// Create user
+	// Create user (role "user", atau role dari undangan)
 	createReq := model.CreateUserRequest{
 		Username: req.Username,
 		Email:    req.Email,
 		Password: req.Password,
 		FullName: req.FullName,
-		Role:     "user", // Default role for registration
 	}
 
-	user, err := service.RegisterUser(createReq)
+	user, invitation, err := service.RegisterUserWithInvitation(createReq, req.InviteToken)
 	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRegistrationDisabled):
+			return ErrorResponse(c, http.StatusForbidden, "Registration is disabled. Ask an administrator for an account.", "REGISTRATION_DISABLED", "")
+		case errors.Is(err, service.ErrInvitationRequired):
+			return ErrorResponse(c, http.StatusForbidden, "Registration requires an invitation", "INVITATION_REQUIRED", "")
+		case errors.Is(err, service.ErrInvalidInvitation):
+			return ErrorResponse(c, http.StatusBadRequest, "Invitation is invalid, used, revoked or expired", "INVALID_INVITATION", "")
+		case errors.Is(err, service.ErrInvitationEmail):
+			return ErrorResponse(c, http.StatusBadRequest, "This invitation was issued for a different email", "INVITATION_EMAIL_MISMATCH", "")
+		}
 		return ErrorResponse(c, http.StatusBadRequest, err.Error(), "REGISTRATION_FAILED", "")
 	}
 
@@ -94,6 +108,12 @@ func Register(c echo.Context) error {
 		IPAddress:    sql.NullString{String: ipAddress, Valid: true},
 		UserAgent:    sql.NullString{String: userAgent, Valid: true},
 	}
+	if invitation != nil {
+		auditLog.Details = map[string]interface{}{
+			"invitation_id": invitation.ID,
+			"role":          invitation.Role,
+		}
+	}
 
 	log.Printf("🔍 DEBUG: Calling model.LogAction...")
 	err = model.LogAction(auditLog)
@@ -124,46 +144,36 @@ func LoginUser(c echo.Context) error {
 		return ErrorResponse(c, http.StatusBadRequest, "Username and password are required", "MISSING_FIELDS", "")
 	}
 
+	// Brute-force protection: per username dan per IP (lihat service/login_guard.go)
+	attemptKeys := service.LoginAttemptKeys(req.Username, c.RealIP())
+	if block := service.CheckAttempts(attemptKeys...); block != nil {
+		return attemptBlocked(c, block)
+	}
+
 	// Authenticate user
 	user, err := service.AuthenticateUser(req.Username, req.Password)
 	if err != nil {
 		if err == model.ErrInvalidCredentials {
+			recordLoginFailure(c, req.Username, "invalid_credentials", attemptKeys)
 			return ErrorResponse(c, http.StatusUnauthorized, "Invalid username or password", "INVALID_CREDENTIALS", "")
 		}
 		return ErrorResponse(c, http.StatusBadRequest, err.Error(), "AUTHENTICATION_FAILED", "")
 	}
 
-	// Generate tokens
-	accessToken, err := service.GenerateAccessToken(user)
+	// 2FA aktif (atau diwajibkan kebijakan): token baru diberikan setelah POST /login/2fa
+	needed, enroll, err := service.TwoFactorStep(user)
 	if err != nil {
-		return ErrorResponse(c, http.StatusInternalServerError, "Failed to generate access token", "TOKEN_GENERATION_FAILED", err.Error())
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve security policy", "INTERNAL_ERROR", err.Error())
 	}
-
-	ipAddress := c.RealIP()
-	userAgent := c.Request().UserAgent()
-	refreshToken, err := service.GenerateRefreshTokenForUser(user, ipAddress, userAgent)
-	if err != nil {
-		return ErrorResponse(c, http.StatusInternalServerError, "Failed to generate refresh token", "TOKEN_GENERATION_FAILED", err.Error())
+	if needed {
+		// Counter baru direset setelah kode 2FA benar, agar tebakan kode tetap terhitung
+		return challengeResponse(c, user, enroll)
 	}
 
-	// Log login
-	err = model.LogAction(&model.AuditLog{
-		UserID:       sql.NullInt64{Int64: user.ID, Valid: true},
-		Action:       "user.login",
-		ResourceType: sql.NullString{String: "user", Valid: true},
-		ResourceID:   sql.NullString{String: user.Username, Valid: true},
-		IPAddress:    sql.NullString{String: ipAddress, Valid: true},
-		UserAgent:    sql.NullString{String: userAgent, Valid: true},
-	})
-	if err != nil {
-		log.Printf("⚠️ Failed to log audit: %v", err)
-	}
+	// Login berhasil: counter username direset (counter IP tetap, berkurang sendiri setelah window)
+	_, _ = service.ClearFailedAttempts(attemptKeys[0])
 
-	return SuccessResponse(c, http.StatusOK, "Login successful", AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		User:         user.ToResponse(),
-	})
+	return issueLogin(c, user, nil, nil)
 }
 
 // RefreshToken handles refresh token to get new access token
@@ -178,12 +188,25 @@ func RefreshToken(c echo.Context) error {
 		return ErrorResponse(c, http.StatusBadRequest, "Refresh token is required", "MISSING_TOKEN", "")
 	}
 
+	// Tebakan refresh token dihitung per IP
+	attemptKey := service.AttemptKey{Scope: service.AttemptScopeRefreshIP, Key: c.RealIP()}
+	if block := service.CheckAttempts(attemptKey); block != nil {
+		return attemptBlocked(c, block)
+	}
+
 	// Validate refresh token and generate new access token
 	accessToken, user, err := service.RefreshAccessToken(req.RefreshToken)
 	if err != nil {
+		// Token expired / revoked wajar terjadi (tab lama), yang dihitung hanya token yang tidak pernah ada
+		if err == model.ErrTokenNotFound {
+			service.RecordFailedAttempt(attemptKey, c.RealIP())
+		}
 		if err == model.ErrTokenNotFound || err == model.ErrTokenExpired || err == model.ErrTokenRevoked {
 			return ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired refresh token", "INVALID_REFRESH_TOKEN", err.Error())
 		}
+		if errors.Is(err, service.ErrTwoFactorEnrollment) {
+			return ErrorResponse(c, http.StatusForbidden, "Two-factor authentication is now required, please login again", "TWO_FACTOR_ENROLLMENT_REQUIRED", "")
+		}
 		return ErrorResponse(c, http.StatusInternalServerError, "Failed to refresh token", "REFRESH_FAILED", err.Error())
 	}
 
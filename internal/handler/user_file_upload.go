@@ -6,8 +6,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 
 	"gowa-yourself/internal/helper"
 	"gowa-yourself/internal/model"
@@ -95,29 +93,10 @@ func UploadAvatar(c echo.Context) error {
 
 	log.Printf("✅ Image compressed: %d bytes → %d bytes", file.Size, len(compressedData))
 
-	// Create user-specific directory
-	userDir := helper.GetUserUploadDir(userClaims.UserID)
-	if err := os.MkdirAll(userDir, 0755); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"success": false,
-			"message": "Failed to create user directory",
-			"error": map[string]string{
-				"code": "DIRECTORY_ERROR",
-			},
-		})
-	}
-
-	// Get file path (always overwrites existing avatar)
-	filePath := helper.GetUserAvatarPath(userClaims.UserID)
-	avatarURL := helper.GetUserAvatarURL(userClaims.UserID)
-
-	// Delete old avatar if exists (will be overwritten anyway, but good practice)
-	if _, err := os.Stat(filePath); err == nil {
-		log.Printf("🗑️ Overwriting existing avatar: %s", filePath)
-	}
-
-	// Save compressed file
-	if err := saveCompressedFile(filePath, compressedData); err != nil {
+	// Save to storage (always overwrites existing avatar)
+	avatarKey := helper.GetUserAvatarKey(userClaims.UserID)
+	if err := helper.GetStorage().Put(c.Request().Context(), avatarKey, compressedData, "image/webp"); err != nil {
+		log.Printf("❌ Failed to store avatar: %v", err)
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"success": false,
 			"message": "Failed to save file",
@@ -140,11 +119,11 @@ func UploadAvatar(c echo.Context) error {
 	}
 
 	// Update user avatar_url in database
-	user.AvatarURL = sql.NullString{String: avatarURL, Valid: true}
+	user.AvatarURL = sql.NullString{String: avatarKey, Valid: true}
 	err = model.UpdateUser(user)
 	if err != nil {
 		// Rollback: delete uploaded file
-		helper.DeleteFile(filePath)
+		helper.GetStorage().Delete(c.Request().Context(), avatarKey)
 
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"success": false,
@@ -166,13 +145,14 @@ func UploadAvatar(c echo.Context) error {
 			"original_size":     file.Size,
 			"compressed_size":   len(compressedData),
 			"format":            "webp",
-			"saved_as":          filepath.Base(filePath),
+			"saved_as":          avatarKey,
 		},
 		IPAddress: sql.NullString{String: c.RealIP(), Valid: true},
 		UserAgent: sql.NullString{String: c.Request().UserAgent(), Valid: true},
 	})
 
-	log.Printf("✅ Avatar uploaded successfully for user %d: %s", userClaims.UserID, avatarURL)
+	avatarURL := helper.StorageURL(avatarKey)
+	log.Printf("✅ Avatar uploaded successfully for user %d: %s", userClaims.UserID, avatarKey)
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
@@ -185,24 +165,3 @@ func UploadAvatar(c echo.Context) error {
 		},
 	})
 }
-
-// saveCompressedFile saves compressed byte data to file
-func saveCompressedFile(filePath string, data []byte) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer file.Close()
-
-	// Set file permissions
-	if err := file.Chmod(0644); err != nil {
-		return fmt.Errorf("failed to set file permissions: %w", err)
-	}
-
-	// Write data
-	if _, err := file.Write(data); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
-
-	return nil
-}
@@ -12,6 +12,23 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// authorizeRoom memastikan user punya akses send ke instance pengirim dan penerima room,
+// karena room mengirim pesan lewat kedua instance. ok=false berarti response error sudah ditulis.
+func authorizeRoom(c echo.Context, id string) (bool, error) {
+	room, err := warmingService.GetWarmingRoomByIDService(id)
+	if err != nil {
+		if errors.Is(err, warmingService.ErrRoomNotFound) {
+			return false, handler.ErrorResponse(c, http.StatusNotFound, "Room not found", "NOT_FOUND", "")
+		}
+		return false, handler.ErrorResponse(c, http.StatusInternalServerError, "Failed to get room", "GET_FAILED", err.Error())
+	}
+
+	if err := handler.CheckSendAccess(c, room.SenderInstanceID, room.ReceiverInstanceID); err != nil {
+		return false, handler.SendAccessErrorResponse(c, err)
+	}
+	return true, nil
+}
+
 // CreateWarmingRoom handles POST /warming/rooms
 func CreateWarmingRoom(c echo.Context) error {
 	var req warmingModel.CreateWarmingRoomRequest
@@ -19,6 +36,10 @@ func CreateWarmingRoom(c echo.Context) error {
 		return handler.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
 	}
 
+	if err := handler.CheckSendAccess(c, req.SenderInstanceID, req.ReceiverInstanceID); err != nil {
+		return handler.SendAccessErrorResponse(c, err)
+	}
+
 	// Extract user ID from JWT context
 	userID, ok := c.Get("user_id").(int64)
 	if !ok {
@@ -111,6 +132,10 @@ func GetWarmingRoomByID(c echo.Context) error {
 func UpdateWarmingRoom(c echo.Context) error {
 	id := c.Param("id")
 
+	if ok, err := authorizeRoom(c, id); !ok {
+		return err
+	}
+
 	var req warmingModel.UpdateWarmingRoomRequest
 	if err := c.Bind(&req); err != nil {
 		return handler.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
@@ -140,6 +165,10 @@ func UpdateWarmingRoom(c echo.Context) error {
 func DeleteWarmingRoom(c echo.Context) error {
 	id := c.Param("id")
 
+	if ok, err := authorizeRoom(c, id); !ok {
+		return err
+	}
+
 	err := warmingService.DeleteWarmingRoomService(id)
 	if err != nil {
 		if errors.Is(err, warmingService.ErrRoomNotFound) {
@@ -157,6 +186,10 @@ func DeleteWarmingRoom(c echo.Context) error {
 func UpdateRoomStatus(c echo.Context) error {
 	id := c.Param("id")
 
+	if ok, err := authorizeRoom(c, id); !ok {
+		return err
+	}
+
 	var req struct {
 		Status string `json:"status"`
 	}
@@ -189,6 +222,10 @@ func UpdateRoomStatus(c echo.Context) error {
 func RestartWarmingRoom(c echo.Context) error {
 	id := c.Param("id")
 
+	if ok, err := authorizeRoom(c, id); !ok {
+		return err
+	}
+
 	err := warmingService.RestartRoomService(id)
 	if err != nil {
 		if errors.Is(err, warmingService.ErrRoomNotFound) {
@@ -3,18 +3,24 @@ package handler
 
 import (
 	"crypto/rand"
+	"database/sql"
 	"encoding/hex"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"gowa-yourself/internal/model"
 	"gowa-yourself/internal/service" // ✅ FIX: Tambahkan import
+	"gowa-yourself/internal/ws"
 
 	"github.com/labstack/echo/v4"
 )
 
 type WebhookConfigRequest struct {
-	URL    string `json:"url"`
-	Secret string `json:"secret"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"` // ws.Event* yang di-subscribe, "*" = semua; tidak dikirim = pakai yang lama
 }
 
 // POST /api/instances/:instanceId/webhook
@@ -68,8 +74,22 @@ func SetWebhookConfig(c echo.Context) error {
 		}
 	}
 
-	// Update DB with url + effectiveSecret
-	if err := model.UpdateInstanceWebhook(instanceID, req.URL, effectiveSecret); err != nil {
+	// Event subscription: validasi terhadap ws.SubscribableEvents, kalau tidak dikirim pakai yang lama
+	events := inst.WebhookEvents
+	if req.Events != nil {
+		for _, e := range req.Events {
+			if e != "*" && !ws.IsSubscribableEvent(e) {
+				return ErrorResponse(c, http.StatusBadRequest,
+					"Unknown webhook event: "+e, "INVALID_EVENT",
+					"Allowed events: *, "+strings.Join(ws.SubscribableEvents, ", "))
+			}
+		}
+		joined := strings.Join(req.Events, ",")
+		events = sql.NullString{String: joined, Valid: joined != ""}
+	}
+
+	// Update DB with url + effectiveSecret + events
+	if err := model.UpdateInstanceWebhook(instanceID, req.URL, effectiveSecret, events); err != nil {
 		if err.Error() == "instance_not_found" {
 			return ErrorResponse(c, http.StatusNotFound,
 				"Instance not found", "INSTANCE_NOT_FOUND", "")
@@ -86,5 +106,91 @@ func SetWebhookConfig(c echo.Context) error {
 		"instanceId": instanceID,
 		"webhookUrl": req.URL,
 		"secret":     effectiveSecret, // user must store this securely
+		"events":     subscribedEvents(events.String),
 	})
 }
+
+// subscribedEvents returns the effective event list (tanpa konfigurasi = incoming_message saja)
+func subscribedEvents(raw string) []string {
+	events := service.ParseWebhookEvents(raw)
+	if len(events) == 0 {
+		return []string{ws.EventIncomingMessage}
+	}
+	return events
+}
+
+// GET /api/instances/:instanceId/webhook-deliveries?status=dead&page=1&limit=50
+func GetWebhookDeliveries(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+	status := c.QueryParam("status")
+
+	if status != "" && status != model.WebhookDeliveryPending &&
+		status != model.WebhookDeliverySuccess && status != model.WebhookDeliveryDead {
+		return ErrorResponse(c, http.StatusBadRequest,
+			"status must be one of: pending, success, dead", "INVALID_STATUS", "")
+	}
+
+	page := 1
+	limit := 50
+	if p, err := strconv.Atoi(c.QueryParam("page")); err == nil && p > 0 {
+		page = p
+	}
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+
+	deliveries, total, err := model.GetWebhookDeliveriesByInstance(instanceID, status, limit, (page-1)*limit)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError,
+			"Failed to get webhook deliveries", "GET_FAILED", err.Error())
+	}
+
+	responses := make([]model.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		responses = append(responses, model.ToWebhookDeliveryResponse(d))
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return SuccessResponse(c, http.StatusOK, "Webhook deliveries retrieved successfully", map[string]interface{}{
+		"instanceId":  instanceID,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"totalPages":  totalPages,
+		"deliveries":  responses,
+		"hasNextPage": page < totalPages,
+	})
+}
+
+// POST /api/instances/:instanceId/webhook-deliveries/:deliveryId/replay
+func ReplayWebhookDelivery(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	deliveryID, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid delivery ID", "INVALID_ID", err.Error())
+	}
+
+	delivery, err := model.GetWebhookDeliveryByID(deliveryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrorResponse(c, http.StatusNotFound, "Webhook delivery not found", "NOT_FOUND", "")
+		}
+		return ErrorResponse(c, http.StatusInternalServerError,
+			"Failed to get webhook delivery", "GET_FAILED", err.Error())
+	}
+
+	// Delivery harus milik instance di URL (RequireInstanceAccess hanya cek instanceId)
+	if delivery.InstanceID.String != instanceID {
+		return ErrorResponse(c, http.StatusNotFound, "Webhook delivery not found", "NOT_FOUND", "")
+	}
+
+	replayed, err := service.ReplayWebhookDelivery(deliveryID)
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError,
+			"Failed to replay webhook delivery", "REPLAY_FAILED", err.Error())
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Webhook delivery replayed", model.ToWebhookDeliveryResponse(*replayed))
+}
@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+	"gowa-yourself/internal/ws"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WebhookRequest is the body for create/update webhook endpoint
+type WebhookRequest struct {
+	Name       string            `json:"name"`
+	URL        string            `json:"url"`
+	Secret     string            `json:"secret"`
+	Events     []string          `json:"events"` // kosong = semua event
+	InstanceID string            `json:"instanceId"`
+	Circle     string            `json:"circle"`
+	Enabled    *bool             `json:"enabled"`
+	Headers    map[string]string `json:"headers"`
+}
+
+// validateWebhookRequest memvalidasi body dan mengecek hak akses scope.
+// Scope instance butuh akses ke instance tsb, scope circle hanya untuk admin.
+func validateWebhookRequest(claims *service.Claims, req *WebhookRequest) *apiError {
+	if req.Name == "" || req.URL == "" {
+		return &apiError{http.StatusBadRequest, "Field 'name' and 'url' are required", "VALIDATION_ERROR", ""}
+	}
+
+	if !strings.HasPrefix(req.URL, "http://") && !strings.HasPrefix(req.URL, "https://") {
+		return &apiError{http.StatusBadRequest, "webhook url must start with http:// or https://", "INVALID_URL", ""}
+	}
+
+	if (req.InstanceID == "") == (req.Circle == "") {
+		return &apiError{http.StatusBadRequest, "Exactly one of 'instanceId' or 'circle' must be set", "VALIDATION_ERROR", ""}
+	}
+
+	for _, e := range req.Events {
+		if e != "*" && !ws.IsSubscribableEvent(e) {
+			return &apiError{http.StatusBadRequest, "Unknown webhook event: " + e, "INVALID_EVENT",
+				"Allowed events: *, " + strings.Join(ws.SubscribableEvents, ", ")}
+		}
+	}
+
+	isAdmin := claims.Role == "admin"
+	if req.Circle != "" && !isAdmin {
+		return &apiError{http.StatusForbidden, "Only admin can create circle-wide webhooks", "FORBIDDEN", ""}
+	}
+
+	if req.InstanceID != "" {
+		if _, err := model.GetInstanceByInstanceID(req.InstanceID); err != nil {
+			return &apiError{http.StatusNotFound, "Instance not found", "INSTANCE_NOT_FOUND", ""}
+		}
+		if !isAdmin {
+			if err := model.CheckUserInstanceLevel(claims.UserID, claims.Role, req.InstanceID, model.PermissionManage); err != nil {
+				return &apiError{http.StatusForbidden, "You do not have access to this instance", "FORBIDDEN", ""}
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyWebhookRequest mengisi field model dari request
+func applyWebhookRequest(w *model.Webhook, req *WebhookRequest) error {
+	w.Name = req.Name
+	w.URL = req.URL
+	w.InstanceID = sql.NullString{String: req.InstanceID, Valid: req.InstanceID != ""}
+	w.Circle = sql.NullString{String: req.Circle, Valid: req.Circle != ""}
+
+	events := strings.Join(req.Events, ",")
+	w.Events = sql.NullString{String: events, Valid: events != ""}
+
+	if req.Secret != "" {
+		w.Secret = sql.NullString{String: req.Secret, Valid: true}
+	}
+
+	if req.Enabled != nil {
+		w.Enabled = *req.Enabled
+	}
+
+	w.Headers = sql.NullString{}
+	if len(req.Headers) > 0 {
+		headers, err := json.Marshal(req.Headers)
+		if err != nil {
+			return err
+		}
+		w.Headers = sql.NullString{String: string(headers), Valid: true}
+	}
+
+	return nil
+}
+
+// getOwnedWebhook loads a webhook by :id and checks ownership (admin boleh semua)
+func getOwnedWebhook(c echo.Context, claims *service.Claims) (*model.Webhook, *apiError) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, "Invalid webhook ID", "BAD_REQUEST", ""}
+	}
+
+	webhook, err := model.GetWebhookByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &apiError{http.StatusNotFound, "Webhook not found", "NOT_FOUND", ""}
+		}
+		return nil, &apiError{http.StatusInternalServerError, "Failed to retrieve webhook", "INTERNAL_ERROR", err.Error()}
+	}
+
+	if claims.Role != "admin" && webhook.UserID.Int64 != claims.UserID {
+		return nil, &apiError{http.StatusForbidden, "Access denied", "FORBIDDEN", ""}
+	}
+
+	return webhook, nil
+}
+
+// GET /api/webhooks?instanceId=&circle=
+func GetWebhooks(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	webhooks, err := model.GetWebhooks(claims.UserID, claims.Role == "admin", c.QueryParam("instanceId"), c.QueryParam("circle"))
+	if err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve webhooks", "INTERNAL_ERROR", err.Error())
+	}
+
+	responses := make([]model.WebhookResponse, 0, len(webhooks))
+	for _, w := range webhooks {
+		responses = append(responses, model.ToWebhookResponse(w))
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Webhooks retrieved successfully", responses)
+}
+
+// GET /api/webhooks/:id
+func GetWebhook(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	webhook, werr := getOwnedWebhook(c, claims)
+	if werr != nil {
+		return werr.send(c)
+	}
+
+	return SuccessResponse(c, http.StatusOK, "Webhook retrieved successfully", model.ToWebhookResponse(*webhook))
+}
+
+// POST /api/webhooks
+func CreateWebhook(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	var req WebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+
+	if verr := validateWebhookRequest(claims, &req); verr != nil {
+		return verr.send(c)
+	}
+
+	webhook := model.Webhook{
+		UserID:  sql.NullInt64{Int64: claims.UserID, Valid: true},
+		Enabled: true,
+	}
+	if err := applyWebhookRequest(&webhook, &req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid headers", "VALIDATION_ERROR", err.Error())
+	}
+
+	// Generate secret kalau tidak dikirim (32 bytes -> 64 hex chars), sama seperti webhook-setconfig
+	if !webhook.Secret.Valid {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError,
+				"Failed to generate webhook secret", "WEBHOOK_SECRET_GENERATION_FAILED", err.Error())
+		}
+		webhook.Secret = sql.NullString{String: hex.EncodeToString(b), Valid: true}
+	}
+
+	if err := model.CreateWebhook(&webhook); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to create webhook", "INTERNAL_ERROR", err.Error())
+	}
+
+	service.InvalidateAllWebhookCache()
+
+	return SuccessResponse(c, http.StatusCreated, "Webhook created successfully", model.ToWebhookResponse(webhook))
+}
+
+// PUT /api/webhooks/:id
+func UpdateWebhook(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	webhook, werr := getOwnedWebhook(c, claims)
+	if werr != nil {
+		return werr.send(c)
+	}
+
+	var req WebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid request body", "BAD_REQUEST", err.Error())
+	}
+
+	if verr := validateWebhookRequest(claims, &req); verr != nil {
+		return verr.send(c)
+	}
+
+	if err := applyWebhookRequest(webhook, &req); err != nil {
+		return ErrorResponse(c, http.StatusBadRequest, "Invalid headers", "VALIDATION_ERROR", err.Error())
+	}
+
+	if err := model.UpdateWebhook(webhook); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to update webhook", "INTERNAL_ERROR", err.Error())
+	}
+
+	service.InvalidateAllWebhookCache()
+
+	return SuccessResponse(c, http.StatusOK, "Webhook updated successfully", model.ToWebhookResponse(*webhook))
+}
+
+// DELETE /api/webhooks/:id
+func DeleteWebhook(c echo.Context) error {
+	claims := getClaims(c)
+	if claims == nil {
+		return ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "")
+	}
+
+	webhook, werr := getOwnedWebhook(c, claims)
+	if werr != nil {
+		return werr.send(c)
+	}
+
+	if err := model.DeleteWebhook(webhook.ID); err != nil {
+		return ErrorResponse(c, http.StatusInternalServerError, "Failed to delete webhook", "INTERNAL_ERROR", err.Error())
+	}
+
+	service.InvalidateAllWebhookCache()
+
+	return SuccessResponse(c, http.StatusOK, "Webhook deleted successfully", nil)
+}
@@ -3,26 +3,108 @@ package handler
 import (
 	"log"
 	"net/http"
+	"strings"
 
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
 	"gowa-yourself/internal/ws"
 
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
 )
 
+// wsTokenProtocol: browser tidak bisa set header Authorization di WebSocket, jadi token boleh dikirim
+// lewat subprotocol: new WebSocket(url, ["bearer", "<access token>"])
+const wsTokenProtocol = "bearer"
+
+// wsAllowedOrigins diisi dari CORS_ALLOW_ORIGINS (lihat SetWebSocketAllowedOrigins)
+var wsAllowedOrigins []string
+
 // upgrader untuk Gorilla
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// TODO: production: batasi origin
+	Subprotocols:    []string{wsTokenProtocol},
+	CheckOrigin:     checkWebSocketOrigin,
+}
+
+// SetWebSocketAllowedOrigins mengatur allow-list origin WebSocket (sama dengan CORS_ALLOW_ORIGINS)
+func SetWebSocketAllowedOrigins(origins []string) {
+	wsAllowedOrigins = nil
+	for _, o := range origins {
+		if o = strings.TrimRight(strings.TrimSpace(o), "/"); o != "" {
+			wsAllowedOrigins = append(wsAllowedOrigins, o)
+		}
+	}
+}
+
+// checkWebSocketOrigin: client non-browser (tanpa header Origin) diizinkan, browser harus dari origin yang terdaftar
+func checkWebSocketOrigin(r *http.Request) bool {
+	origin := strings.TrimRight(r.Header.Get("Origin"), "/")
+	if origin == "" {
 		return true
-	},
+	}
+	for _, allowed := range wsAllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	log.Printf("ws: rejected connection from origin %s", origin)
+	return false
+}
+
+// webSocketToken mengambil access token dari query ?token=, subprotocol "bearer, <token>",
+// atau header Authorization (untuk client non-browser)
+func webSocketToken(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+
+	protocols := websocket.Subprotocols(r)
+	for i, p := range protocols {
+		if strings.EqualFold(p, wsTokenProtocol) && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+
+	if parts := strings.Split(r.Header.Get("Authorization"), " "); len(parts) == 2 && parts[0] == "Bearer" {
+		return parts[1]
+	}
+	return ""
+}
+
+// authenticateWebSocket memvalidasi token sebelum upgrade (sama seperti JWTAuthMiddleware)
+func authenticateWebSocket(r *http.Request) (*service.Claims, *apiError) {
+	token := webSocketToken(r)
+	if token == "" {
+		return nil, &apiError{http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED", "Pass the access token as ?token= or the Sec-WebSocket-Protocol header"}
+	}
+
+	claims, err := service.ValidateAccessToken(token)
+	if err != nil {
+		return nil, &apiError{http.StatusUnauthorized, "Invalid or expired token", "INVALID_TOKEN", ""}
+	}
+
+	isBlacklisted, err := model.IsAccessTokenRevoked(token, claims.UserID, claims.IssuedAtTime(), claims.ImpersonatorID)
+	if err != nil {
+		return nil, &apiError{http.StatusInternalServerError, "Failed to validate token", "TOKEN_VALIDATION_ERROR", err.Error()}
+	}
+	if isBlacklisted {
+		return nil, &apiError{http.StatusUnauthorized, "Token has been revoked. Please login again.", "TOKEN_REVOKED", ""}
+	}
+
+	return claims, nil
 }
 
 // WebSocketHandler meng-handle koneksi WS di route /ws
+// User non-admin hanya menerima event untuk instance di user_instances dan warming room miliknya.
 func WebSocketHandler(hub *ws.Hub) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		claims, apiErr := authenticateWebSocket(c.Request())
+		if apiErr != nil {
+			return apiErr.send(c)
+		}
+
 		conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
 		if err != nil {
 			log.Printf("ws upgrade error: %v", err)
@@ -30,6 +112,16 @@ func WebSocketHandler(hub *ws.Hub) echo.HandlerFunc {
 		}
 
 		client := ws.NewClient(hub, conn)
+		client.UserID = claims.UserID
+		client.Role = claims.Role
+
+		if err := hub.LoadAccess(client); err != nil {
+			log.Printf("ws: failed to load access for user %d: %v", claims.UserID, err)
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "failed to load access"))
+			_ = conn.Close()
+			return nil
+		}
+
 		hub.Register(client)
 
 		go client.WritePump()
@@ -98,6 +98,11 @@ func CreateWorkerConfig(c echo.Context) error {
 		return ErrorResponse(c, http.StatusBadRequest, "worker_name, circle, and application are required", "VALIDATION_ERROR", "")
 	}
 
+	// Worker mengirim lewat instance di circle, jadi user harus punya akses send ke circle tsb
+	if err := CheckCircleSendAccess(c, req.Circle); err != nil {
+		return SendAccessErrorResponse(c, err)
+	}
+
 	if req.MessageType != "direct" && req.MessageType != "group" {
 		req.MessageType = "direct" // Default
 	}
@@ -189,6 +194,11 @@ func UpdateWorkerConfig(c echo.Context) error {
 		return ErrorResponse(c, http.StatusBadRequest, "worker_name, circle, and application are required", "VALIDATION_ERROR", "")
 	}
 
+	// Worker mengirim lewat instance di circle, jadi user harus punya akses send ke circle tsb
+	if err := CheckCircleSendAccess(c, req.Circle); err != nil {
+		return SendAccessErrorResponse(c, err)
+	}
+
 	if req.MessageType != "direct" && req.MessageType != "group" {
 		req.MessageType = "direct"
 	}
@@ -299,6 +309,13 @@ func ToggleWorkerConfig(c echo.Context) error {
 		return ErrorResponse(c, http.StatusForbidden, "Access denied", "FORBIDDEN", "")
 	}
 
+	// Mengaktifkan kembali worker butuh akses send ke circle (akses bisa sudah dicabut)
+	if !existingConfig.Enabled {
+		if err := CheckCircleSendAccess(c, existingConfig.Circle); err != nil {
+			return SendAccessErrorResponse(c, err)
+		}
+	}
+
 	if err := model.ToggleWorkerConfig(c.Request().Context(), id); err != nil {
 		return ErrorResponse(c, http.StatusInternalServerError, "Failed to toggle worker config", "INTERNAL_ERROR", err.Error())
 	}
@@ -0,0 +1,205 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Voice note WhatsApp wajib OGG/Opus mono, waveform-nya 64 sampel bernilai 0-100
+const (
+	waveformSamples  = 64
+	analysisRate     = 8000 // sample rate PCM untuk hitung durasi & waveform
+	transcodeTimeout = 2 * time.Minute
+)
+
+// ProcessedAudio adalah audio yang siap di-upload ke WhatsApp
+type ProcessedAudio struct {
+	Data     []byte
+	Mimetype string
+	Seconds  uint32
+	Waveform []byte // hanya diisi untuk voice note
+}
+
+// IsSupportedAudio mengecek ekstensi file audio yang diterima endpoint /audio
+func IsSupportedAudio(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".mp3", ".wav", ".m4a", ".ogg", ".opus":
+		return true
+	}
+	return false
+}
+
+// AudioMimeType returns MIME type audio berdasarkan ekstensi
+func AudioMimeType(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".m4a":
+		return "audio/mp4"
+	case ".ogg", ".opus":
+		return "audio/ogg; codecs=opus"
+	case ".wav":
+		return "audio/wav"
+	default:
+		return "audio/mpeg"
+	}
+}
+
+// ffmpegInputFormat returns demuxer ffmpeg untuk ekstensi yang sudah divalidasi. Format input
+// dipaksa (tanpa auto-probe) agar file .mp3 yang ternyata playlist HLS / concat tidak diproses.
+func ffmpegInputFormat(ext string) (string, error) {
+	switch ext {
+	case ".mp3":
+		return "mp3", nil
+	case ".wav":
+		return "wav", nil
+	case ".m4a":
+		return "mov", nil
+	case ".ogg", ".opus":
+		return "ogg", nil
+	}
+	return "", fmt.Errorf("unsupported audio format %q", ext)
+}
+
+// ffmpegPath returns binary ffmpeg (FFMPEG_PATH, default "ffmpeg" dari PATH)
+func ffmpegPath() string {
+	if p := os.Getenv("FFMPEG_PATH"); p != "" {
+		return p
+	}
+	return "ffmpeg"
+}
+
+// ProcessAudio menyiapkan audio untuk dikirim.
+// ptt=true: transcode ke OGG/Opus + hitung durasi & waveform (voice note).
+// ptt=false: mp3/m4a/ogg dikirim apa adanya, wav di-transcode ke OGG/Opus karena tidak bisa diputar di WhatsApp.
+func ProcessAudio(data []byte, filename string, ptt bool) (*ProcessedAudio, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	if !ptt && ext != ".wav" {
+		audio := &ProcessedAudio{Data: data, Mimetype: AudioMimeType(filename)}
+		// Durasi hanya informasi tambahan, gagal decode tidak membatalkan pengiriman
+		if pcm, err := runFFmpeg(data, ext, "-f", "s16le", "-ac", "1", "-ar", fmt.Sprint(analysisRate)); err == nil {
+			audio.Seconds = pcmSeconds(pcm)
+		}
+		return audio, nil
+	}
+
+	opus, err := runFFmpeg(data, ext, "-c:a", "libopus", "-b:a", "32k", "-ac", "1", "-ar", "48000", "-application", "voip", "-f", "ogg")
+	if err != nil {
+		return nil, fmt.Errorf("failed to transcode audio to opus: %w", err)
+	}
+
+	pcm, err := runFFmpeg(data, ext, "-f", "s16le", "-ac", "1", "-ar", fmt.Sprint(analysisRate))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode audio: %w", err)
+	}
+
+	audio := &ProcessedAudio{
+		Data:     opus,
+		Mimetype: "audio/ogg; codecs=opus",
+		Seconds:  pcmSeconds(pcm),
+	}
+	if ptt {
+		audio.Waveform = pcmWaveform(pcm)
+	}
+
+	return audio, nil
+}
+
+// runFFmpeg menjalankan ffmpeg dengan input file sementara (m4a butuh seek, tidak bisa via pipe)
+// dan mengembalikan output dari stdout. Input hanya boleh dibaca dari file lokal itu sendiri
+// (-protocol_whitelist file) sehingga isi file tidak bisa membuat ffmpeg membuka URL lain.
+func runFFmpeg(data []byte, ext string, outputArgs ...string) ([]byte, error) {
+	format, err := ffmpegInputFormat(ext)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := os.CreateTemp("", "sudevwa-audio-*"+ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(input.Name())
+
+	if _, err := input.Write(data); err != nil {
+		input.Close()
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	input.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), transcodeTimeout)
+	defer cancel()
+
+	args := append([]string{
+		"-hide_banner", "-loglevel", "error",
+		"-protocol_whitelist", "file", "-f", format, "-i", input.Name(),
+		"-vn", "-map_metadata", "-1",
+	}, outputArgs...)
+	args = append(args, "pipe:1")
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ffmpegPath(), args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("ffmpeg produced no output")
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// pcmSeconds menghitung durasi dari PCM s16le mono (dibulatkan ke atas, minimal 1 detik)
+func pcmSeconds(pcm []byte) uint32 {
+	samples := len(pcm) / 2
+	seconds := uint32(math.Ceil(float64(samples) / analysisRate))
+	if seconds == 0 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// pcmWaveform membagi audio menjadi 64 blok dan mengambil rata-rata amplitudo tiap blok (skala 0-100)
+func pcmWaveform(pcm []byte) []byte {
+	samples := len(pcm) / 2
+	waveform := make([]byte, waveformSamples)
+	if samples == 0 {
+		return waveform
+	}
+
+	levels := make([]float64, waveformSamples)
+	var peak float64
+	for i := 0; i < waveformSamples; i++ {
+		start := i * samples / waveformSamples
+		end := (i + 1) * samples / waveformSamples
+		if end <= start {
+			continue
+		}
+
+		var sum float64
+		for j := start; j < end; j++ {
+			sample := int16(binary.LittleEndian.Uint16(pcm[j*2:]))
+			sum += math.Abs(float64(sample))
+		}
+		levels[i] = sum / float64(end-start)
+		peak = math.Max(peak, levels[i])
+	}
+
+	if peak == 0 {
+		return waveform
+	}
+	for i, level := range levels {
+		waveform[i] = byte(math.Round(level / peak * 100))
+	}
+
+	return waveform
+}
@@ -0,0 +1,55 @@
+package helper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFFmpegInputFormat(t *testing.T) {
+	tests := []struct {
+		ext    string
+		format string
+	}{
+		{".mp3", "mp3"},
+		{".wav", "wav"},
+		{".m4a", "mov"},
+		{".ogg", "ogg"},
+		{".opus", "ogg"},
+	}
+	for _, tt := range tests {
+		got, err := ffmpegInputFormat(tt.ext)
+		if err != nil || got != tt.format {
+			t.Errorf("ffmpegInputFormat(%q) = %q, %v, want %q", tt.ext, got, err, tt.format)
+		}
+	}
+
+	for _, ext := range []string{"", ".m3u8", ".txt", ".MP3"} {
+		if _, err := ffmpegInputFormat(ext); err == nil {
+			t.Errorf("ffmpegInputFormat(%q) accepted", ext)
+		}
+	}
+}
+
+// Playlist HLS yang disamarkan sebagai .mp3 tidak boleh membuat ffmpeg membuka URL di dalamnya
+func TestRunFFmpegIgnoresPlaylistInput(t *testing.T) {
+	if _, err := exec.LookPath(ffmpegPath()); err != nil {
+		t.Skip("ffmpeg is not installed")
+	}
+
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	playlist := "#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXTINF:1,\n" + server.URL + "/internal.mp3\n#EXT-X-ENDLIST\n"
+	if _, err := runFFmpeg([]byte(playlist), ".mp3", "-f", "s16le"); err == nil {
+		t.Error("playlist was decoded as mp3")
+	}
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Fatal("ffmpeg fetched a URL from the input file")
+	}
+}
@@ -51,7 +51,7 @@ func GetMimeType(mediaType, filename string) string {
 	case "video":
 		return "video/mp4"
 	case "audio":
-		return "audio/mpeg"
+		return AudioMimeType(filename)
 	default: // document
 		switch ext {
 		case ".pdf":
@@ -50,7 +50,8 @@ func InitCustomSchema() {
 
         ALTER TABLE instances
         ADD COLUMN IF NOT EXISTS webhook_url TEXT,
-        ADD COLUMN IF NOT EXISTS webhook_secret TEXT;
+        ADD COLUMN IF NOT EXISTS webhook_secret TEXT,
+        ADD COLUMN IF NOT EXISTS webhook_events TEXT;
 
         ALTER TABLE instances
         ADD COLUMN IF NOT EXISTS used BOOLEAN NOT NULL DEFAULT true,
@@ -314,8 +315,8 @@ func InitCustomSchema() {
 			password_hash VARCHAR(255),  -- Nullable for OAuth users
 			full_name VARCHAR(100),
 			avatar_url VARCHAR(500),  -- Profile picture from OAuth provider
-			auth_provider VARCHAR(20) NOT NULL DEFAULT 'local',  -- 'local' or 'google'
-			oauth_provider_id VARCHAR(255),  -- Google user ID
+			auth_provider VARCHAR(20) NOT NULL DEFAULT 'local',  -- 'local', 'google' or 'oidc'
+			oauth_provider_id VARCHAR(255),  -- OIDC subject (sub) dari provider
 			role VARCHAR(20) NOT NULL DEFAULT 'user',
 			is_active BOOLEAN DEFAULT true,
 			email_verified BOOLEAN DEFAULT false,
@@ -323,7 +324,7 @@ func InitCustomSchema() {
 			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
 			last_login_at TIMESTAMP WITH TIME ZONE,
 			CONSTRAINT chk_role CHECK (role IN ('admin', 'user', 'viewer')),
-			CONSTRAINT chk_auth_provider CHECK (auth_provider IN ('local', 'google'))
+			CONSTRAINT chk_auth_provider CHECK (auth_provider IN ('local', 'google', 'oidc'))
 		);
 
 		CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
@@ -336,8 +337,8 @@ func InitCustomSchema() {
 		COMMENT ON COLUMN users.username IS 'Unique username for login';
 		COMMENT ON COLUMN users.email IS 'Unique email address';
 		COMMENT ON COLUMN users.password_hash IS 'Bcrypt hashed password (NULL for OAuth users)';
-		COMMENT ON COLUMN users.auth_provider IS 'Authentication provider: local (password) or google (OAuth)';
-		COMMENT ON COLUMN users.oauth_provider_id IS 'External provider user ID (e.g., Google user ID)';
+		COMMENT ON COLUMN users.auth_provider IS 'Authentication provider: local (password), google or oidc (SSO)';
+		COMMENT ON COLUMN users.oauth_provider_id IS 'External provider user ID (OIDC sub claim)';
 		COMMENT ON COLUMN users.role IS 'User role: admin (full access), user (standard), viewer (read-only)';
 
 		-- =====================================================
@@ -380,8 +381,8 @@ func InitCustomSchema() {
 		CREATE INDEX IF NOT EXISTS idx_user_instances_user_id ON user_instances(user_id);
 		CREATE INDEX IF NOT EXISTS idx_user_instances_instance_id ON user_instances(instance_id);
 
-		COMMENT ON TABLE user_instances IS 'User-instance access control (presence = authorized)';
-		COMMENT ON COLUMN user_instances.permission_level IS 'Legacy field, not used for authorization (kept for compatibility)';
+		COMMENT ON TABLE user_instances IS 'User-instance access control with per-instance permission level';
+		COMMENT ON COLUMN user_instances.permission_level IS 'read, send, manage or owner (see model.Permission*)';
 
 		-- =====================================================
 		-- Table: audit_logs
@@ -427,6 +428,23 @@ func InitCustomSchema() {
 		log.Println("✅ created_by field added to instances table")
 	}
 
+	// Permission level per instance: nilai lama 'access' (dulu semua akses penuh) dipetakan ke owner
+	// untuk pembuat instance dan manage untuk user lain
+	permissionLevelSchema := `
+		ALTER TABLE user_instances ALTER COLUMN permission_level SET DEFAULT 'read';
+
+		UPDATE user_instances ui
+		SET permission_level = CASE WHEN i.created_by = ui.user_id THEN 'owner' ELSE 'manage' END
+		FROM instances i
+		WHERE i.instance_id = ui.instance_id
+		  AND ui.permission_level NOT IN ('read', 'send', 'manage', 'owner');
+	`
+	if _, err := db.Exec(permissionLevelSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not migrate user_instances.permission_level: %v", err)
+	} else {
+		log.Println("✅ user_instances permission levels ensured")
+	}
+
 	// =====================================================
 	// TOKEN BLACKLIST (for immediate logout/password change)
 	// =====================================================
@@ -453,6 +471,157 @@ func InitCustomSchema() {
 		log.Println("✅ Token blacklist table created successfully")
 	}
 
+	// =====================================================
+	// API KEYS (machine-to-machine access via X-API-Key)
+	// =====================================================
+	apiKeysSchema := `
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id BIGSERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			prefix VARCHAR(32) UNIQUE NOT NULL,
+			key_hash VARCHAR(64) NOT NULL,
+			scopes TEXT[] NOT NULL DEFAULT '{}',
+			instance_ids TEXT[] NOT NULL DEFAULT '{}',
+			expires_at TIMESTAMP WITH TIME ZONE,
+			last_used_at TIMESTAMP WITH TIME ZONE,
+			last_used_ip VARCHAR(45),
+			revoked_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+
+		COMMENT ON TABLE api_keys IS 'Long-lived API keys for machine-to-machine access (X-API-Key header)';
+		COMMENT ON COLUMN api_keys.key_hash IS 'SHA-256 hex of the secret part, plaintext is only shown once';
+		COMMENT ON COLUMN api_keys.instance_ids IS 'Instance allow-list, empty = all instances of the user';
+	`
+	if _, err := db.Exec(apiKeysSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not create api_keys: %v", err)
+	} else {
+		log.Println("✅ API keys table ensured")
+	}
+
+	// =====================================================
+	// USER ADMINISTRATION (forced password reset, session revocation, invitations)
+	// =====================================================
+	userAdminSchema := `
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS sessions_revoked_at TIMESTAMP WITH TIME ZONE;
+
+		COMMENT ON COLUMN users.must_change_password IS 'Set by admin password reset, cleared when the user changes the password';
+		COMMENT ON COLUMN users.sessions_revoked_at IS 'Access tokens issued before this time are rejected';
+
+		CREATE TABLE IF NOT EXISTS user_invitations (
+			id BIGSERIAL PRIMARY KEY,
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			email VARCHAR(255),
+			role VARCHAR(20) NOT NULL DEFAULT 'user',
+			created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			used_at TIMESTAMP WITH TIME ZONE,
+			used_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			revoked_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			CONSTRAINT chk_invitation_role CHECK (role IN ('admin', 'user', 'viewer'))
+		);
+
+		COMMENT ON TABLE user_invitations IS 'Single-use registration invitations (REGISTRATION_MODE=invite)';
+		COMMENT ON COLUMN user_invitations.email IS 'Optional, registration must use this email';
+	`
+	if _, err := db.Exec(userAdminSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not ensure user administration schema: %v", err)
+	} else {
+		log.Println("✅ User administration schema ensured")
+	}
+
+	// =====================================================
+	// TWO-FACTOR AUTHENTICATION (TOTP + recovery codes)
+	// =====================================================
+	twoFactorSchema := `
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64);
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP WITH TIME ZONE;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
+
+		COMMENT ON COLUMN users.totp_secret IS 'Base32 TOTP secret, pending until totp_enabled = true';
+		COMMENT ON COLUMN users.totp_last_step IS 'Last accepted TOTP time step (a code cannot be reused)';
+
+		CREATE TABLE IF NOT EXISTS user_recovery_codes (
+			id BIGSERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			code_hash VARCHAR(64) NOT NULL,
+			used_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_user_id ON user_recovery_codes(user_id);
+
+		COMMENT ON TABLE user_recovery_codes IS 'Single-use 2FA recovery codes (SHA-256)';
+
+		CREATE TABLE IF NOT EXISTS used_two_factor_challenges (
+			jti VARCHAR(64) PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			used_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_used_two_factor_challenges_expires_at ON used_two_factor_challenges(expires_at);
+
+		COMMENT ON TABLE used_two_factor_challenges IS 'Challenge token (jti) yang sudah ditukar dengan login, tidak bisa dipakai ulang';
+	`
+	if _, err := db.Exec(twoFactorSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not ensure two-factor schema: %v", err)
+	} else {
+		log.Println("✅ Two-factor authentication schema ensured")
+	}
+
+	// SSO (OIDC): database lama hanya mengizinkan 'local' / 'google', dan satu identitas
+	// provider hanya boleh terhubung ke satu user. Akun local yang ditautkan tetap 'local'
+	// (password tetap berlaku), provider identitasnya disimpan di oauth_provider.
+	oidcSchema := `
+		ALTER TABLE users DROP CONSTRAINT IF EXISTS chk_auth_provider;
+		ALTER TABLE users ADD CONSTRAINT chk_auth_provider CHECK (auth_provider IN ('local', 'google', 'oidc'));
+
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS oauth_provider VARCHAR(20);
+		ALTER TABLE users DROP CONSTRAINT IF EXISTS chk_oauth_provider;
+		ALTER TABLE users ADD CONSTRAINT chk_oauth_provider CHECK (oauth_provider IS NULL OR oauth_provider IN ('google', 'oidc'));
+
+		DROP INDEX IF EXISTS idx_users_oauth_identity;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_users_oauth_link
+			ON users(COALESCE(oauth_provider, auth_provider), oauth_provider_id) WHERE oauth_provider_id IS NOT NULL;
+
+		COMMENT ON COLUMN users.oauth_provider IS 'SSO provider of an identity linked to a local account (NULL = auth_provider)';
+	`
+	if _, err := db.Exec(oidcSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not ensure SSO schema: %v", err)
+	} else {
+		log.Println("✅ SSO (OIDC) schema ensured")
+	}
+
+	// Brute-force protection: penghitung gagal login per username / IP (dibagi antar replica cluster)
+	authFailuresSchema := `
+		CREATE TABLE IF NOT EXISTS auth_failures (
+			scope VARCHAR(20) NOT NULL,
+			key VARCHAR(255) NOT NULL,
+			failures INTEGER NOT NULL DEFAULT 0,
+			first_failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			locked_until TIMESTAMP WITH TIME ZONE,
+			PRIMARY KEY (scope, key)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_auth_failures_last_failed_at ON auth_failures(last_failed_at);
+
+		COMMENT ON TABLE auth_failures IS 'Failed login / refresh / API key attempts per username or IP, with temporary lockout';
+		COMMENT ON COLUMN auth_failures.scope IS 'username, login_ip, refresh_ip or api_key_ip';
+	`
+	if _, err := db.Exec(authFailuresSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not ensure auth_failures table: %v", err)
+	} else {
+		log.Println("✅ Brute-force protection schema ensured")
+	}
+
 	// =====================================================
 	// SYSTEM SETTINGS TABLE
 	// =====================================================
@@ -694,6 +863,10 @@ func InitCustomSchema() {
 	`
 	_, _ = db.Exec(addOutboxColumnLogic)
 
+	// Delivery receipt tracking for outbox (WhatsApp message ID + final receipt state)
+	// dan nomor tujuan yang sudah dinormalkan (dedup replace_pending)
+	ensureOutboxColumns(db)
+
 	// =====================================================
 	// SIM ATTENDANCE SCHEMA
 	// =====================================================
@@ -758,6 +931,425 @@ func InitCustomSchema() {
 	} else {
 		log.Println("✅ Instance message stats table ensured")
 	}
+
+	// =====================================================
+	// MESSAGES SCHEMA (Persistent Inbox)
+	// =====================================================
+	messagesSchema := `
+		CREATE TABLE IF NOT EXISTS messages (
+			id BIGSERIAL PRIMARY KEY,
+			instance_id VARCHAR(255) NOT NULL REFERENCES instances(instance_id) ON DELETE CASCADE,
+			message_id VARCHAR(128) NOT NULL,
+			chat_jid VARCHAR(255) NOT NULL,
+			sender_jid VARCHAR(255),
+			sender_number VARCHAR(50),
+			push_name VARCHAR(255),
+			from_me BOOLEAN NOT NULL DEFAULT false,
+			is_group BOOLEAN NOT NULL DEFAULT false,
+			message_type VARCHAR(30) NOT NULL DEFAULT 'text',
+			body TEXT,
+			media_mime VARCHAR(255),
+			media_file_name VARCHAR(255),
+			media_size BIGINT,
+			quoted_message_id VARCHAR(128),
+			quoted_participant VARCHAR(255),
+			message_timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			CONSTRAINT unique_instance_message UNIQUE (instance_id, message_id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_messages_instance_chat_ts ON messages(instance_id, chat_jid, message_timestamp DESC, id DESC);
+		CREATE INDEX IF NOT EXISTS idx_messages_instance_ts ON messages(instance_id, message_timestamp DESC);
+		-- Lookup pesan keluar hanya dengan message_id (bulk revoke dari outbox)
+		CREATE INDEX IF NOT EXISTS idx_messages_outgoing_message_id ON messages(message_id) WHERE from_me = true;
+
+		COMMENT ON TABLE messages IS 'Persistent inbox: every incoming and outgoing message per instance';
+		COMMENT ON COLUMN messages.chat_jid IS 'Chat JID (user or group) the message belongs to';
+		COMMENT ON COLUMN messages.sender_number IS 'Sender phone number (LID resolved when possible)';
+		COMMENT ON COLUMN messages.message_type IS 'text, image, video, audio, document, sticker, location, contact, reaction, poll, other';
+	`
+	if _, err := db.Exec(messagesSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not create messages table: %v", err)
+	} else {
+		log.Println("✅ Messages table ensured")
+	}
+
+	// Delivery status lifecycle for messages (sent -> server_ack -> delivered -> read -> played / failed)
+	messageStatusSchema := `
+		ALTER TABLE messages
+		ADD COLUMN IF NOT EXISTS status VARCHAR(20),
+		ADD COLUMN IF NOT EXISTS status_updated_at TIMESTAMP WITH TIME ZONE;
+
+		CREATE INDEX IF NOT EXISTS idx_messages_status ON messages(status);
+
+		COMMENT ON COLUMN messages.status IS 'Outgoing delivery status: sent, server_ack, delivered, read, played, failed (NULL for incoming)';
+	`
+	if _, err := db.Exec(messageStatusSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not add status columns to messages: %v", err)
+	} else {
+		log.Println("✅ Message status columns ensured")
+	}
+
+	// Receipt per peserta untuk pesan grup (messages.status grup = status terjauh dari peserta mana pun)
+	messageReceiptsSchema := `
+		CREATE TABLE IF NOT EXISTS message_receipts (
+			instance_id     VARCHAR(255) NOT NULL,
+			message_id      VARCHAR(255) NOT NULL,
+			participant_jid VARCHAR(255) NOT NULL,
+			status          VARCHAR(20) NOT NULL,
+			updated_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (instance_id, message_id, participant_jid)
+		);
+
+		COMMENT ON TABLE message_receipts IS 'Per-participant delivery status of outgoing group messages';
+		COMMENT ON COLUMN messages.status IS 'Outgoing delivery status: sent, server_ack, delivered, read, played, failed (NULL for incoming). For groups: furthest status reported by any participant';
+	`
+	if _, err := db.Exec(messageReceiptsSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not create message_receipts table: %v", err)
+	} else {
+		log.Println("✅ Message receipts table ensured")
+	}
+
+	// Incoming media yang sudah di-download (path lokal + hash untuk verifikasi)
+	messageMediaSchema := `
+		ALTER TABLE messages
+		ADD COLUMN IF NOT EXISTS media_sha256 VARCHAR(64),
+		ADD COLUMN IF NOT EXISTS media_path TEXT;
+
+		COMMENT ON COLUMN messages.media_path IS 'Storage path of downloaded media (NULL if not downloaded)';
+	`
+	if _, err := db.Exec(messageMediaSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not add media columns to messages: %v", err)
+	} else {
+		log.Println("✅ Message media columns ensured")
+	}
+
+	// Default country untuk normalisasi nomor telepon (per instance, fallback per circle)
+	phoneCountrySchema := `
+		ALTER TABLE instances
+		ADD COLUMN IF NOT EXISTS default_country VARCHAR(2);
+
+		CREATE TABLE IF NOT EXISTS circle_settings (
+			circle          VARCHAR(255) PRIMARY KEY,
+			default_country VARCHAR(2) NOT NULL,
+			updated_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		COMMENT ON COLUMN instances.default_country IS 'ISO 3166-1 alpha-2 country for national phone numbers (NULL = circle / DEFAULT_PHONE_COUNTRY)';
+		COMMENT ON TABLE circle_settings IS 'Per-circle settings, e.g. default phone country for instances and blast workers';
+	`
+	if _, err := db.Exec(phoneCountrySchema); err != nil {
+		log.Printf("⚠️ Warning: Could not add default country settings: %v", err)
+	} else {
+		log.Println("✅ Default phone country settings ensured")
+	}
+
+	// Edit / revoke (delete for everyone) pesan keluar
+	messageEditSchema := `
+		ALTER TABLE messages
+		ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP WITH TIME ZONE,
+		ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP WITH TIME ZONE;
+
+		COMMENT ON COLUMN messages.edited_at IS 'Last time the message text was edited (NULL if never edited)';
+		COMMENT ON COLUMN messages.revoked_at IS 'Time the message was deleted for everyone (NULL if not revoked)';
+	`
+	if _, err := db.Exec(messageEditSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not add edit/revoke columns to messages: %v", err)
+	} else {
+		log.Println("✅ Message edit/revoke columns ensured")
+	}
+
+	// Poll yang dikirim / diterima dan vote-nya (untuk tally hasil poll)
+	pollSchema := `
+		CREATE TABLE IF NOT EXISTS polls (
+			id                BIGSERIAL PRIMARY KEY,
+			instance_id       VARCHAR(255) NOT NULL,
+			message_id        VARCHAR(255) NOT NULL,
+			chat_jid          VARCHAR(255) NOT NULL,
+			creator_jid       VARCHAR(255),
+			question          TEXT NOT NULL,
+			options           TEXT NOT NULL,
+			selectable_count  INT NOT NULL DEFAULT 0,
+			created_at        TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			UNIQUE (instance_id, message_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS poll_votes (
+			id                BIGSERIAL PRIMARY KEY,
+			instance_id       VARCHAR(255) NOT NULL,
+			poll_message_id   VARCHAR(255) NOT NULL,
+			voter_jid         VARCHAR(255) NOT NULL,
+			selected_options  TEXT NOT NULL DEFAULT '[]',
+			voted_at          TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			UNIQUE (instance_id, poll_message_id, voter_jid)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_poll_votes_poll ON poll_votes(instance_id, poll_message_id);
+
+		COMMENT ON COLUMN polls.options IS 'JSON array of option names';
+		COMMENT ON COLUMN poll_votes.selected_options IS 'JSON array of selected option names (latest vote only, empty = retracted)';
+	`
+	if _, err := db.Exec(pollSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not create poll tables: %v", err)
+	} else {
+		log.Println("✅ Poll tables ensured")
+	}
+
+	// Riwayat event grup (join / leave / promote / demote, perubahan info, instance masuk grup)
+	groupEventSchema := `
+		CREATE TABLE IF NOT EXISTS group_events (
+			id                BIGSERIAL PRIMARY KEY,
+			instance_id       VARCHAR(255) NOT NULL,
+			group_jid         VARCHAR(255) NOT NULL,
+			event_type        VARCHAR(50) NOT NULL,
+			action            VARCHAR(50),
+			actor_jid         VARCHAR(255),
+			participants      TEXT NOT NULL DEFAULT '[]',
+			details           TEXT,
+			event_timestamp   TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at        TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_group_events_group ON group_events(instance_id, group_jid, event_timestamp DESC);
+
+		COMMENT ON COLUMN group_events.event_type IS 'GROUP_PARTICIPANTS_CHANGED, GROUP_UPDATED or GROUP_JOINED';
+		COMMENT ON COLUMN group_events.participants IS 'JSON array of participant JIDs';
+		COMMENT ON COLUMN group_events.details IS 'JSON payload of the published event';
+	`
+	if _, err := db.Exec(groupEventSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not create group_events table: %v", err)
+	} else {
+		log.Println("✅ Group events table ensured")
+	}
+
+	// Cluster: node yang hidup + lease kepemilikan instance (supaya satu device hanya connect di satu replica)
+	clusterSchema := `
+		CREATE TABLE IF NOT EXISTS cluster_nodes (
+			node_id         VARCHAR(255) PRIMARY KEY,
+			advertise_url   TEXT NOT NULL DEFAULT '',
+			started_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			heartbeat_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS instance_leases (
+			instance_id     VARCHAR(255) PRIMARY KEY,
+			node_id         VARCHAR(255) NOT NULL,
+			acquired_at     TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			expires_at      TIMESTAMP WITH TIME ZONE NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_instance_leases_node ON instance_leases(node_id);
+
+		COMMENT ON COLUMN cluster_nodes.advertise_url IS 'Base URL other replicas use to proxy / redirect requests to this node';
+		COMMENT ON COLUMN instance_leases.expires_at IS 'Lease is renewed by the owner heartbeat; expired leases can be taken over';
+	`
+	if _, err := db.Exec(clusterSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not create cluster tables: %v", err)
+	} else {
+		log.Println("✅ Cluster nodes & instance leases tables ensured")
+	}
+
+	// Payload realtime yang terlalu besar untuk NOTIFY (> 8000 byte), dibaca node lain lewat referensi id
+	relaySchema := `
+		CREATE TABLE IF NOT EXISTS realtime_relay_payloads (
+			id          BIGSERIAL PRIMARY KEY,
+			payload     TEXT NOT NULL,
+			created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_realtime_relay_payloads_created ON realtime_relay_payloads(created_at);
+	`
+	if _, err := db.Exec(relaySchema); err != nil {
+		log.Printf("⚠️ Warning: Could not create realtime_relay_payloads table: %v", err)
+	} else {
+		log.Println("✅ Realtime relay payloads table ensured")
+	}
+
+	// =====================================================
+	// WEBHOOK DELIVERIES SCHEMA (Durable delivery + retry + DLQ)
+	// =====================================================
+	webhookDeliverySchema := `
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id                  BIGSERIAL PRIMARY KEY,
+			instance_id         VARCHAR(255),
+			worker_id           INT,
+			event               VARCHAR(100) NOT NULL,
+			url                 TEXT NOT NULL,
+			payload             TEXT NOT NULL,
+			signature           VARCHAR(128),
+			status              VARCHAR(20) NOT NULL DEFAULT 'pending'
+			                    CHECK (status IN ('pending', 'success', 'dead')),
+			attempts            INT NOT NULL DEFAULT 0,
+			max_attempts        INT NOT NULL DEFAULT 8,
+			next_attempt_at     TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			last_attempt_at     TIMESTAMP WITH TIME ZONE,
+			last_status_code    INT,
+			last_error          TEXT,
+			delivered_at        TIMESTAMP WITH TIME ZONE,
+			created_at          TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at          TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+
+		COMMENT ON TABLE webhook_deliveries IS 'Antrian pengiriman webhook yang durable (retry dengan exponential backoff, dead-letter, replay)';
+		COMMENT ON COLUMN webhook_deliveries.instance_id IS 'Instance pemilik event (NULL untuk webhook dari outbox worker)';
+		COMMENT ON COLUMN webhook_deliveries.worker_id IS 'worker_config id untuk webhook dari outbox worker';
+		COMMENT ON COLUMN webhook_deliveries.signature IS 'HMAC SHA256 payload (X-SUDEVWA-Signature), dihitung saat enqueue';
+		COMMENT ON COLUMN webhook_deliveries.status IS 'pending (menunggu/ retry), success, dead (melebihi max_attempts)';
+
+		CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+		CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_instance ON webhook_deliveries(instance_id, created_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_worker ON webhook_deliveries(worker_id, created_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_delivered ON webhook_deliveries(delivered_at) WHERE status = 'success';
+
+		-- Webhook outbox worker menyimpan instance pengirim + application, supaya bisa dilihat / di-replay per instance
+		ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS application VARCHAR(255);
+		COMMENT ON COLUMN webhook_deliveries.instance_id IS 'Instance pemilik event (untuk outbox worker: instance yang mengirim pesan)';
+		COMMENT ON COLUMN webhook_deliveries.application IS 'Application outbox untuk webhook dari outbox worker';
+	`
+	if _, err := db.Exec(webhookDeliverySchema); err != nil {
+		log.Printf("⚠️ Warning: Could not create webhook_deliveries table: %v", err)
+	} else {
+		log.Println("✅ Webhook deliveries table ensured")
+	}
+
+	// =====================================================
+	// WEBHOOKS SCHEMA (Multiple endpoint per instance / circle)
+	// =====================================================
+	webhooksSchema := `
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id              BIGSERIAL PRIMARY KEY,
+			user_id         INT,
+			name            VARCHAR(255) NOT NULL,
+			url             TEXT NOT NULL,
+			secret          TEXT,
+			events          TEXT,
+			instance_id     VARCHAR(255),
+			circle          VARCHAR(255),
+			enabled         BOOLEAN NOT NULL DEFAULT true,
+			headers         TEXT,
+			created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+			CONSTRAINT check_webhook_scope
+				CHECK ((instance_id IS NOT NULL AND circle IS NULL) OR (instance_id IS NULL AND circle IS NOT NULL))
+		);
+
+		COMMENT ON TABLE webhooks IS 'Endpoint webhook tambahan, scope per instance atau per circle';
+		COMMENT ON COLUMN webhooks.events IS 'Event filter (comma separated ws.Event*), NULL/kosong = semua event';
+		COMMENT ON COLUMN webhooks.headers IS 'Custom HTTP headers (JSON object)';
+
+		CREATE INDEX IF NOT EXISTS idx_webhooks_instance ON webhooks(instance_id) WHERE enabled = true;
+		CREATE INDEX IF NOT EXISTS idx_webhooks_circle ON webhooks(circle) WHERE enabled = true;
+		CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(user_id);
+
+		ALTER TABLE webhook_deliveries
+		ADD COLUMN IF NOT EXISTS webhook_id BIGINT,
+		ADD COLUMN IF NOT EXISTS headers TEXT;
+	`
+	if _, err := db.Exec(webhooksSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not create webhooks table: %v", err)
+	} else {
+		log.Println("✅ Webhooks table ensured")
+	}
+
+	// =====================================================
+	// REVOKE JOBS SCHEMA (Bulk revoke blast outbox di background)
+	// =====================================================
+	revokeJobsSchema := `
+		CREATE TABLE IF NOT EXISTS revoke_jobs (
+			id              BIGSERIAL PRIMARY KEY,
+			user_id         INT,
+			application     VARCHAR(255),
+			table_id        VARCHAR(255),
+			total           INT NOT NULL DEFAULT 0,
+			skipped         INT NOT NULL DEFAULT 0,
+			created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS revoke_job_items (
+			id              BIGSERIAL PRIMARY KEY,
+			job_id          BIGINT NOT NULL REFERENCES revoke_jobs(id) ON DELETE CASCADE,
+			instance_id     VARCHAR(255) NOT NULL,
+			wa_message_id   VARCHAR(255) NOT NULL,
+			status          VARCHAR(20) NOT NULL DEFAULT 'pending'
+			                CHECK (status IN ('pending', 'revoked', 'failed')),
+			error           TEXT,
+			next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			processed_at    TIMESTAMP WITH TIME ZONE
+		);
+
+		COMMENT ON TABLE revoke_jobs IS 'Bulk revoke blast outbox, diproses bertahap oleh revoke worker';
+		COMMENT ON COLUMN revoke_jobs.skipped IS 'Pesan yang dilewati (tidak ada akses ke instance, sudah di-revoke, atau tidak ditemukan)';
+		COMMENT ON COLUMN revoke_job_items.next_attempt_at IS 'Lease: item hanya diambil node pemilik instance setelah waktu ini';
+
+		CREATE INDEX IF NOT EXISTS idx_revoke_job_items_job ON revoke_job_items(job_id);
+		CREATE INDEX IF NOT EXISTS idx_revoke_job_items_pending ON revoke_job_items(instance_id, next_attempt_at) WHERE status = 'pending';
+	`
+	if _, err := db.Exec(revokeJobsSchema); err != nil {
+		log.Printf("⚠️ Warning: Could not create revoke_jobs tables: %v", err)
+	} else {
+		log.Println("✅ Revoke jobs tables ensured")
+	}
+}
+
+// ensureOutboxColumns menambahkan kolom wa_message_id, receipt_status & destination_normalized ke tabel outbox.
+// Outbox bisa berada di DB terpisah (OUTBOX_DATABASE_URL, Postgres atau MySQL), jadi migrasi
+// dijalankan di AppDB dan juga di OutboxDB bila berbeda.
+func ensureOutboxColumns(db *sql.DB) {
+	pgLogic := `
+		DO $$ 
+		BEGIN 
+			BEGIN
+				ALTER TABLE outbox ADD COLUMN wa_message_id VARCHAR(128);
+			EXCEPTION
+				WHEN duplicate_column THEN RAISE NOTICE 'column wa_message_id already exists, skipping';
+			END;
+			BEGIN
+				ALTER TABLE outbox ADD COLUMN receipt_status VARCHAR(20);
+			EXCEPTION
+				WHEN duplicate_column THEN RAISE NOTICE 'column receipt_status already exists, skipping';
+			END;
+			BEGIN
+				ALTER TABLE outbox ADD COLUMN destination_normalized VARCHAR(100);
+			EXCEPTION
+				WHEN duplicate_column THEN RAISE NOTICE 'column destination_normalized already exists, skipping';
+			END;
+		END $$;
+
+		CREATE INDEX IF NOT EXISTS idx_outbox_wa_message_id ON outbox(wa_message_id);
+		CREATE INDEX IF NOT EXISTS idx_outbox_status_destination ON outbox(status, destination_normalized);
+	`
+	if _, err := db.Exec(pgLogic); err != nil {
+		log.Printf("⚠️ Warning: Could not add receipt & destination columns to outbox: %v", err)
+	} else {
+		log.Println("✅ Outbox receipt & destination columns ensured")
+	}
+
+	outboxDB := database.OutboxDB
+	if outboxDB == nil || outboxDB == db {
+		return
+	}
+
+	if database.OutboxDriver == "mysql" {
+		// MySQL tidak punya ADD COLUMN IF NOT EXISTS, error duplicate column (1060) diabaikan
+		for _, stmt := range []string{
+			"ALTER TABLE outbox ADD COLUMN wa_message_id VARCHAR(128) NULL",
+			"ALTER TABLE outbox ADD COLUMN receipt_status VARCHAR(20) NULL",
+			"CREATE INDEX idx_outbox_wa_message_id ON outbox(wa_message_id)",
+			"ALTER TABLE outbox ADD COLUMN destination_normalized VARCHAR(100) NULL",
+			"CREATE INDEX idx_outbox_status_destination ON outbox(status, destination_normalized)",
+		} {
+			_, _ = outboxDB.Exec(stmt)
+		}
+		log.Println("✅ External outbox (mysql) receipt & destination columns checked")
+		return
+	}
+
+	if _, err := outboxDB.Exec(pgLogic); err != nil {
+		log.Printf("⚠️ Warning: Could not add receipt & destination columns to external outbox: %v", err)
+	} else {
+		log.Println("✅ External outbox receipt & destination columns ensured")
+	}
 }
 
 // seedInitialTemplates populates warming_templates with initial conversation templates
@@ -0,0 +1,241 @@
+package helper
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrObjectNotFound dikembalikan Storage.Get jika key tidak ada
+var ErrObjectNotFound = errors.New("storage: object not found")
+
+// StorageObject adalah isi file yang dibaca dari storage
+type StorageObject struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+}
+
+// Storage adalah backend penyimpanan file (avatar, logo sistem, media masuk).
+// Key selalu relatif dan memakai "/" (misal "avatars/1/avatar.webp").
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (*StorageObject, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns URL yang bisa diakses tanpa login sampai ttl habis
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// inlineContentTypes adalah tipe yang boleh ditampilkan inline dari origin API. Tipe lain (html, svg,
+// xml, pdf, ...) bisa menjalankan script di origin dashboard, jadi selalu dikirim sebagai attachment.
+var inlineContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+	"audio/ogg":  true,
+	"audio/mpeg": true,
+	"audio/mp4":  true,
+	"audio/aac":  true,
+	"audio/amr":  true,
+	"audio/wav":  true,
+	"audio/webm": true,
+	"video/mp4":  true,
+	"video/3gpp": true,
+	"video/webm": true,
+}
+
+// IsInlineContentType reports whether the content type (tanpa parameter) aman ditampilkan inline
+func IsInlineContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return inlineContentTypes[mediaType]
+}
+
+// fileStorage default ke disk lokal supaya aman dipakai sebelum InitStorage dipanggil
+var fileStorage Storage = NewLocalStorage(UploadDir)
+
+// InitStorage memilih backend dari STORAGE_DRIVER (local | s3)
+func InitStorage() error {
+	driver := strings.ToLower(os.Getenv("STORAGE_DRIVER"))
+
+	switch driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = UploadDir
+		}
+		// Signed URL /files/* tanpa secret bisa dipalsukan siapa saja
+		if os.Getenv("STORAGE_SIGNING_SECRET") == "" && os.Getenv("JWT_SECRET") == "" {
+			return fmt.Errorf("STORAGE_SIGNING_SECRET or JWT_SECRET must be set to sign local file URLs")
+		}
+		fileStorage = NewLocalStorage(dir)
+		log.Printf("✅ Storage: local disk (%s)", dir)
+
+	case "s3", "minio":
+		s3, err := NewS3StorageFromEnv()
+		if err != nil {
+			return err
+		}
+		fileStorage = s3
+		log.Printf("✅ Storage: S3-compatible (%s, bucket %s)", s3.Endpoint, s3.Bucket)
+
+	default:
+		return fmt.Errorf("unknown STORAGE_DRIVER: %s (use local or s3)", driver)
+	}
+
+	return nil
+}
+
+// GetStorage returns the active storage backend
+func GetStorage() Storage {
+	return fileStorage
+}
+
+// StorageURLTTL masa berlaku signed URL (STORAGE_URL_TTL_MINUTES, default 60)
+func StorageURLTTL() time.Duration {
+	return time.Duration(GetEnvAsInt("STORAGE_URL_TTL_MINUTES", 60)) * time.Minute
+}
+
+// StorageKey menormalkan nilai yang tersimpan di DB menjadi key storage.
+// Data lama masih menyimpan path statis ("/uploads/avatars/1/avatar.webp").
+func StorageKey(stored string) string {
+	key := strings.TrimPrefix(filepath.ToSlash(stored), "./")
+	key = strings.TrimPrefix(key, "/")
+	return strings.TrimPrefix(key, "uploads/")
+}
+
+// StorageURL mengubah nilai file di DB (key atau path lama) menjadi signed URL.
+// URL eksternal (http/https) dikembalikan apa adanya.
+func StorageURL(stored string) string {
+	if stored == "" || strings.HasPrefix(stored, "http://") || strings.HasPrefix(stored, "https://") {
+		return stored
+	}
+
+	signed, err := fileStorage.SignedURL(StorageKey(stored), StorageURLTTL())
+	if err != nil {
+		log.Printf("⚠️ Failed to sign storage URL for %s: %v", stored, err)
+		return ""
+	}
+	return signed
+}
+
+// storageSigningSecret kunci HMAC untuk signed URL lokal: STORAGE_SIGNING_SECRET, atau key yang
+// diturunkan dari JWT_SECRET (HMAC dengan purpose tersendiri) supaya JWT_SECRET mentah tidak dipakai ulang
+func storageSigningSecret() []byte {
+	if secret := os.Getenv("STORAGE_SIGNING_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	mac.Write([]byte("storage-signed-url"))
+	return mac.Sum(nil)
+}
+
+func fileSignature(key string, expires int64) string {
+	mac := hmac.New(sha256.New, storageSigningSecret())
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyFileSignature mengecek signature dan masa berlaku URL /files/<key>
+func VerifyFileSignature(key, expires, signature string) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	return hmac.Equal([]byte(fileSignature(key, exp)), []byte(signature))
+}
+
+// =====================================================
+// LOCAL DISK
+// =====================================================
+
+// LocalStorage menyimpan file di disk; URL-nya dilayani oleh route GET /files/*
+type LocalStorage struct {
+	Dir string
+}
+
+// NewLocalStorage creates a local disk storage rooted at dir
+func NewLocalStorage(dir string) *LocalStorage {
+	return &LocalStorage{Dir: dir}
+}
+
+// path mengubah key menjadi path file, menolak path traversal
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := filepath.Clean("/" + filepath.FromSlash(key))
+	if clean == string(filepath.Separator) {
+		return "", fmt.Errorf("invalid storage key: %q", key)
+	}
+	return filepath.Join(s.Dir, clean), nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (*StorageObject, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	return &StorageObject{
+		Body:        f,
+		ContentType: mime.TypeByExtension(filepath.Ext(path)),
+		Size:        info.Size(),
+	}, nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return DeleteFile(path)
+}
+
+func (s *LocalStorage) SignedURL(key string, ttl time.Duration) (string, error) {
+	expires := time.Now().Add(ttl).Unix()
+	return fmt.Sprintf("/files/%s?expires=%d&signature=%s",
+		(&url.URL{Path: key}).EscapedPath(), expires, fileSignature(key, expires)), nil
+}
@@ -0,0 +1,57 @@
+package helper
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestInitStorageRequiresSigningSecret(t *testing.T) {
+	t.Setenv("STORAGE_DRIVER", "local")
+	t.Setenv("STORAGE_LOCAL_DIR", t.TempDir())
+	t.Setenv("STORAGE_SIGNING_SECRET", "")
+	t.Setenv("JWT_SECRET", "")
+
+	if err := InitStorage(); err == nil {
+		t.Fatal("InitStorage succeeded without STORAGE_SIGNING_SECRET and JWT_SECRET")
+	}
+
+	t.Setenv("JWT_SECRET", "jwt-secret")
+	if err := InitStorage(); err != nil {
+		t.Fatalf("InitStorage with JWT_SECRET: %v", err)
+	}
+}
+
+func TestStorageSigningSecretIsDerivedFromJWTSecret(t *testing.T) {
+	t.Setenv("STORAGE_SIGNING_SECRET", "")
+	t.Setenv("JWT_SECRET", "jwt-secret")
+
+	if string(storageSigningSecret()) == "jwt-secret" {
+		t.Fatal("raw JWT_SECRET is used as the signing key")
+	}
+
+	t.Setenv("STORAGE_SIGNING_SECRET", "storage-secret")
+	if got := string(storageSigningSecret()); got != "storage-secret" {
+		t.Fatalf("signing key = %q, want STORAGE_SIGNING_SECRET", got)
+	}
+}
+
+func TestVerifyFileSignature(t *testing.T) {
+	t.Setenv("STORAGE_SIGNING_SECRET", "storage-secret")
+
+	expires := time.Now().Add(time.Minute).Unix()
+	exp := strconv.FormatInt(expires, 10)
+	sig := fileSignature("avatars/1/avatar.webp", expires)
+
+	if !VerifyFileSignature("avatars/1/avatar.webp", exp, sig) {
+		t.Fatal("valid signature rejected")
+	}
+	if VerifyFileSignature("avatars/2/avatar.webp", exp, sig) {
+		t.Fatal("signature accepted for another key")
+	}
+
+	past := time.Now().Add(-time.Minute).Unix()
+	if VerifyFileSignature("avatars/1/avatar.webp", strconv.FormatInt(past, 10), fileSignature("avatars/1/avatar.webp", past)) {
+		t.Fatal("expired signature accepted")
+	}
+}
@@ -0,0 +1,100 @@
+package helper
+
+import (
+	"testing"
+	"time"
+)
+
+// Secret SHA1 dari RFC 6238 Appendix B: ASCII "12345678901234567890"
+const (
+	rfc6238Key    = "12345678901234567890"
+	rfc6238Base32 = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+)
+
+// Test vector SHA1 RFC 6238 (kode 8 digit dipotong ke 6 digit terakhir, sesuai TOTPDigits)
+func TestTOTPCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		rfc  string // kode 8 digit di RFC
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		got := totpCode([]byte(rfc6238Key), tt.unix/TOTPPeriod)
+		if want := tt.rfc[len(tt.rfc)-TOTPDigits:]; got != want {
+			t.Errorf("totpCode(T=%d) = %s, want %s", tt.unix, got, want)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / TOTPPeriod
+	key := []byte(rfc6238Key)
+
+	tests := []struct {
+		name   string
+		offset int64 // step relatif terhadap waktu sekarang
+		valid  bool
+	}{
+		{"current step", 0, true},
+		{"one step behind", -1, true},
+		{"one step ahead", 1, true},
+		{"two steps behind", -2, false},
+		{"two steps ahead", 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := totpCode(key, current+tt.offset)
+			step, ok := ValidateTOTP(rfc6238Base32, code, now)
+			if ok != tt.valid {
+				t.Fatalf("ValidateTOTP ok = %v, want %v", ok, tt.valid)
+			}
+			if ok && step != current+tt.offset {
+				t.Fatalf("ValidateTOTP step = %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPInput(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code := totpCode([]byte(rfc6238Key), now.Unix()/TOTPPeriod)
+
+	if _, ok := ValidateTOTP("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code, now); !ok {
+		t.Error("lowercase secret rejected")
+	}
+	if _, ok := ValidateTOTP(rfc6238Base32, " "+code+"\n", now); !ok {
+		t.Error("code with surrounding whitespace rejected")
+	}
+	if _, ok := ValidateTOTP(rfc6238Base32, code[:5], now); ok {
+		t.Error("5-digit code accepted")
+	}
+	if _, ok := ValidateTOTP(rfc6238Base32, "89005924", now); ok {
+		t.Error("8-digit code accepted")
+	}
+	if _, ok := ValidateTOTP("not base32!", code, now); ok {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret is not base32: %v", err)
+	}
+	if len(key) != totpSecretBytes {
+		t.Fatalf("key length = %d, want %d", len(key), totpSecretBytes)
+	}
+}
@@ -143,9 +143,9 @@ func GetUserAvatarPath(userID int64) string {
 	return filepath.Join(userDir, "avatar.webp")
 }
 
-// GetUserAvatarURL returns the URL for user's avatar
-func GetUserAvatarURL(userID int64) string {
-	return fmt.Sprintf("/uploads/avatars/%d/avatar.webp", userID)
+// GetUserAvatarKey returns the storage key for user's avatar
+func GetUserAvatarKey(userID int64) string {
+	return fmt.Sprintf("avatars/%d/avatar.webp", userID)
 }
 
 // SaveFile saves uploaded file to disk
@@ -24,6 +24,7 @@ const (
 	TargetFileSizeBytes   = TargetFileSizeKB * 1024
 	MaxDecompressedSizeMB = 50
 	MaxDecompressedSize   = MaxDecompressedSizeMB * 1024 * 1024
+	GroupPhotoDimension   = 640 // foto profil grup WhatsApp: JPEG persegi 640x640
 )
 
 // CompressAndResize processes uploaded image: validates, resizes, and compresses to WebP
@@ -67,6 +68,36 @@ func CompressAndResize(file multipart.File, fileHeader *multipart.FileHeader) ([
 	return webpData, nil
 }
 
+// PrepareGroupPhoto validates an uploaded image, crops it to a centered square and encodes it as JPEG for SetGroupPhoto
+func PrepareGroupPhoto(data []byte) ([]byte, error) {
+	if DetectMaliciousContent(data[:min(len(data), 8192)]) {
+		return nil, errors.New("malicious content detected in file")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported image format or corrupted file")
+	}
+
+	if err := ValidateDecompressedSize(img); err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() < MinAvatarDimension || bounds.Dy() < MinAvatarDimension {
+		return nil, fmt.Errorf("image too small: minimum %dx%d pixels", MinAvatarDimension, MinAvatarDimension)
+	}
+
+	img = imaging.Fill(img, GroupPhotoDimension, GroupPhotoDimension, imaging.Center, imaging.Lanczos)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, fmt.Errorf("failed to encode JPEG: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
 // ValidateImageContent performs deep validation on image file
 func ValidateImageContent(file multipart.File) error {
 	// Read file content for malicious pattern detection
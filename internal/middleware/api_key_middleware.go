@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// APIKeyHeader adalah header untuk autentikasi machine-to-machine (alternatif Authorization: Bearer)
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyRoute memetakan route (c.Path()) ke scope yang dibutuhkan. Path berakhiran * = prefix.
+// Route yang tidak ada di daftar (profil user, api-keys, attendance, admin) tidak bisa diakses dengan API key.
+type apiKeyRoute struct {
+	path   string
+	method string // kosong = semua method
+	scope  string // kosong = cukup key yang valid
+}
+
+var apiKeyRoutes = []apiKeyRoute{
+	{"/api/send/*", "", model.APIKeyScopeSend},
+	{"/api/send-group/*", "", model.APIKeyScopeSend},
+	{"/api/by-number/*", "", model.APIKeyScopeSend},
+	{"/api/check/*", "", model.APIKeyScopeSend},
+	{"/api/messages/*", http.MethodGet, model.APIKeyScopeReadMessages},
+	{"/api/messages/*", "", model.APIKeyScopeSend},
+	{"/api/chats/*", "", model.APIKeyScopeReadMessages},
+	{"/api/polls/*", "", model.APIKeyScopeReadMessages},
+	{"/api/media/*", "", model.APIKeyScopeReadMessages},
+	{"/api/listen/*", "", model.APIKeyScopeReadMessages},
+	{"/api/events/stream*", "", model.APIKeyScopeReadMessages},
+	{"/api/contacts/*", "", model.APIKeyScopeReadContacts},
+	{"/api/groups/*", http.MethodGet, model.APIKeyScopeReadContacts},
+	{"/api/groups/*", "", model.APIKeyScopeManageGroups},
+	{"/api/instances", http.MethodGet, model.APIKeyScopeReadInstances},
+	{"/api/instances/:instanceId/timeline", http.MethodGet, model.APIKeyScopeReadInstances},
+	{"/api/status/*", "", model.APIKeyScopeReadInstances},
+	{"/api/info-device/*", "", model.APIKeyScopeReadInstances},
+	{"/api/timeline", "", model.APIKeyScopeReadInstances},
+	{"/api/circles/settings", http.MethodGet, model.APIKeyScopeReadInstances},
+	{"/api/login", http.MethodPost, model.APIKeyScopeManageInstances},
+	{"/api/qr/*", "", model.APIKeyScopeManageInstances},
+	{"/api/qr-cancel/*", "", model.APIKeyScopeManageInstances},
+	{"/api/pair-code/*", "", model.APIKeyScopeManageInstances},
+	{"/api/logout/*", "", model.APIKeyScopeManageInstances},
+	{"/api/instances/*", "", model.APIKeyScopeManageInstances},
+	{"/api/webhooks*", "", model.APIKeyScopeManageInstances},
+	{"/api/warming/*", "", model.APIKeyScopeWarming},
+	{"/api/blast-outbox/*", "", model.APIKeyScopeOutbox},
+	{"/api/me", http.MethodGet, ""},
+	{"/api/phone/countries", http.MethodGet, ""},
+	{"/api/system/identity", http.MethodGet, ""},
+}
+
+// apiKeyScopeFor mencari scope untuk route, ok=false jika route tidak boleh diakses dengan API key
+func apiKeyScopeFor(method, path string) (string, bool) {
+	for _, r := range apiKeyRoutes {
+		if r.method != "" && r.method != method {
+			continue
+		}
+		if prefix, isPrefix := strings.CutSuffix(r.path, "*"); isPrefix {
+			if strings.HasPrefix(path, prefix) {
+				return r.scope, true
+			}
+		} else if path == r.path {
+			return r.scope, true
+		}
+	}
+	return "", false
+}
+
+// authenticateAPIKey dipanggil JWTAuthMiddleware jika request membawa X-API-Key:
+// validasi key, cek scope route dan allow-list instance, lalu catat pemakaian ke audit_logs.
+func authenticateAPIKey(c echo.Context, next echo.HandlerFunc, rawKey string) error {
+	// Tebakan API key dihitung per IP, sama seperti /login
+	attemptKey := service.AttemptKey{Scope: service.AttemptScopeAPIKeyIP, Key: c.RealIP()}
+	if block := service.CheckAttempts(attemptKey); block != nil {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(block.RetryAfter.Seconds()))))
+		return apiKeyError(c, http.StatusTooManyRequests, "Too many invalid API key attempts from your IP address", "IP_LOCKED")
+	}
+
+	key, claims, err := service.AuthenticateAPIKey(rawKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAPIKeyRevoked):
+			return apiKeyError(c, http.StatusUnauthorized, "API key has been revoked", "API_KEY_REVOKED")
+		case errors.Is(err, service.ErrAPIKeyExpired):
+			return apiKeyError(c, http.StatusUnauthorized, "API key has expired", "API_KEY_EXPIRED")
+		case errors.Is(err, service.ErrAPIKeyUserInactive):
+			return apiKeyError(c, http.StatusForbidden, "API key owner is inactive", "USER_INACTIVE")
+		case errors.Is(err, service.ErrInvalidAPIKey), errors.Is(err, model.ErrUserNotFound):
+			service.RecordFailedAttempt(attemptKey, c.RealIP())
+			return apiKeyError(c, http.StatusUnauthorized, "Invalid API key", "INVALID_API_KEY")
+		default:
+			return apiKeyError(c, http.StatusInternalServerError, "Failed to validate API key", "API_KEY_VALIDATION_ERROR")
+		}
+	}
+
+	scope, allowed := apiKeyScopeFor(c.Request().Method, c.Path())
+	if !allowed {
+		return apiKeyError(c, http.StatusForbidden, "This endpoint cannot be accessed with an API key", "API_KEY_ROUTE_NOT_ALLOWED")
+	}
+	if scope != "" && !service.APIKeyHasScope(key, scope) {
+		return apiKeyError(c, http.StatusForbidden, "API key is missing scope '"+scope+"'", "API_KEY_SCOPE_MISSING")
+	}
+
+	instanceID := c.Param("instanceId")
+	if instanceID == "" && c.Param("phoneNumber") != "" {
+		if inst, err := model.GetActiveInstanceByPhoneNumber(c.Param("phoneNumber")); err == nil {
+			instanceID = inst.InstanceID
+		}
+	}
+	if instanceID != "" && !service.APIKeyAllowsInstance(key, instanceID) {
+		return apiKeyError(c, http.StatusForbidden, "API key is not allowed to access this instance", "API_KEY_INSTANCE_NOT_ALLOWED")
+	}
+
+	c.Set("user_claims", claims)
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("role", claims.Role)
+	c.Set("api_key", key)
+
+	err = next(c)
+
+	status := c.Response().Status
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		status = he.Code
+	}
+	go recordAPIKeyUsage(key, c.RealIP(), c.Request().UserAgent(), c.Request().Method, c.Path(), instanceID, status)
+
+	return err
+}
+
+// recordAPIKeyUsage memperbarui last_used dan mencatat request ke audit_logs (action api_key.use)
+func recordAPIKeyUsage(key *model.APIKey, ip, userAgent, method, path, instanceID string, status int) {
+	if err := model.TouchAPIKey(key.ID, ip); err != nil {
+		log.Printf("⚠️ Failed to update last used of api key %s: %v", key.Prefix, err)
+	}
+
+	details := map[string]interface{}{
+		"prefix": key.Prefix,
+		"method": method,
+		"path":   path,
+		"status": status,
+	}
+	if instanceID != "" {
+		details["instance_id"] = instanceID
+	}
+
+	err := model.LogAction(&model.AuditLog{
+		UserID:       sql.NullInt64{Int64: key.UserID, Valid: true},
+		Action:       "api_key.use",
+		ResourceType: sql.NullString{String: "api_key", Valid: true},
+		ResourceID:   sql.NullString{String: strconv.FormatInt(key.ID, 10), Valid: true},
+		Details:      details,
+		IPAddress:    sql.NullString{String: ip, Valid: ip != ""},
+		UserAgent:    sql.NullString{String: userAgent, Valid: userAgent != ""},
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to audit api key %s usage: %v", key.Prefix, err)
+	}
+}
+
+func apiKeyError(c echo.Context, status int, message, code string) error {
+	return c.JSON(status, map[string]interface{}{
+		"success": false,
+		"message": message,
+		"error": map[string]string{
+			"code": code,
+		},
+	})
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"gowa-yourself/config"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ForwardToInstanceOwner meneruskan request untuk instance yang session-nya dipegang replica lain.
+// For routes with :instanceId or :phoneNumber parameter; route lain dan mode non-cluster langsung lewat.
+// CLUSTER_FORWARD_MODE=proxy (default) meneruskan lewat reverse proxy, redirect membalas 307 ke node pemilik.
+func ForwardToInstanceOwner() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !config.ClusterEnabled {
+				return next(c)
+			}
+
+			// Request dari replica lain (signature HMAC valid) tidak diproxy berulang. Header forward
+			// dari client dibuang supaya tidak bisa dipakai untuk melewati node pemilik instance.
+			if c.Request().Header.Get(service.ClusterForwardedByHeader) != "" {
+				if service.VerifyClusterForward(c.Request()) {
+					return next(c)
+				}
+				service.StripClusterForward(c.Request())
+			}
+
+			instanceID := c.Param("instanceId")
+			if instanceID == "" {
+				if phoneNumber := c.Param("phoneNumber"); phoneNumber != "" {
+					if inst, err := model.GetActiveInstanceByPhoneNumber(phoneNumber); err == nil {
+						instanceID = inst.InstanceID
+					}
+				}
+			}
+			if instanceID == "" || service.IsLocalInstance(instanceID) {
+				return next(c)
+			}
+
+			owner, err := model.GetInstanceLeaseOwner(instanceID)
+			if err != nil {
+				log.Printf("⚠️ Cluster: %v", err)
+				return next(c)
+			}
+			// Tidak ada pemilik (atau node ini sendiri): handle lokal, mis. GetQR akan mengambil lease
+			if owner == nil || owner.NodeID == config.ClusterNodeID {
+				return next(c)
+			}
+
+			if owner.AdvertiseURL == "" {
+				return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+					"success": false,
+					"message": "Instance is served by node " + owner.NodeID + " which has no CLUSTER_ADVERTISE_URL",
+				})
+			}
+
+			target, err := url.Parse(owner.AdvertiseURL)
+			if err != nil {
+				return c.JSON(http.StatusBadGateway, map[string]interface{}{
+					"success": false,
+					"message": "Invalid advertise URL for node " + owner.NodeID,
+				})
+			}
+
+			if config.ClusterForwardMode == "redirect" {
+				return c.Redirect(http.StatusTemporaryRedirect, strings.TrimRight(owner.AdvertiseURL, "/")+c.Request().URL.RequestURI())
+			}
+
+			proxy := httputil.NewSingleHostReverseProxy(target)
+			proxy.ModifyResponse = func(resp *http.Response) error {
+				// Header CORS sudah diset middleware CORS node ini, jangan sampai dobel
+				for key := range resp.Header {
+					if strings.HasPrefix(key, "Access-Control-") {
+						resp.Header.Del(key)
+					}
+				}
+				return nil
+			}
+			proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+				log.Printf("⚠️ Cluster: proxy to node %s failed for instance %s: %v", owner.NodeID, instanceID, err)
+				_ = c.JSON(http.StatusBadGateway, map[string]interface{}{
+					"success": false,
+					"message": "Instance owner node " + owner.NodeID + " is unreachable",
+				})
+			}
+
+			req := c.Request()
+			if err := service.SignClusterForward(req); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"success": false,
+					"message": "Failed to read request body",
+				})
+			}
+			req.Host = target.Host
+
+			proxy.ServeHTTP(c.Response(), req)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// impersonationBlockedRoutes: selama impersonation admin tidak boleh membuat kredensial,
+// mengubah profil / avatar (termasuk email, dipakai untuk SSO dan reset) atau password / 2FA atas nama user
+var impersonationBlockedRoutes = []struct {
+	path   string
+	method string
+}{
+	{"/api/me", http.MethodPut},
+	{"/api/me/avatar", http.MethodPost},
+	{"/api/me/password", http.MethodPut},
+	{"/api/api-keys", http.MethodPost},
+	{"/api/me/2fa/setup", http.MethodPost},
+	{"/api/me/2fa/enable", http.MethodPost},
+	{"/api/me/2fa/disable", http.MethodPost},
+	{"/api/me/2fa/recovery-codes", http.MethodPost},
+}
+
+// serveImpersonated dipanggil JWTAuthMiddleware untuk token impersonation: tolak route terlarang,
+// lalu catat setiap request ke audit_logs (action user.impersonation_request).
+func serveImpersonated(c echo.Context, next echo.HandlerFunc, claims *service.Claims) error {
+	for _, r := range impersonationBlockedRoutes {
+		if c.Path() == r.path && c.Request().Method == r.method {
+			return c.JSON(http.StatusForbidden, map[string]interface{}{
+				"success": false,
+				"message": "This action is not allowed while impersonating a user",
+				"error": map[string]string{
+					"code": "IMPERSONATION_NOT_ALLOWED",
+				},
+			})
+		}
+	}
+
+	c.Set("impersonator_id", claims.ImpersonatorID)
+
+	err := next(c)
+
+	status := c.Response().Status
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		status = he.Code
+	}
+	go recordImpersonatedRequest(claims, c.RealIP(), c.Request().UserAgent(), c.Request().Method, c.Path(), c.Param("instanceId"), status)
+
+	return err
+}
+
+// recordImpersonatedRequest mencatat request atas nama user dengan admin sebagai pelaku
+func recordImpersonatedRequest(claims *service.Claims, ip, userAgent, method, path, instanceID string, status int) {
+	details := map[string]interface{}{
+		"impersonated_user_id":  claims.UserID,
+		"impersonated_username": claims.Username,
+		"impersonator":          claims.ImpersonatorUsername,
+		"method":                method,
+		"path":                  path,
+		"status":                status,
+	}
+	if instanceID != "" {
+		details["instance_id"] = instanceID
+	}
+
+	err := model.LogAction(&model.AuditLog{
+		UserID:       sql.NullInt64{Int64: claims.ImpersonatorID, Valid: true},
+		Action:       "user.impersonation_request",
+		ResourceType: sql.NullString{String: "user", Valid: true},
+		ResourceID:   sql.NullString{String: strconv.FormatInt(claims.UserID, 10), Valid: true},
+		Details:      details,
+		IPAddress:    sql.NullString{String: ip, Valid: ip != ""},
+		UserAgent:    sql.NullString{String: userAgent, Valid: userAgent != ""},
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to audit impersonated request of user %d by %d: %v", claims.UserID, claims.ImpersonatorID, err)
+	}
+}
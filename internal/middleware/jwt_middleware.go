@@ -15,6 +15,11 @@ import (
 func JWTAuthMiddleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			// API key (machine-to-machine) lewat header X-API-Key, lihat api_key_middleware.go
+			if apiKey := c.Request().Header.Get(APIKeyHeader); apiKey != "" {
+				return authenticateAPIKey(c, next, apiKey)
+			}
+
 			// Get Authorization header
 			authHeader := c.Request().Header.Get("Authorization")
 			if authHeader == "" {
@@ -53,8 +58,8 @@ func JWTAuthMiddleware() echo.MiddlewareFunc {
 				})
 			}
 
-			// Check if token is blacklisted
-			isBlacklisted, err := model.IsTokenBlacklisted(tokenString)
+			// Check if token is blacklisted, or the user was deactivated / had all sessions revoked
+			isBlacklisted, err := model.IsAccessTokenRevoked(tokenString, claims.UserID, claims.IssuedAtTime(), claims.ImpersonatorID)
 			if err != nil {
 				return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 					"success": false,
@@ -81,7 +86,27 @@ func JWTAuthMiddleware() echo.MiddlewareFunc {
 			c.Set("username", claims.Username)
 			c.Set("role", claims.Role)
 
+			// Setelah admin reset password, user harus ganti password dulu
+			if claims.PasswordChangeRequired && !passwordChangeAllowed(c.Path()) {
+				return c.JSON(http.StatusForbidden, map[string]interface{}{
+					"success": false,
+					"message": "Password change required. Please change your password via PUT /api/me/password.",
+					"error": map[string]string{
+						"code": "PASSWORD_CHANGE_REQUIRED",
+					},
+				})
+			}
+
+			if claims.ImpersonatorID != 0 {
+				return serveImpersonated(c, next, claims)
+			}
+
 			return next(c)
 		}
 	}
 }
+
+// passwordChangeAllowed: route yang tetap boleh diakses selama PASSWORD_CHANGE_REQUIRED
+func passwordChangeAllowed(path string) bool {
+	return path == "/api/me" || path == "/api/me/password" || path == "/api/logout"
+}
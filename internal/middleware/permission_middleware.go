@@ -9,10 +9,11 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-// RequireInstanceAccess ensures the user has permission to access the requested instance.
+// RequireInstanceAccess ensures the user has at least the given permission level on the requested instance.
 // For routes with :instanceId parameter.
-// Logic: Admin has full access. Standard User must be linked to the instance in user_instances.
-func RequireInstanceAccess() echo.MiddlewareFunc {
+// Logic: Admin has full access. Other users must be linked to the instance in user_instances with a
+// permission_level >= level (viewer role is capped at read, see model.EffectivePermission).
+func RequireInstanceAccess(level string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Get claims from context (set by JWTMiddleware)
@@ -42,8 +43,8 @@ func RequireInstanceAccess() echo.MiddlewareFunc {
 				})
 			}
 
-			// Check if user has ANY permission to this instance in DB
-			_, err := model.CheckUserInstancePermission(userClaims.UserID, instanceID)
+			// Check permission level of this user on the instance
+			assigned, err := model.CheckUserInstancePermission(userClaims.UserID, instanceID)
 			if err != nil {
 				if err == model.ErrNoPermission {
 					return c.JSON(http.StatusForbidden, map[string]interface{}{
@@ -57,15 +58,15 @@ func RequireInstanceAccess() echo.MiddlewareFunc {
 				})
 			}
 
-			return next(c)
+			return checkPermissionLevel(c, next, userClaims.Role, assigned, level)
 		}
 	}
 }
 
-// RequirePhoneNumberAccess ensures the user has permission to access instance by phone number.
-// For routes with :phoneNumber parameter.
-// Logic: Admin has full access. Standard User must be linked to the instance in user_instances.
-func RequirePhoneNumberAccess() echo.MiddlewareFunc {
+// RequirePhoneNumberAccess ensures the user has at least the given permission level on the instance
+// of the phone number. For routes with :phoneNumber parameter.
+// Logic: same as RequireInstanceAccess.
+func RequirePhoneNumberAccess(level string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Get claims from context (set by JWTMiddleware)
@@ -100,8 +101,8 @@ func RequirePhoneNumberAccess() echo.MiddlewareFunc {
 				})
 			}
 
-			// Check if user has permission to this instance
-			_, err = model.CheckUserInstancePermission(userClaims.UserID, inst.InstanceID)
+			// Check permission level of this user on the instance
+			assigned, err := model.CheckUserInstancePermission(userClaims.UserID, inst.InstanceID)
 			if err != nil {
 				if err == model.ErrNoPermission {
 					return c.JSON(http.StatusForbidden, map[string]interface{}{
@@ -115,7 +116,26 @@ func RequirePhoneNumberAccess() echo.MiddlewareFunc {
 				})
 			}
 
-			return next(c)
+			return checkPermissionLevel(c, next, userClaims.Role, assigned, level)
 		}
 	}
 }
+
+// checkPermissionLevel membandingkan level efektif user (assignment + batas role) dengan level route
+func checkPermissionLevel(c echo.Context, next echo.HandlerFunc, role, assigned, required string) error {
+	effective := model.EffectivePermission(role, assigned)
+	if !model.PermissionAtLeast(effective, required) {
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"success": false,
+			"message": "Insufficient permission. This action requires '" + required + "' permission on the instance.",
+			"error": map[string]string{
+				"code":       "INSUFFICIENT_PERMISSION",
+				"required":   required,
+				"permission": effective,
+			},
+		})
+	}
+
+	c.Set("instance_permission", effective)
+	return next(c)
+}
@@ -3,6 +3,7 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 
 	"gowa-yourself/internal/service"
 
@@ -59,3 +60,35 @@ func RequireRole(roles ...string) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// RestrictViewer membatasi role viewer ke request baca (GET) di luar route instance,
+// kecuali self-service (profil, logout, API key milik sendiri). Route instance dicek RequireInstanceAccess.
+func RestrictViewer() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userClaims, ok := c.Get("user_claims").(*service.Claims)
+			if !ok || userClaims == nil || userClaims.Role != "viewer" {
+				return next(c)
+			}
+
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+
+			path := c.Path()
+			if path == "/api/me" || strings.HasPrefix(path, "/api/me/") || path == "/api/logout" ||
+				strings.HasPrefix(path, "/api/api-keys") {
+				return next(c)
+			}
+
+			return c.JSON(http.StatusForbidden, map[string]interface{}{
+				"success": false,
+				"message": "Viewer accounts have read-only access",
+				"error": map[string]string{
+					"code": "READ_ONLY_ROLE",
+				},
+			})
+		}
+	}
+}
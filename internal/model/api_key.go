@@ -0,0 +1,219 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gowa-yourself/database"
+
+	"github.com/lib/pq"
+)
+
+// Scope API key. Satu route hanya butuh satu scope (lihat middleware/api_key_middleware.go).
+const (
+	APIKeyScopeSend            = "send"             // kirim / edit / revoke pesan
+	APIKeyScopeReadMessages    = "read:messages"    // chat, riwayat pesan, media, status pesan, event stream
+	APIKeyScopeReadContacts    = "read:contacts"    // kontak dan daftar / info grup
+	APIKeyScopeManageGroups    = "manage:groups"    // buat grup, ubah peserta / setting grup
+	APIKeyScopeReadInstances   = "read:instances"   // daftar instance, status, timeline
+	APIKeyScopeManageInstances = "manage:instances" // login QR / pair code, logout, hapus instance, webhook (termasuk read:instances)
+	APIKeyScopeWarming         = "warming"          // warming scripts, templates, rooms, logs
+	APIKeyScopeOutbox          = "outbox"           // blast outbox queue dan worker config
+)
+
+// APIKeyScopes adalah semua scope yang valid
+var APIKeyScopes = []string{
+	APIKeyScopeSend,
+	APIKeyScopeReadMessages,
+	APIKeyScopeReadContacts,
+	APIKeyScopeManageGroups,
+	APIKeyScopeReadInstances,
+	APIKeyScopeManageInstances,
+	APIKeyScopeWarming,
+	APIKeyScopeOutbox,
+}
+
+// IsValidAPIKeyScope mengecek apakah scope ada di APIKeyScopes
+func IsValidAPIKeyScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey represents a long-lived machine-to-machine key. Hanya hash SHA-256 yang disimpan;
+// prefix dipakai untuk lookup dan untuk mengenali key di UI / log.
+type APIKey struct {
+	ID          int64
+	UserID      int64
+	Name        string
+	Prefix      string
+	KeyHash     string
+	Scopes      []string
+	InstanceIDs []string // kosong = semua instance yang boleh diakses user
+	ExpiresAt   sql.NullTime
+	LastUsedAt  sql.NullTime
+	LastUsedIP  sql.NullString
+	RevokedAt   sql.NullTime
+	CreatedAt   time.Time
+}
+
+// APIKeyResponse for JSON response (tanpa hash)
+type APIKeyResponse struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"userId"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Scopes      []string   `json:"scopes"`
+	InstanceIDs []string   `json:"instanceIds"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	LastUsedAt  *time.Time `json:"lastUsedAt"`
+	LastUsedIP  string     `json:"lastUsedIp,omitempty"`
+	RevokedAt   *time.Time `json:"revokedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// ToAPIKeyResponse converts APIKey to APIKeyResponse
+func ToAPIKeyResponse(k APIKey) APIKeyResponse {
+	resp := APIKeyResponse{
+		ID:          k.ID,
+		UserID:      k.UserID,
+		Name:        k.Name,
+		Prefix:      k.Prefix,
+		Scopes:      k.Scopes,
+		InstanceIDs: k.InstanceIDs,
+		LastUsedIP:  k.LastUsedIP.String,
+		CreatedAt:   k.CreatedAt,
+	}
+	if resp.Scopes == nil {
+		resp.Scopes = []string{}
+	}
+	if resp.InstanceIDs == nil {
+		resp.InstanceIDs = []string{}
+	}
+	if k.ExpiresAt.Valid {
+		resp.ExpiresAt = &k.ExpiresAt.Time
+	}
+	if k.LastUsedAt.Valid {
+		resp.LastUsedAt = &k.LastUsedAt.Time
+	}
+	if k.RevokedAt.Valid {
+		resp.RevokedAt = &k.RevokedAt.Time
+	}
+	return resp
+}
+
+const apiKeyColumns = `
+	id, user_id, name, prefix, key_hash, scopes, instance_ids, expires_at, last_used_at, last_used_ip, revoked_at, created_at
+`
+
+// CreateAPIKey inserts a new API key
+func CreateAPIKey(k *APIKey) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, instance_ids, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	err := database.AppDB.QueryRow(query,
+		k.UserID, k.Name, k.Prefix, k.KeyHash, pq.Array(k.Scopes), pq.Array(k.InstanceIDs), k.ExpiresAt,
+	).Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKeyByPrefix retrieves an API key by its public prefix
+func GetAPIKeyByPrefix(prefix string) (*APIKey, error) {
+	return getAPIKey(`SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix = $1`, prefix)
+}
+
+// GetAPIKeyByID retrieves an API key by ID
+func GetAPIKeyByID(id int64) (*APIKey, error) {
+	return getAPIKey(`SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id)
+}
+
+// GetAPIKeys returns API keys milik user; userID 0 = semua user (admin)
+func GetAPIKeys(userID int64) ([]APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys`
+	var args []interface{}
+	if userID != 0 {
+		query += ` WHERE user_id = $1`
+		args = append(args, userID)
+	}
+	query += ` ORDER BY created_at DESC`
+
+	rows, err := database.AppDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAPIKeys(rows)
+}
+
+// RevokeAPIKey marks an API key as revoked
+func RevokeAPIKey(id int64) error {
+	if _, err := database.AppDB.Exec(`UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id); err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	return nil
+}
+
+// TouchAPIKey mencatat waktu dan IP pemakaian terakhir (maksimal sekali per menit per key)
+func TouchAPIKey(id int64, ip string) error {
+	_, err := database.AppDB.Exec(`
+		UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute' OR last_used_ip IS DISTINCT FROM $2)
+	`, id, ip)
+	return err
+}
+
+func getAPIKey(query string, arg interface{}) (*APIKey, error) {
+	rows, err := database.AppDB.Query(query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api key: %w", err)
+	}
+	defer rows.Close()
+
+	keys, err := scanAPIKeys(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return &keys[0], nil
+}
+
+func scanAPIKeys(rows *sql.Rows) ([]APIKey, error) {
+	var keys []APIKey
+	for rows.Next() {
+		var k APIKey
+		if err := rows.Scan(
+			&k.ID,
+			&k.UserID,
+			&k.Name,
+			&k.Prefix,
+			&k.KeyHash,
+			pq.Array(&k.Scopes),
+			pq.Array(&k.InstanceIDs),
+			&k.ExpiresAt,
+			&k.LastUsedAt,
+			&k.LastUsedIP,
+			&k.RevokedAt,
+			&k.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, rows.Err()
+}
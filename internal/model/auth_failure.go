@@ -0,0 +1,168 @@
+// internal/model/auth_failure.go
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"gowa-yourself/database"
+)
+
+// AuthFailure is the failed-attempt counter of a username or IP
+type AuthFailure struct {
+	Scope         string       `json:"scope"`
+	Key           string       `json:"key"`
+	Failures      int          `json:"failures"`
+	FirstFailedAt time.Time    `json:"first_failed_at"`
+	LastFailedAt  time.Time    `json:"last_failed_at"`
+	LockedUntil   sql.NullTime `json:"-"`
+}
+
+// AuthFailureResponse is the JSON format of AuthFailure
+type AuthFailureResponse struct {
+	Scope         string     `json:"scope"`
+	Key           string     `json:"key"`
+	Failures      int        `json:"failures"`
+	FirstFailedAt time.Time  `json:"first_failed_at"`
+	LastFailedAt  time.Time  `json:"last_failed_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
+
+// ToResponse converts AuthFailure to its JSON format
+func (f *AuthFailure) ToResponse() AuthFailureResponse {
+	resp := AuthFailureResponse{
+		Scope:         f.Scope,
+		Key:           f.Key,
+		Failures:      f.Failures,
+		FirstFailedAt: f.FirstFailedAt,
+		LastFailedAt:  f.LastFailedAt,
+	}
+	if f.LockedUntil.Valid {
+		resp.LockedUntil = &f.LockedUntil.Time
+	}
+	return resp
+}
+
+// GetAuthFailure retrieves the counter of a scope/key (nil jika belum pernah gagal)
+func GetAuthFailure(scope, key string) (*AuthFailure, error) {
+	db := database.AppDB
+
+	f := &AuthFailure{}
+	err := db.QueryRow(`
+		SELECT scope, key, failures, first_failed_at, last_failed_at, locked_until
+		FROM auth_failures WHERE scope = $1 AND key = $2
+	`, scope, key).Scan(&f.Scope, &f.Key, &f.Failures, &f.FirstFailedAt, &f.LastFailedAt, &f.LockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// IncrementAuthFailure menambah counter secara atomik. Counter mulai dari 1 lagi jika kegagalan
+// terakhir lebih lama dari window atau lockout sebelumnya sudah berakhir. Saat counter mencapai
+// maxFailures, key dikunci selama lockout. Returns counter terbaru dan true jika lockout baru dimulai.
+func IncrementAuthFailure(scope, key string, window time.Duration, maxFailures int, lockout time.Duration) (*AuthFailure, bool, error) {
+	db := database.AppDB
+
+	f := &AuthFailure{}
+	err := db.QueryRow(`
+		INSERT INTO auth_failures (scope, key, failures, first_failed_at, last_failed_at)
+		VALUES ($1, $2, 1, NOW(), NOW())
+		ON CONFLICT (scope, key) DO UPDATE SET
+			failures = CASE
+				WHEN auth_failures.last_failed_at < NOW() - $3 * INTERVAL '1 second'
+					OR auth_failures.locked_until < NOW() THEN 1
+				ELSE auth_failures.failures + 1
+			END,
+			first_failed_at = CASE
+				WHEN auth_failures.last_failed_at < NOW() - $3 * INTERVAL '1 second'
+					OR auth_failures.locked_until < NOW() THEN NOW()
+				ELSE auth_failures.first_failed_at
+			END,
+			locked_until = CASE WHEN auth_failures.locked_until < NOW() THEN NULL ELSE auth_failures.locked_until END,
+			last_failed_at = NOW()
+		RETURNING scope, key, failures, first_failed_at, last_failed_at, locked_until
+	`, scope, key, int64(window.Seconds())).Scan(&f.Scope, &f.Key, &f.Failures, &f.FirstFailedAt, &f.LastFailedAt, &f.LockedUntil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if f.Failures < maxFailures || f.LockedUntil.Valid {
+		return f, false, nil
+	}
+
+	err = db.QueryRow(`
+		UPDATE auth_failures SET locked_until = NOW() + $3 * INTERVAL '1 second'
+		WHERE scope = $1 AND key = $2 AND locked_until IS NULL
+		RETURNING locked_until
+	`, scope, key, int64(lockout.Seconds())).Scan(&f.LockedUntil)
+	if err == sql.ErrNoRows {
+		// Request paralel sudah mengunci lebih dulu
+		return f, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return f, true, nil
+}
+
+// ClearAuthFailure menghapus counter (login berhasil / unlock admin); returns false jika tidak ada
+func ClearAuthFailure(scope, key string) (bool, error) {
+	db := database.AppDB
+
+	result, err := db.Exec(`DELETE FROM auth_failures WHERE scope = $1 AND key = $2`, scope, key)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// ListAuthFailures returns the counters that are locked or still inside the window
+func ListAuthFailures(window time.Duration) ([]AuthFailure, error) {
+	db := database.AppDB
+
+	rows, err := db.Query(`
+		SELECT scope, key, failures, first_failed_at, last_failed_at, locked_until
+		FROM auth_failures
+		WHERE locked_until > NOW() OR last_failed_at > NOW() - $1 * INTERVAL '1 second'
+		ORDER BY locked_until DESC NULLS LAST, last_failed_at DESC
+		LIMIT 500
+	`, int64(window.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	failures := []AuthFailure{}
+	for rows.Next() {
+		var f AuthFailure
+		if err := rows.Scan(&f.Scope, &f.Key, &f.Failures, &f.FirstFailedAt, &f.LastFailedAt, &f.LockedUntil); err != nil {
+			return nil, err
+		}
+		failures = append(failures, f)
+	}
+
+	return failures, rows.Err()
+}
+
+// DeleteStaleAuthFailures removes counters that are no longer locked and older than the given age
+func DeleteStaleAuthFailures(olderThan time.Duration) error {
+	db := database.AppDB
+
+	_, err := db.Exec(`
+		DELETE FROM auth_failures
+		WHERE last_failed_at < NOW() - $1 * INTERVAL '1 second'
+		  AND (locked_until IS NULL OR locked_until < NOW())
+	`, int64(olderThan.Seconds()))
+	return err
+}
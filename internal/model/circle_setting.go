@@ -0,0 +1,99 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gowa-yourself/database"
+)
+
+// CircleSetting represents a row in the circle_settings table
+type CircleSetting struct {
+	Circle         string    `json:"circle"`
+	DefaultCountry string    `json:"defaultCountry"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// GetCircleSettings returns settings of all circles
+func GetCircleSettings() ([]CircleSetting, error) {
+	rows, err := database.AppDB.Query(`SELECT circle, default_country, updated_at FROM circle_settings ORDER BY circle`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query circle settings: %w", err)
+	}
+	defer rows.Close()
+
+	settings := []CircleSetting{}
+	for rows.Next() {
+		var s CircleSetting
+		if err := rows.Scan(&s.Circle, &s.DefaultCountry, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan circle setting: %w", err)
+		}
+		settings = append(settings, s)
+	}
+
+	return settings, rows.Err()
+}
+
+// UpsertCircleSetting creates or updates the settings of a circle
+func UpsertCircleSetting(circle, defaultCountry string) (*CircleSetting, error) {
+	query := `
+		INSERT INTO circle_settings (circle, default_country, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (circle) DO UPDATE SET default_country = EXCLUDED.default_country, updated_at = NOW()
+		RETURNING circle, default_country, updated_at
+	`
+
+	var s CircleSetting
+	if err := database.AppDB.QueryRow(query, circle, defaultCountry).Scan(&s.Circle, &s.DefaultCountry, &s.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to save circle setting: %w", err)
+	}
+
+	return &s, nil
+}
+
+// DeleteCircleSetting removes the settings of a circle (kembali ke default global)
+func DeleteCircleSetting(circle string) error {
+	result, err := database.AppDB.Exec(`DELETE FROM circle_settings WHERE circle = $1`, circle)
+	if err != nil {
+		return fmt.Errorf("failed to delete circle setting: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetInstanceDefaultCountry returns default country untuk normalisasi nomor dari instance:
+// instances.default_country -> circle_settings.default_country -> "" (pakai DEFAULT_PHONE_COUNTRY)
+func GetInstanceDefaultCountry(instanceID string) string {
+	query := `
+		SELECT COALESCE(i.default_country, cs.default_country, '')
+		FROM instances i
+		LEFT JOIN circle_settings cs ON cs.circle = i.circle
+		WHERE i.instance_id = $1
+	`
+
+	var country string
+	if err := database.AppDB.QueryRow(query, instanceID).Scan(&country); err != nil {
+		return ""
+	}
+
+	return country
+}
+
+// GetCircleDefaultCountry returns default country dari circle ("" jika tidak diset)
+func GetCircleDefaultCountry(circle string) string {
+	var country string
+	if err := database.AppDB.QueryRow(`SELECT default_country FROM circle_settings WHERE circle = $1`, circle).Scan(&country); err != nil {
+		return ""
+	}
+
+	return country
+}
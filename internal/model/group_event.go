@@ -0,0 +1,152 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gowa-yourself/database"
+)
+
+// GroupEvent represents a row in the group_events table
+type GroupEvent struct {
+	ID             int64
+	InstanceID     string
+	GroupJID       string
+	EventType      string
+	Action         sql.NullString
+	ActorJID       sql.NullString
+	Participants   []string
+	Details        json.RawMessage
+	EventTimestamp time.Time
+	CreatedAt      time.Time
+}
+
+// GroupEventResponse for JSON response
+type GroupEventResponse struct {
+	ID           int64           `json:"id"`
+	InstanceID   string          `json:"instanceId"`
+	GroupJID     string          `json:"groupJid"`
+	EventType    string          `json:"eventType"`
+	Action       *string         `json:"action"`
+	Actor        *string         `json:"actor"`
+	Participants []string        `json:"participants"`
+	Details      json.RawMessage `json:"details,omitempty"`
+	Timestamp    time.Time       `json:"timestamp"`
+}
+
+// ToGroupEventResponse converts GroupEvent to response format
+func ToGroupEventResponse(e GroupEvent) GroupEventResponse {
+	resp := GroupEventResponse{
+		ID:           e.ID,
+		InstanceID:   e.InstanceID,
+		GroupJID:     e.GroupJID,
+		EventType:    e.EventType,
+		Participants: e.Participants,
+		Details:      e.Details,
+		Timestamp:    e.EventTimestamp,
+	}
+	if e.Action.Valid {
+		resp.Action = &e.Action.String
+	}
+	if e.ActorJID.Valid {
+		resp.Actor = &e.ActorJID.String
+	}
+	if resp.Participants == nil {
+		resp.Participants = []string{}
+	}
+	return resp
+}
+
+// SaveGroupEvent inserts one group event into the history table
+func SaveGroupEvent(e *GroupEvent) error {
+	if e.Participants == nil {
+		e.Participants = []string{}
+	}
+	participants, err := json.Marshal(e.Participants)
+	if err != nil {
+		return fmt.Errorf("failed to marshal group event participants: %w", err)
+	}
+
+	var details sql.NullString
+	if len(e.Details) > 0 {
+		details = sql.NullString{String: string(e.Details), Valid: true}
+	}
+
+	query := `
+		INSERT INTO group_events (instance_id, group_jid, event_type, action, actor_jid, participants, details, event_timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+	err = database.AppDB.QueryRow(query,
+		e.InstanceID, e.GroupJID, e.EventType, e.Action, e.ActorJID, string(participants), details, e.EventTimestamp,
+	).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save group event: %w", err)
+	}
+
+	return nil
+}
+
+// GetGroupEvents returns the event history of a group, newest first (cursor pagination seperti GetChatMessages)
+func GetGroupEvents(instanceID, groupJID, eventType string, beforeTime *time.Time, beforeID int64, limit int) ([]GroupEvent, error) {
+	query := `
+		SELECT id, instance_id, group_jid, event_type, action, actor_jid, participants, details, event_timestamp, created_at
+		FROM group_events
+		WHERE instance_id = $1 AND group_jid = $2
+	`
+	args := []interface{}{instanceID, groupJID}
+	argIndex := 3
+
+	if eventType != "" {
+		query += fmt.Sprintf(" AND event_type = $%d", argIndex)
+		args = append(args, eventType)
+		argIndex++
+	}
+
+	if beforeTime != nil {
+		query += fmt.Sprintf(" AND (event_timestamp, id) < ($%d, $%d)", argIndex, argIndex+1)
+		args = append(args, *beforeTime, beforeID)
+		argIndex += 2
+	}
+
+	query += fmt.Sprintf(" ORDER BY event_timestamp DESC, id DESC LIMIT $%d", argIndex)
+	args = append(args, limit)
+
+	rows, err := database.AppDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query group events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []GroupEvent{}
+	for rows.Next() {
+		var e GroupEvent
+		var participants string
+		var details sql.NullString
+		if err := rows.Scan(
+			&e.ID,
+			&e.InstanceID,
+			&e.GroupJID,
+			&e.EventType,
+			&e.Action,
+			&e.ActorJID,
+			&participants,
+			&details,
+			&e.EventTimestamp,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan group event: %w", err)
+		}
+		if err := json.Unmarshal([]byte(participants), &e.Participants); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal group event participants: %w", err)
+		}
+		if details.Valid {
+			e.Details = json.RawMessage(details.String)
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
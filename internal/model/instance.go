@@ -33,8 +33,10 @@ type Instance struct {
 	Circle          string
 	WebhookURL      sql.NullString
 	WebhookSecret   sql.NullString
+	WebhookEvents   sql.NullString // comma separated, NULL = hanya incoming_message
 	Used            bool           `json:"used"`
 	Keterangan      sql.NullString `json:"keterangan"`
+	DefaultCountry  sql.NullString `json:"default_country"`
 	CreatedBy       sql.NullInt64  `json:"created_by"`
 }
 
@@ -61,6 +63,7 @@ type InstanceResp struct {
 	Circle            string    `json:"circle"`
 	Used              bool      `json:"used"`
 	Keterangan        string    `json:"keterangan"`
+	DefaultCountry    string    `json:"defaultCountry,omitempty"`
 	CreatedBy         int64     `json:"createdBy,omitempty"`
 }
 
@@ -186,6 +189,7 @@ func GetAllInstances() ([]Instance, error) {
 			circle,
 			used,
 			keterangan,
+			default_country,
 			created_by
         FROM instances
         ORDER BY 
@@ -229,6 +233,7 @@ func GetAllInstances() ([]Instance, error) {
 			&inst.Circle,
 			&inst.Used,
 			&inst.Keterangan,
+			&inst.DefaultCountry,
 			&inst.CreatedBy,
 		)
 
@@ -386,8 +391,11 @@ func GetInstanceByInstanceID(instanceID string) (*Instance, error) {
             session_data,
 			webhook_url,
 			webhook_secret,
+			webhook_events,
+			COALESCE(circle, ''),
 			used,
 			keterangan,
+			default_country,
 			created_by
         FROM instances
         WHERE instance_id = $1
@@ -432,8 +440,11 @@ func GetInstanceByInstanceID(instanceID string) (*Instance, error) {
 		&inst.SessionData,
 		&inst.WebhookURL,
 		&inst.WebhookSecret,
+		&inst.WebhookEvents,
+		&inst.Circle,
 		&inst.Used,
 		&inst.Keterangan,
+		&inst.DefaultCountry,
 		&inst.CreatedBy,
 	)
 	if err != nil {
@@ -511,6 +522,9 @@ func ToResponse(inst Instance) InstanceResp {
 	if inst.Keterangan.Valid {
 		resp.Keterangan = inst.Keterangan.String
 	}
+	if inst.DefaultCountry.Valid {
+		resp.DefaultCountry = inst.DefaultCountry.String
+	}
 
 	if inst.CreatedBy.Valid {
 		resp.CreatedBy = inst.CreatedBy.Int64
@@ -521,9 +535,10 @@ func ToResponse(inst Instance) InstanceResp {
 
 // UpdateInstanceFieldsRequest for PATCH /instances/:instanceId
 type UpdateInstanceFieldsRequest struct {
-	Used       *bool   `json:"used"`       // pointer to allow null (optional)
-	Keterangan *string `json:"keterangan"` // pointer to allow null (optional)
-	Circle     *string `json:"circle"`     // pointer to allow null (optional)
+	Used           *bool   `json:"used"`           // pointer to allow null (optional)
+	Keterangan     *string `json:"keterangan"`     // pointer to allow null (optional)
+	Circle         *string `json:"circle"`         // pointer to allow null (optional)
+	DefaultCountry *string `json:"defaultCountry"` // ISO alpha-2, "" = ikut circle / global
 }
 
 // UpdateInstanceFields updates used and keterangan fields
@@ -552,6 +567,12 @@ func UpdateInstanceFields(instanceID string, req *UpdateInstanceFieldsRequest) e
 		argCount++
 	}
 
+	if req.DefaultCountry != nil {
+		updates = append(updates, fmt.Sprintf("default_country = NULLIF($%d, '')", argCount))
+		args = append(args, *req.DefaultCountry)
+		argCount++
+	}
+
 	if len(updates) == 0 {
 		return fmt.Errorf("no fields to update")
 	}
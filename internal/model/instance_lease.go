@@ -0,0 +1,217 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gowa-yourself/database"
+
+	"github.com/lib/pq"
+)
+
+// ClusterNode adalah satu replica API yang terdaftar di cluster_nodes
+type ClusterNode struct {
+	NodeID       string    `json:"nodeId"`
+	AdvertiseURL string    `json:"advertiseUrl"`
+	StartedAt    time.Time `json:"startedAt"`
+	HeartbeatAt  time.Time `json:"heartbeatAt"`
+}
+
+// InstanceLease menandai replica mana yang memegang koneksi WhatsApp sebuah instance
+type InstanceLease struct {
+	InstanceID string    `json:"instanceId"`
+	NodeID     string    `json:"nodeId"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// OrphanedInstance adalah instance yang punya device tersimpan tapi tidak dipegang replica manapun
+type OrphanedInstance struct {
+	InstanceID string
+	JID        string
+}
+
+// UpsertClusterNode mendaftarkan node sekaligus memperbarui heartbeat-nya
+func UpsertClusterNode(nodeID, advertiseURL string) error {
+	query := `
+		INSERT INTO cluster_nodes (node_id, advertise_url, started_at, heartbeat_at)
+		VALUES ($1, $2, NOW(), NOW())
+		ON CONFLICT (node_id) DO UPDATE
+		SET advertise_url = EXCLUDED.advertise_url, heartbeat_at = NOW()
+	`
+	if _, err := database.AppDB.Exec(query, nodeID, advertiseURL); err != nil {
+		return fmt.Errorf("failed to upsert cluster node: %w", err)
+	}
+	return nil
+}
+
+// GetLiveClusterNodes returns node yang heartbeat-nya masih dalam ttl
+func GetLiveClusterNodes(ttl time.Duration) ([]ClusterNode, error) {
+	query := `
+		SELECT node_id, advertise_url, started_at, heartbeat_at
+		FROM cluster_nodes
+		WHERE heartbeat_at > NOW() - make_interval(secs => $1)
+		ORDER BY node_id
+	`
+	rows, err := database.AppDB.Query(query, ttl.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cluster nodes: %w", err)
+	}
+	defer rows.Close()
+
+	nodes := []ClusterNode{}
+	for rows.Next() {
+		var n ClusterNode
+		if err := rows.Scan(&n.NodeID, &n.AdvertiseURL, &n.StartedAt, &n.HeartbeatAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cluster node: %w", err)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
+
+// DeleteStaleClusterNodes menghapus node yang sudah lama tidak heartbeat (olderThan)
+func DeleteStaleClusterNodes(olderThan time.Duration) error {
+	_, err := database.AppDB.Exec(
+		`DELETE FROM cluster_nodes WHERE heartbeat_at < NOW() - make_interval(secs => $1)`,
+		olderThan.Seconds(),
+	)
+	return err
+}
+
+// TryAcquireInstanceLease mengambil lease instance untuk nodeID. Berhasil jika belum ada lease,
+// lease sudah expired, atau lease memang milik nodeID (sekaligus diperpanjang).
+func TryAcquireInstanceLease(instanceID, nodeID string, ttl time.Duration) (bool, error) {
+	query := `
+		INSERT INTO instance_leases (instance_id, node_id, acquired_at, expires_at)
+		VALUES ($1, $2, NOW(), NOW() + make_interval(secs => $3))
+		ON CONFLICT (instance_id) DO UPDATE
+		SET node_id = EXCLUDED.node_id,
+		    acquired_at = CASE WHEN instance_leases.node_id = EXCLUDED.node_id THEN instance_leases.acquired_at ELSE NOW() END,
+		    expires_at = EXCLUDED.expires_at
+		WHERE instance_leases.node_id = EXCLUDED.node_id OR instance_leases.expires_at < NOW()
+		RETURNING node_id
+	`
+	var owner string
+	err := database.AppDB.QueryRow(query, instanceID, nodeID, ttl.Seconds()).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease for %s: %w", instanceID, err)
+	}
+	return owner == nodeID, nil
+}
+
+// RenewInstanceLeases memperpanjang lease milik nodeID, returns instance yang berhasil diperpanjang.
+// Instance yang tidak ada di hasil berarti lease-nya sudah diambil replica lain.
+func RenewInstanceLeases(nodeID string, instanceIDs []string, ttl time.Duration) ([]string, error) {
+	if len(instanceIDs) == 0 {
+		return []string{}, nil
+	}
+
+	query := `
+		UPDATE instance_leases
+		SET expires_at = NOW() + make_interval(secs => $2)
+		WHERE node_id = $1 AND instance_id = ANY($3)
+		RETURNING instance_id
+	`
+	rows, err := database.AppDB.Query(query, nodeID, ttl.Seconds(), pq.Array(instanceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to renew leases: %w", err)
+	}
+	defer rows.Close()
+
+	renewed := make([]string, 0, len(instanceIDs))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan renewed lease: %w", err)
+		}
+		renewed = append(renewed, id)
+	}
+	return renewed, rows.Err()
+}
+
+// ReleaseInstanceLease melepas lease (hanya jika masih milik nodeID)
+func ReleaseInstanceLease(instanceID, nodeID string) error {
+	_, err := database.AppDB.Exec(
+		`DELETE FROM instance_leases WHERE instance_id = $1 AND node_id = $2`,
+		instanceID, nodeID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release lease for %s: %w", instanceID, err)
+	}
+	return nil
+}
+
+// GetInstanceLeaseOwner returns node pemilik lease yang masih berlaku, nil jika tidak ada
+func GetInstanceLeaseOwner(instanceID string) (*ClusterNode, error) {
+	query := `
+		SELECT n.node_id, n.advertise_url, n.started_at, n.heartbeat_at
+		FROM instance_leases l
+		JOIN cluster_nodes n ON n.node_id = l.node_id
+		WHERE l.instance_id = $1 AND l.expires_at > NOW()
+	`
+	var n ClusterNode
+	err := database.AppDB.QueryRow(query, instanceID).Scan(&n.NodeID, &n.AdvertiseURL, &n.StartedAt, &n.HeartbeatAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lease owner for %s: %w", instanceID, err)
+	}
+	return &n, nil
+}
+
+// GetAllInstanceLeases returns semua lease yang masih berlaku
+func GetAllInstanceLeases() ([]InstanceLease, error) {
+	rows, err := database.AppDB.Query(`
+		SELECT instance_id, node_id, acquired_at, expires_at
+		FROM instance_leases
+		WHERE expires_at > NOW()
+		ORDER BY node_id, instance_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query instance leases: %w", err)
+	}
+	defer rows.Close()
+
+	leases := []InstanceLease{}
+	for rows.Next() {
+		var l InstanceLease
+		if err := rows.Scan(&l.InstanceID, &l.NodeID, &l.AcquiredAt, &l.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan instance lease: %w", err)
+		}
+		leases = append(leases, l)
+	}
+	return leases, rows.Err()
+}
+
+// GetOrphanedInstances returns instance yang sudah pernah login (punya JID) tapi
+// tidak punya lease yang berlaku, mis. karena replica pemiliknya mati
+func GetOrphanedInstances() ([]OrphanedInstance, error) {
+	rows, err := database.AppDB.Query(`
+		SELECT i.instance_id, i.jid
+		FROM instances i
+		LEFT JOIN instance_leases l ON l.instance_id = i.instance_id AND l.expires_at > NOW()
+		WHERE i.jid IS NOT NULL AND i.jid <> '' AND i.status <> 'logged_out' AND l.instance_id IS NULL
+		ORDER BY i.instance_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orphaned instances: %w", err)
+	}
+	defer rows.Close()
+
+	list := []OrphanedInstance{}
+	for rows.Next() {
+		var o OrphanedInstance
+		if err := rows.Scan(&o.InstanceID, &o.JID); err != nil {
+			return nil, fmt.Errorf("failed to scan orphaned instance: %w", err)
+		}
+		list = append(list, o)
+	}
+	return list, rows.Err()
+}
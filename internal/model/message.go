@@ -0,0 +1,464 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gowa-yourself/database"
+
+	"github.com/lib/pq"
+)
+
+// Message represents a row in the messages table (persistent inbox)
+type Message struct {
+	ID                int64
+	InstanceID        string
+	MessageID         string
+	ChatJID           string
+	SenderJID         sql.NullString
+	SenderNumber      sql.NullString
+	PushName          sql.NullString
+	FromMe            bool
+	IsGroup           bool
+	MessageType       string
+	Body              sql.NullString
+	MediaMime         sql.NullString
+	MediaFileName     sql.NullString
+	MediaSize         sql.NullInt64
+	MediaSHA256       sql.NullString
+	MediaPath         sql.NullString
+	QuotedMessageID   sql.NullString
+	QuotedParticipant sql.NullString
+	Status            sql.NullString
+	StatusUpdatedAt   sql.NullTime
+	EditedAt          sql.NullTime
+	RevokedAt         sql.NullTime
+	MessageTimestamp  time.Time
+	CreatedAt         time.Time
+}
+
+// MessageResponse for JSON response
+type MessageResponse struct {
+	ID                int64      `json:"id"`
+	InstanceID        string     `json:"instanceId"`
+	MessageID         string     `json:"messageId"`
+	ChatJID           string     `json:"chatJid"`
+	SenderJID         string     `json:"senderJid,omitempty"`
+	SenderNumber      string     `json:"senderNumber,omitempty"`
+	PushName          string     `json:"pushName,omitempty"`
+	FromMe            bool       `json:"fromMe"`
+	IsGroup           bool       `json:"isGroup"`
+	MessageType       string     `json:"messageType"`
+	Body              string     `json:"body"`
+	MediaMime         string     `json:"mediaMime,omitempty"`
+	MediaFileName     string     `json:"mediaFileName,omitempty"`
+	MediaSize         int64      `json:"mediaSize,omitempty"`
+	MediaSHA256       string     `json:"mediaSha256,omitempty"`
+	MediaDownloaded   bool       `json:"mediaDownloaded,omitempty"`
+	QuotedMessageID   string     `json:"quotedMessageId,omitempty"`
+	QuotedParticipant string     `json:"quotedParticipant,omitempty"`
+	Status            string     `json:"status,omitempty"`
+	StatusUpdatedAt   *time.Time `json:"statusUpdatedAt,omitempty"`
+	EditedAt          *time.Time `json:"editedAt,omitempty"`
+	RevokedAt         *time.Time `json:"revokedAt,omitempty"`
+	Timestamp         time.Time  `json:"timestamp"`
+}
+
+// ChatSummary is one row of the chat list (latest message per chat)
+type ChatSummary struct {
+	ChatJID         string    `json:"chatJid"`
+	IsGroup         bool      `json:"isGroup"`
+	PushName        string    `json:"pushName,omitempty"`
+	LastMessageID   string    `json:"lastMessageId"`
+	LastMessageType string    `json:"lastMessageType"`
+	LastBody        string    `json:"lastBody"`
+	LastFromMe      bool      `json:"lastFromMe"`
+	LastTimestamp   time.Time `json:"lastTimestamp"`
+	MessageCount    int64     `json:"messageCount"`
+}
+
+// ToMessageResponse converts Message to MessageResponse
+func ToMessageResponse(m Message) MessageResponse {
+	resp := MessageResponse{
+		ID:                m.ID,
+		InstanceID:        m.InstanceID,
+		MessageID:         m.MessageID,
+		ChatJID:           m.ChatJID,
+		SenderJID:         m.SenderJID.String,
+		SenderNumber:      m.SenderNumber.String,
+		PushName:          m.PushName.String,
+		FromMe:            m.FromMe,
+		IsGroup:           m.IsGroup,
+		MessageType:       m.MessageType,
+		Body:              m.Body.String,
+		MediaMime:         m.MediaMime.String,
+		MediaFileName:     m.MediaFileName.String,
+		MediaSize:         m.MediaSize.Int64,
+		MediaSHA256:       m.MediaSHA256.String,
+		MediaDownloaded:   m.MediaPath.Valid,
+		QuotedMessageID:   m.QuotedMessageID.String,
+		QuotedParticipant: m.QuotedParticipant.String,
+		Status:            m.Status.String,
+		Timestamp:         m.MessageTimestamp,
+	}
+
+	if m.StatusUpdatedAt.Valid {
+		resp.StatusUpdatedAt = &m.StatusUpdatedAt.Time
+	}
+	if m.EditedAt.Valid {
+		resp.EditedAt = &m.EditedAt.Time
+	}
+	if m.RevokedAt.Valid {
+		resp.RevokedAt = &m.RevokedAt.Time
+	}
+
+	return resp
+}
+
+// Message delivery status lifecycle (hanya untuk pesan keluar / from_me)
+const (
+	MessageStatusSent      = "sent"
+	MessageStatusServerAck = "server_ack"
+	MessageStatusDelivered = "delivered"
+	MessageStatusRead      = "read"
+	MessageStatusPlayed    = "played"
+	MessageStatusFailed    = "failed"
+)
+
+// messageStatusRank urutan status, dipakai supaya status tidak pernah mundur
+// (misal receipt "delivered" yang datang terlambat setelah "read").
+// failed hanya bisa menimpa status sebelum delivered; delivered setelah failed
+// (pesan tetap sampai setelah retry) menimpa failed.
+var messageStatusRank = map[string]int{
+	MessageStatusSent:      1,
+	MessageStatusServerAck: 2,
+	MessageStatusFailed:    3,
+	MessageStatusDelivered: 4,
+	MessageStatusRead:      5,
+	MessageStatusPlayed:    6,
+}
+
+// statusRankSQL returns a CASE expression yang memetakan kolom status ke rank-nya
+func statusRankSQL(column string) string {
+	return "CASE " + column +
+		" WHEN 'sent' THEN 1 WHEN 'server_ack' THEN 2 WHEN 'failed' THEN 3 WHEN 'delivered' THEN 4" +
+		" WHEN 'read' THEN 5 WHEN 'played' THEN 6 ELSE 0 END"
+}
+
+// SaveMessage inserts a message, ignoring duplicates (same instance_id + message_id)
+func SaveMessage(m *Message) error {
+	query := `
+		INSERT INTO messages (
+			instance_id, message_id, chat_jid, sender_jid, sender_number, push_name,
+			from_me, is_group, message_type, body, media_mime, media_file_name, media_size, media_sha256,
+			quoted_message_id, quoted_participant, status, status_updated_at, message_timestamp
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		ON CONFLICT (instance_id, message_id) DO NOTHING
+	`
+
+	_, err := database.AppDB.Exec(query,
+		m.InstanceID,
+		m.MessageID,
+		m.ChatJID,
+		m.SenderJID,
+		m.SenderNumber,
+		m.PushName,
+		m.FromMe,
+		m.IsGroup,
+		m.MessageType,
+		m.Body,
+		m.MediaMime,
+		m.MediaFileName,
+		m.MediaSize,
+		m.MediaSHA256,
+		m.QuotedMessageID,
+		m.QuotedParticipant,
+		m.Status,
+		m.StatusUpdatedAt,
+		m.MessageTimestamp,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save message: %w", err)
+	}
+
+	return nil
+}
+
+// GetChatList returns the latest message of every chat for an instance, newest first.
+// The cursor is the (timestamp, chat_jid) of the last chat from the previous page: timestamp WhatsApp
+// hanya per detik, jadi chat dengan detik yang sama diurutkan lagi berdasarkan chat_jid.
+func GetChatList(instanceID string, before *time.Time, beforeJID string, limit int) ([]ChatSummary, error) {
+	query := `
+		SELECT chat_jid, is_group, push_name, message_id, message_type, body, from_me, message_timestamp, total
+		FROM (
+			SELECT DISTINCT ON (chat_jid)
+				chat_jid, is_group, push_name, message_id, message_type, body, from_me, message_timestamp,
+				COUNT(*) OVER (PARTITION BY chat_jid) AS total
+			FROM messages
+			WHERE instance_id = $1
+			ORDER BY chat_jid, message_timestamp DESC, id DESC
+		) latest
+		WHERE 1=1
+	`
+	args := []interface{}{instanceID}
+	argIndex := 2
+
+	if before != nil {
+		query += fmt.Sprintf(" AND (message_timestamp, chat_jid) < ($%d, $%d)", argIndex, argIndex+1)
+		args = append(args, *before, beforeJID)
+		argIndex += 2
+	}
+
+	query += fmt.Sprintf(" ORDER BY message_timestamp DESC, chat_jid DESC LIMIT $%d", argIndex)
+	args = append(args, limit)
+
+	rows, err := database.AppDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chat list: %w", err)
+	}
+	defer rows.Close()
+
+	var chats []ChatSummary
+	for rows.Next() {
+		var chat ChatSummary
+		var pushName, body sql.NullString
+		if err := rows.Scan(
+			&chat.ChatJID,
+			&chat.IsGroup,
+			&pushName,
+			&chat.LastMessageID,
+			&chat.LastMessageType,
+			&body,
+			&chat.LastFromMe,
+			&chat.LastTimestamp,
+			&chat.MessageCount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan chat: %w", err)
+		}
+		chat.PushName = pushName.String
+		chat.LastBody = body.String
+		chats = append(chats, chat)
+	}
+
+	return chats, rows.Err()
+}
+
+// GetChatMessages returns messages of one chat, newest first.
+// The cursor is the (timestamp, id) of the oldest message from the previous page.
+func GetChatMessages(instanceID, chatJID string, beforeTime *time.Time, beforeID int64, limit int) ([]Message, error) {
+	query := `
+		SELECT id, instance_id, message_id, chat_jid, sender_jid, sender_number, push_name,
+		       from_me, is_group, message_type, body, media_mime, media_file_name, media_size, media_sha256, media_path,
+		       quoted_message_id, quoted_participant, status, status_updated_at, edited_at, revoked_at, message_timestamp, created_at
+		FROM messages
+		WHERE instance_id = $1 AND chat_jid = $2
+	`
+	args := []interface{}{instanceID, chatJID}
+	argIndex := 3
+
+	if beforeTime != nil {
+		query += fmt.Sprintf(" AND (message_timestamp, id) < ($%d, $%d)", argIndex, argIndex+1)
+		args = append(args, *beforeTime, beforeID)
+		argIndex += 2
+	}
+
+	query += fmt.Sprintf(" ORDER BY message_timestamp DESC, id DESC LIMIT $%d", argIndex)
+	args = append(args, limit)
+
+	rows, err := database.AppDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
+// GetMessageByMessageID retrieves a single message by its WhatsApp message ID
+func GetMessageByMessageID(instanceID, messageID string) (*Message, error) {
+	query := `
+		SELECT id, instance_id, message_id, chat_jid, sender_jid, sender_number, push_name,
+		       from_me, is_group, message_type, body, media_mime, media_file_name, media_size, media_sha256, media_path,
+		       quoted_message_id, quoted_participant, status, status_updated_at, edited_at, revoked_at, message_timestamp, created_at
+		FROM messages
+		WHERE instance_id = $1 AND message_id = $2
+	`
+
+	rows, err := database.AppDB.Query(query, instanceID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message: %w", err)
+	}
+	defer rows.Close()
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return &messages[0], nil
+}
+
+// UpdateMessageMediaPath menyimpan lokasi media yang sudah di-download
+func UpdateMessageMediaPath(instanceID, messageID, path string) error {
+	query := `UPDATE messages SET media_path = $1 WHERE instance_id = $2 AND message_id = $3`
+	if _, err := database.AppDB.Exec(query, path, instanceID, messageID); err != nil {
+		return fmt.Errorf("failed to update message media path: %w", err)
+	}
+	return nil
+}
+
+// GetOutgoingMessages finds outgoing messages by WhatsApp message ID across all instances
+// (dipakai untuk bulk revoke dari outbox yang hanya menyimpan wa_message_id). Satu query per
+// 1000 ID; returns map message_id -> pesan terbaru, ID yang tidak ditemukan tidak ada di map.
+func GetOutgoingMessages(ctx context.Context, messageIDs []string) (map[string]*Message, error) {
+	query := `
+		SELECT DISTINCT ON (message_id)
+		       id, instance_id, message_id, chat_jid, sender_jid, sender_number, push_name,
+		       from_me, is_group, message_type, body, media_mime, media_file_name, media_size, media_sha256, media_path,
+		       quoted_message_id, quoted_participant, status, status_updated_at, edited_at, revoked_at, message_timestamp, created_at
+		FROM messages
+		WHERE message_id = ANY($1) AND from_me = true
+		ORDER BY message_id, id DESC
+	`
+
+	result := make(map[string]*Message, len(messageIDs))
+	for start := 0; start < len(messageIDs); start += 1000 {
+		end := start + 1000
+		if end > len(messageIDs) {
+			end = len(messageIDs)
+		}
+
+		rows, err := database.AppDB.QueryContext(ctx, query, pq.Array(messageIDs[start:end]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to query messages: %w", err)
+		}
+		messages, err := scanMessages(rows)
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range messages {
+			result[messages[i].MessageID] = &messages[i]
+		}
+	}
+
+	return result, nil
+}
+
+// MarkMessageEdited menyimpan teks baru dari pesan yang di-edit
+func MarkMessageEdited(instanceID, messageID, body string, at time.Time) error {
+	query := `UPDATE messages SET body = $1, edited_at = $2 WHERE instance_id = $3 AND message_id = $4`
+	if _, err := database.AppDB.Exec(query, body, at, instanceID, messageID); err != nil {
+		return fmt.Errorf("failed to mark message edited: %w", err)
+	}
+	return nil
+}
+
+// MarkMessageRevoked menandai pesan sudah dihapus untuk semua orang
+func MarkMessageRevoked(instanceID, messageID string, at time.Time) error {
+	query := `UPDATE messages SET revoked_at = $1 WHERE instance_id = $2 AND message_id = $3 AND revoked_at IS NULL`
+	if _, err := database.AppDB.Exec(query, at, instanceID, messageID); err != nil {
+		return fmt.Errorf("failed to mark message revoked: %w", err)
+	}
+	return nil
+}
+
+// scanMessages is a helper function to scan rows into Message slice
+func scanMessages(rows *sql.Rows) ([]Message, error) {
+	var messages []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(
+			&m.ID,
+			&m.InstanceID,
+			&m.MessageID,
+			&m.ChatJID,
+			&m.SenderJID,
+			&m.SenderNumber,
+			&m.PushName,
+			&m.FromMe,
+			&m.IsGroup,
+			&m.MessageType,
+			&m.Body,
+			&m.MediaMime,
+			&m.MediaFileName,
+			&m.MediaSize,
+			&m.MediaSHA256,
+			&m.MediaPath,
+			&m.QuotedMessageID,
+			&m.QuotedParticipant,
+			&m.Status,
+			&m.StatusUpdatedAt,
+			&m.EditedAt,
+			&m.RevokedAt,
+			&m.MessageTimestamp,
+			&m.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+
+	return messages, rows.Err()
+}
+
+// MessageStatusUpdate is a message whose status was advanced by UpdateMessageStatus
+type MessageStatusUpdate struct {
+	MessageID string
+	ChatJID   string
+}
+
+// UpdateMessageStatus advances the status of outgoing messages. Status hanya diubah jika
+// rank-nya lebih tinggi dari status sekarang. Returns the messages that were actually updated.
+// Untuk pesan grup status ini adalah status terjauh dari peserta mana pun (receipt pertama),
+// status per peserta ada di message_receipts.
+func UpdateMessageStatus(instanceID string, messageIDs []string, status string, at time.Time) ([]MessageStatusUpdate, error) {
+	rank, ok := messageStatusRank[status]
+	if !ok {
+		return nil, fmt.Errorf("unknown message status: %s", status)
+	}
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		UPDATE messages
+		SET status = $1, status_updated_at = $2
+		WHERE instance_id = $3 AND from_me = true AND ` + statusRankSQL("status") + ` < $4
+		  AND message_id IN (`
+	args := []interface{}{status, at, instanceID, rank}
+	argIndex := 5
+
+	for i, id := range messageIDs {
+		if i > 0 {
+			query += ", "
+		}
+		query += fmt.Sprintf("$%d", argIndex)
+		args = append(args, id)
+		argIndex++
+	}
+	query += ") RETURNING message_id, chat_jid"
+
+	rows, err := database.AppDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update message status: %w", err)
+	}
+	defer rows.Close()
+
+	var updated []MessageStatusUpdate
+	for rows.Next() {
+		var u MessageStatusUpdate
+		if err := rows.Scan(&u.MessageID, &u.ChatJID); err != nil {
+			return nil, fmt.Errorf("failed to scan updated message: %w", err)
+		}
+		updated = append(updated, u)
+	}
+
+	return updated, rows.Err()
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"gowa-yourself/database"
+)
+
+// MessageReceipt is the delivery status of an outgoing group message for one participant
+type MessageReceipt struct {
+	ParticipantJID string    `json:"participantJid"`
+	Status         string    `json:"status"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// UpsertGroupReceipts advances the status of a participant for outgoing group messages.
+// Hanya pesan grup dari kita (from_me) yang dicatat, dan status per peserta tidak pernah mundur.
+// Returns the messages whose receipt for this participant actually changed.
+func UpsertGroupReceipts(instanceID string, messageIDs []string, participantJID, status string, at time.Time) ([]MessageStatusUpdate, error) {
+	rank, ok := messageStatusRank[status]
+	if !ok {
+		return nil, fmt.Errorf("unknown message status: %s", status)
+	}
+	if len(messageIDs) == 0 || participantJID == "" {
+		return nil, nil
+	}
+
+	query := `
+		INSERT INTO message_receipts (instance_id, message_id, participant_jid, status, updated_at)
+		SELECT m.instance_id, m.message_id, $1, $2, $3
+		FROM messages m
+		WHERE m.instance_id = $4 AND m.from_me = true AND m.is_group = true
+		  AND m.message_id IN (`
+	args := []interface{}{participantJID, status, at, instanceID}
+	argIndex := 5
+
+	for i, id := range messageIDs {
+		if i > 0 {
+			query += ", "
+		}
+		query += fmt.Sprintf("$%d", argIndex)
+		args = append(args, id)
+		argIndex++
+	}
+	query += fmt.Sprintf(`)
+		ON CONFLICT (instance_id, message_id, participant_jid) DO UPDATE
+		SET status = EXCLUDED.status, updated_at = EXCLUDED.updated_at
+		WHERE %s < $%d
+		RETURNING message_id, (SELECT chat_jid FROM messages WHERE instance_id = $4 AND message_id = message_receipts.message_id)`,
+		statusRankSQL("message_receipts.status"), argIndex)
+	args = append(args, rank)
+
+	rows, err := database.AppDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert group receipts: %w", err)
+	}
+	defer rows.Close()
+
+	var updated []MessageStatusUpdate
+	for rows.Next() {
+		var u MessageStatusUpdate
+		if err := rows.Scan(&u.MessageID, &u.ChatJID); err != nil {
+			return nil, fmt.Errorf("failed to scan group receipt: %w", err)
+		}
+		updated = append(updated, u)
+	}
+
+	return updated, rows.Err()
+}
+
+// GetMessageReceipts returns the per-participant receipts of an outgoing group message
+func GetMessageReceipts(instanceID, messageID string) ([]MessageReceipt, error) {
+	rows, err := database.AppDB.Query(`
+		SELECT participant_jid, status, updated_at
+		FROM message_receipts
+		WHERE instance_id = $1 AND message_id = $2
+		ORDER BY updated_at
+	`, instanceID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message receipts: %w", err)
+	}
+	defer rows.Close()
+
+	receipts := []MessageReceipt{}
+	for rows.Next() {
+		var r MessageReceipt
+		if err := rows.Scan(&r.ParticipantJID, &r.Status, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan message receipt: %w", err)
+		}
+		receipts = append(receipts, r)
+	}
+
+	return receipts, rows.Err()
+}
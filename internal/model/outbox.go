@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"gowa-yourself/database"
+	"gowa-yourself/internal/phone"
 	"strconv"
 	"strings"
 	"time"
@@ -26,9 +27,13 @@ type Outbox struct {
 	File            sql.NullString `json:"file"`
 	ErrorCount      int            `json:"error_count"`
 	MsgError        sql.NullString `json:"msg_error"`
+	WaMessageID     sql.NullString `json:"wa_message_id"`
+	ReceiptStatus   sql.NullString `json:"receipt_status"`
 }
 
-// CreateOutboxBatch inserts multiple outbox records in a single transaction
+// CreateOutboxBatch inserts multiple outbox records in a single transaction.
+// Destination sudah dinormalkan ke E.164 oleh handler, jadi juga disimpan sebagai destination_normalized
+// (kunci dedup replace_pending di worker).
 func CreateOutboxBatch(ctx context.Context, records []Outbox) error {
 	if len(records) == 0 {
 		return nil
@@ -43,15 +48,15 @@ func CreateOutboxBatch(ctx context.Context, records []Outbox) error {
 	query := `
 		INSERT INTO outbox (
 			type, from_number, client_id, destination, messages, 
-			status, priority, application, sendingDateTime, table_id, file
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			status, priority, application, sendingDateTime, table_id, file, destination_normalized
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
 	if database.OutboxDriver == "mysql" {
 		query = `
 			INSERT INTO outbox (
 				type, from_number, client_id, destination, messages, 
-				status, priority, application, sendingDateTime, table_id, file
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				status, priority, application, sendingDateTime, table_id, file, destination_normalized
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`
 	}
 
@@ -75,6 +80,7 @@ func CreateOutboxBatch(ctx context.Context, records []Outbox) error {
 			r.SendingDateTime,
 			r.TableID,
 			r.File,
+			r.Destination,
 		)
 		if err != nil {
 			return err
@@ -84,14 +90,15 @@ func CreateOutboxBatch(ctx context.Context, records []Outbox) error {
 	return tx.Commit()
 }
 
-// CreateOutboxSingle inserts a single outbox record and returns the inserted ID
+// CreateOutboxSingle inserts a single outbox record and returns the inserted ID (destination_normalized
+// sama seperti CreateOutboxBatch)
 func CreateOutboxSingle(ctx context.Context, r *Outbox) error {
 	if database.OutboxDriver == "mysql" {
 		query := `
 			INSERT INTO outbox (
 				type, from_number, client_id, destination, messages, 
-				status, priority, application, sendingDateTime, table_id, file
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				status, priority, application, sendingDateTime, table_id, file, destination_normalized
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`
 		res, err := database.OutboxDB.ExecContext(
 			ctx,
@@ -107,6 +114,7 @@ func CreateOutboxSingle(ctx context.Context, r *Outbox) error {
 			r.SendingDateTime,
 			r.TableID,
 			r.File,
+			r.Destination,
 		)
 		if err != nil {
 			return err
@@ -123,8 +131,8 @@ func CreateOutboxSingle(ctx context.Context, r *Outbox) error {
 	query := `
 		INSERT INTO outbox (
 			type, from_number, client_id, destination, messages, 
-			status, priority, application, sendingDateTime, table_id, file
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			status, priority, application, sendingDateTime, table_id, file, destination_normalized
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id_outbox, insertDateTime
 	`
 
@@ -142,6 +150,7 @@ func CreateOutboxSingle(ctx context.Context, r *Outbox) error {
 		r.SendingDateTime,
 		r.TableID,
 		r.File,
+		r.Destination,
 	).Scan(&r.IDOutbox, &r.InsertDateTime)
 
 	return err
@@ -156,7 +165,8 @@ func GetOutboxQueue(ctx context.Context, status *int, application string, search
 
 	query = `
 		SELECT id_outbox, type, from_number, client_id, destination, messages,
-		       status, priority, application, sendingDateTime, insertDateTime, table_id, file, error_count, msg_error
+		       status, priority, application, sendingDateTime, insertDateTime, table_id, file, error_count, msg_error,
+		       wa_message_id, receipt_status
 		FROM outbox
 		WHERE 1=1
 	`
@@ -222,6 +232,8 @@ func GetOutboxQueue(ctx context.Context, status *int, application string, search
 			&r.File,
 			&r.ErrorCount,
 			&r.MsgError,
+			&r.WaMessageID,
+			&r.ReceiptStatus,
 		)
 		if err != nil {
 			return nil, err
@@ -283,14 +295,16 @@ func GetOutboxQueueCount(ctx context.Context, status *int, application string, s
 func GetOutboxByID(ctx context.Context, id int) (*Outbox, error) {
 	query := `
 		SELECT id_outbox, type, from_number, client_id, destination, messages,
-		       status, priority, application, sendingDateTime, insertDateTime, table_id, file, error_count, msg_error
+		       status, priority, application, sendingDateTime, insertDateTime, table_id, file, error_count, msg_error,
+		       wa_message_id, receipt_status
 		FROM outbox
 		WHERE id_outbox = $1
 	`
 	if database.OutboxDriver == "mysql" {
 		query = `
 			SELECT id_outbox, type, from_number, client_id, destination, messages,
-			       status, priority, application, sendingDateTime, insertDateTime, table_id, file, error_count, msg_error
+			       status, priority, application, sendingDateTime, insertDateTime, table_id, file, error_count, msg_error,
+			       wa_message_id, receipt_status
 			FROM outbox
 			WHERE id_outbox = ?
 		`
@@ -313,6 +327,8 @@ func GetOutboxByID(ctx context.Context, id int) (*Outbox, error) {
 		&r.File,
 		&r.ErrorCount,
 		&r.MsgError,
+		&r.WaMessageID,
+		&r.ReceiptStatus,
 	)
 
 	if err == sql.ErrNoRows {
@@ -370,8 +386,9 @@ func BulkUpdateOutboxStatus(ctx context.Context, toStatus int, ids []int) (int64
 	return res.RowsAffected()
 }
 
-// CancelPendingOutboxForApp cancels existing pending (status 0) outbox records for a given destination and application
-func CancelPendingOutboxForApp(ctx context.Context, destination string, application string) (int64, error) {
+// CancelPendingOutboxForApp cancels existing pending (status 0) outbox records for a given destination and application.
+// country adalah default country untuk mencocokkan format nomor lain (08xx / 628xx / +628xx), kosong = DEFAULT_PHONE_COUNTRY
+func CancelPendingOutboxForApp(ctx context.Context, destination string, application string, country string) (int64, error) {
 	if destination == "" {
 		return 0, nil
 	}
@@ -388,25 +405,18 @@ func CancelPendingOutboxForApp(ctx context.Context, destination string, applicat
 		return "$" + strconv.Itoa(idx)
 	}
 
-	// Alternate phone format support (08xx vs 628xx)
-	var altDestination string
-	if strings.HasPrefix(destination, "0") {
-		altDestination = "62" + destination[1:]
-	} else if strings.HasPrefix(destination, "62") {
-		altDestination = "0" + destination[2:]
-	}
+	// Semua format penulisan nomor yang setara (E.164, nasional, dengan '+')
+	variants := phone.Variants(destination, country)
 
 	query = "UPDATE outbox SET status = 2, msg_error = 'Superseded by new request (replace_pending)' WHERE status = 0"
 
-	if altDestination != "" {
-		query += " AND (destination = " + placeholder(argCount) + " OR destination = " + placeholder(argCount+1) + ")"
-		args = append(args, destination, altDestination)
-		argCount += 2
-	} else {
-		query += " AND destination = " + placeholder(argCount)
-		args = append(args, destination)
+	placeholders := make([]string, len(variants))
+	for i, v := range variants {
+		placeholders[i] = placeholder(argCount)
+		args = append(args, v)
 		argCount++
 	}
+	query += " AND destination IN (" + strings.Join(placeholders, ", ") + ")"
 
 	if application != "" {
 		query += " AND LOWER(application) = LOWER(" + placeholder(argCount) + ")"
@@ -421,3 +431,96 @@ func CancelPendingOutboxForApp(ctx context.Context, destination string, applicat
 
 	return res.RowsAffected()
 }
+
+// UpdateOutboxReceiptStatus updates receipt_status of outbox rows sent with the given WhatsApp message ID.
+// Status tidak akan mundur (misal "delivered" setelah "read").
+func UpdateOutboxReceiptStatus(ctx context.Context, waMessageID string, status string) (int64, error) {
+	rank, ok := messageStatusRank[status]
+	if waMessageID == "" || !ok {
+		return 0, nil
+	}
+
+	query := "UPDATE outbox SET receipt_status = $1 WHERE wa_message_id = $2 AND " + statusRankSQL("receipt_status") + " < $3"
+	if database.OutboxDriver == "mysql" {
+		query = "UPDATE outbox SET receipt_status = ? WHERE wa_message_id = ? AND " + statusRankSQL("receipt_status") + " < ?"
+	}
+
+	res, err := database.OutboxDB.ExecContext(ctx, query, status, waMessageID, rank)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// GetOutboxApplicationByWAMessageID returns application of the outbox row sent with the given WhatsApp message ID
+// (kosong jika pesan tidak berasal dari outbox).
+func GetOutboxApplicationByWAMessageID(ctx context.Context, waMessageID string) (string, error) {
+	query := "SELECT COALESCE(application, '') FROM outbox WHERE wa_message_id = $1 LIMIT 1"
+	if database.OutboxDriver == "mysql" {
+		query = "SELECT COALESCE(application, '') FROM outbox WHERE wa_message_id = ? LIMIT 1"
+	}
+
+	var application string
+	err := database.OutboxDB.QueryRowContext(ctx, query, waMessageID).Scan(&application)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return application, err
+}
+
+// GetSentOutboxMessageIDs returns wa_message_id of sent outbox rows filtered by application,
+// table_id and/or specific outbox IDs (filter yang kosong diabaikan, minimal satu harus diisi).
+func GetSentOutboxMessageIDs(ctx context.Context, application string, tableID string, ids []int) ([]string, error) {
+	isMySQL := database.OutboxDriver == "mysql"
+	var args []interface{}
+	argCount := 1
+
+	placeholder := func(idx int) string {
+		if isMySQL {
+			return "?"
+		}
+		return "$" + strconv.Itoa(idx)
+	}
+
+	query := "SELECT wa_message_id FROM outbox WHERE status = 1 AND wa_message_id IS NOT NULL AND wa_message_id <> ''"
+
+	if application != "" {
+		query += " AND LOWER(application) = LOWER(" + placeholder(argCount) + ")"
+		args = append(args, application)
+		argCount++
+	}
+
+	if tableID != "" {
+		query += " AND table_id = " + placeholder(argCount)
+		args = append(args, tableID)
+		argCount++
+	}
+
+	if len(ids) > 0 {
+		var idPlaceholders []string
+		for _, id := range ids {
+			idPlaceholders = append(idPlaceholders, placeholder(argCount))
+			args = append(args, id)
+			argCount++
+		}
+		query += " AND id_outbox IN (" + strings.Join(idPlaceholders, ", ") + ")"
+	}
+
+	rows, err := database.OutboxDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messageIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		messageIDs = append(messageIDs, id)
+	}
+
+	return messageIDs, rows.Err()
+}
@@ -0,0 +1,191 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gowa-yourself/database"
+)
+
+// Poll represents a row in the polls table
+type Poll struct {
+	ID              int64
+	InstanceID      string
+	MessageID       string
+	ChatJID         string
+	CreatorJID      sql.NullString
+	Question        string
+	Options         []string
+	SelectableCount int
+	CreatedAt       time.Time
+}
+
+// PollVote is the latest vote of one voter
+type PollVote struct {
+	VoterJID        string
+	SelectedOptions []string
+	VotedAt         time.Time
+}
+
+// PollOptionResult is the tally of one option
+type PollOptionResult struct {
+	Name   string   `json:"name"`
+	Votes  int      `json:"votes"`
+	Voters []string `json:"voters"`
+}
+
+// PollResultResponse for JSON response
+type PollResultResponse struct {
+	InstanceID      string             `json:"instanceId"`
+	MessageID       string             `json:"messageId"`
+	ChatJID         string             `json:"chatJid"`
+	Question        string             `json:"question"`
+	SelectableCount int                `json:"selectableCount"`
+	Options         []PollOptionResult `json:"options"`
+	TotalVoters     int                `json:"totalVoters"`
+	CreatedAt       time.Time          `json:"createdAt"`
+}
+
+// ToPollResultResponse menghitung tally per opsi dari vote terakhir tiap voter
+func ToPollResultResponse(p Poll, votes []PollVote) PollResultResponse {
+	resp := PollResultResponse{
+		InstanceID:      p.InstanceID,
+		MessageID:       p.MessageID,
+		ChatJID:         p.ChatJID,
+		Question:        p.Question,
+		SelectableCount: p.SelectableCount,
+		Options:         make([]PollOptionResult, len(p.Options)),
+		CreatedAt:       p.CreatedAt,
+	}
+
+	index := make(map[string]int, len(p.Options))
+	for i, name := range p.Options {
+		resp.Options[i] = PollOptionResult{Name: name, Voters: []string{}}
+		index[name] = i
+	}
+
+	for _, v := range votes {
+		if len(v.SelectedOptions) == 0 {
+			continue
+		}
+		resp.TotalVoters++
+		for _, name := range v.SelectedOptions {
+			if i, ok := index[name]; ok {
+				resp.Options[i].Votes++
+				resp.Options[i].Voters = append(resp.Options[i].Voters, v.VoterJID)
+			}
+		}
+	}
+
+	return resp
+}
+
+// SavePoll inserts a poll, ignoring duplicates (same instance_id + message_id)
+func SavePoll(p *Poll) error {
+	options, err := json.Marshal(p.Options)
+	if err != nil {
+		return fmt.Errorf("failed to marshal poll options: %w", err)
+	}
+
+	query := `
+		INSERT INTO polls (instance_id, message_id, chat_jid, creator_jid, question, options, selectable_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (instance_id, message_id) DO NOTHING
+	`
+	_, err = database.AppDB.Exec(query,
+		p.InstanceID, p.MessageID, p.ChatJID, p.CreatorJID, p.Question, string(options), p.SelectableCount)
+	if err != nil {
+		return fmt.Errorf("failed to save poll: %w", err)
+	}
+
+	return nil
+}
+
+// GetPoll retrieves a poll by its WhatsApp message ID
+func GetPoll(instanceID, messageID string) (*Poll, error) {
+	query := `
+		SELECT id, instance_id, message_id, chat_jid, creator_jid, question, options, selectable_count, created_at
+		FROM polls
+		WHERE instance_id = $1 AND message_id = $2
+	`
+
+	var p Poll
+	var options string
+	err := database.AppDB.QueryRow(query, instanceID, messageID).Scan(
+		&p.ID,
+		&p.InstanceID,
+		&p.MessageID,
+		&p.ChatJID,
+		&p.CreatorJID,
+		&p.Question,
+		&options,
+		&p.SelectableCount,
+		&p.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(options), &p.Options); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal poll options: %w", err)
+	}
+
+	return &p, nil
+}
+
+// UpsertPollVote menyimpan vote terakhir dari voter (vote baru menggantikan vote lama)
+func UpsertPollVote(instanceID, pollMessageID, voterJID string, selected []string, votedAt time.Time) error {
+	if selected == nil {
+		selected = []string{}
+	}
+	options, err := json.Marshal(selected)
+	if err != nil {
+		return fmt.Errorf("failed to marshal poll vote: %w", err)
+	}
+
+	query := `
+		INSERT INTO poll_votes (instance_id, poll_message_id, voter_jid, selected_options, voted_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (instance_id, poll_message_id, voter_jid)
+		DO UPDATE SET selected_options = EXCLUDED.selected_options, voted_at = EXCLUDED.voted_at
+		WHERE poll_votes.voted_at <= EXCLUDED.voted_at
+	`
+	if _, err := database.AppDB.Exec(query, instanceID, pollMessageID, voterJID, string(options), votedAt); err != nil {
+		return fmt.Errorf("failed to save poll vote: %w", err)
+	}
+
+	return nil
+}
+
+// GetPollVotes returns the latest vote of every voter of a poll
+func GetPollVotes(instanceID, pollMessageID string) ([]PollVote, error) {
+	query := `
+		SELECT voter_jid, selected_options, voted_at
+		FROM poll_votes
+		WHERE instance_id = $1 AND poll_message_id = $2
+		ORDER BY voted_at
+	`
+
+	rows, err := database.AppDB.Query(query, instanceID, pollMessageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query poll votes: %w", err)
+	}
+	defer rows.Close()
+
+	var votes []PollVote
+	for rows.Next() {
+		var v PollVote
+		var selected string
+		if err := rows.Scan(&v.VoterJID, &selected, &v.VotedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan poll vote: %w", err)
+		}
+		if err := json.Unmarshal([]byte(selected), &v.SelectedOptions); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal poll vote: %w", err)
+		}
+		votes = append(votes, v)
+	}
+
+	return votes, rows.Err()
+}
@@ -200,3 +200,55 @@ func DeleteOldestUserToken(userID int64) error {
 	_, err := db.Exec(query, userID)
 	return err
 }
+
+// GetUserActiveTokens lists the active (non-revoked, non-expired) refresh tokens of a user
+func GetUserActiveTokens(userID int64) ([]RefreshToken, error) {
+	db := database.AppDB
+
+	query := `
+		SELECT id, user_id, token, expires_at, created_at, revoked, ip_address, user_agent
+		FROM refresh_tokens
+		WHERE user_id = $1 AND revoked = false AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`
+
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []RefreshToken{}
+	for rows.Next() {
+		var rt RefreshToken
+		if err := rows.Scan(&rt.ID, &rt.UserID, &rt.Token, &rt.ExpiresAt, &rt.CreatedAt, &rt.Revoked, &rt.IPAddress, &rt.UserAgent); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, rt)
+	}
+
+	return tokens, rows.Err()
+}
+
+// RevokeUserTokenByID revokes a single refresh token of a user by its ID
+func RevokeUserTokenByID(userID, tokenID int64) error {
+	db := database.AppDB
+
+	query := `UPDATE refresh_tokens SET revoked = true WHERE id = $1 AND user_id = $2 AND revoked = false`
+
+	result, err := db.Exec(query, tokenID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTokenNotFound
+	}
+
+	return nil
+}
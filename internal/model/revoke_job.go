@@ -0,0 +1,241 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gowa-yourself/database"
+
+	"github.com/lib/pq"
+)
+
+// Revoke job item status
+const (
+	RevokeItemPending = "pending"
+	RevokeItemRevoked = "revoked"
+	RevokeItemFailed  = "failed"
+)
+
+// RevokeJob represents a row in revoke_jobs
+type RevokeJob struct {
+	ID          int64
+	UserID      sql.NullInt64
+	Application sql.NullString
+	TableID     sql.NullString
+	Total       int
+	Skipped     int
+	CreatedAt   time.Time
+}
+
+// RevokeJobItem represents a row in revoke_job_items
+type RevokeJobItem struct {
+	ID          int64
+	JobID       int64
+	InstanceID  string
+	WAMessageID string
+	Status      string
+	Error       sql.NullString
+	ProcessedAt sql.NullTime
+}
+
+// RevokeJobFailure for JSON response
+type RevokeJobFailure struct {
+	InstanceID  string `json:"instance_id"`
+	WAMessageID string `json:"wa_message_id"`
+	Error       string `json:"error"`
+}
+
+// RevokeJobResponse for JSON response
+type RevokeJobResponse struct {
+	ID           int64              `json:"id"`
+	Status       string             `json:"status"` // running | completed
+	Application  string             `json:"application,omitempty"`
+	TableID      string             `json:"table_id,omitempty"`
+	Total        int                `json:"total"`
+	Skipped      int                `json:"skipped"`
+	PendingCount int                `json:"pending_count"`
+	RevokedCount int                `json:"revoked_count"`
+	FailedCount  int                `json:"failed_count"`
+	Failed       []RevokeJobFailure `json:"failed"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
+// CreateRevokeJob menyimpan job beserta item-nya dalam satu transaksi
+func CreateRevokeJob(job *RevokeJob, items []RevokeJobItem) error {
+	tx, err := database.AppDB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	job.Total = len(items)
+	err = tx.QueryRow(`
+		INSERT INTO revoke_jobs (user_id, application, table_id, total, skipped)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, job.UserID, job.Application, job.TableID, job.Total, job.Skipped).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create revoke job: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO revoke_job_items (job_id, instance_id, wa_message_id) VALUES ($1, $2, $3)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare revoke job items: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, item := range items {
+		if _, err := stmt.Exec(job.ID, item.InstanceID, item.WAMessageID); err != nil {
+			return fmt.Errorf("failed to create revoke job item: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit revoke job: %w", err)
+	}
+	return nil
+}
+
+// ClaimRevokeJobItems mengambil item pending milik instance yang ada di node ini dan menggeser
+// next_attempt_at sejauh lease, supaya tidak diambil dua kali (aman untuk multi node).
+func ClaimRevokeJobItems(instanceIDs []string, limit int, lease time.Duration) ([]RevokeJobItem, error) {
+	if len(instanceIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		UPDATE revoke_job_items
+		SET next_attempt_at = NOW() + ($1 * INTERVAL '1 second')
+		WHERE id IN (
+			SELECT id FROM revoke_job_items
+			WHERE status = 'pending' AND next_attempt_at <= NOW() AND instance_id = ANY($2)
+			ORDER BY id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, job_id, instance_id, wa_message_id, status, error, processed_at
+	`
+
+	rows, err := database.AppDB.Query(query, int(lease.Seconds()), pq.Array(instanceIDs), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim revoke job items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []RevokeJobItem
+	for rows.Next() {
+		var it RevokeJobItem
+		if err := rows.Scan(&it.ID, &it.JobID, &it.InstanceID, &it.WAMessageID, &it.Status, &it.Error, &it.ProcessedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan revoke job item: %w", err)
+		}
+		items = append(items, it)
+	}
+
+	return items, rows.Err()
+}
+
+// FinishRevokeJobItem mencatat hasil revoke satu item (errMsg kosong = berhasil)
+func FinishRevokeJobItem(id int64, errMsg string) error {
+	status := RevokeItemRevoked
+	if errMsg != "" {
+		status = RevokeItemFailed
+	}
+
+	query := `
+		UPDATE revoke_job_items
+		SET status = $1, error = $2, processed_at = NOW()
+		WHERE id = $3
+	`
+	if _, err := database.AppDB.Exec(query, status, sql.NullString{String: errMsg, Valid: errMsg != ""}, id); err != nil {
+		return fmt.Errorf("failed to update revoke job item: %w", err)
+	}
+	return nil
+}
+
+// ExpireRevokeJobItems menandai gagal item yang masih pending setelah maxAge
+// (instance tidak pernah online di node mana pun)
+func ExpireRevokeJobItems(maxAge time.Duration) error {
+	query := `
+		UPDATE revoke_job_items
+		SET status = 'failed', error = 'instance was not connected before the revoke window expired', processed_at = NOW()
+		WHERE status = 'pending' AND job_id IN (
+			SELECT id FROM revoke_jobs WHERE created_at < NOW() - ($1 * INTERVAL '1 second')
+		)
+	`
+	if _, err := database.AppDB.Exec(query, int(maxAge.Seconds())); err != nil {
+		return fmt.Errorf("failed to expire revoke job items: %w", err)
+	}
+	return nil
+}
+
+// GetRevokeJob retrieves a job with its progress
+func GetRevokeJob(id int64) (*RevokeJob, *RevokeJobResponse, error) {
+	var job RevokeJob
+	err := database.AppDB.QueryRow(`
+		SELECT id, user_id, application, table_id, total, skipped, created_at
+		FROM revoke_jobs WHERE id = $1
+	`, id).Scan(&job.ID, &job.UserID, &job.Application, &job.TableID, &job.Total, &job.Skipped, &job.CreatedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp := &RevokeJobResponse{
+		ID:          job.ID,
+		Application: job.Application.String,
+		TableID:     job.TableID.String,
+		Total:       job.Total,
+		Skipped:     job.Skipped,
+		Failed:      []RevokeJobFailure{},
+		CreatedAt:   job.CreatedAt,
+	}
+
+	rows, err := database.AppDB.Query(`SELECT status, COUNT(*) FROM revoke_job_items WHERE job_id = $1 GROUP BY status`, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to count revoke job items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan revoke job count: %w", err)
+		}
+		switch status {
+		case RevokeItemPending:
+			resp.PendingCount = count
+		case RevokeItemRevoked:
+			resp.RevokedCount = count
+		case RevokeItemFailed:
+			resp.FailedCount = count
+		}
+	}
+
+	failedRows, err := database.AppDB.Query(`
+		SELECT instance_id, wa_message_id, COALESCE(error, '')
+		FROM revoke_job_items
+		WHERE job_id = $1 AND status = 'failed'
+		ORDER BY id
+		LIMIT 100
+	`, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query failed revoke job items: %w", err)
+	}
+	defer failedRows.Close()
+
+	for failedRows.Next() {
+		var f RevokeJobFailure
+		if err := failedRows.Scan(&f.InstanceID, &f.WAMessageID, &f.Error); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan failed revoke job item: %w", err)
+		}
+		resp.Failed = append(resp.Failed, f)
+	}
+
+	resp.Status = "completed"
+	if resp.PendingCount > 0 {
+		resp.Status = "running"
+	}
+
+	return &job, resp, nil
+}
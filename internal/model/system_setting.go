@@ -30,8 +30,14 @@ type SystemSetting struct {
 	UpdatedAt time.Time       `json:"updated_at"`
 }
 
+// SecurityPolicy adalah kebijakan keamanan global yang diatur admin
+type SecurityPolicy struct {
+	RequireTwoFactorForAdmins bool `json:"require_2fa_for_admins"`
+}
+
 const (
 	KeySystemIdentity = "system_identity"
+	KeySecurityPolicy = "security_policy"
 )
 
 // GetSystemIdentity retrieves the global identity images
@@ -80,3 +86,46 @@ func UpdateSystemIdentitySettings(identity *SystemIdentity) error {
 
 	return nil
 }
+
+// GetSecurityPolicy retrieves the security policy (default: semua nonaktif)
+func GetSecurityPolicy() (*SecurityPolicy, error) {
+	db := database.AppDB
+	var value json.RawMessage
+
+	err := db.QueryRow("SELECT value FROM system_settings WHERE key = $1", KeySecurityPolicy).Scan(&value)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &SecurityPolicy{}, nil
+		}
+		return nil, fmt.Errorf("failed to get security policy: %w", err)
+	}
+
+	var policy SecurityPolicy
+	if err := json.Unmarshal(value, &policy); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal security policy: %w", err)
+	}
+
+	return &policy, nil
+}
+
+// UpdateSecurityPolicy saves the security policy
+func UpdateSecurityPolicy(policy *SecurityPolicy) error {
+	db := database.AppDB
+
+	newValue, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal security policy: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO system_settings (key, value, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO UPDATE
+		SET value = EXCLUDED.value, updated_at = NOW()
+	`, KeySecurityPolicy, newValue)
+	if err != nil {
+		return fmt.Errorf("failed to save security policy: %w", err)
+	}
+
+	return nil
+}
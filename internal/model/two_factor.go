@@ -0,0 +1,183 @@
+// internal/model/two_factor.go
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"gowa-yourself/database"
+)
+
+// UserTOTP is the TOTP state of a user
+type UserTOTP struct {
+	Secret    sql.NullString // terisi sejak setup, aktif setelah Enabled = true
+	Enabled   bool
+	EnabledAt sql.NullTime
+}
+
+// GetUserTOTP retrieves the TOTP state of a user
+func GetUserTOTP(userID int64) (*UserTOTP, error) {
+	db := database.AppDB
+
+	t := &UserTOTP{}
+	err := db.QueryRow(`SELECT totp_secret, totp_enabled, totp_enabled_at FROM users WHERE id = $1`, userID).
+		Scan(&t.Secret, &t.Enabled, &t.EnabledAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// SetPendingTOTPSecret menyimpan secret baru yang belum aktif (setup ulang menimpa secret pending lama).
+// Returns false jika 2FA sudah aktif.
+func SetPendingTOTPSecret(userID int64, secret string) (bool, error) {
+	db := database.AppDB
+
+	result, err := db.Exec(`
+		UPDATE users SET totp_secret = $2, totp_last_step = NULL, updated_at = NOW()
+		WHERE id = $1 AND totp_enabled = false
+	`, userID, secret)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// EnableTOTP activates the pending secret
+func EnableTOTP(userID int64) error {
+	db := database.AppDB
+
+	_, err := db.Exec(`
+		UPDATE users SET totp_enabled = true, totp_enabled_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND totp_secret IS NOT NULL
+	`, userID)
+	return err
+}
+
+// DisableTOTP removes the secret and all recovery codes of a user
+func DisableTOTP(userID int64) error {
+	tx, err := database.AppDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE users SET totp_secret = NULL, totp_enabled = false, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ConsumeTOTPStep mencatat time step kode yang diterima secara atomik.
+// Returns false jika step tsb (atau yang lebih baru) sudah pernah dipakai (replay).
+func ConsumeTOTPStep(userID, step int64) (bool, error) {
+	db := database.AppDB
+
+	result, err := db.Exec(`
+		UPDATE users SET totp_last_step = $2
+		WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// ConsumeTwoFactorChallenge menandai challenge token (jti) sudah dipakai.
+// Returns false jika jti tersebut sudah pernah dipakai.
+func ConsumeTwoFactorChallenge(jti string, userID int64, expiresAt time.Time) (bool, error) {
+	db := database.AppDB
+
+	// Challenge yang sudah kedaluwarsa ditolak oleh validasi token, barisnya tidak perlu disimpan
+	if _, err := db.Exec(`DELETE FROM used_two_factor_challenges WHERE expires_at < NOW()`); err != nil {
+		return false, err
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO used_two_factor_challenges (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING
+	`, jti, userID, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// ReplaceRecoveryCodes menghapus recovery code lama dan menyimpan hash yang baru
+func ReplaceRecoveryCodes(userID int64, codeHashes []string) error {
+	tx, err := database.AppDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.Exec(`INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, h); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseRecoveryCode menandai recovery code terpakai; returns false jika tidak ada / sudah dipakai
+func UseRecoveryCode(userID int64, codeHash string) (bool, error) {
+	db := database.AppDB
+
+	result, err := db.Exec(`
+		UPDATE user_recovery_codes SET used_at = NOW()
+		WHERE id = (
+			SELECT id FROM user_recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+		) AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// CountUnusedRecoveryCodes returns the number of recovery codes that can still be used
+func CountUnusedRecoveryCodes(userID int64) (int, error) {
+	db := database.AppDB
+
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
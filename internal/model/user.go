@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"gowa-yourself/database"
+	"gowa-yourself/internal/helper"
 )
 
 // User represents a user account in the system
@@ -25,6 +26,9 @@ type User struct {
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 	LastLoginAt     sql.NullTime
+	// Diset admin lewat reset password: user wajib ganti password sebelum memakai API lain
+	MustChangePassword bool
+	TwoFactorEnabled   bool // TOTP aktif (lihat model/two_factor.go)
 }
 
 // UserResponse is the JSON response format for user data (without sensitive fields)
@@ -40,6 +44,9 @@ type UserResponse struct {
 	EmailVerified bool      `json:"email_verified"`
 	CreatedAt     time.Time `json:"created_at"`
 	LastLoginAt   time.Time `json:"last_login_at,omitempty"`
+	// true setelah admin reset password, sampai user mengganti password-nya
+	MustChangePassword bool `json:"must_change_password"`
+	TwoFactorEnabled   bool `json:"two_factor_enabled"`
 }
 
 // CreateUserRequest is the request payload for creating a new user
@@ -115,7 +122,7 @@ func GetUserByUsername(username string) (*User, error) {
 	query := `
 		SELECT id, username, email, password_hash, full_name, avatar_url,
 			auth_provider, oauth_provider_id, role, is_active, email_verified,
-			created_at, updated_at, last_login_at
+			created_at, updated_at, last_login_at, must_change_password, totp_enabled
 		FROM users
 		WHERE username = $1
 	`
@@ -136,6 +143,8 @@ func GetUserByUsername(username string) (*User, error) {
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastLoginAt,
+		&user.MustChangePassword,
+		&user.TwoFactorEnabled,
 	)
 
 	if err == sql.ErrNoRows {
@@ -155,7 +164,7 @@ func GetUserByEmail(email string) (*User, error) {
 	query := `
 		SELECT id, username, email, password_hash, full_name, avatar_url,
 			auth_provider, oauth_provider_id, role, is_active, email_verified,
-			created_at, updated_at, last_login_at
+			created_at, updated_at, last_login_at, must_change_password, totp_enabled
 		FROM users
 		WHERE email = $1
 	`
@@ -176,6 +185,8 @@ func GetUserByEmail(email string) (*User, error) {
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastLoginAt,
+		&user.MustChangePassword,
+		&user.TwoFactorEnabled,
 	)
 
 	if err == sql.ErrNoRows {
@@ -195,7 +206,7 @@ func GetUserByID(id int64) (*User, error) {
 	query := `
 		SELECT id, username, email, password_hash, full_name, avatar_url,
 			auth_provider, oauth_provider_id, role, is_active, email_verified,
-			created_at, updated_at, last_login_at
+			created_at, updated_at, last_login_at, must_change_password, totp_enabled
 		FROM users
 		WHERE id = $1
 	`
@@ -216,6 +227,8 @@ func GetUserByID(id int64) (*User, error) {
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastLoginAt,
+		&user.MustChangePassword,
+		&user.TwoFactorEnabled,
 	)
 
 	if err == sql.ErrNoRows {
@@ -261,7 +274,7 @@ func UpdateUserPassword(userID int64, newPasswordHash string) error {
 
 	query := `
 		UPDATE users
-		SET password_hash = $1, updated_at = NOW()
+		SET password_hash = $1, must_change_password = false, updated_at = NOW()
 		WHERE id = $2 AND auth_provider = 'local'
 	`
 
@@ -322,7 +335,7 @@ func ListUsers() ([]User, error) {
 	query := `
 		SELECT id, username, email, password_hash, full_name, avatar_url,
 			auth_provider, oauth_provider_id, role, is_active, email_verified,
-			created_at, updated_at, last_login_at
+			created_at, updated_at, last_login_at, must_change_password, totp_enabled
 		FROM users
 		ORDER BY created_at DESC
 	`
@@ -351,6 +364,8 @@ func ListUsers() ([]User, error) {
 			&user.CreatedAt,
 			&user.UpdatedAt,
 			&user.LastLoginAt,
+			&user.MustChangePassword,
+			&user.TwoFactorEnabled,
 		)
 		if err != nil {
 			return nil, err
@@ -364,21 +379,24 @@ func ListUsers() ([]User, error) {
 // ToResponse converts User to UserResponse (removes sensitive data)
 func (u *User) ToResponse() UserResponse {
 	resp := UserResponse{
-		ID:            u.ID,
-		Username:      u.Username,
-		Email:         u.Email,
-		AuthProvider:  u.AuthProvider,
-		Role:          u.Role,
-		IsActive:      u.IsActive,
-		EmailVerified: u.EmailVerified,
-		CreatedAt:     u.CreatedAt,
+		ID:                 u.ID,
+		Username:           u.Username,
+		Email:              u.Email,
+		AuthProvider:       u.AuthProvider,
+		Role:               u.Role,
+		IsActive:           u.IsActive,
+		EmailVerified:      u.EmailVerified,
+		CreatedAt:          u.CreatedAt,
+		MustChangePassword: u.MustChangePassword,
+		TwoFactorEnabled:   u.TwoFactorEnabled,
 	}
 
 	if u.FullName.Valid {
 		resp.FullName = u.FullName.String
 	}
 	if u.AvatarURL.Valid {
-		resp.AvatarURL = u.AvatarURL.String
+		// Avatar disimpan sebagai key storage, dikirim ke client sebagai signed URL
+		resp.AvatarURL = helper.StorageURL(u.AvatarURL.String)
 	}
 	if u.LastLoginAt.Valid {
 		resp.LastLoginAt = u.LastLoginAt.Time
@@ -0,0 +1,213 @@
+// internal/model/user_admin.go
+package model
+
+import (
+	"strconv"
+	"time"
+
+	"gowa-yourself/database"
+)
+
+// UserFilter is the filter for SearchUsers (admin user list)
+type UserFilter struct {
+	Search string // username, email atau full name (ILIKE)
+	Role   string
+	Active *bool
+	Limit  int
+	Offset int
+}
+
+// userFilterWhere menyusun klausa WHERE untuk UserFilter
+func userFilterWhere(f UserFilter) (string, []interface{}) {
+	where := " WHERE 1=1"
+	var args []interface{}
+
+	if f.Search != "" {
+		args = append(args, "%"+f.Search+"%")
+		p := "$" + strconv.Itoa(len(args))
+		where += " AND (username ILIKE " + p + " OR email ILIKE " + p + " OR full_name ILIKE " + p + ")"
+	}
+	if f.Role != "" {
+		args = append(args, f.Role)
+		where += " AND role = $" + strconv.Itoa(len(args))
+	}
+	if f.Active != nil {
+		args = append(args, *f.Active)
+		where += " AND is_active = $" + strconv.Itoa(len(args))
+	}
+
+	return where, args
+}
+
+// SearchUsers retrieves users matching the filter, with the total count for pagination
+func SearchUsers(f UserFilter) ([]User, int, error) {
+	db := database.AppDB
+
+	where, args := userFilterWhere(f)
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, f.Limit, f.Offset)
+	query := `
+		SELECT id, username, email, password_hash, full_name, avatar_url,
+			auth_provider, oauth_provider_id, role, is_active, email_verified,
+			created_at, updated_at, last_login_at, must_change_password, totp_enabled
+		FROM users` + where + `
+		ORDER BY created_at DESC
+		LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var user User
+		err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.PasswordHash,
+			&user.FullName,
+			&user.AvatarURL,
+			&user.AuthProvider,
+			&user.OAuthProviderID,
+			&user.Role,
+			&user.IsActive,
+			&user.EmailVerified,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.LastLoginAt,
+			&user.MustChangePassword,
+			&user.TwoFactorEnabled,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+
+	return users, total, rows.Err()
+}
+
+// UpdateUserRole changes the role of a user
+func UpdateUserRole(userID int64, role string) error {
+	db := database.AppDB
+
+	result, err := db.Exec(`UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2`, role, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetUserActive activates or deactivates a user account
+func SetUserActive(userID int64, active bool) error {
+	db := database.AppDB
+
+	result, err := db.Exec(`UPDATE users SET is_active = $1, updated_at = NOW() WHERE id = $2`, active, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetTemporaryPassword sets a password chosen by an admin and forces the user to change it
+func SetTemporaryPassword(userID int64, passwordHash string) error {
+	db := database.AppDB
+
+	query := `
+		UPDATE users
+		SET password_hash = $1, must_change_password = true, updated_at = NOW()
+		WHERE id = $2 AND auth_provider = 'local'
+	`
+
+	result, err := db.Exec(query, passwordHash, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// CountActiveAdmins returns the number of active admin accounts
+func CountActiveAdmins() (int, error) {
+	db := database.AppDB
+
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM users WHERE role = 'admin' AND is_active = true`).Scan(&count)
+	return count, err
+}
+
+// RevokeUserAccessTokens invalidates every access token issued to the user until now.
+// Access token adalah JWT stateless, jadi yang disimpan hanya batas waktu iat (sessions_revoked_at).
+func RevokeUserAccessTokens(userID int64) error {
+	db := database.AppDB
+
+	_, err := db.Exec(`UPDATE users SET sessions_revoked_at = NOW() WHERE id = $1`, userID)
+	return err
+}
+
+// IsAccessTokenRevoked checks the token blacklist and the user state in one query:
+// token di-blacklist, user nonaktif / terhapus, atau token diterbitkan sebelum sessions_revoked_at.
+// Untuk token impersonation (impersonatorID != 0) admin yang melakukan impersonation juga harus
+// masih aktif, masih admin, dan sesinya tidak di-revoke setelah token diterbitkan.
+func IsAccessTokenRevoked(token string, userID int64, issuedAt time.Time, impersonatorID int64) (bool, error) {
+	db := database.AppDB
+
+	// iat JWT presisi detik, jadi sessions_revoked_at dibulatkan ke bawah supaya token yang
+	// diterbitkan tepat setelah revoke (login ulang) tetap valid
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM token_blacklist WHERE token = $1 AND expires_at > NOW())
+			OR NOT EXISTS (
+				SELECT 1 FROM users
+				WHERE id = $2 AND is_active = true
+				  AND (sessions_revoked_at IS NULL OR date_trunc('second', sessions_revoked_at) <= $3)
+			)
+			OR ($4::BIGINT <> 0 AND NOT EXISTS (
+				SELECT 1 FROM users
+				WHERE id = $4 AND is_active = true AND role = 'admin'
+				  AND (sessions_revoked_at IS NULL OR date_trunc('second', sessions_revoked_at) <= $3)
+			))
+	`
+
+	var revoked bool
+	err := db.QueryRow(query, token, userID, issuedAt, impersonatorID).Scan(&revoked)
+	if err != nil {
+		return false, err
+	}
+
+	return revoked, nil
+}
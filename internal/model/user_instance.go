@@ -13,8 +13,9 @@ import (
 type UserInstance struct {
 	ID              int64
 	UserID          int64
+	Username        string // diisi GetInstanceUsers
 	InstanceID      string
-	PermissionLevel string // Legacy field (not used for authorization)
+	PermissionLevel string // read, send, manage atau owner (lihat Permission*)
 	CreatedAt       time.Time
 }
 
@@ -23,7 +24,66 @@ var (
 	ErrInsufficientPermission = errors.New("insufficient permission level")
 )
 
-// AssignInstanceToUser creates a user-instance relationship
+// Permission level per instance, berurutan: level yang lebih tinggi mencakup level di bawahnya.
+const (
+	PermissionRead   = "read"   // lihat status, chat, kontak, grup, event
+	PermissionSend   = "send"   // kirim / edit / revoke pesan
+	PermissionManage = "manage" // login / logout, setting instance, webhook, admin grup
+	PermissionOwner  = "owner"  // hapus instance
+)
+
+// PermissionLevels adalah semua level yang valid, dari yang terendah
+var PermissionLevels = []string{PermissionRead, PermissionSend, PermissionManage, PermissionOwner}
+
+var permissionRank = map[string]int{
+	PermissionRead:   1,
+	PermissionSend:   2,
+	PermissionManage: 3,
+	PermissionOwner:  4,
+}
+
+// IsValidPermissionLevel mengecek apakah level ada di PermissionLevels
+func IsValidPermissionLevel(level string) bool {
+	_, ok := permissionRank[level]
+	return ok
+}
+
+// PermissionAtLeast true jika level >= required
+func PermissionAtLeast(level, required string) bool {
+	return permissionRank[level] >= permissionRank[required]
+}
+
+// RoleDefaultPermission adalah level default saat instance di-assign tanpa level eksplisit
+func RoleDefaultPermission(role string) string {
+	switch role {
+	case "admin":
+		return PermissionOwner
+	case "viewer":
+		return PermissionRead
+	default:
+		return PermissionSend
+	}
+}
+
+// EffectivePermission menggabungkan level assignment dengan batas role:
+// admin selalu owner, viewer paling tinggi read.
+func EffectivePermission(role, assigned string) string {
+	switch role {
+	case "admin":
+		return PermissionOwner
+	case "viewer":
+		if IsValidPermissionLevel(assigned) {
+			return PermissionRead
+		}
+		return ""
+	}
+	if !IsValidPermissionLevel(assigned) {
+		return ""
+	}
+	return assigned
+}
+
+// AssignInstanceToUser creates a user-instance relationship (atau mengubah level jika sudah ada)
 func AssignInstanceToUser(userID int64, instanceID string, permission string) error {
 	db := database.AppDB
 
@@ -72,10 +132,11 @@ func GetInstanceUsers(instanceID string) ([]UserInstance, error) {
 	db := database.AppDB
 
 	query := `
-		SELECT id, user_id, instance_id, permission_level, created_at
-		FROM user_instances
-		WHERE instance_id = $1
-		ORDER BY created_at ASC
+		SELECT ui.id, ui.user_id, u.username, ui.instance_id, ui.permission_level, ui.created_at
+		FROM user_instances ui
+		JOIN users u ON u.id = ui.user_id
+		WHERE ui.instance_id = $1
+		ORDER BY ui.created_at ASC
 	`
 
 	rows, err := db.Query(query, instanceID)
@@ -87,7 +148,7 @@ func GetInstanceUsers(instanceID string) ([]UserInstance, error) {
 	var userInstances []UserInstance
 	for rows.Next() {
 		var ui UserInstance
-		if err := rows.Scan(&ui.ID, &ui.UserID, &ui.InstanceID, &ui.PermissionLevel, &ui.CreatedAt); err != nil {
+		if err := rows.Scan(&ui.ID, &ui.UserID, &ui.Username, &ui.InstanceID, &ui.PermissionLevel, &ui.CreatedAt); err != nil {
 			return nil, err
 		}
 		userInstances = append(userInstances, ui)
@@ -120,6 +181,25 @@ func CheckUserInstancePermission(userID int64, instanceID string) (string, error
 	return permissionLevel, nil
 }
 
+// CheckUserInstanceLevel memastikan user punya level minimal required pada instance
+// (dengan batas role, lihat EffectivePermission). Return ErrNoPermission jika tidak ter-assign,
+// ErrInsufficientPermission jika level kurang.
+func CheckUserInstanceLevel(userID int64, role, instanceID, required string) error {
+	if role == "admin" {
+		return nil
+	}
+
+	assigned, err := CheckUserInstancePermission(userID, instanceID)
+	if err != nil {
+		return err
+	}
+
+	if !PermissionAtLeast(EffectivePermission(role, assigned), required) {
+		return ErrInsufficientPermission
+	}
+	return nil
+}
+
 // RemoveUserInstance removes a user's access to an instance
 func RemoveUserInstance(userID int64, instanceID string) error {
 	db := database.AppDB
@@ -0,0 +1,207 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gowa-yourself/database"
+)
+
+// ErrInvitationUnavailable berarti undangan tidak ada, sudah dipakai, dicabut atau kedaluwarsa
+var ErrInvitationUnavailable = errors.New("invitation is invalid, used, revoked or expired")
+
+// UserInvitation is a single-use token for /register when REGISTRATION_MODE=invite.
+// Hanya hash SHA-256 token yang disimpan.
+type UserInvitation struct {
+	ID        int64
+	TokenHash string
+	Email     sql.NullString // jika diisi, email pendaftar harus sama
+	Role      string
+	CreatedBy sql.NullInt64
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	UsedBy    sql.NullInt64
+	RevokedAt sql.NullTime
+	CreatedAt time.Time
+}
+
+// UserInvitationResponse for JSON response (tanpa hash)
+type UserInvitationResponse struct {
+	ID        int64      `json:"id"`
+	Email     string     `json:"email,omitempty"`
+	Role      string     `json:"role"`
+	CreatedBy int64      `json:"createdBy,omitempty"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt"`
+	UsedBy    int64      `json:"usedBy,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// ToUserInvitationResponse converts UserInvitation to UserInvitationResponse
+func ToUserInvitationResponse(inv UserInvitation) UserInvitationResponse {
+	resp := UserInvitationResponse{
+		ID:        inv.ID,
+		Email:     inv.Email.String,
+		Role:      inv.Role,
+		CreatedBy: inv.CreatedBy.Int64,
+		ExpiresAt: inv.ExpiresAt,
+		UsedBy:    inv.UsedBy.Int64,
+		CreatedAt: inv.CreatedAt,
+	}
+	if inv.UsedAt.Valid {
+		resp.UsedAt = &inv.UsedAt.Time
+	}
+	if inv.RevokedAt.Valid {
+		resp.RevokedAt = &inv.RevokedAt.Time
+	}
+	return resp
+}
+
+const userInvitationColumns = `
+	id, token_hash, email, role, created_by, expires_at, used_at, used_by, revoked_at, created_at
+`
+
+// CreateUserInvitation inserts a new invitation
+func CreateUserInvitation(inv *UserInvitation) error {
+	query := `
+		INSERT INTO user_invitations (token_hash, email, role, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := database.AppDB.QueryRow(query,
+		inv.TokenHash, inv.Email, inv.Role, inv.CreatedBy, inv.ExpiresAt,
+	).Scan(&inv.ID, &inv.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserInvitations returns all invitations, newest first; pending=true hanya yang masih bisa dipakai
+func GetUserInvitations(pending bool) ([]UserInvitation, error) {
+	query := `SELECT ` + userInvitationColumns + ` FROM user_invitations`
+	if pending {
+		query += ` WHERE used_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()`
+	}
+	query += ` ORDER BY created_at DESC`
+
+	rows, err := database.AppDB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invitations: %w", err)
+	}
+	defer rows.Close()
+
+	return scanUserInvitations(rows)
+}
+
+// GetUserInvitationByID retrieves an invitation by ID
+func GetUserInvitationByID(id int64) (*UserInvitation, error) {
+	return getUserInvitation(`SELECT `+userInvitationColumns+` FROM user_invitations WHERE id = $1`, id)
+}
+
+// GetUserInvitationByTokenHash retrieves an invitation by the hash of its token
+func GetUserInvitationByTokenHash(tokenHash string) (*UserInvitation, error) {
+	return getUserInvitation(`SELECT `+userInvitationColumns+` FROM user_invitations WHERE token_hash = $1`, tokenHash)
+}
+
+// ClaimUserInvitation menandai undangan terpakai secara atomik (dua pendaftaran bersamaan
+// dengan token yang sama: hanya satu yang berhasil)
+func ClaimUserInvitation(id int64) error {
+	result, err := database.AppDB.Exec(`
+		UPDATE user_invitations SET used_at = NOW()
+		WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to claim invitation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrInvitationUnavailable
+	}
+
+	return nil
+}
+
+// ReleaseUserInvitation membatalkan klaim jika pembuatan user gagal
+func ReleaseUserInvitation(id int64) error {
+	_, err := database.AppDB.Exec(`UPDATE user_invitations SET used_at = NULL WHERE id = $1 AND used_by IS NULL`, id)
+	return err
+}
+
+// SetUserInvitationUsedBy mencatat user yang dibuat dari undangan
+func SetUserInvitationUsedBy(id, userID int64) error {
+	_, err := database.AppDB.Exec(`UPDATE user_invitations SET used_by = $2 WHERE id = $1`, id, userID)
+	return err
+}
+
+// RevokeUserInvitation marks an unused invitation as revoked
+func RevokeUserInvitation(id int64) error {
+	result, err := database.AppDB.Exec(`
+		UPDATE user_invitations SET revoked_at = NOW()
+		WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrInvitationUnavailable
+	}
+
+	return nil
+}
+
+func getUserInvitation(query string, arg interface{}) (*UserInvitation, error) {
+	rows, err := database.AppDB.Query(query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invitation: %w", err)
+	}
+	defer rows.Close()
+
+	invitations, err := scanUserInvitations(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(invitations) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return &invitations[0], nil
+}
+
+func scanUserInvitations(rows *sql.Rows) ([]UserInvitation, error) {
+	var invitations []UserInvitation
+	for rows.Next() {
+		var inv UserInvitation
+		if err := rows.Scan(
+			&inv.ID,
+			&inv.TokenHash,
+			&inv.Email,
+			&inv.Role,
+			&inv.CreatedBy,
+			&inv.ExpiresAt,
+			&inv.UsedAt,
+			&inv.UsedBy,
+			&inv.RevokedAt,
+			&inv.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan invitation: %w", err)
+		}
+		invitations = append(invitations, inv)
+	}
+
+	return invitations, rows.Err()
+}
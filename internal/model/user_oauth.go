@@ -0,0 +1,99 @@
+// internal/model/user_oauth.go
+package model
+
+import (
+	"database/sql"
+	"errors"
+
+	"gowa-yourself/database"
+)
+
+// ErrIdentityAlreadyLinked is returned when the account is already linked to another SSO identity
+var ErrIdentityAlreadyLinked = errors.New("account is already linked to another identity")
+
+const userColumns = `
+	id, username, email, password_hash, full_name, avatar_url,
+	auth_provider, oauth_provider_id, role, is_active, email_verified,
+	created_at, updated_at, last_login_at, must_change_password, totp_enabled
+`
+
+// getUserWhere menjalankan SELECT users dengan klausa WHERE tertentu
+func getUserWhere(where string, args ...interface{}) (*User, error) {
+	db := database.AppDB
+
+	user := &User{}
+	err := db.QueryRow("SELECT "+userColumns+" FROM users WHERE "+where, args...).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.PasswordHash,
+		&user.FullName,
+		&user.AvatarURL,
+		&user.AuthProvider,
+		&user.OAuthProviderID,
+		&user.Role,
+		&user.IsActive,
+		&user.EmailVerified,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.LastLoginAt,
+		&user.MustChangePassword,
+		&user.TwoFactorEnabled,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetUserByOAuthIdentity retrieves the user linked to an SSO identity (provider + OIDC sub).
+// Akun SSO menyimpan provider di auth_provider, akun local yang ditautkan di oauth_provider.
+func GetUserByOAuthIdentity(provider, subject string) (*User, error) {
+	return getUserWhere("COALESCE(oauth_provider, auth_provider) = $1 AND oauth_provider_id = $2", provider, subject)
+}
+
+// GetUserByEmailIgnoreCase retrieves a user by email, case-insensitive (email dari IdP bisa beda kapitalisasi)
+func GetUserByEmailIgnoreCase(email string) (*User, error) {
+	return getUserWhere("LOWER(email) = LOWER($1) ORDER BY id LIMIT 1", email)
+}
+
+// LinkOAuthIdentity menautkan identitas SSO ke akun local. auth_provider tetap 'local', jadi
+// password (login, ganti password, nonaktifkan 2FA) tetap berlaku. Gagal jika akun bukan
+// akun local atau sudah terhubung ke identitas lain.
+func LinkOAuthIdentity(userID int64, provider, subject string) error {
+	db := database.AppDB
+
+	result, err := db.Exec(`
+		UPDATE users
+		SET oauth_provider = $2, oauth_provider_id = $3, email_verified = true, updated_at = NOW()
+		WHERE id = $1 AND auth_provider = 'local'
+			AND (oauth_provider_id IS NULL OR (oauth_provider = $2 AND oauth_provider_id = $3))
+	`, userID, provider, subject)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrIdentityAlreadyLinked
+	}
+
+	return nil
+}
+
+// UsernameExists checks whether a username is already taken
+func UsernameExists(username string) (bool, error) {
+	db := database.AppDB
+
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(username) = LOWER($1))`, username).Scan(&exists)
+	return exists, err
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"fmt"
 	"gowa-yourself/database"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/phone"
 	"strings"
 	"time"
 
@@ -153,23 +155,23 @@ func CheckDuplicateWhitelistedNumber(whitelistedNumber string, excludeRoomID *uu
 	return count > 0, nil
 }
 
+// normalizeWhitelistedNumber menormalkan nomor ke E.164 memakai default country instance pengirim.
+// Nomor yang tidak valid cukup dibersihkan (tanpa spasi, '-' dan '+') seperti sebelumnya.
+func normalizeWhitelistedNumber(number, country string) string {
+	if normalized, err := phone.Normalize(number, country); err == nil {
+		return normalized
+	}
+
+	cleaned := strings.ReplaceAll(number, " ", "")
+	cleaned = strings.ReplaceAll(cleaned, "-", "")
+	return strings.TrimPrefix(cleaned, "+")
+}
+
 // CreateWarmingRoom inserts new room
 func CreateWarmingRoom(req *CreateWarmingRoomRequest, userID int64) (*WarmingRoom, error) {
-	// Normalize whitelisted number for HUMAN_VS_BOT rooms (08xxx -> 628xxx)
+	// Normalize whitelisted number for HUMAN_VS_BOT rooms (08xxx -> 628xxx, E.164)
 	if req.RoomType == "HUMAN_VS_BOT" && req.WhitelistedNumber != "" {
-		// Use existing FormatPhoneNumber logic to normalize
-		cleaned := strings.ReplaceAll(req.WhitelistedNumber, " ", "")
-		cleaned = strings.ReplaceAll(cleaned, "-", "")
-		cleaned = strings.TrimPrefix(cleaned, "+")
-
-		// Convert 08xxx to 628xxx
-		if strings.HasPrefix(cleaned, "08") {
-			cleaned = "62" + cleaned[1:]
-		} else if strings.HasPrefix(cleaned, "8") && !strings.HasPrefix(cleaned, "62") {
-			cleaned = "62" + cleaned
-		}
-
-		req.WhitelistedNumber = cleaned
+		req.WhitelistedNumber = normalizeWhitelistedNumber(req.WhitelistedNumber, model.GetInstanceDefaultCountry(req.SenderInstanceID))
 
 		// Check for duplicate whitelisted number
 		isDuplicate, err := CheckDuplicateWhitelistedNumber(req.WhitelistedNumber, nil)
@@ -404,6 +406,10 @@ func UpdateWarmingRoom(id string, req *UpdateWarmingRoomRequest) error {
 
 	// Check for duplicate whitelisted number if updating HUMAN_VS_BOT room
 	if req.RoomType == "HUMAN_VS_BOT" && req.WhitelistedNumber != "" {
+		var senderInstanceID string
+		_ = database.AppDB.QueryRow(`SELECT sender_instance_id FROM warming_rooms WHERE id = $1`, roomID).Scan(&senderInstanceID)
+		req.WhitelistedNumber = normalizeWhitelistedNumber(req.WhitelistedNumber, model.GetInstanceDefaultCountry(senderInstanceID))
+
 		isDuplicate, err := CheckDuplicateWhitelistedNumber(req.WhitelistedNumber, &roomID)
 		if err != nil {
 			return err
@@ -814,3 +820,26 @@ func GetActiveHumanRoomBySender(senderNumber string) (*WarmingRoom, error) {
 
 	return room, nil
 }
+
+// GetOwnedWarmingRoomIDs returns ID room yang boleh dilihat user non-admin
+// (aturan sama dengan GetAllWarmingRooms: milik user atau tanpa pemilik)
+func GetOwnedWarmingRoomIDs(userID int64) ([]string, error) {
+	rows, err := database.AppDB.Query(
+		`SELECT id FROM warming_rooms WHERE created_by = $1 OR created_by IS NULL`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query owned warming rooms: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan warming room id: %w", err)
+		}
+		ids = append(ids, id.String())
+	}
+	return ids, rows.Err()
+}
@@ -1,17 +1,23 @@
 package model
 
 import (
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"gowa-yourself/database"
+	"strings"
+	"time"
 )
 
-func UpdateInstanceWebhook(instanceID, url, secret string) error {
+// UpdateInstanceWebhook menyimpan url, secret dan daftar event (comma separated) webhook instance
+func UpdateInstanceWebhook(instanceID, url, secret string, events sql.NullString) error {
 	res, err := database.AppDB.Exec(`
         UPDATE instances
-        SET webhook_url   = $1,
-            webhook_secret = $2
-        WHERE instance_id = $3
-    `, url, secret, instanceID)
+        SET webhook_url    = $1,
+            webhook_secret = $2,
+            webhook_events = $3
+        WHERE instance_id = $4
+    `, url, secret, events, instanceID)
 	if err != nil {
 		return err
 	}
@@ -28,3 +34,219 @@ func UpdateInstanceWebhook(instanceID, url, secret string) error {
 
 	return nil
 }
+
+// Webhook represents an endpoint in the webhooks table (scope instance atau circle)
+type Webhook struct {
+	ID         int64
+	UserID     sql.NullInt64
+	Name       string
+	URL        string
+	Secret     sql.NullString
+	Events     sql.NullString // comma separated, kosong = semua event
+	InstanceID sql.NullString
+	Circle     sql.NullString
+	Enabled    bool
+	Headers    sql.NullString // JSON object
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// WebhookResponse for JSON response
+type WebhookResponse struct {
+	ID         int64             `json:"id"`
+	UserID     *int64            `json:"userId,omitempty"`
+	Name       string            `json:"name"`
+	URL        string            `json:"url"`
+	Secret     string            `json:"secret,omitempty"`
+	Events     []string          `json:"events"`
+	InstanceID string            `json:"instanceId,omitempty"`
+	Circle     string            `json:"circle,omitempty"`
+	Enabled    bool              `json:"enabled"`
+	Headers    map[string]string `json:"headers"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	UpdatedAt  time.Time         `json:"updatedAt"`
+}
+
+// ToWebhookResponse converts Webhook to WebhookResponse
+func ToWebhookResponse(w Webhook) WebhookResponse {
+	resp := WebhookResponse{
+		ID:         w.ID,
+		Name:       w.Name,
+		URL:        w.URL,
+		Secret:     w.Secret.String,
+		Events:     []string{},
+		InstanceID: w.InstanceID.String,
+		Circle:     w.Circle.String,
+		Enabled:    w.Enabled,
+		Headers:    w.HeaderMap(),
+		CreatedAt:  w.CreatedAt,
+		UpdatedAt:  w.UpdatedAt,
+	}
+
+	if w.UserID.Valid {
+		resp.UserID = &w.UserID.Int64
+	}
+	for _, e := range strings.Split(w.Events.String, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			resp.Events = append(resp.Events, e)
+		}
+	}
+
+	return resp
+}
+
+// HeaderMap decodes the custom headers JSON (invalid/kosong = map kosong)
+func (w Webhook) HeaderMap() map[string]string {
+	headers := map[string]string{}
+	if w.Headers.Valid && w.Headers.String != "" {
+		_ = json.Unmarshal([]byte(w.Headers.String), &headers)
+	}
+	return headers
+}
+
+const webhookColumns = `
+	id, user_id, name, url, secret, events, instance_id, circle, enabled, headers, created_at, updated_at
+`
+
+// GetWebhooks returns webhook endpoints, admin melihat semua, user lain hanya miliknya
+func GetWebhooks(userID int64, isAdmin bool, instanceID, circle string) ([]Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE 1=1`
+	args := []interface{}{}
+	argIndex := 1
+
+	if !isAdmin {
+		query += fmt.Sprintf(" AND user_id = $%d", argIndex)
+		args = append(args, userID)
+		argIndex++
+	}
+	if instanceID != "" {
+		query += fmt.Sprintf(" AND instance_id = $%d", argIndex)
+		args = append(args, instanceID)
+		argIndex++
+	}
+	if circle != "" {
+		query += fmt.Sprintf(" AND circle = $%d", argIndex)
+		args = append(args, circle)
+		argIndex++
+	}
+
+	query += " ORDER BY created_at DESC"
+
+	rows, err := database.AppDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	return scanWebhooks(rows)
+}
+
+// GetWebhookByID retrieves a single webhook endpoint
+func GetWebhookByID(id int64) (*Webhook, error) {
+	rows, err := database.AppDB.Query(`SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks, err := scanWebhooks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return &webhooks[0], nil
+}
+
+// GetMatchingWebhooks returns enabled endpoints for an instance: scope instance_id atau circle yang sama
+func GetMatchingWebhooks(instanceID, circle string) ([]Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE enabled = true AND (instance_id = $1 OR ($2 <> '' AND circle = $2))
+		ORDER BY id
+	`
+
+	rows, err := database.AppDB.Query(query, instanceID, circle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query matching webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	return scanWebhooks(rows)
+}
+
+// CreateWebhook inserts a new webhook endpoint
+func CreateWebhook(w *Webhook) error {
+	query := `
+		INSERT INTO webhooks (user_id, name, url, secret, events, instance_id, circle, enabled, headers)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := database.AppDB.QueryRow(query,
+		w.UserID, w.Name, w.URL, w.Secret, w.Events, w.InstanceID, w.Circle, w.Enabled, w.Headers,
+	).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateWebhook updates an existing webhook endpoint
+func UpdateWebhook(w *Webhook) error {
+	query := `
+		UPDATE webhooks
+		SET name = $1, url = $2, secret = $3, events = $4, instance_id = $5, circle = $6,
+		    enabled = $7, headers = $8, updated_at = NOW()
+		WHERE id = $9
+		RETURNING updated_at
+	`
+
+	err := database.AppDB.QueryRow(query,
+		w.Name, w.URL, w.Secret, w.Events, w.InstanceID, w.Circle, w.Enabled, w.Headers, w.ID,
+	).Scan(&w.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteWebhook deletes a webhook endpoint
+func DeleteWebhook(id int64) error {
+	if _, err := database.AppDB.Exec(`DELETE FROM webhooks WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// scanWebhooks is a helper function to scan rows into Webhook slice
+func scanWebhooks(rows *sql.Rows) ([]Webhook, error) {
+	var webhooks []Webhook
+	for rows.Next() {
+		var w Webhook
+		if err := rows.Scan(
+			&w.ID,
+			&w.UserID,
+			&w.Name,
+			&w.URL,
+			&w.Secret,
+			&w.Events,
+			&w.InstanceID,
+			&w.Circle,
+			&w.Enabled,
+			&w.Headers,
+			&w.CreatedAt,
+			&w.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, rows.Err()
+}
@@ -0,0 +1,327 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gowa-yourself/database"
+)
+
+// Webhook delivery status
+const (
+	WebhookDeliveryPending = "pending"
+	WebhookDeliverySuccess = "success"
+	WebhookDeliveryDead    = "dead"
+)
+
+// WebhookDelivery represents a row in webhook_deliveries
+type WebhookDelivery struct {
+	ID             int64
+	InstanceID     sql.NullString
+	WorkerID       sql.NullInt64
+	Application    sql.NullString // application outbox (webhook dari outbox worker)
+	WebhookID      sql.NullInt64
+	Event          string
+	URL            string
+	Payload        string
+	Signature      sql.NullString
+	Headers        sql.NullString // custom headers (JSON) dari endpoint di tabel webhooks
+	Status         string
+	Attempts       int
+	MaxAttempts    int
+	NextAttemptAt  time.Time
+	LastAttemptAt  sql.NullTime
+	LastStatusCode sql.NullInt64
+	LastError      sql.NullString
+	DeliveredAt    sql.NullTime
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// WebhookDeliveryResponse for JSON response
+type WebhookDeliveryResponse struct {
+	ID             int64      `json:"id"`
+	InstanceID     string     `json:"instanceId,omitempty"`
+	WorkerID       *int64     `json:"workerId,omitempty"`
+	Application    string     `json:"application,omitempty"`
+	WebhookID      *int64     `json:"webhookId,omitempty"`
+	Event          string     `json:"event"`
+	URL            string     `json:"url"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	MaxAttempts    int        `json:"maxAttempts"`
+	NextAttemptAt  *time.Time `json:"nextAttemptAt,omitempty"`
+	LastAttemptAt  *time.Time `json:"lastAttemptAt,omitempty"`
+	LastStatusCode *int64     `json:"lastStatusCode,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// ToWebhookDeliveryResponse converts WebhookDelivery to WebhookDeliveryResponse
+func ToWebhookDeliveryResponse(d WebhookDelivery) WebhookDeliveryResponse {
+	resp := WebhookDeliveryResponse{
+		ID:          d.ID,
+		InstanceID:  d.InstanceID.String,
+		Application: d.Application.String,
+		Event:       d.Event,
+		URL:         d.URL,
+		Payload:     d.Payload,
+		Status:      d.Status,
+		Attempts:    d.Attempts,
+		MaxAttempts: d.MaxAttempts,
+		LastError:   d.LastError.String,
+		CreatedAt:   d.CreatedAt,
+	}
+
+	if d.WorkerID.Valid {
+		resp.WorkerID = &d.WorkerID.Int64
+	}
+	if d.WebhookID.Valid {
+		resp.WebhookID = &d.WebhookID.Int64
+	}
+	if d.Status == WebhookDeliveryPending {
+		resp.NextAttemptAt = &d.NextAttemptAt
+	}
+	if d.LastAttemptAt.Valid {
+		resp.LastAttemptAt = &d.LastAttemptAt.Time
+	}
+	if d.LastStatusCode.Valid {
+		resp.LastStatusCode = &d.LastStatusCode.Int64
+	}
+	if d.DeliveredAt.Valid {
+		resp.DeliveredAt = &d.DeliveredAt.Time
+	}
+
+	return resp
+}
+
+const webhookDeliveryColumns = `
+	id, instance_id, worker_id, application, webhook_id, event, url, payload, signature, headers, status, attempts, max_attempts,
+	next_attempt_at, last_attempt_at, last_status_code, last_error, delivered_at, created_at, updated_at
+`
+
+// CreateWebhookDelivery inserts a new pending delivery. nextAttemptAt menentukan kapan dispatcher
+// boleh mengambilnya (dipakai sebagai lease untuk percobaan pertama yang langsung dikirim).
+func CreateWebhookDelivery(d *WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (
+			instance_id, worker_id, application, webhook_id, event, url, payload, signature, headers, status, max_attempts, next_attempt_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at
+	`
+
+	d.Status = WebhookDeliveryPending
+	err := database.AppDB.QueryRow(query,
+		d.InstanceID,
+		d.WorkerID,
+		d.Application,
+		d.WebhookID,
+		d.Event,
+		d.URL,
+		d.Payload,
+		d.Signature,
+		d.Headers,
+		d.Status,
+		d.MaxAttempts,
+		d.NextAttemptAt,
+	).Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDueWebhookDeliveries mengambil delivery pending yang sudah jatuh tempo dan menggeser
+// next_attempt_at sejauh lease, supaya tidak diambil dua kali (aman untuk multi node).
+func ClaimDueWebhookDeliveries(limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = NOW() + ($1 * INTERVAL '1 second'), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + webhookDeliveryColumns
+
+	rows, err := database.AppDB.Query(query, int(lease.Seconds()), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	return scanWebhookDeliveries(rows)
+}
+
+// MarkWebhookDeliverySuccess records a successful attempt
+func MarkWebhookDeliverySuccess(id int64, statusCode int) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'success', attempts = attempts + 1, last_attempt_at = NOW(),
+		    last_status_code = $1, last_error = NULL, delivered_at = NOW(), updated_at = NOW()
+		WHERE id = $2
+	`
+	if _, err := database.AppDB.Exec(query, statusCode, id); err != nil {
+		return fmt.Errorf("failed to mark webhook delivery success: %w", err)
+	}
+	return nil
+}
+
+// DeleteDeliveredWebhookDeliveries menghapus maksimal limit delivery berstatus success yang terkirim
+// sebelum cutoff (retention). Delivery pending / dead tetap disimpan untuk retry dan replay.
+func DeleteDeliveredWebhookDeliveries(cutoff time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM webhook_deliveries
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'success' AND delivered_at < $1
+			LIMIT $2
+		)
+	`
+	result, err := database.AppDB.Exec(query, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete delivered webhook deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// MarkWebhookDeliveryFailed records a failed attempt. Jika attempts sudah mencapai max_attempts,
+// delivery dipindah ke status dead (dead-letter), selain itu dijadwalkan ulang pada nextAttemptAt.
+func MarkWebhookDeliveryFailed(id int64, statusCode int, errMsg string, nextAttemptAt time.Time) (string, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1,
+		    status = CASE WHEN attempts + 1 >= max_attempts THEN 'dead' ELSE 'pending' END,
+		    next_attempt_at = $1,
+		    last_attempt_at = NOW(),
+		    last_status_code = $2,
+		    last_error = $3,
+		    updated_at = NOW()
+		WHERE id = $4
+		RETURNING status
+	`
+
+	code := sql.NullInt64{Int64: int64(statusCode), Valid: statusCode > 0}
+
+	var status string
+	if err := database.AppDB.QueryRow(query, nextAttemptAt, code, errMsg, id).Scan(&status); err != nil {
+		return "", fmt.Errorf("failed to mark webhook delivery failed: %w", err)
+	}
+	return status, nil
+}
+
+// ResetWebhookDeliveryForReplay mengembalikan delivery (success/dead) ke pending dengan attempts 0
+func ResetWebhookDeliveryForReplay(id int64, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = $1, delivered_at = NULL, updated_at = NOW()
+		WHERE id = $2
+	`
+	res, err := database.AppDB.Exec(query, nextAttemptAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to reset webhook delivery: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetWebhookDeliveryByID retrieves a single delivery
+func GetWebhookDeliveryByID(id int64) (*WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+
+	rows, err := database.AppDB.Query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook delivery: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries, err := scanWebhookDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return &deliveries[0], nil
+}
+
+// GetWebhookDeliveriesByInstance returns delivery history of an instance, newest first
+func GetWebhookDeliveriesByInstance(instanceID, status string, limit, offset int) ([]WebhookDelivery, int, error) {
+	where := ` WHERE instance_id = $1`
+	args := []interface{}{instanceID}
+	argIndex := 2
+
+	if status != "" {
+		where += fmt.Sprintf(" AND status = $%d", argIndex)
+		args = append(args, status)
+		argIndex++
+	}
+
+	var total int
+	if err := database.AppDB.QueryRow(`SELECT COUNT(*) FROM webhook_deliveries`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries` + where +
+		fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, limit, offset)
+
+	rows, err := database.AppDB.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries, err := scanWebhookDeliveries(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// scanWebhookDeliveries is a helper function to scan rows into WebhookDelivery slice
+func scanWebhookDeliveries(rows *sql.Rows) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(
+			&d.ID,
+			&d.InstanceID,
+			&d.WorkerID,
+			&d.Application,
+			&d.WebhookID,
+			&d.Event,
+			&d.URL,
+			&d.Payload,
+			&d.Signature,
+			&d.Headers,
+			&d.Status,
+			&d.Attempts,
+			&d.MaxAttempts,
+			&d.NextAttemptAt,
+			&d.LastAttemptAt,
+			&d.LastStatusCode,
+			&d.LastError,
+			&d.DeliveredAt,
+			&d.CreatedAt,
+			&d.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
@@ -264,6 +264,29 @@ func ShouldReplacePendingForApp(ctx context.Context, app string) (bool, error) {
 	return count > 0, nil
 }
 
+// GetApplicationDefaultCountry returns default country circle dari worker yang memproses application tsb
+// ("" jika tidak ada worker / circle belum diset, berarti pakai DEFAULT_PHONE_COUNTRY)
+func GetApplicationDefaultCountry(ctx context.Context, app string) string {
+	if app == "" {
+		return ""
+	}
+
+	query := `
+		SELECT cs.default_country
+		FROM outbox_worker_config w
+		JOIN circle_settings cs ON cs.circle = w.circle
+		WHERE LOWER(w.application) = LOWER($1)
+		ORDER BY w.enabled DESC, w.id
+		LIMIT 1
+	`
+	var country string
+	if err := database.AppDB.QueryRowContext(ctx, query, app).Scan(&country); err != nil {
+		return ""
+	}
+
+	return country
+}
+
 // GetAvailableCircles retrieves distinct circles from instances table
 func GetAvailableCircles(ctx context.Context) ([]string, error) {
 	query := `SELECT DISTINCT circle FROM instances WHERE used = true ORDER BY circle`
@@ -286,6 +309,26 @@ func GetAvailableCircles(ctx context.Context) ([]string, error) {
 	return circles, rows.Err()
 }
 
+// GetCircleInstanceIDs returns instance yang dipakai worker blast untuk satu circle (used = true)
+func GetCircleInstanceIDs(ctx context.Context, circle string) ([]string, error) {
+	rows, err := database.AppDB.QueryContext(ctx, `SELECT instance_id FROM instances WHERE circle = $1 AND used = true`, circle)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
 // GetAvailableApplications retrieves distinct applications from outbox table
 func GetAvailableApplications(ctx context.Context) ([]string, error) {
 	query := `
@@ -0,0 +1,75 @@
+package phone
+
+// Country adalah metadata penomoran satu negara (mirip metadata libphonenumber, versi ringkas).
+// Panjang dihitung dari national significant number (NSN): nomor tanpa kode negara dan trunk prefix.
+type Country struct {
+	Region      string   // ISO 3166-1 alpha-2, mis. "ID"
+	Name        string   // nama negara
+	CallingCode string   // kode negara, mis. "62"
+	TrunkPrefix string   // prefix nasional, mis. "0" (kosong jika tidak ada)
+	MinLength   int      // panjang minimal NSN
+	MaxLength   int      // panjang maksimal NSN
+	Leading     []string // awalan NSN yang valid (kosong = semua)
+	Mobile      []string // awalan nomor mobile yang boleh ditulis tanpa trunk prefix (mis. 8xx di Indonesia)
+}
+
+// countries adalah metadata penomoran yang dikenal. Nomor internasional dengan kode negara
+// di luar tabel ini tetap diterima selama panjangnya valid menurut E.164.
+var countries = []Country{
+	// Asia Tenggara
+	// ID: awalan dibatasi sama seperti validasi lama (1, 2, 5, 8, 9)
+	{Region: "ID", Name: "Indonesia", CallingCode: "62", TrunkPrefix: "0", MinLength: 9, MaxLength: 13, Leading: []string{"1", "2", "5", "8", "9"}, Mobile: []string{"8"}},
+	{Region: "MY", Name: "Malaysia", CallingCode: "60", TrunkPrefix: "0", MinLength: 8, MaxLength: 10, Leading: []string{"1", "3", "4", "5", "6", "7", "8", "9"}, Mobile: []string{"1"}},
+	{Region: "SG", Name: "Singapore", CallingCode: "65", MinLength: 8, MaxLength: 8, Leading: []string{"3", "6", "8", "9"}},
+	{Region: "BN", Name: "Brunei", CallingCode: "673", MinLength: 7, MaxLength: 7},
+	{Region: "TH", Name: "Thailand", CallingCode: "66", TrunkPrefix: "0", MinLength: 8, MaxLength: 9, Mobile: []string{"6", "8", "9"}},
+	{Region: "PH", Name: "Philippines", CallingCode: "63", TrunkPrefix: "0", MinLength: 8, MaxLength: 10, Mobile: []string{"9"}},
+	{Region: "VN", Name: "Vietnam", CallingCode: "84", TrunkPrefix: "0", MinLength: 9, MaxLength: 10, Mobile: []string{"3", "5", "7", "8", "9"}},
+	{Region: "KH", Name: "Cambodia", CallingCode: "855", TrunkPrefix: "0", MinLength: 8, MaxLength: 9},
+	{Region: "MM", Name: "Myanmar", CallingCode: "95", TrunkPrefix: "0", MinLength: 7, MaxLength: 10, Mobile: []string{"9"}},
+	{Region: "LA", Name: "Laos", CallingCode: "856", TrunkPrefix: "0", MinLength: 8, MaxLength: 10},
+	{Region: "TL", Name: "Timor-Leste", CallingCode: "670", MinLength: 7, MaxLength: 8},
+
+	// Asia lainnya
+	{Region: "IN", Name: "India", CallingCode: "91", TrunkPrefix: "0", MinLength: 10, MaxLength: 10, Mobile: []string{"6", "7", "8", "9"}},
+	{Region: "CN", Name: "China", CallingCode: "86", TrunkPrefix: "0", MinLength: 10, MaxLength: 11, Mobile: []string{"1"}},
+	{Region: "HK", Name: "Hong Kong", CallingCode: "852", MinLength: 8, MaxLength: 8},
+	{Region: "TW", Name: "Taiwan", CallingCode: "886", TrunkPrefix: "0", MinLength: 8, MaxLength: 9, Mobile: []string{"9"}},
+	{Region: "JP", Name: "Japan", CallingCode: "81", TrunkPrefix: "0", MinLength: 9, MaxLength: 10, Mobile: []string{"7", "8", "9"}},
+	{Region: "KR", Name: "South Korea", CallingCode: "82", TrunkPrefix: "0", MinLength: 8, MaxLength: 10, Mobile: []string{"1"}},
+	{Region: "PK", Name: "Pakistan", CallingCode: "92", TrunkPrefix: "0", MinLength: 9, MaxLength: 10, Mobile: []string{"3"}},
+	{Region: "BD", Name: "Bangladesh", CallingCode: "880", TrunkPrefix: "0", MinLength: 9, MaxLength: 10, Mobile: []string{"1"}},
+	{Region: "SA", Name: "Saudi Arabia", CallingCode: "966", TrunkPrefix: "0", MinLength: 9, MaxLength: 9, Mobile: []string{"5"}},
+	{Region: "AE", Name: "United Arab Emirates", CallingCode: "971", TrunkPrefix: "0", MinLength: 8, MaxLength: 9, Mobile: []string{"5"}},
+	{Region: "QA", Name: "Qatar", CallingCode: "974", MinLength: 8, MaxLength: 8},
+	{Region: "TR", Name: "Turkey", CallingCode: "90", TrunkPrefix: "0", MinLength: 10, MaxLength: 10, Mobile: []string{"5"}},
+
+	// Oseania
+	{Region: "AU", Name: "Australia", CallingCode: "61", TrunkPrefix: "0", MinLength: 9, MaxLength: 9, Mobile: []string{"4"}},
+	{Region: "NZ", Name: "New Zealand", CallingCode: "64", TrunkPrefix: "0", MinLength: 8, MaxLength: 10, Mobile: []string{"2"}},
+
+	// Eropa, Amerika, Afrika
+	{Region: "GB", Name: "United Kingdom", CallingCode: "44", TrunkPrefix: "0", MinLength: 10, MaxLength: 10, Mobile: []string{"7"}},
+	{Region: "NL", Name: "Netherlands", CallingCode: "31", TrunkPrefix: "0", MinLength: 9, MaxLength: 9, Mobile: []string{"6"}},
+	{Region: "DE", Name: "Germany", CallingCode: "49", TrunkPrefix: "0", MinLength: 6, MaxLength: 13, Mobile: []string{"15", "16", "17"}},
+	{Region: "FR", Name: "France", CallingCode: "33", TrunkPrefix: "0", MinLength: 9, MaxLength: 9, Mobile: []string{"6", "7"}},
+	{Region: "RU", Name: "Russia", CallingCode: "7", TrunkPrefix: "8", MinLength: 10, MaxLength: 10, Mobile: []string{"9"}},
+	{Region: "US", Name: "United States", CallingCode: "1", TrunkPrefix: "1", MinLength: 10, MaxLength: 10},
+	{Region: "CA", Name: "Canada", CallingCode: "1", TrunkPrefix: "1", MinLength: 10, MaxLength: 10},
+	{Region: "MX", Name: "Mexico", CallingCode: "52", MinLength: 10, MaxLength: 10},
+	{Region: "BR", Name: "Brazil", CallingCode: "55", TrunkPrefix: "0", MinLength: 10, MaxLength: 11},
+	{Region: "EG", Name: "Egypt", CallingCode: "20", TrunkPrefix: "0", MinLength: 9, MaxLength: 10, Mobile: []string{"1"}},
+	{Region: "NG", Name: "Nigeria", CallingCode: "234", TrunkPrefix: "0", MinLength: 8, MaxLength: 10, Mobile: []string{"7", "8", "9"}},
+}
+
+var (
+	byRegion      = map[string]Country{}
+	byCallingCode = map[string][]Country{}
+)
+
+func init() {
+	for _, c := range countries {
+		byRegion[c.Region] = c
+		byCallingCode[c.CallingCode] = append(byCallingCode[c.CallingCode], c)
+	}
+}
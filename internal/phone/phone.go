@@ -0,0 +1,201 @@
+// Package phone menormalkan nomor telepon ke format E.164 (tanpa '+') memakai metadata
+// penomoran per negara dan default country yang bisa diatur per instance / circle.
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Batas panjang nomor E.164 (kode negara + NSN)
+const (
+	minE164Length = 8
+	maxE164Length = 15
+)
+
+// FallbackRegion dipakai jika DEFAULT_PHONE_COUNTRY tidak diset (perilaku lama: Indonesia)
+const FallbackRegion = "ID"
+
+var (
+	ErrInvalidCharacters = errors.New("invalid phone number format: contains invalid characters")
+	ErrTooShort          = errors.New("phone number too short")
+	ErrTooLong           = errors.New("phone number too long")
+
+	validFormat = regexp.MustCompile(`^[\d\s\+\-\(\)\.]+$`)
+	nonDigit    = regexp.MustCompile(`[^\d]`)
+)
+
+// DefaultRegion returns default country global (env DEFAULT_PHONE_COUNTRY, default ID)
+func DefaultRegion() string {
+	if region := strings.ToUpper(strings.TrimSpace(os.Getenv("DEFAULT_PHONE_COUNTRY"))); IsSupportedRegion(region) {
+		return region
+	}
+	return FallbackRegion
+}
+
+// IsSupportedRegion mengecek apakah kode negara (ISO alpha-2) ada di metadata
+func IsSupportedRegion(region string) bool {
+	_, ok := byRegion[strings.ToUpper(region)]
+	return ok
+}
+
+// Lookup returns metadata negara berdasarkan kode ISO alpha-2
+func Lookup(region string) (Country, bool) {
+	c, ok := byRegion[strings.ToUpper(region)]
+	return c, ok
+}
+
+// Countries returns semua negara yang dikenal (urutan sesuai tabel metadata)
+func Countries() []Country {
+	list := make([]Country, len(countries))
+	copy(list, countries)
+	return list
+}
+
+// validNSN mengecek panjang dan awalan national significant number
+func (c Country) validNSN(nsn string) bool {
+	if len(nsn) < c.MinLength || len(nsn) > c.MaxLength {
+		return false
+	}
+	return len(c.Leading) == 0 || hasAnyPrefix(nsn, c.Leading)
+}
+
+// Normalize mengubah nomor ke E.164 tanpa '+' (mis. "0812-3456-7890" -> "6281234567890").
+// region adalah default country untuk nomor nasional; kosong = DefaultRegion().
+//
+// Urutan pengecekan untuk nomor tanpa '+' / '00':
+//  1. sudah diawali kode negara default (mis. 628xx)
+//  2. diawali trunk prefix negara default (mis. 08xx)
+//  3. nomor nasional tanpa trunk prefix (mis. 8xx di Indonesia, 9xxx xxxx di Singapura)
+//  4. diawali kode negara lain (mis. 60xx untuk Malaysia)
+func Normalize(raw, region string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !validFormat.MatchString(raw) {
+		return "", ErrInvalidCharacters
+	}
+
+	international := strings.HasPrefix(raw, "+")
+	digits := nonDigit.ReplaceAllString(raw, "")
+	if !international && strings.HasPrefix(digits, "00") {
+		international = true
+		digits = digits[2:]
+	}
+
+	if len(digits) < minE164Length-1 {
+		return "", ErrTooShort
+	}
+
+	if international {
+		return parseInternational(digits)
+	}
+
+	country, ok := Lookup(region)
+	if !ok {
+		country, _ = Lookup(DefaultRegion())
+	}
+
+	if strings.HasPrefix(digits, country.CallingCode) && country.validNSN(digits[len(country.CallingCode):]) {
+		return digits, nil
+	}
+
+	if country.TrunkPrefix != "" && strings.HasPrefix(digits, country.TrunkPrefix) {
+		nsn := digits[len(country.TrunkPrefix):]
+		if country.validNSN(nsn) {
+			return country.CallingCode + nsn, nil
+		}
+		// US/CA: trunk prefix sama dengan kode negara, biarkan dicek sebagai nomor internasional
+		if country.TrunkPrefix != country.CallingCode {
+			return "", fmt.Errorf("invalid %s phone number", country.Name)
+		}
+	}
+
+	bareNational := country.TrunkPrefix == "" || country.TrunkPrefix == country.CallingCode || hasAnyPrefix(digits, country.Mobile)
+	if bareNational && country.validNSN(digits) {
+		return country.CallingCode + digits, nil
+	}
+
+	if number, err := parseInternational(digits); err == nil {
+		return number, nil
+	}
+
+	return "", fmt.Errorf("invalid phone number for %s, use international format (e.g. +%s...)", country.Name, country.CallingCode)
+}
+
+// parseInternational memvalidasi nomor yang sudah diawali kode negara
+func parseInternational(digits string) (string, error) {
+	if len(digits) < minE164Length {
+		return "", ErrTooShort
+	}
+	if len(digits) > maxE164Length {
+		return "", ErrTooLong
+	}
+
+	// Kode negara 1-3 digit, cari yang dikenal di metadata
+	for l := 1; l <= 3 && l < len(digits); l++ {
+		candidates, ok := byCallingCode[digits[:l]]
+		if !ok {
+			continue
+		}
+		nsn := digits[l:]
+		for _, c := range candidates {
+			if c.validNSN(nsn) {
+				return digits, nil
+			}
+		}
+		return "", fmt.Errorf("invalid %s phone number", candidates[0].Name)
+	}
+
+	// Kode negara di luar metadata: cukup valid panjang E.164
+	return digits, nil
+}
+
+// Split memecah nomor E.164 menjadi negara dan NSN (ok=false jika kode negara tidak dikenal)
+func Split(e164 string) (Country, string, bool) {
+	for l := 1; l <= 3 && l < len(e164); l++ {
+		if candidates, ok := byCallingCode[e164[:l]]; ok {
+			return candidates[0], e164[l:], true
+		}
+	}
+	return Country{}, "", false
+}
+
+// Variants returns bentuk-bentuk penulisan yang setara untuk nomor yang sama
+// (E.164 dan format nasional dengan trunk prefix), dipakai untuk dedup outbox
+// yang destination-nya tidak selalu sudah dinormalkan. Input yang bukan nomor valid
+// dikembalikan apa adanya.
+func Variants(raw, region string) []string {
+	variants := []string{raw}
+	add := func(v string) {
+		for _, existing := range variants {
+			if existing == v {
+				return
+			}
+		}
+		variants = append(variants, v)
+	}
+
+	e164, err := Normalize(raw, region)
+	if err != nil {
+		return variants
+	}
+
+	add(e164)
+	add("+" + e164)
+	if country, nsn, ok := Split(e164); ok && country.TrunkPrefix != "" && country.TrunkPrefix != country.CallingCode {
+		add(country.TrunkPrefix + nsn)
+	}
+
+	return variants
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
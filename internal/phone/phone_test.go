@@ -0,0 +1,94 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Setenv("DEFAULT_PHONE_COUNTRY", "")
+
+	tests := []struct {
+		name    string
+		raw     string
+		region  string
+		want    string
+		err     error // sentinel yang diharapkan (nil = cukup wantErr)
+		wantErr bool
+	}{
+		// Indonesia
+		{name: "ID trunk prefix", raw: "0812-3456-7890", region: "ID", want: "6281234567890"},
+		{name: "ID plus calling code", raw: "+62 812 3456 7890", region: "ID", want: "6281234567890"},
+		{name: "ID calling code without plus", raw: "6281234567890", region: "ID", want: "6281234567890"},
+		{name: "ID bare mobile", raw: "81234567890", region: "ID", want: "6281234567890"},
+		{name: "ID 00 prefix", raw: "0062 812 3456 7890", region: "ID", want: "6281234567890"},
+		{name: "ID landline", raw: "(021) 555-1234", region: "ID", want: "62215551234"},
+		{name: "ID invalid leading digit", raw: "0712345678", region: "ID", wantErr: true},
+		{name: "empty region falls back to ID", raw: "081234567890", region: "", want: "6281234567890"},
+		{name: "unknown region falls back to ID", raw: "081234567890", region: "XX", want: "6281234567890"},
+
+		// Malaysia
+		{name: "MY trunk prefix", raw: "012-345 6789", region: "MY", want: "60123456789"},
+		{name: "MY bare mobile", raw: "123456789", region: "MY", want: "60123456789"},
+		{name: "MY calling code without plus", raw: "60123456789", region: "MY", want: "60123456789"},
+		{name: "MY number with ID default", raw: "60123456789", region: "ID", want: "60123456789"},
+		{name: "MY 00 prefix with ID default", raw: "0060 12 345 6789", region: "ID", want: "60123456789"},
+
+		// Singapura (tanpa trunk prefix)
+		{name: "SG national", raw: "9123 4567", region: "SG", want: "6591234567"},
+		{name: "SG calling code without plus", raw: "6591234567", region: "SG", want: "6591234567"},
+		{name: "SG plus calling code with ID default", raw: "+65 9123 4567", region: "ID", want: "6591234567"},
+		{name: "SG national with ID default is read as India", raw: "91234567", region: "ID", wantErr: true},
+		{name: "SG invalid leading digit", raw: "+65 1234 5678", region: "SG", wantErr: true},
+
+		// Amerika Serikat: trunk prefix sama dengan kode negara
+		{name: "US national", raw: "(415) 555-2671", region: "US", want: "14155552671"},
+		{name: "US with trunk prefix", raw: "1 415 555 2671", region: "US", want: "14155552671"},
+		{name: "US plus calling code with ID default", raw: "+1 415 555 2671", region: "ID", want: "14155552671"},
+		{name: "US 00 prefix with ID default", raw: "001 415 555 2671", region: "ID", want: "14155552671"},
+		{name: "US too many digits", raw: "+1 4155 552 6710", region: "US", wantErr: true},
+
+		// Panjang dan karakter
+		{name: "invalid characters", raw: "0812-abc-7890", region: "ID", err: ErrInvalidCharacters},
+		{name: "empty", raw: "", region: "ID", err: ErrInvalidCharacters},
+		{name: "too short national", raw: "08123", region: "ID", err: ErrTooShort},
+		{name: "too short international", raw: "+1 415 555", region: "ID", err: ErrTooShort},
+		{name: "too short after 00", raw: "00 6281 23", region: "ID", err: ErrTooShort},
+		{name: "too long international", raw: "+62 8123 4567 8901 234", region: "ID", err: ErrTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.raw, tt.region)
+
+			if tt.err != nil || tt.wantErr {
+				if err == nil {
+					t.Fatalf("Normalize(%q, %q) = %q, want error", tt.raw, tt.region, got)
+				}
+				if tt.err != nil && !errors.Is(err, tt.err) {
+					t.Fatalf("Normalize(%q, %q) error = %v, want %v", tt.raw, tt.region, err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Normalize(%q, %q) error = %v", tt.raw, tt.region, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Normalize(%q, %q) = %q, want %q", tt.raw, tt.region, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeDefaultRegionFromEnv(t *testing.T) {
+	t.Setenv("DEFAULT_PHONE_COUNTRY", "my")
+
+	got, err := Normalize("012-345 6789", "")
+	if err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if got != "60123456789" {
+		t.Fatalf("Normalize = %q, want 60123456789", got)
+	}
+}
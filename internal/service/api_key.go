@@ -0,0 +1,122 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"gowa-yourself/internal/model"
+)
+
+// Format API key: swa_<prefix>_<secret>. Prefix (publik) dipakai untuk lookup,
+// secret hanya disimpan sebagai hash SHA-256.
+const (
+	apiKeyTag         = "swa"
+	apiKeyPrefixBytes = 6
+	apiKeySecretBytes = 32
+)
+
+var (
+	ErrInvalidAPIKey      = errors.New("invalid api key")
+	ErrAPIKeyRevoked      = errors.New("api key revoked")
+	ErrAPIKeyExpired      = errors.New("api key expired")
+	ErrAPIKeyUserInactive = errors.New("api key owner is inactive")
+)
+
+// GenerateAPIKey mengisi Prefix dan KeyHash lalu menyimpan key; returns plaintext key (hanya ditampilkan sekali)
+func GenerateAPIKey(key *model.APIKey) (string, error) {
+	prefix, err := randomHex(apiKeyPrefixBytes)
+	if err != nil {
+		return "", err
+	}
+	secret, err := randomHex(apiKeySecretBytes)
+	if err != nil {
+		return "", err
+	}
+
+	key.Prefix = apiKeyTag + "_" + prefix
+	key.KeyHash = hashAPIKeySecret(secret)
+	if err := model.CreateAPIKey(key); err != nil {
+		return "", err
+	}
+
+	return key.Prefix + "_" + secret, nil
+}
+
+// AuthenticateAPIKey memvalidasi key dari header X-API-Key dan mengembalikan claims pemiliknya
+// (role diambil dari data user sekarang, bukan saat key dibuat)
+func AuthenticateAPIKey(raw string) (*model.APIKey, *Claims, error) {
+	parts := strings.Split(strings.TrimSpace(raw), "_")
+	if len(parts) != 3 || parts[0] != apiKeyTag {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := model.GetAPIKeyByPrefix(parts[0] + "_" + parts[1])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKeySecret(parts[2])), []byte(key.KeyHash)) != 1 {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if key.RevokedAt.Valid {
+		return nil, nil, ErrAPIKeyRevoked
+	}
+	if key.ExpiresAt.Valid && time.Now().After(key.ExpiresAt.Time) {
+		return nil, nil, ErrAPIKeyExpired
+	}
+
+	user, err := model.GetUserByID(key.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, ErrAPIKeyUserInactive
+	}
+
+	return key, &Claims{UserID: user.ID, Username: user.Username, Role: user.Role}, nil
+}
+
+// APIKeyHasScope mengecek scope key. manage:instances sudah termasuk read:instances.
+func APIKeyHasScope(key *model.APIKey, scope string) bool {
+	for _, s := range key.Scopes {
+		if s == scope || (s == model.APIKeyScopeManageInstances && scope == model.APIKeyScopeReadInstances) {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyAllowsInstance mengecek allow-list instance key (kosong = semua instance milik user)
+func APIKeyAllowsInstance(key *model.APIKey, instanceID string) bool {
+	if len(key.InstanceIDs) == 0 {
+		return true
+	}
+	for _, id := range key.InstanceIDs {
+		if id == instanceID {
+			return true
+		}
+	}
+	return false
+}
+
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
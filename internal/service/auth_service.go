@@ -2,10 +2,13 @@
 package service
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"gowa-yourself/internal/helper"
@@ -16,10 +19,14 @@ import (
 
 // JWT configuration
 var (
-	jwtSecret               []byte
-	accessTokenExpiry       time.Duration
-	refreshTokenExpiry      time.Duration
-	maxRefreshTokensPerUser int
+	jwtSecret                []byte
+	accessTokenExpiry        time.Duration
+	refreshTokenExpiry       time.Duration
+	maxRefreshTokensPerUser  int
+	impersonationExpiry      time.Duration
+	registrationMode         string
+	twoFactorChallengeExpiry time.Duration
+	totpIssuer               string
 )
 
 // InitAuthConfig initializes authentication configuration from environment variables
@@ -47,6 +54,34 @@ func InitAuthConfig(secret string) {
 	} else {
 		maxRefreshTokensPerUser, _ = strconv.Atoi(maxTokens)
 	}
+
+	// Impersonation token expiry (default: 15 minutes, tanpa refresh token)
+	impersonationExpiry, _ = time.ParseDuration(os.Getenv("IMPERSONATION_TOKEN_EXPIRY"))
+	if impersonationExpiry <= 0 {
+		impersonationExpiry = 15 * time.Minute
+	}
+
+	// 2FA: masa berlaku challenge token login tahap kedua (default: 5 menit) dan nama issuer di authenticator app
+	twoFactorChallengeExpiry, _ = time.ParseDuration(os.Getenv("TWO_FACTOR_CHALLENGE_EXPIRY"))
+	if twoFactorChallengeExpiry <= 0 {
+		twoFactorChallengeExpiry = 5 * time.Minute
+	}
+	totpIssuer = os.Getenv("TOTP_ISSUER")
+	if totpIssuer == "" {
+		totpIssuer = "SUDEVWA"
+	}
+
+	// Registration mode for POST /register (default: open)
+	registrationMode = strings.ToLower(strings.TrimSpace(os.Getenv("REGISTRATION_MODE")))
+	if registrationMode != RegistrationInvite && registrationMode != RegistrationDisabled {
+		registrationMode = RegistrationOpen
+	}
+
+	// Brute-force protection untuk /login, /login/2fa, /refresh dan X-API-Key
+	initLoginGuard()
+
+	// SSO providers (Google / OIDC generik), aktif jika client ID diisi
+	initOIDCProviders()
 }
 
 // Claims represents JWT claims
@@ -54,9 +89,30 @@ type Claims struct {
 	UserID   int64  `json:"user_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	// Diisi saat login ulang setelah admin reset password: hanya /api/me* dan /api/logout yang boleh
+	PasswordChangeRequired bool `json:"pwd_change,omitempty"`
+	// Diisi pada token impersonation (admin login sebagai user untuk support)
+	ImpersonatorID       int64  `json:"impersonator_id,omitempty"`
+	ImpersonatorUsername string `json:"impersonator,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// IssuedAtTime returns the iat of the token (zero time if missing)
+func (c *Claims) IssuedAtTime() time.Time {
+	if c.IssuedAt == nil {
+		return time.Time{}
+	}
+	return c.IssuedAt.Time
+}
+
+// derivedKey menurunkan key HMAC dari JWT_SECRET untuk token dengan tujuan lain
+// (challenge 2FA, state SSO) sehingga tidak bisa dipakai sebagai access token
+func derivedKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
 // RegisterUser creates a new user account
 func RegisterUser(req model.CreateUserRequest) (*model.User, error) {
 	// Validate input
@@ -88,7 +144,7 @@ func RegisterUser(req model.CreateUserRequest) (*model.User, error) {
 	}
 
 	// Validate role
-	if role != "admin" && role != "user" && role != "viewer" {
+	if !IsValidRole(role) {
 		return nil, errors.New("invalid role")
 	}
 
@@ -154,9 +210,10 @@ func GenerateAccessToken(user *model.User) (string, error) {
 	expirationTime := time.Now().Add(accessTokenExpiry)
 
 	claims := &Claims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Role:     user.Role,
+		UserID:                 user.ID,
+		Username:               user.Username,
+		Role:                   user.Role,
+		PasswordChangeRequired: user.MustChangePassword,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -222,6 +279,17 @@ func RefreshAccessToken(refreshTokenString string) (string, *model.User, error)
 		return "", nil, errors.New("user account is disabled")
 	}
 
+	// Kebijakan 2FA diaktifkan setelah user login: wajib login ulang dan setup 2FA
+	if !user.TwoFactorEnabled {
+		required, err := TwoFactorRequired(user)
+		if err != nil {
+			return "", nil, err
+		}
+		if required {
+			return "", nil, ErrTwoFactorEnrollment
+		}
+	}
+
 	// Generate new access token
 	accessToken, err := GenerateAccessToken(user)
 	if err != nil {
@@ -255,7 +323,10 @@ func RevokeUserSession(refreshToken string) error {
 	return model.RevokeRefreshToken(refreshToken)
 }
 
-// RevokeAllUserSessions revokes all refresh tokens for a user
+// RevokeAllUserSessions revokes all refresh tokens for a user and every access token issued so far
 func RevokeAllUserSessions(userID int64) error {
-	return model.RevokeAllUserTokens(userID)
+	if err := model.RevokeAllUserTokens(userID); err != nil {
+		return err
+	}
+	return model.RevokeUserAccessTokens(userID)
 }
@@ -0,0 +1,382 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gowa-yourself/config"
+	"gowa-yourself/database"
+	"gowa-yourself/internal/model"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Jumlah instance maksimal yang diserahkan ke replica lain per putaran rebalance,
+// supaya penambahan node tidak memutus banyak device sekaligus
+const clusterRebalanceBatch = 2
+
+// ErrInstanceOwnedElsewhere dikembalikan jika lease instance sedang dipegang replica lain
+var ErrInstanceOwnedElsewhere = errors.New("instance is owned by another node")
+
+// StartCluster mendaftarkan node ini lalu menjalankan dua loop:
+//   - heartbeat: perbarui cluster_nodes, perpanjang lease session lokal, lepas session yang lease-nya hilang
+//   - claim: ambil alih instance tanpa lease yang jatuh ke shard node ini (pengganti LoadAllDevices)
+func StartCluster() {
+	if err := model.UpsertClusterNode(config.ClusterNodeID, config.ClusterAdvertiseURL); err != nil {
+		log.Printf("⚠️ Cluster: failed to register node %s: %v", config.ClusterNodeID, err)
+	}
+	log.Printf("🧩 Cluster node %s started (lease ttl %v, heartbeat %v, forward mode %s)",
+		config.ClusterNodeID, config.ClusterLeaseTTL, config.ClusterHeartbeatInterval, config.ClusterForwardMode)
+
+	go clusterHeartbeatLoop()
+	go clusterClaimLoop()
+}
+
+// IsLocalInstance mengecek apakah session instance ada di replica ini
+func IsLocalInstance(instanceID string) bool {
+	sessionsLock.RLock()
+	defer sessionsLock.RUnlock()
+	_, ok := sessions[instanceID]
+	return ok
+}
+
+// LocalInstanceIDs returns instance yang session-nya ada di replica ini
+func LocalInstanceIDs() []string {
+	sessionsLock.RLock()
+	defer sessionsLock.RUnlock()
+	ids := make([]string, 0, len(sessions))
+	for id := range sessions {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// clusterForwardMaxSkew adalah umur maksimal signature request yang diteruskan antar replica
+const clusterForwardMaxSkew = time.Minute
+
+// Header untuk request yang diteruskan ke replica lain
+const (
+	ClusterForwardedByHeader = "X-Sudevwa-Forwarded-By"
+	ClusterForwardTimeHeader = "X-Sudevwa-Forward-Timestamp"
+	ClusterForwardSignHeader = "X-Sudevwa-Forward-Signature"
+)
+
+// clusterForwardKey returns CLUSTER_SECRET, atau key turunan JWT_SECRET (sudah sama di semua replica)
+func clusterForwardKey() []byte {
+	if config.ClusterSecret != "" {
+		return []byte(config.ClusterSecret)
+	}
+	return derivedKey("cluster-forward")
+}
+
+// clusterForwardSignature = HMAC(node, timestamp, method, request URI, SHA-256 body)
+func clusterForwardSignature(nodeID, timestamp, method, requestURI, bodyHash string) string {
+	mac := hmac.New(sha256.New, clusterForwardKey())
+	mac.Write([]byte(nodeID + "\n" + timestamp + "\n" + method + "\n" + requestURI + "\n" + bodyHash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// clusterForwardBodyHash membaca body request untuk di-hash lalu memasangnya kembali
+// supaya masih bisa dibaca proxy / handler
+func clusterForwardBodyHash(req *http.Request) (string, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// SignClusterForward menandai request yang akan diteruskan node ini ke replica pemilik instance
+func SignClusterForward(req *http.Request) error {
+	bodyHash, err := clusterForwardBodyHash(req)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(ClusterForwardedByHeader, config.ClusterNodeID)
+	req.Header.Set(ClusterForwardTimeHeader, timestamp)
+	req.Header.Set(ClusterForwardSignHeader, clusterForwardSignature(config.ClusterNodeID, timestamp, req.Method, req.URL.RequestURI(), bodyHash))
+	return nil
+}
+
+// VerifyClusterForward mengecek bahwa header forward benar-benar dibuat replica lain
+// (bukan dikirim client untuk melewati forwarding ke node pemilik) dan body tidak diubah
+func VerifyClusterForward(req *http.Request) bool {
+	nodeID := req.Header.Get(ClusterForwardedByHeader)
+	timestamp := req.Header.Get(ClusterForwardTimeHeader)
+	signature := req.Header.Get(ClusterForwardSignHeader)
+	if nodeID == "" || timestamp == "" || signature == "" {
+		return false
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > clusterForwardMaxSkew || age < -clusterForwardMaxSkew {
+		return false
+	}
+
+	bodyHash, err := clusterForwardBodyHash(req)
+	if err != nil {
+		return false
+	}
+
+	expected := clusterForwardSignature(nodeID, timestamp, req.Method, req.URL.RequestURI(), bodyHash)
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
+
+// StripClusterForward membuang header forward dari request client
+func StripClusterForward(req *http.Request) {
+	req.Header.Del(ClusterForwardedByHeader)
+	req.Header.Del(ClusterForwardTimeHeader)
+	req.Header.Del(ClusterForwardSignHeader)
+}
+
+// PreferredNode memilih node untuk instance dengan rendezvous hashing: setiap instance punya
+// urutan node yang stabil, jadi saat node bertambah / berkurang hanya ~1/N instance yang pindah.
+func PreferredNode(instanceID string, nodes []model.ClusterNode) string {
+	var best string
+	var bestScore uint64
+	for _, n := range nodes {
+		h := fnv.New64a()
+		h.Write([]byte(n.NodeID))
+		h.Write([]byte{'/'})
+		h.Write([]byte(instanceID))
+		if score := h.Sum64(); best == "" || score > bestScore {
+			best, bestScore = n.NodeID, score
+		}
+	}
+	return best
+}
+
+func clusterHeartbeatLoop() {
+	ticker := time.NewTicker(config.ClusterHeartbeatInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		clusterHeartbeat()
+	}
+}
+
+func clusterHeartbeat() {
+	if err := model.UpsertClusterNode(config.ClusterNodeID, config.ClusterAdvertiseURL); err != nil {
+		log.Printf("⚠️ Cluster: heartbeat failed: %v", err)
+		return
+	}
+
+	sessionsLock.RLock()
+	owned := make([]string, 0, len(sessions))
+	for id := range sessions {
+		owned = append(owned, id)
+	}
+	sessionsLock.RUnlock()
+
+	renewed, err := model.RenewInstanceLeases(config.ClusterNodeID, owned, config.ClusterLeaseTTL)
+	if err != nil {
+		// Jangan lepas session hanya karena DB sementara tidak bisa diakses
+		log.Printf("⚠️ Cluster: %v", err)
+		return
+	}
+
+	stillOwned := make(map[string]bool, len(renewed))
+	for _, id := range renewed {
+		stillOwned[id] = true
+	}
+	for _, id := range owned {
+		if !stillOwned[id] {
+			log.Printf("⚠️ Cluster: lease for %s taken over by another node, disconnecting local session", id)
+			dropLocalSession(id)
+		}
+	}
+
+	// Bersihkan node yang sudah lama mati supaya tabel tidak menumpuk
+	if err := model.DeleteStaleClusterNodes(24 * time.Hour); err != nil {
+		log.Printf("⚠️ Cluster: failed to clean stale nodes: %v", err)
+	}
+}
+
+func clusterClaimLoop() {
+	// Tunggu satu interval supaya replica yang start bersamaan sudah saling terdaftar
+	// sebelum membagi shard
+	time.Sleep(config.ClusterHeartbeatInterval)
+
+	for {
+		clusterClaimOrphans()
+		clusterRebalance()
+		time.Sleep(config.ClusterHeartbeatInterval)
+	}
+}
+
+// clusterClaimOrphans connect instance tanpa lease yang shard-nya jatuh ke node ini.
+// Termasuk semua device saat boot dan instance milik replica yang mati (lease expired).
+func clusterClaimOrphans() {
+	nodes, err := model.GetLiveClusterNodes(config.ClusterLeaseTTL)
+	if err != nil {
+		log.Printf("⚠️ Cluster: %v", err)
+		return
+	}
+
+	orphans, err := model.GetOrphanedInstances()
+	if err != nil {
+		log.Printf("⚠️ Cluster: %v", err)
+		return
+	}
+
+	connected := 0
+	for _, o := range orphans {
+		if PreferredNode(o.InstanceID, nodes) != config.ClusterNodeID || IsLocalInstance(o.InstanceID) {
+			continue
+		}
+
+		jid, err := types.ParseJID(o.JID)
+		if err != nil {
+			continue
+		}
+		device, err := database.Container.GetDevice(context.Background(), jid)
+		if err != nil || device == nil || device.ID == nil {
+			// Device sudah logout / dihapus dari store whatsmeow
+			continue
+		}
+
+		// Random delay antar connect seperti LoadAllDevices (hindari pola bot farm)
+		if connected > 0 {
+			time.Sleep(time.Duration(rand.Intn(8)+3) * time.Second)
+		}
+
+		acquired, err := model.TryAcquireInstanceLease(o.InstanceID, config.ClusterNodeID, config.ClusterLeaseTTL)
+		if err != nil {
+			log.Printf("⚠️ Cluster: %v", err)
+			continue
+		}
+		if !acquired {
+			continue
+		}
+
+		if err := connectDevice(device, o.InstanceID); err != nil {
+			log.Printf("⚠️ Cluster: failed to connect %s (instance %s): %v", o.JID, o.InstanceID, err)
+			releaseInstanceLease(o.InstanceID)
+			continue
+		}
+		connected++
+		log.Printf("🧩 Cluster: node %s took over instance %s", config.ClusterNodeID, o.InstanceID)
+	}
+}
+
+// clusterRebalance menyerahkan sebagian kecil session yang shard-nya milik node lain yang hidup
+// (mis. setelah replica baru bergabung). Node tujuan akan mengambilnya lewat clusterClaimOrphans.
+func clusterRebalance() {
+	nodes, err := model.GetLiveClusterNodes(config.ClusterLeaseTTL)
+	if err != nil || len(nodes) < 2 {
+		return
+	}
+
+	handedOff := 0
+	for id, session := range GetAllSessions() {
+		if handedOff >= clusterRebalanceBatch {
+			return
+		}
+		// Hanya device yang sudah login dan stabil; QR / pairing yang sedang berjalan jangan diganggu
+		if session.JID == "" || !session.IsConnected || isLoggingOut(id) {
+			continue
+		}
+		if preferred := PreferredNode(id, nodes); preferred != config.ClusterNodeID {
+			log.Printf("🧩 Cluster: handing instance %s over to node %s", id, preferred)
+			dropLocalSession(id)
+			releaseInstanceLease(id)
+			handedOff++
+		}
+	}
+}
+
+// acquireInstanceLease dipanggil sebelum session baru dibuat di node ini (no-op jika cluster mati)
+func acquireInstanceLease(instanceID string) error {
+	if !config.ClusterEnabled {
+		return nil
+	}
+
+	acquired, err := model.TryAcquireInstanceLease(instanceID, config.ClusterNodeID, config.ClusterLeaseTTL)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return fmt.Errorf("%w: %s", ErrInstanceOwnedElsewhere, instanceID)
+	}
+	return nil
+}
+
+// releaseInstanceLease melepas lease instance milik node ini (no-op jika cluster mati)
+func releaseInstanceLease(instanceID string) {
+	if !config.ClusterEnabled {
+		return
+	}
+	if err := model.ReleaseInstanceLease(instanceID, config.ClusterNodeID); err != nil {
+		log.Printf("⚠️ Cluster: %v", err)
+	}
+}
+
+// handleStreamReplaced: di mode cluster, StreamReplaced biasanya berarti replica lain sudah
+// connect device yang sama. Jika lease bukan milik node ini lagi, session lokal dilepas.
+func handleStreamReplaced(instanceID string) {
+	if !config.ClusterEnabled {
+		return
+	}
+
+	owner, err := model.GetInstanceLeaseOwner(instanceID)
+	if err != nil {
+		log.Printf("⚠️ Cluster: %v", err)
+		return
+	}
+	if owner != nil && owner.NodeID != config.ClusterNodeID {
+		log.Printf("⚠️ Cluster: instance %s now owned by node %s, dropping local session", instanceID, owner.NodeID)
+		// Dipanggil dari dalam event handler whatsmeow: RemoveEventHandlers harus di goroutine lain
+		go dropLocalSession(instanceID)
+	}
+}
+
+// dropLocalSession memutus session dari memory TANPA logout device dan tanpa mengubah status di DB,
+// karena device akan langsung di-connect ulang oleh replica lain
+func dropLocalSession(instanceID string) {
+	sessionsLock.Lock()
+	session, ok := sessions[instanceID]
+	delete(sessions, instanceID)
+	sessionsLock.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if session.HeartbeatCancel != nil {
+		session.HeartbeatCancel()
+	}
+	if session.Client != nil {
+		session.Client.RemoveEventHandlers()
+		session.Client.Disconnect()
+	}
+}
+
+func isLoggingOut(instanceID string) bool {
+	loggingOutLock.RLock()
+	defer loggingOutLock.RUnlock()
+	return loggingOut[instanceID]
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/ws"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// handleGroupInfo menerjemahkan *events.GroupInfo ke GROUP_PARTICIPANTS_CHANGED (satu event per
+// action) dan GROUP_UPDATED (jika ada perubahan info / setting), lalu menyimpannya ke group_events.
+func handleGroupInfo(instanceID string, v *events.GroupInfo) {
+	groupJID := v.JID.String()
+	actor := groupActor(instanceID, v.Sender, v.SenderPN)
+	timestamp := v.Timestamp.UTC()
+	if timestamp.IsZero() {
+		timestamp = time.Now().UTC()
+	}
+
+	participantChanges := []struct {
+		action string
+		jids   []types.JID
+	}{
+		{"join", v.Join},
+		{"leave", v.Leave},
+		{"promote", v.Promote},
+		{"demote", v.Demote},
+	}
+	for _, change := range participantChanges {
+		if len(change.jids) == 0 {
+			continue
+		}
+
+		data := ws.GroupParticipantsChangedData{
+			InstanceID:   instanceID,
+			GroupJID:     groupJID,
+			Action:       change.action,
+			Participants: groupParticipantJIDs(instanceID, change.jids),
+			Actor:        actor,
+			Timestamp:    timestamp,
+		}
+		if change.action == "join" {
+			data.Reason = v.JoinReason
+		}
+
+		publishGroupEvent(ws.EventGroupParticipantsChanged, change.action, data.Participants, data, instanceID, groupJID, actor, timestamp)
+	}
+
+	updated := ws.GroupUpdatedData{
+		InstanceID: instanceID,
+		GroupJID:   groupJID,
+		Actor:      actor,
+		Timestamp:  timestamp,
+	}
+	changed := false
+
+	if v.Name != nil {
+		updated.Subject = &v.Name.Name
+		changed = true
+	}
+	if v.Topic != nil {
+		description := v.Topic.Topic
+		if v.Topic.TopicDeleted {
+			description = ""
+		}
+		updated.Description = &description
+		changed = true
+	}
+	if v.Announce != nil {
+		updated.Announce = &v.Announce.IsAnnounce
+		changed = true
+	}
+	if v.Locked != nil {
+		updated.Locked = &v.Locked.IsLocked
+		changed = true
+	}
+	if v.Ephemeral != nil {
+		timer := v.Ephemeral.DisappearingTimer
+		if !v.Ephemeral.IsEphemeral {
+			timer = 0
+		}
+		updated.EphemeralTimer = &timer
+		changed = true
+	}
+	if v.MembershipApprovalMode != nil {
+		updated.JoinApprovalRequired = &v.MembershipApprovalMode.IsJoinApprovalRequired
+		changed = true
+	}
+	if v.NewInviteLink != nil {
+		updated.InviteLinkChanged = true
+		changed = true
+	}
+	if v.Delete != nil && v.Delete.Deleted {
+		updated.Deleted = true
+		changed = true
+	}
+
+	if changed {
+		publishGroupEvent(ws.EventGroupUpdated, "", nil, updated, instanceID, groupJID, actor, timestamp)
+	}
+}
+
+// handleJoinedGroup dipanggil ketika instance ditambahkan ke grup, membuat grup, atau join via link
+func handleJoinedGroup(instanceID string, v *events.JoinedGroup) {
+	groupJID := v.JID.String()
+	actor := groupActor(instanceID, v.Sender, v.SenderPN)
+	timestamp := time.Now().UTC()
+
+	data := ws.GroupJoinedData{
+		InstanceID:       instanceID,
+		GroupJID:         groupJID,
+		Name:             v.Name,
+		Actor:            actor,
+		Reason:           v.Reason,
+		Type:             v.Type,
+		ParticipantCount: len(v.Participants),
+		Timestamp:        timestamp,
+	}
+
+	publishGroupEvent(ws.EventGroupJoined, v.Reason, nil, data, instanceID, groupJID, actor, timestamp)
+}
+
+// publishGroupEvent menyimpan event ke riwayat group_events lalu mengirimnya lewat Realtime
+// (WebSocket global & listener instance, plus webhook instance yang subscribe)
+func publishGroupEvent(eventType, action string, participants []string, data interface{}, instanceID, groupJID, actor string, timestamp time.Time) {
+	details, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("⚠️ Failed to marshal %s for group %s: %v", eventType, groupJID, err)
+	}
+
+	record := &model.GroupEvent{
+		InstanceID:     instanceID,
+		GroupJID:       groupJID,
+		EventType:      eventType,
+		Participants:   participants,
+		Details:        details,
+		EventTimestamp: timestamp,
+	}
+	if action != "" {
+		record.Action.String, record.Action.Valid = action, true
+	}
+	if actor != "" {
+		record.ActorJID.String, record.ActorJID.Valid = actor, true
+	}
+	if err := model.SaveGroupEvent(record); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+
+	if Realtime != nil {
+		Realtime.Publish(ws.WsEvent{
+			Event:     eventType,
+			Timestamp: time.Now().UTC(),
+			Data:      data,
+		})
+	}
+}
+
+// groupActor returns JID (nomor telepon jika LID bisa di-resolve) dari admin yang melakukan perubahan
+func groupActor(instanceID string, sender, senderPN *types.JID) string {
+	if senderPN != nil && !senderPN.IsEmpty() {
+		return senderPN.ToNonAD().String()
+	}
+	if sender == nil || sender.IsEmpty() {
+		return ""
+	}
+	return groupParticipantJIDs(instanceID, []types.JID{*sender})[0]
+}
+
+// groupParticipantJIDs mengubah JID peserta ke string, LID di-resolve ke nomor telepon jika bisa
+func groupParticipantJIDs(instanceID string, jids []types.JID) []string {
+	list := make([]string, 0, len(jids))
+	for _, jid := range jids {
+		jid = jid.ToNonAD()
+		if jid.Server == types.HiddenUserServer {
+			if number := ResolveSenderNumber(instanceID, jid); number != jid.User {
+				jid = types.NewJID(number, types.DefaultUserServer)
+			}
+		}
+		list = append(list, jid.String())
+	}
+	return list
+}
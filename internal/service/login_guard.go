@@ -0,0 +1,207 @@
+package service
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/ws"
+)
+
+// Brute-force protection: kegagalan dihitung per username dan per IP (tabel auth_failures).
+// Setelah kegagalan ke-2 request berikutnya harus menunggu (1s, 2s, 4s, ... maks loginMaxDelay),
+// dan setelah batas tercapai key dikunci selama LOGIN_LOCKOUT_DURATION.
+const (
+	AttemptScopeUsername  = "username"
+	AttemptScopeLoginIP   = "login_ip"
+	AttemptScopeRefreshIP = "refresh_ip"
+	AttemptScopeAPIKeyIP  = "api_key_ip"
+
+	loginBaseDelay = time.Second
+	loginMaxDelay  = 30 * time.Second
+
+	// Counter yang tidak dikunci dan lebih lama dari ini dihapus
+	authFailureRetention = 24 * time.Hour
+)
+
+// Brute-force configuration
+var (
+	loginMaxAttempts        int
+	loginIPMaxAttempts      int
+	loginLockoutDuration    time.Duration
+	loginAttemptWindow      time.Duration
+	securityAlertWebhookURL string
+	securityAlertSecret     string
+)
+
+// AttemptKey identifies a failed-attempt counter (scope + username / IP)
+type AttemptKey struct {
+	Scope string
+	Key   string
+}
+
+// AttemptBlock describes why a request is rejected before credentials are checked
+type AttemptBlock struct {
+	Scope      string
+	Locked     bool // true = lockout, false = progressive delay
+	RetryAfter time.Duration
+}
+
+// initLoginGuard reads the brute-force configuration from environment variables
+func initLoginGuard() {
+	loginMaxAttempts = helper.GetEnvAsInt("LOGIN_MAX_ATTEMPTS", 5)
+	loginIPMaxAttempts = helper.GetEnvAsInt("LOGIN_IP_MAX_ATTEMPTS", 20)
+
+	loginLockoutDuration, _ = time.ParseDuration(os.Getenv("LOGIN_LOCKOUT_DURATION"))
+	if loginLockoutDuration <= 0 {
+		loginLockoutDuration = 15 * time.Minute
+	}
+	loginAttemptWindow, _ = time.ParseDuration(os.Getenv("LOGIN_ATTEMPT_WINDOW"))
+	if loginAttemptWindow <= 0 {
+		loginAttemptWindow = 15 * time.Minute
+	}
+
+	securityAlertWebhookURL = os.Getenv("SECURITY_ALERT_WEBHOOK_URL")
+	securityAlertSecret = os.Getenv("SECURITY_ALERT_WEBHOOK_SECRET")
+}
+
+// LoginAttemptKeys returns the counters for a login: username (case-insensitive) dan IP
+func LoginAttemptKeys(username, ip string) []AttemptKey {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if len(username) > 100 {
+		username = username[:100]
+	}
+	return []AttemptKey{
+		{Scope: AttemptScopeUsername, Key: username},
+		{Scope: AttemptScopeLoginIP, Key: ip},
+	}
+}
+
+// CheckAttempts returns a block jika salah satu key sedang dikunci atau masih dalam progressive delay.
+// Error database tidak memblokir login (fail open), hanya dicatat.
+func CheckAttempts(keys ...AttemptKey) *AttemptBlock {
+	now := time.Now()
+	for _, k := range keys {
+		if k.Key == "" {
+			continue
+		}
+
+		f, err := model.GetAuthFailure(k.Scope, k.Key)
+		if err != nil {
+			log.Printf("⚠️ Failed to check auth failures for %s %s: %v", k.Scope, k.Key, err)
+			continue
+		}
+		if f == nil {
+			continue
+		}
+
+		if f.LockedUntil.Valid && f.LockedUntil.Time.After(now) {
+			return &AttemptBlock{Scope: k.Scope, Locked: true, RetryAfter: f.LockedUntil.Time.Sub(now)}
+		}
+		if f.LockedUntil.Valid || now.Sub(f.LastFailedAt) > loginAttemptWindow {
+			continue
+		}
+		if wait := f.LastFailedAt.Add(progressiveDelay(f.Failures)).Sub(now); wait > 0 {
+			return &AttemptBlock{Scope: k.Scope, RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// RecordFailedAttempt menambah counter key; jika key baru saja dikunci, kirim security alert.
+// Returns counter terbaru (nil jika gagal disimpan).
+func RecordFailedAttempt(k AttemptKey, ip string) *model.AuthFailure {
+	if k.Key == "" {
+		return nil
+	}
+
+	f, locked, err := model.IncrementAuthFailure(k.Scope, k.Key, loginAttemptWindow, maxAttemptsFor(k.Scope), loginLockoutDuration)
+	if err != nil {
+		log.Printf("⚠️ Failed to record auth failure for %s %s: %v", k.Scope, k.Key, err)
+		return nil
+	}
+
+	if locked {
+		log.Printf("🔒 %s %s locked until %s after %d failed attempts", k.Scope, k.Key, f.LockedUntil.Time.Format(time.RFC3339), f.Failures)
+		go sendSecurityAlert(ws.SecurityAlertData{
+			Type:        "lockout",
+			Scope:       k.Scope,
+			Key:         k.Key,
+			Failures:    f.Failures,
+			IPAddress:   ip,
+			LockedUntil: f.LockedUntil.Time,
+			Timestamp:   time.Now().UTC(),
+		})
+		go func() {
+			if err := model.DeleteStaleAuthFailures(authFailureRetention); err != nil {
+				log.Printf("⚠️ Failed to prune auth failures: %v", err)
+			}
+		}()
+	}
+
+	return f
+}
+
+// ClearFailedAttempts menghapus counter (login berhasil atau unlock oleh admin)
+func ClearFailedAttempts(k AttemptKey) (bool, error) {
+	return model.ClearAuthFailure(k.Scope, k.Key)
+}
+
+// ListFailedAttempts returns counters that are locked or still inside the attempt window
+func ListFailedAttempts() ([]model.AuthFailure, error) {
+	return model.ListAuthFailures(loginAttemptWindow)
+}
+
+// IsValidAttemptScope checks the scope name used by the admin unlock endpoint
+func IsValidAttemptScope(scope string) bool {
+	switch scope {
+	case AttemptScopeUsername, AttemptScopeLoginIP, AttemptScopeRefreshIP, AttemptScopeAPIKeyIP:
+		return true
+	}
+	return false
+}
+
+// maxAttemptsFor: IP di belakang NAT bisa dipakai banyak user, jadi batasnya lebih longgar
+func maxAttemptsFor(scope string) int {
+	if scope == AttemptScopeUsername {
+		return loginMaxAttempts
+	}
+	return loginIPMaxAttempts
+}
+
+// progressiveDelay: 0 untuk kegagalan pertama, lalu 1s, 2s, 4s, ... dibatasi loginMaxDelay
+func progressiveDelay(failures int) time.Duration {
+	if failures < 2 {
+		return 0
+	}
+
+	delay := loginBaseDelay
+	for i := 2; i < failures && delay < loginMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > loginMaxDelay {
+		delay = loginMaxDelay
+	}
+	return delay
+}
+
+// sendSecurityAlert mengirim SECURITY_ALERT ke admin lewat WebSocket / SSE dan ke SECURITY_ALERT_WEBHOOK_URL
+func sendSecurityAlert(data ws.SecurityAlertData) {
+	if Realtime != nil {
+		Realtime.Publish(ws.WsEvent{
+			Event:     ws.EventSecurityAlert,
+			Timestamp: data.Timestamp,
+			Data:      data,
+		})
+	}
+
+	if securityAlertWebhookURL != "" {
+		EnqueueWebhook("", ws.EventSecurityAlert, WebhookEndpoint{
+			URL:    securityAlertWebhookURL,
+			Secret: securityAlertSecret,
+		}, data)
+	}
+}
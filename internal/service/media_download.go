@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// mediaExtensions adalah allow-list ekstensi key storage per mime. Nama file dari pengirim tidak dipakai,
+// supaya key tidak pernah berakhiran .html / .svg (storage lokal menebak Content-Type dari ekstensi).
+var mediaExtensions = map[string]string{
+	"image/jpeg":               ".jpg",
+	"image/png":                ".png",
+	"image/webp":               ".webp",
+	"image/gif":                ".gif",
+	"video/mp4":                ".mp4",
+	"video/3gpp":               ".3gp",
+	"audio/ogg":                ".ogg",
+	"audio/mpeg":               ".mp3",
+	"audio/mp4":                ".m4a",
+	"audio/aac":                ".aac",
+	"audio/amr":                ".amr",
+	"application/pdf":          ".pdf",
+	"application/zip":          ".zip",
+	"text/plain":               ".txt",
+	"text/csv":                 ".csv",
+	"application/msword":       ".doc",
+	"application/vnd.ms-excel": ".xls",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   ".docx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         ".xlsx",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": ".pptx",
+}
+
+// IncomingMediaMaxBytes batas ukuran media masuk yang di-download (INCOMING_MEDIA_MAX_MB, default 100)
+func IncomingMediaMaxBytes() int64 {
+	return int64(helper.GetEnvAsInt("INCOMING_MEDIA_MAX_MB", 100)) * 1024 * 1024
+}
+
+// mediaExtension menentukan ekstensi file dari allow-list mime, lalu dari tipe pesan (selain itu .bin)
+func mediaExtension(content MessageContent) string {
+	mimeType := strings.ToLower(strings.TrimSpace(strings.Split(content.Mime, ";")[0]))
+	if ext, ok := mediaExtensions[mimeType]; ok {
+		return ext
+	}
+
+	switch content.Type {
+	case "image":
+		return ".jpg"
+	case "video":
+		return ".mp4"
+	case "audio":
+		return ".ogg"
+	case "sticker":
+		return ".webp"
+	}
+	return ".bin"
+}
+
+// mediaDownloadJob adalah media pesan masuk yang menunggu di-download
+type mediaDownloadJob struct {
+	instanceID string
+	messageID  string
+	content    MessageContent
+}
+
+var (
+	mediaDownloadQueue chan mediaDownloadJob
+	mediaDownloadOnce  sync.Once
+)
+
+// startMediaDownloadWorkers menjalankan INCOMING_MEDIA_WORKERS goroutine (default 4) dengan antrian
+// INCOMING_MEDIA_QUEUE_SIZE (default 500). Dipanggil sekali saat media pertama di-queue.
+func startMediaDownloadWorkers() {
+	workers := helper.GetEnvAsInt("INCOMING_MEDIA_WORKERS", 4)
+	if workers < 1 {
+		workers = 1
+	}
+	queueSize := helper.GetEnvAsInt("INCOMING_MEDIA_QUEUE_SIZE", 500)
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	mediaDownloadQueue = make(chan mediaDownloadJob, queueSize)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range mediaDownloadQueue {
+				processMediaDownload(job)
+			}
+		}()
+	}
+}
+
+// QueueIncomingMedia menaruh media pesan masuk ke antrian download di background, supaya event handler
+// whatsmeow (pesan, receipt, history sync) tidak tertahan oleh download besar / lambat.
+// Returns false jika antrian penuh (media tidak di-download).
+func QueueIncomingMedia(instanceID, messageID string, content MessageContent) bool {
+	mediaDownloadOnce.Do(startMediaDownloadWorkers)
+
+	select {
+	case mediaDownloadQueue <- mediaDownloadJob{instanceID: instanceID, messageID: messageID, content: content}:
+		return true
+	default:
+		log.Printf("⚠️ Media download queue full, skipping %s media %s for instance %s", content.Type, messageID, instanceID)
+		return false
+	}
+}
+
+// processMediaDownload men-download satu media dengan client instance yang sedang aktif
+func processMediaDownload(job mediaDownloadJob) {
+	session, err := GetSession(job.instanceID)
+	if err != nil || session.Client == nil {
+		log.Printf("⚠️ Skipping media %s: instance %s is not connected", job.messageID, job.instanceID)
+		return
+	}
+
+	if _, err := DownloadIncomingMedia(session.Client, job.instanceID, job.messageID, job.content); err != nil {
+		log.Printf("⚠️ Failed to download %s media %s for instance %s: %v", job.content.Type, job.messageID, job.instanceID, err)
+	}
+}
+
+// DownloadIncomingMedia men-download media dari pesan masuk lewat whatsmeow, menyimpannya ke storage
+// (key media/<instanceId>/<messageId>.<ext>) dan mencatat key-nya di tabel messages. Returns key.
+func DownloadIncomingMedia(client *whatsmeow.Client, instanceID, messageID string, content MessageContent) (string, error) {
+	if content.Downloadable == nil {
+		return "", fmt.Errorf("message has no downloadable media")
+	}
+
+	if maxBytes := IncomingMediaMaxBytes(); int64(content.Size) > maxBytes {
+		return "", fmt.Errorf("media too large: %d bytes (max %d)", content.Size, maxBytes)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	data, err := client.Download(ctx, content.Downloadable)
+	if err != nil {
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+
+	// Content-Type di storage (dipakai langsung oleh URL S3) hanya tipe inline yang aman
+	contentType := content.Mime
+	if !helper.IsInlineContentType(contentType) {
+		contentType = "application/octet-stream"
+	}
+
+	key := incomingMediaKey(instanceID, messageID, content)
+	if err := helper.GetStorage().Put(ctx, key, data, contentType); err != nil {
+		return "", err
+	}
+
+	if err := model.UpdateMessageMediaPath(instanceID, messageID, key); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// incomingMediaKey adalah key storage media pesan masuk: media/<instanceId>/<messageId><ext>.
+// Deterministik, sehingga URL-nya bisa dikirim di payload sebelum download selesai.
+func incomingMediaKey(instanceID, messageID string, content MessageContent) string {
+	return path.Join("media", path.Base(instanceID), path.Base(messageID)+mediaExtension(content))
+}
+
+// mediaPayload mendeskripsikan isi pesan non-teks untuk payload WS/webhook.
+// mediaQueued = media sedang di-download di background; media_url (signed) mengembalikan 404
+// sampai download selesai.
+func mediaPayload(instanceID, messageID string, content MessageContent, mediaQueued bool) map[string]interface{} {
+	fields := map[string]interface{}{}
+
+	if content.Downloadable != nil {
+		fields["media_type"] = content.Type
+		fields["mime"] = content.Mime
+		fields["file_name"] = content.FileName
+		fields["size"] = content.Size
+		fields["sha256"] = content.SHA256
+		if mediaQueued {
+			fields["media_status"] = "downloading"
+			if mediaURL := helper.StorageURL(incomingMediaKey(instanceID, messageID, content)); mediaURL != "" {
+				fields["media_url"] = mediaURL
+			}
+		} else {
+			fields["media_status"] = "unavailable"
+		}
+	}
+
+	switch content.Type {
+	case "location":
+		fields["location"] = map[string]interface{}{
+			"latitude":  content.Latitude,
+			"longitude": content.Longitude,
+			"name":      content.LocationName,
+			"address":   content.Address,
+		}
+	case "contact":
+		fields["contact"] = map[string]interface{}{
+			"display_name": content.Body,
+			"vcard":        content.VCard,
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gowa-yourself/internal/model"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// Error untuk edit / revoke / pin, dipetakan ke HTTP status oleh handler
+var (
+	ErrSessionNotReady    = errors.New("session is not connected")
+	ErrMessageNotFound    = errors.New("message not found")
+	ErrNotOwnMessage      = errors.New("only messages sent by this instance can be edited")
+	ErrMessageNotEditable = errors.New("only text messages can be edited")
+	ErrEditWindowExpired  = errors.New("edit window has expired")
+	ErrMessageRevoked     = errors.New("message has already been revoked")
+)
+
+// Durasi pin yang didukung WhatsApp
+var PinDurations = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// loadActionTarget memastikan session siap dan pesan ada di inbox instance tsb
+func loadActionTarget(instanceID, messageID string) (*model.Session, *model.Message, types.JID, error) {
+	session, err := GetSession(instanceID)
+	if err != nil || !session.IsConnected || !session.Client.IsConnected() || session.Client.Store.ID == nil {
+		return nil, nil, types.JID{}, ErrSessionNotReady
+	}
+
+	msg, err := model.GetMessageByMessageID(instanceID, messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, types.JID{}, ErrMessageNotFound
+		}
+		return nil, nil, types.JID{}, err
+	}
+
+	chat, err := types.ParseJID(msg.ChatJID)
+	if err != nil {
+		return nil, nil, types.JID{}, fmt.Errorf("invalid chat JID %q: %w", msg.ChatJID, err)
+	}
+
+	return session, msg, chat, nil
+}
+
+// messageSender returns pengirim asli pesan (kosong = pesan milik kita sendiri)
+func messageSender(msg *model.Message) types.JID {
+	if msg.FromMe || !msg.SenderJID.Valid {
+		return types.EmptyJID
+	}
+	sender, err := types.ParseJID(msg.SenderJID.String)
+	if err != nil {
+		return types.EmptyJID
+	}
+	return sender
+}
+
+// EditMessage mengganti teks pesan keluar (hanya pesan teks, dalam whatsmeow.EditWindow)
+func EditMessage(instanceID, messageID, text string) (*model.Message, error) {
+	session, msg, chat, err := loadActionTarget(instanceID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !msg.FromMe {
+		return nil, ErrNotOwnMessage
+	}
+	if msg.RevokedAt.Valid {
+		return nil, ErrMessageRevoked
+	}
+	if msg.MessageType != "text" {
+		return nil, ErrMessageNotEditable
+	}
+	if time.Since(msg.MessageTimestamp) > whatsmeow.EditWindow {
+		return nil, ErrEditWindowExpired
+	}
+
+	edit := session.Client.BuildEdit(chat, messageID, &waE2E.Message{Conversation: proto.String(text)})
+	if _, err := session.Client.SendMessage(context.Background(), chat, edit); err != nil {
+		return nil, fmt.Errorf("failed to send edit: %w", err)
+	}
+
+	now := time.Now()
+	if err := model.MarkMessageEdited(instanceID, messageID, text, now); err != nil {
+		return nil, err
+	}
+	msg.Body = sql.NullString{String: text, Valid: true}
+	msg.EditedAt = sql.NullTime{Time: now, Valid: true}
+
+	return msg, nil
+}
+
+// RevokeMessage menghapus pesan untuk semua orang. Pesan orang lain hanya bisa di-revoke
+// di grup dan instance harus admin grup tsb (WhatsApp yang menolak kalau bukan admin).
+func RevokeMessage(instanceID, messageID string) (*model.Message, error) {
+	session, msg, chat, err := loadActionTarget(instanceID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.RevokedAt.Valid {
+		return nil, ErrMessageRevoked
+	}
+	if !msg.FromMe && !msg.IsGroup {
+		return nil, ErrNotOwnMessage
+	}
+
+	revoke := session.Client.BuildRevoke(chat, messageSender(msg), messageID)
+	if _, err := session.Client.SendMessage(context.Background(), chat, revoke); err != nil {
+		return nil, fmt.Errorf("failed to send revoke: %w", err)
+	}
+
+	now := time.Now()
+	if err := model.MarkMessageRevoked(instanceID, messageID, now); err != nil {
+		return nil, err
+	}
+	msg.RevokedAt = sql.NullTime{Time: now, Valid: true}
+
+	return msg, nil
+}
+
+// PinMessage pin (duration > 0) atau unpin (duration == 0) pesan di chat / grup
+func PinMessage(instanceID, messageID string, duration time.Duration) (*model.Message, error) {
+	session, msg, chat, err := loadActionTarget(instanceID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.RevokedAt.Valid {
+		return nil, ErrMessageRevoked
+	}
+
+	pinType := waE2E.PinInChatMessage_PIN_FOR_ALL
+	if duration == 0 {
+		pinType = waE2E.PinInChatMessage_UNPIN_FOR_ALL
+	}
+
+	pin := &waE2E.Message{
+		PinInChatMessage: &waE2E.PinInChatMessage{
+			Key:               session.Client.BuildMessageKey(chat, messageSender(msg), messageID),
+			Type:              pinType.Enum(),
+			SenderTimestampMS: proto.Int64(time.Now().UnixMilli()),
+		},
+	}
+	if duration > 0 {
+		pin.MessageContextInfo = &waE2E.MessageContextInfo{
+			MessageAddOnDurationInSecs: proto.Uint32(uint32(duration.Seconds())),
+		}
+	}
+
+	if _, err := session.Client.SendMessage(context.Background(), chat, pin); err != nil {
+		return nil, fmt.Errorf("failed to send pin: %w", err)
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/ws"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// receiptStatus memetakan tipe receipt WhatsApp ke status pesan.
+// Receipt lain (retry, sender, self, history sync, dll) tidak mengubah status.
+func receiptStatus(t types.ReceiptType) string {
+	switch t {
+	case types.ReceiptTypeDelivered:
+		return model.MessageStatusDelivered
+	case types.ReceiptTypeRead:
+		return model.MessageStatusRead
+	case types.ReceiptTypePlayed:
+		return model.MessageStatusPlayed
+	case types.ReceiptTypeServerError:
+		return model.MessageStatusFailed
+	default:
+		return ""
+	}
+}
+
+// handleReceipt memperbarui status pesan keluar dari *events.Receipt,
+// lalu meneruskannya ke outbox dan Realtime (WebSocket + webhook) sebagai event message_status.
+// Receipt grup dicatat per peserta (message_receipts); event-nya membawa status peserta tersebut.
+func handleReceipt(instanceID string, v *events.Receipt) {
+	// Receipt dari device kita sendiri (mis. membaca pesan masuk di HP) bukan status pesan keluar
+	if v.IsFromMe {
+		return
+	}
+
+	status := receiptStatus(v.Type)
+	if status == "" {
+		return
+	}
+
+	timestamp := v.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	updated, err := model.UpdateMessageStatus(instanceID, v.MessageIDs, status, timestamp)
+	if err != nil {
+		log.Printf("⚠️ Failed to update message status for instance %s: %v", instanceID, err)
+		return
+	}
+
+	if v.IsGroup {
+		handleGroupReceipt(instanceID, v, status, timestamp, updated)
+		return
+	}
+
+	for _, u := range updated {
+		// Application outbox ikut dikirim supaya client WS bisa subscribe topic outbox:<application>
+		var application string
+		if n, err := model.UpdateOutboxReceiptStatus(context.Background(), u.MessageID, status); err != nil {
+			log.Printf("⚠️ Failed to update outbox receipt for message %s: %v", u.MessageID, err)
+		} else if n > 0 {
+			application, _ = model.GetOutboxApplicationByWAMessageID(context.Background(), u.MessageID)
+		}
+
+		data := ws.MessageStatusData{
+			InstanceID:  instanceID,
+			MessageID:   u.MessageID,
+			ChatJID:     u.ChatJID,
+			Status:      status,
+			Recipient:   v.Sender.ToNonAD().String(),
+			Application: application,
+			Timestamp:   timestamp.UTC(),
+		}
+
+		// Realtime adalah WebhookPublisher: event juga diteruskan ke webhook instance yang subscribe
+		if Realtime != nil {
+			Realtime.Publish(ws.WsEvent{
+				Event:     ws.EventMessageStatus,
+				Timestamp: time.Now().UTC(),
+				Data:      data,
+			})
+		}
+	}
+}
+
+// handleGroupReceipt mencatat receipt satu peserta grup. messages.status (status terjauh dari peserta
+// mana pun) dan receipt_status outbox sudah diperbarui handleReceipt; event message_status dikirim
+// per peserta dengan group=true, jadi "read" berarti peserta tersebut membaca, bukan seluruh grup.
+func handleGroupReceipt(instanceID string, v *events.Receipt, status string, timestamp time.Time, aggregated []model.MessageStatusUpdate) {
+	for _, u := range aggregated {
+		if _, err := model.UpdateOutboxReceiptStatus(context.Background(), u.MessageID, status); err != nil {
+			log.Printf("⚠️ Failed to update outbox receipt for message %s: %v", u.MessageID, err)
+		}
+	}
+
+	participant := v.Sender.ToNonAD().String()
+	updated, err := model.UpsertGroupReceipts(instanceID, v.MessageIDs, participant, status, timestamp)
+	if err != nil {
+		log.Printf("⚠️ Failed to update group receipts for instance %s: %v", instanceID, err)
+		return
+	}
+
+	for _, u := range updated {
+		application, _ := model.GetOutboxApplicationByWAMessageID(context.Background(), u.MessageID)
+
+		if Realtime != nil {
+			Realtime.Publish(ws.WsEvent{
+				Event:     ws.EventMessageStatus,
+				Timestamp: time.Now().UTC(),
+				Data: ws.MessageStatusData{
+					InstanceID:  instanceID,
+					MessageID:   u.MessageID,
+					ChatJID:     u.ChatJID,
+					Status:      status,
+					Recipient:   participant,
+					Group:       true,
+					Application: application,
+					Timestamp:   timestamp.UTC(),
+				},
+			})
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"gowa-yourself/internal/model"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// MessageContent adalah ringkasan isi pesan WhatsApp yang dipakai untuk disimpan ke inbox.
+type MessageContent struct {
+	Type              string // text, image, video, audio, document, sticker, location, contact, reaction, poll, other
+	Body              string // text atau caption
+	Mime              string
+	FileName          string
+	Size              uint64
+	SHA256            string // hex, dari FileSHA256 proto
+	Latitude          float64
+	Longitude         float64
+	Address           string
+	LocationName      string
+	VCard             string
+	QuotedMessageID   string
+	QuotedParticipant string
+
+	// Downloadable diisi untuk pesan media (image, video, audio, document, sticker)
+	Downloadable whatsmeow.DownloadableMessage
+}
+
+// ExtractMessageContent membaca tipe, teks/caption, metadata media dan quoted message dari proto pesan.
+func ExtractMessageContent(msg *waE2E.Message) MessageContent {
+	content := MessageContent{Type: "other"}
+	if msg == nil {
+		return content
+	}
+
+	var ctxInfo *waE2E.ContextInfo
+
+	switch {
+	case msg.GetConversation() != "":
+		content.Type = "text"
+		content.Body = msg.GetConversation()
+
+	case msg.ExtendedTextMessage != nil:
+		content.Type = "text"
+		content.Body = msg.GetExtendedTextMessage().GetText()
+		ctxInfo = msg.GetExtendedTextMessage().GetContextInfo()
+
+	case msg.ImageMessage != nil:
+		m := msg.GetImageMessage()
+		content.Type = "image"
+		content.Body = m.GetCaption()
+		content.Mime = m.GetMimetype()
+		content.Size = m.GetFileLength()
+		content.SHA256 = hex.EncodeToString(m.GetFileSHA256())
+		content.Downloadable = m
+		ctxInfo = m.GetContextInfo()
+
+	case msg.VideoMessage != nil:
+		m := msg.GetVideoMessage()
+		content.Type = "video"
+		content.Body = m.GetCaption()
+		content.Mime = m.GetMimetype()
+		content.Size = m.GetFileLength()
+		content.SHA256 = hex.EncodeToString(m.GetFileSHA256())
+		content.Downloadable = m
+		ctxInfo = m.GetContextInfo()
+
+	case msg.AudioMessage != nil:
+		m := msg.GetAudioMessage()
+		content.Type = "audio"
+		content.Mime = m.GetMimetype()
+		content.Size = m.GetFileLength()
+		content.SHA256 = hex.EncodeToString(m.GetFileSHA256())
+		content.Downloadable = m
+		ctxInfo = m.GetContextInfo()
+
+	case msg.DocumentMessage != nil:
+		m := msg.GetDocumentMessage()
+		content.Type = "document"
+		content.Body = m.GetCaption()
+		content.Mime = m.GetMimetype()
+		content.FileName = m.GetFileName()
+		content.Size = m.GetFileLength()
+		content.SHA256 = hex.EncodeToString(m.GetFileSHA256())
+		content.Downloadable = m
+		ctxInfo = m.GetContextInfo()
+
+	case msg.StickerMessage != nil:
+		m := msg.GetStickerMessage()
+		content.Type = "sticker"
+		content.Mime = m.GetMimetype()
+		content.Size = m.GetFileLength()
+		content.SHA256 = hex.EncodeToString(m.GetFileSHA256())
+		content.Downloadable = m
+		ctxInfo = m.GetContextInfo()
+
+	case msg.LocationMessage != nil:
+		m := msg.GetLocationMessage()
+		content.Type = "location"
+		content.Latitude = m.GetDegreesLatitude()
+		content.Longitude = m.GetDegreesLongitude()
+		content.Address = m.GetAddress()
+		content.LocationName = m.GetName()
+		content.Body = fmt.Sprintf("%f,%f", m.GetDegreesLatitude(), m.GetDegreesLongitude())
+		if m.GetName() != "" {
+			content.Body = m.GetName() + " (" + content.Body + ")"
+		}
+		ctxInfo = m.GetContextInfo()
+
+	case msg.ContactMessage != nil:
+		m := msg.GetContactMessage()
+		content.Type = "contact"
+		content.Body = m.GetDisplayName()
+		content.VCard = m.GetVcard()
+		ctxInfo = m.GetContextInfo()
+
+	case msg.ContactsArrayMessage != nil:
+		m := msg.GetContactsArrayMessage()
+		content.Type = "contact"
+		content.Body = m.GetDisplayName()
+		ctxInfo = m.GetContextInfo()
+
+	case msg.ReactionMessage != nil:
+		m := msg.GetReactionMessage()
+		content.Type = "reaction"
+		content.Body = m.GetText()
+		content.QuotedMessageID = m.GetKey().GetID()
+		content.QuotedParticipant = m.GetKey().GetParticipant()
+
+	case pollCreationMessage(msg) != nil:
+		poll := pollCreationMessage(msg)
+		content.Type = "poll"
+		content.Body = poll.GetName()
+		ctxInfo = poll.GetContextInfo()
+	}
+
+	if ctxInfo != nil && ctxInfo.GetStanzaID() != "" {
+		content.QuotedMessageID = ctxInfo.GetStanzaID()
+		content.QuotedParticipant = ctxInfo.GetParticipant()
+	}
+
+	return content
+}
+
+// ResolveSenderNumber mengembalikan nomor telepon pengirim.
+// Jika pengirim memakai LID (@lid), dicoba resolve ke nomor asli lewat LID store whatsmeow.
+func ResolveSenderNumber(instanceID string, sender types.JID) string {
+	if sender.Server != types.HiddenUserServer {
+		return sender.User
+	}
+
+	session, err := GetSession(instanceID)
+	if err != nil || session.Client == nil {
+		return sender.User
+	}
+
+	phoneJID, err := session.Client.Store.LIDs.GetPNForLID(context.Background(), sender)
+	if err != nil || phoneJID.User == "" {
+		return sender.User
+	}
+
+	return phoneJID.User
+}
+
+// saveMessageEvent menyimpan satu *events.Message (masuk maupun echo dari HP sendiri) ke tabel messages.
+func saveMessageEvent(instanceID string, v *events.Message, senderNumber string) {
+	// Edit / revoke (dari lawan chat atau HP sendiri) memperbarui pesan aslinya, bukan pesan baru
+	if protocol := v.Message.GetProtocolMessage(); protocol != nil {
+		targetID := protocol.GetKey().GetID()
+		switch protocol.GetType() {
+		case waE2E.ProtocolMessage_REVOKE:
+			if err := model.MarkMessageRevoked(instanceID, targetID, v.Info.Timestamp); err != nil {
+				log.Printf("⚠️ %v", err)
+			}
+			return
+		case waE2E.ProtocolMessage_MESSAGE_EDIT:
+			edited := ExtractMessageContent(protocol.GetEditedMessage())
+			if err := model.MarkMessageEdited(instanceID, targetID, edited.Body, v.Info.Timestamp); err != nil {
+				log.Printf("⚠️ %v", err)
+			}
+			return
+		}
+	}
+
+	content := ExtractMessageContent(v.Message)
+
+	m := &model.Message{
+		InstanceID:        instanceID,
+		MessageID:         v.Info.ID,
+		ChatJID:           v.Info.Chat.ToNonAD().String(),
+		SenderJID:         sql.NullString{String: v.Info.Sender.ToNonAD().String(), Valid: !v.Info.Sender.IsEmpty()},
+		SenderNumber:      sql.NullString{String: senderNumber, Valid: senderNumber != ""},
+		PushName:          sql.NullString{String: v.Info.PushName, Valid: v.Info.PushName != ""},
+		FromMe:            v.Info.IsFromMe,
+		IsGroup:           v.Info.IsGroup,
+		MessageType:       content.Type,
+		Body:              sql.NullString{String: content.Body, Valid: content.Body != ""},
+		MediaMime:         sql.NullString{String: content.Mime, Valid: content.Mime != ""},
+		MediaFileName:     sql.NullString{String: content.FileName, Valid: content.FileName != ""},
+		MediaSize:         sql.NullInt64{Int64: int64(content.Size), Valid: content.Size > 0},
+		MediaSHA256:       sql.NullString{String: content.SHA256, Valid: content.SHA256 != ""},
+		QuotedMessageID:   sql.NullString{String: content.QuotedMessageID, Valid: content.QuotedMessageID != ""},
+		QuotedParticipant: sql.NullString{String: content.QuotedParticipant, Valid: content.QuotedParticipant != ""},
+		MessageTimestamp:  v.Info.Timestamp,
+	}
+
+	// Pesan dari HP sendiri sudah pasti terkirim ke server
+	if v.Info.IsFromMe {
+		m.Status = sql.NullString{String: model.MessageStatusSent, Valid: true}
+		m.StatusUpdatedAt = sql.NullTime{Time: v.Info.Timestamp, Valid: true}
+	}
+
+	if err := model.SaveMessage(m); err != nil {
+		log.Printf("⚠️ Failed to store message %s for instance %s: %v", v.Info.ID, instanceID, err)
+	}
+
+	savePollCreation(instanceID, v.Info.Chat, v.Info.Sender, v.Info.ID, v.Message)
+}
+
+// saveHistorySync menyimpan pesan-pesan dari history sync ke tabel messages.
+func saveHistorySync(instanceID string, v *events.HistorySync) {
+	session, err := GetSession(instanceID)
+	if err != nil || session.Client == nil {
+		return
+	}
+
+	stored := 0
+	for _, conv := range v.Data.GetConversations() {
+		chatJID, err := types.ParseJID(conv.GetID())
+		if err != nil {
+			continue
+		}
+
+		for _, histMsg := range conv.GetMessages() {
+			evt, err := session.Client.ParseWebMessage(chatJID, histMsg.GetMessage())
+			if err != nil {
+				continue
+			}
+			saveMessageEvent(instanceID, evt, ResolveSenderNumber(instanceID, evt.Info.Sender))
+			stored++
+		}
+	}
+
+	if stored > 0 {
+		log.Printf("✓ History sync stored %d messages for instance: %s", stored, instanceID)
+	}
+}
+
+// RecordOutgoingMessage menyimpan pesan yang dikirim lewat API ke tabel messages.
+// Dipanggil setelah Client.SendMessage berhasil.
+func RecordOutgoingMessage(instanceID string, to types.JID, msg *waE2E.Message, resp whatsmeow.SendResponse) {
+	content := ExtractMessageContent(msg)
+
+	var ownJID types.JID
+	var senderJID, senderNumber string
+	if session, err := GetSession(instanceID); err == nil && session.Client != nil && session.Client.Store.ID != nil {
+		ownJID = *session.Client.Store.ID
+		senderJID = ownJID.ToNonAD().String()
+		senderNumber = ownJID.User
+	}
+
+	timestamp := resp.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	m := &model.Message{
+		InstanceID:        instanceID,
+		MessageID:         resp.ID,
+		ChatJID:           to.ToNonAD().String(),
+		SenderJID:         sql.NullString{String: senderJID, Valid: senderJID != ""},
+		SenderNumber:      sql.NullString{String: senderNumber, Valid: senderNumber != ""},
+		FromMe:            true,
+		IsGroup:           to.Server == types.GroupServer,
+		MessageType:       content.Type,
+		Body:              sql.NullString{String: content.Body, Valid: content.Body != ""},
+		MediaMime:         sql.NullString{String: content.Mime, Valid: content.Mime != ""},
+		MediaFileName:     sql.NullString{String: content.FileName, Valid: content.FileName != ""},
+		MediaSize:         sql.NullInt64{Int64: int64(content.Size), Valid: content.Size > 0},
+		MediaSHA256:       sql.NullString{String: content.SHA256, Valid: content.SHA256 != ""},
+		QuotedMessageID:   sql.NullString{String: content.QuotedMessageID, Valid: content.QuotedMessageID != ""},
+		QuotedParticipant: sql.NullString{String: content.QuotedParticipant, Valid: content.QuotedParticipant != ""},
+		Status:            sql.NullString{String: model.MessageStatusServerAck, Valid: true},
+		StatusUpdatedAt:   sql.NullTime{Time: timestamp, Valid: true},
+		MessageTimestamp:  timestamp,
+	}
+
+	if err := model.SaveMessage(m); err != nil {
+		log.Printf("⚠️ Failed to store outgoing message %s for instance %s: %v", resp.ID, instanceID, err)
+	}
+
+	savePollCreation(instanceID, to, ownJID, resp.ID, msg)
+}
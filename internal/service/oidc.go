@@ -0,0 +1,709 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gowa-yourself/internal/model"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Single sign-on lewat OpenID Connect (authorization code + PKCE).
+// Nama provider sekaligus nilai users.auth_provider: "google" atau "oidc" (Keycloak, Authentik, ...).
+const (
+	OIDCProviderGoogle  = "google"
+	OIDCProviderGeneric = "oidc"
+
+	googleIssuer = "https://accounts.google.com"
+
+	oidcFlowExpiry     = 10 * time.Minute
+	oidcDiscoveryTTL   = time.Hour
+	oidcKeysTTL        = time.Hour
+	oidcKeysMinRefresh = time.Minute // refresh JWKS saat kid tidak dikenal, maksimal 1x per menit
+)
+
+var (
+	ErrOIDCUnknownProvider   = errors.New("unknown or disabled SSO provider")
+	ErrOIDCInvalidState      = errors.New("invalid or expired SSO login state")
+	ErrOIDCExchangeFailed    = errors.New("failed to exchange authorization code")
+	ErrOIDCInvalidIDToken    = errors.New("invalid ID token")
+	ErrOIDCEmailNotVerified  = errors.New("email is missing or not verified by the provider")
+	ErrOIDCDomainNotAllowed  = errors.New("email domain is not allowed")
+	ErrOIDCNoAccount         = errors.New("no account for this identity and auto-provisioning is disabled")
+	ErrOIDCAccountLinked     = errors.New("an account with this email is linked to another identity")
+	ErrOIDCAccountNotLinked  = errors.New("an account with this email already exists and linking by email is disabled")
+	ErrOIDCAdminNotLinked    = errors.New("admin accounts are never linked by email")
+	ErrOIDCAccountDisabled   = errors.New("user account is disabled")
+	ErrOIDCProviderMisconfig = errors.New("SSO provider discovery failed")
+)
+
+// OIDCProvider is a configured OpenID Connect provider
+type OIDCProvider struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	clientSecret string
+	scopes       string
+
+	mu           sync.Mutex
+	discovery    *oidcDiscovery
+	discoveredAt time.Time
+	keys         map[string]interface{} // kid -> *rsa.PublicKey / *ecdsa.PublicKey
+	keysAt       time.Time
+}
+
+// OIDCProviderInfo is the public info of an enabled provider (untuk tombol login di frontend)
+type OIDCProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	LoginURL    string `json:"login_url"`
+}
+
+// OIDCIdentity is the verified identity from an ID token
+type OIDCIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+	Username      string // preferred_username (Keycloak)
+}
+
+// OIDCFlowClaims disimpan di cookie selama redirect ke provider (state, nonce, PKCE verifier)
+type OIDCFlowClaims struct {
+	Provider     string `json:"provider"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	jwt.RegisteredClaims
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcIDTokenClaims struct {
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"` // bool, beberapa IdP mengirim string "true"
+	Name              string      `json:"name"`
+	Picture           string      `json:"picture"`
+	PreferredUsername string      `json:"preferred_username"`
+	Nonce             string      `json:"nonce"`
+	AuthorizedParty   string      `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// SSO configuration
+var (
+	oidcProviders          = map[string]*OIDCProvider{}
+	oauthRedirectBaseURL   string
+	oauthSuccessRedirect   string
+	oauthAllowedDomains    []string
+	oauthAutoProvision     bool
+	oauthDefaultRole       string
+	oauthLinkByEmail       bool
+	oidcHTTPClient         = &http.Client{Timeout: 10 * time.Second}
+	usernameInvalidPattern = regexp.MustCompile(`[^a-z0-9._-]+`)
+)
+
+// initOIDCProviders reads the SSO configuration from environment variables
+func initOIDCProviders() {
+	oidcProviders = map[string]*OIDCProvider{}
+
+	oauthRedirectBaseURL = strings.TrimRight(os.Getenv("OAUTH_REDIRECT_BASE_URL"), "/")
+	oauthSuccessRedirect = os.Getenv("OAUTH_SUCCESS_REDIRECT_URL")
+	oauthAutoProvision = os.Getenv("OAUTH_AUTO_PROVISION") != "false"
+	oauthLinkByEmail = os.Getenv("OAUTH_LINK_BY_EMAIL") == "true" // default off: email IdP menentukan akses ke akun password
+
+	oauthAllowedDomains = nil
+	for _, d := range strings.Split(os.Getenv("OAUTH_ALLOWED_DOMAINS"), ",") {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			oauthAllowedDomains = append(oauthAllowedDomains, strings.TrimPrefix(d, "@"))
+		}
+	}
+
+	oauthDefaultRole = os.Getenv("OAUTH_DEFAULT_ROLE")
+	if !IsValidRole(oauthDefaultRole) {
+		oauthDefaultRole = "user"
+	}
+
+	if id := os.Getenv("GOOGLE_CLIENT_ID"); id != "" {
+		oidcProviders[OIDCProviderGoogle] = &OIDCProvider{
+			Name:         OIDCProviderGoogle,
+			DisplayName:  "Google",
+			Issuer:       googleIssuer,
+			ClientID:     id,
+			clientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+			scopes:       "openid email profile",
+		}
+	}
+
+	if issuer, id := os.Getenv("OIDC_ISSUER"), os.Getenv("OIDC_CLIENT_ID"); issuer != "" && id != "" {
+		displayName := os.Getenv("OIDC_DISPLAY_NAME")
+		if displayName == "" {
+			displayName = "SSO"
+		}
+		scopes := os.Getenv("OIDC_SCOPES")
+		if scopes == "" {
+			scopes = "openid email profile"
+		}
+		oidcProviders[OIDCProviderGeneric] = &OIDCProvider{
+			Name:         OIDCProviderGeneric,
+			DisplayName:  displayName,
+			Issuer:       strings.TrimRight(issuer, "/"),
+			ClientID:     id,
+			clientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			scopes:       scopes,
+		}
+	}
+
+	if len(oidcProviders) > 0 && oauthRedirectBaseURL == "" {
+		log.Println("⚠️ Warning: OAUTH_REDIRECT_BASE_URL is not set, SSO login is disabled")
+		oidcProviders = map[string]*OIDCProvider{}
+		return
+	}
+	for name := range oidcProviders {
+		log.Printf("✅ SSO provider enabled: %s", name)
+	}
+}
+
+// ListOIDCProviders returns the enabled SSO providers
+func ListOIDCProviders() []OIDCProviderInfo {
+	providers := []OIDCProviderInfo{}
+	for _, name := range []string{OIDCProviderGoogle, OIDCProviderGeneric} {
+		if p, ok := oidcProviders[name]; ok {
+			providers = append(providers, OIDCProviderInfo{
+				Name:        p.Name,
+				DisplayName: p.DisplayName,
+				LoginURL:    "/auth/" + p.Name + "/login",
+			})
+		}
+	}
+	return providers
+}
+
+// OAuthSuccessRedirectURL returns the frontend URL that receives the SSO result ("" = JSON response)
+func OAuthSuccessRedirectURL() string {
+	return oauthSuccessRedirect
+}
+
+// OAuthSecureCookie returns true jika API diakses lewat HTTPS (cookie state diberi flag Secure)
+func OAuthSecureCookie() bool {
+	return strings.HasPrefix(oauthRedirectBaseURL, "https://")
+}
+
+// StartOIDCLogin builds the authorization URL and the signed flow token (disimpan di cookie oleh handler)
+func StartOIDCLogin(providerName string) (string, string, error) {
+	p, ok := oidcProviders[providerName]
+	if !ok {
+		return "", "", ErrOIDCUnknownProvider
+	}
+
+	disc, err := p.getDiscovery()
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	flow := &OIDCFlowClaims{
+		Provider:     p.Name,
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(oidcFlowExpiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	flowToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, flow).SignedString(derivedKey("oidc-flow"))
+	if err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.ClientID)
+	q.Set("redirect_uri", p.redirectURI())
+	q.Set("scope", p.scopes)
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	if p.Name == OIDCProviderGoogle && len(oauthAllowedDomains) == 1 {
+		q.Set("hd", oauthAllowedDomains[0]) // hanya petunjuk untuk account chooser, tetap dicek di callback
+	}
+
+	sep := "?"
+	if strings.Contains(disc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return disc.AuthorizationEndpoint + sep + q.Encode(), flowToken, nil
+}
+
+// CompleteOIDCLogin memvalidasi callback, menukar code, memverifikasi ID token lalu mencari,
+// menautkan atau membuat user. Returns user dan aksi yang terjadi ("login", "link" atau "provision").
+func CompleteOIDCLogin(providerName, code, state, flowToken string) (*model.User, string, error) {
+	p, ok := oidcProviders[providerName]
+	if !ok {
+		return nil, "", ErrOIDCUnknownProvider
+	}
+
+	flow := &OIDCFlowClaims{}
+	token, err := jwt.ParseWithClaims(flowToken, flow, func(token *jwt.Token) (interface{}, error) {
+		return derivedKey("oidc-flow"), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid || flow.Provider != p.Name || state == "" ||
+		subtle.ConstantTimeCompare([]byte(flow.State), []byte(state)) != 1 {
+		return nil, "", ErrOIDCInvalidState
+	}
+
+	idToken, err := p.exchangeCode(code, flow.CodeVerifier)
+	if err != nil {
+		return nil, "", err
+	}
+
+	identity, err := p.verifyIDToken(idToken, flow.Nonce)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return resolveOIDCUser(identity)
+}
+
+// resolveOIDCUser: identitas yang sudah tertaut -> login; email terverifikasi milik akun lain -> tautkan;
+// selain itu buat akun baru dengan OAUTH_DEFAULT_ROLE
+func resolveOIDCUser(identity *OIDCIdentity) (*model.User, string, error) {
+	if len(oauthAllowedDomains) > 0 && !emailDomainAllowed(identity) {
+		return nil, "", ErrOIDCDomainNotAllowed
+	}
+
+	user, err := model.GetUserByOAuthIdentity(identity.Provider, identity.Subject)
+	if err == nil {
+		if !user.IsActive {
+			return nil, "", ErrOIDCAccountDisabled
+		}
+		_ = model.UpdateLastLogin(user.ID)
+		return user, "login", nil
+	}
+	if !errors.Is(err, model.ErrUserNotFound) {
+		return nil, "", err
+	}
+
+	// Identitas baru: linking dan provisioning hanya dengan email yang diverifikasi provider
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, "", ErrOIDCEmailNotVerified
+	}
+
+	user, err = model.GetUserByEmailIgnoreCase(identity.Email)
+	if err == nil {
+		if err := checkOIDCEmailLink(user); err != nil {
+			return nil, "", err
+		}
+		if err := model.LinkOAuthIdentity(user.ID, identity.Provider, identity.Subject); err != nil {
+			if errors.Is(err, model.ErrIdentityAlreadyLinked) {
+				return nil, "", ErrOIDCAccountLinked
+			}
+			return nil, "", err
+		}
+		user.OAuthProviderID = sql.NullString{String: identity.Subject, Valid: true}
+		user.EmailVerified = true
+		_ = model.UpdateLastLogin(user.ID)
+		return user, "link", nil
+	}
+	if !errors.Is(err, model.ErrUserNotFound) {
+		return nil, "", err
+	}
+
+	if !oidcCanProvision() {
+		return nil, "", ErrOIDCNoAccount
+	}
+
+	username, err := availableUsername(identity)
+	if err != nil {
+		return nil, "", err
+	}
+
+	user = &model.User{
+		Username:        username,
+		Email:           identity.Email,
+		FullName:        sql.NullString{String: identity.Name, Valid: identity.Name != ""},
+		AvatarURL:       sql.NullString{String: identity.Picture, Valid: identity.Picture != ""},
+		AuthProvider:    identity.Provider,
+		OAuthProviderID: sql.NullString{String: identity.Subject, Valid: true},
+		Role:            oauthDefaultRole,
+		IsActive:        true,
+		EmailVerified:   true,
+	}
+	if err := model.CreateUser(user); err != nil {
+		return nil, "", err
+	}
+	_ = model.UpdateLastLogin(user.ID)
+
+	return user, "provision", nil
+}
+
+// oidcCanProvision: akun baru lewat SSO hanya jika OAUTH_AUTO_PROVISION aktif dan REGISTRATION_MODE=open,
+// sehingga mode invite / disabled tidak bisa dilewati dengan login SSO
+func oidcCanProvision() bool {
+	return oauthAutoProvision && RegistrationMode() == RegistrationOpen
+}
+
+// checkOIDCEmailLink: linking by email hanya untuk akun local yang aktif dan bukan admin
+// (OAUTH_LINK_BY_EMAIL=true). Akun admin harus login dengan password.
+func checkOIDCEmailLink(user *model.User) error {
+	if user.AuthProvider != "local" {
+		return ErrOIDCAccountLinked
+	}
+	if !oauthLinkByEmail {
+		return ErrOIDCAccountNotLinked
+	}
+	if user.Role == "admin" {
+		return ErrOIDCAdminNotLinked
+	}
+	if !user.IsActive {
+		return ErrOIDCAccountDisabled
+	}
+	return nil
+}
+
+// emailDomainAllowed mengecek domain email terhadap OAUTH_ALLOWED_DOMAINS (email wajib terverifikasi)
+func emailDomainAllowed(identity *OIDCIdentity) bool {
+	at := strings.LastIndex(identity.Email, "@")
+	if at < 0 || !identity.EmailVerified {
+		return false
+	}
+	domain := strings.ToLower(identity.Email[at+1:])
+	for _, d := range oauthAllowedDomains {
+		if domain == d {
+			return true
+		}
+	}
+	return false
+}
+
+// availableUsername membuat username unik dari preferred_username / bagian lokal email
+func availableUsername(identity *OIDCIdentity) (string, error) {
+	base := identity.Username
+	if base == "" {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	base = strings.Trim(usernameInvalidPattern.ReplaceAllString(strings.ToLower(base), "-"), "-.")
+	if len(base) < 3 {
+		base = "user"
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		exists, err := model.UsernameExists(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+		suffix, err := randomHex(2)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "-" + suffix
+	}
+	return "", errors.New("could not generate a unique username")
+}
+
+func (p *OIDCProvider) redirectURI() string {
+	return oauthRedirectBaseURL + "/auth/" + p.Name + "/callback"
+}
+
+// getDiscovery membaca /.well-known/openid-configuration (di-cache)
+func (p *OIDCProvider) getDiscovery() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && time.Since(p.discoveredAt) < oidcDiscoveryTTL {
+		return p.discovery, nil
+	}
+
+	disc := &oidcDiscovery{}
+	if err := oidcGetJSON(p.Issuer+"/.well-known/openid-configuration", disc); err != nil {
+		log.Printf("⚠️ OIDC discovery failed for %s: %v", p.Name, err)
+		return nil, ErrOIDCProviderMisconfig
+	}
+	if disc.AuthorizationEndpoint == "" || disc.TokenEndpoint == "" || disc.JWKSURI == "" {
+		log.Printf("⚠️ OIDC discovery for %s is missing endpoints", p.Name)
+		return nil, ErrOIDCProviderMisconfig
+	}
+	if strings.TrimRight(disc.Issuer, "/") != p.Issuer {
+		log.Printf("⚠️ OIDC issuer mismatch for %s: configured %s, discovered %s", p.Name, p.Issuer, disc.Issuer)
+		return nil, ErrOIDCProviderMisconfig
+	}
+
+	p.discovery = disc
+	p.discoveredAt = time.Now()
+	return disc, nil
+}
+
+// exchangeCode menukar authorization code (dengan PKCE verifier) menjadi ID token
+func (p *OIDCProvider) exchangeCode(code, verifier string) (string, error) {
+	if code == "" {
+		return "", ErrOIDCExchangeFailed
+	}
+
+	disc, err := p.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURI())
+	form.Set("client_id", p.ClientID)
+	form.Set("code_verifier", verifier)
+	if p.clientSecret != "" {
+		form.Set("client_secret", p.clientSecret)
+	}
+
+	resp, err := oidcHTTPClient.PostForm(disc.TokenEndpoint, form)
+	if err != nil {
+		log.Printf("⚠️ OIDC token request failed for %s: %v", p.Name, err)
+		return "", ErrOIDCExchangeFailed
+	}
+	defer resp.Body.Close()
+
+	var tokenResp struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(body, &tokenResp); err != nil || resp.StatusCode != http.StatusOK || tokenResp.IDToken == "" {
+		log.Printf("⚠️ OIDC token exchange rejected by %s (status %d): %s %s", p.Name, resp.StatusCode, tokenResp.Error, tokenResp.ErrorDescription)
+		return "", ErrOIDCExchangeFailed
+	}
+
+	return tokenResp.IDToken, nil
+}
+
+// verifyIDToken memverifikasi signature (JWKS), issuer, audience, expiry dan nonce
+func (p *OIDCProvider) verifyIDToken(idToken, nonce string) (*OIDCIdentity, error) {
+	disc, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &oidcIDTokenClaims{}
+	token, err := jwt.ParseWithClaims(idToken, claims, p.keyFunc,
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384"}),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil || !token.Valid {
+		log.Printf("⚠️ Invalid ID token from %s: %v", p.Name, err)
+		return nil, ErrOIDCInvalidIDToken
+	}
+
+	// Google kadang mengirim iss tanpa skema
+	issuerOK := claims.Issuer == disc.Issuer ||
+		(p.Name == OIDCProviderGoogle && claims.Issuer == strings.TrimPrefix(googleIssuer, "https://"))
+	if !issuerOK || claims.Subject == "" ||
+		subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 ||
+		(claims.AuthorizedParty != "" && claims.AuthorizedParty != p.ClientID) {
+		return nil, ErrOIDCInvalidIDToken
+	}
+
+	verified := false
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		verified = v
+	case string:
+		verified = v == "true"
+	}
+
+	return &OIDCIdentity{
+		Provider:      p.Name,
+		Subject:       claims.Subject,
+		Email:         strings.TrimSpace(claims.Email),
+		EmailVerified: verified,
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+		Username:      claims.PreferredUsername,
+	}, nil
+}
+
+// keyFunc mencari public key dari JWKS provider berdasarkan kid (refresh jika kid baru / cache kadaluarsa)
+func (p *OIDCProvider) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	stale := time.Since(p.keysAt) > oidcKeysTTL
+	canRefresh := time.Since(p.keysAt) > oidcKeysMinRefresh
+	jwksURI := ""
+	if p.discovery != nil {
+		jwksURI = p.discovery.JWKSURI
+	}
+	p.mu.Unlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+	if !canRefresh || jwksURI == "" {
+		if ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	keys, err := fetchJWKS(jwksURI)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysAt = time.Now()
+	p.mu.Unlock()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	// Token tanpa kid: pakai satu-satunya key jika JWKS hanya berisi satu
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// fetchJWKS downloads and parses the provider's signing keys
+func fetchJWKS(uri string) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := oidcGetJSON(uri, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func oidcGetJSON(uri string, out interface{}) error {
+	resp, err := oidcHTTPClient.Get(uri)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", uri, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
@@ -0,0 +1,434 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"gowa-yourself/internal/model"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	mockClientID     = "test-client"
+	mockClientSecret = "test-secret"
+	mockRedirectBase = "https://api.example.test"
+)
+
+// mockIdP adalah provider OIDC palsu: discovery, JWKS dan token endpoint (PKCE S256)
+type mockIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	codes  map[string]url.Values // code -> query authorization request
+
+	discoveryIssuer string
+	// claims mengubah claim ID token sebelum ditandatangani
+	claims func(jwt.MapClaims)
+	// signKey menandatangani ID token dengan key lain (bukan key di JWKS)
+	signKey *rsa.PrivateKey
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	m := &mockIdP{t: t, key: key, codes: map[string]url.Values{}}
+
+	mux := http.NewServeMux()
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := m.server.URL
+		if m.discoveryIssuer != "" {
+			issuer = m.discoveryIssuer
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key-1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+			}},
+		})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		auth, ok := m.codes[r.PostForm.Get("code")]
+		challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok ||
+			r.PostForm.Get("grant_type") != "authorization_code" ||
+			r.PostForm.Get("client_id") != mockClientID ||
+			r.PostForm.Get("client_secret") != mockClientSecret ||
+			r.PostForm.Get("redirect_uri") != auth.Get("redirect_uri") ||
+			base64.RawURLEncoding.EncodeToString(challenge[:]) != auth.Get("code_challenge") {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		delete(m.codes, r.PostForm.Get("code"))
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "access",
+			"id_token":     m.idToken(auth.Get("nonce")),
+		})
+	})
+
+	return m
+}
+
+func (m *mockIdP) idToken(nonce string) string {
+	m.t.Helper()
+
+	claims := jwt.MapClaims{
+		"iss":                m.server.URL,
+		"aud":                mockClientID,
+		"sub":                "subject-1",
+		"exp":                time.Now().Add(5 * time.Minute).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              nonce,
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"name":               "Alice",
+		"preferred_username": "alice",
+	}
+	if m.claims != nil {
+		m.claims(claims)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "key-1"
+	key := m.key
+	if m.signKey != nil {
+		key = m.signKey
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		m.t.Fatalf("sign ID token: %v", err)
+	}
+	return signed
+}
+
+// setupMockOIDC mendaftarkan mock sebagai provider generik "oidc"
+func setupMockOIDC(t *testing.T) *mockIdP {
+	m := newMockIdP(t)
+
+	InitAuthConfig("test-secret")
+	t.Setenv("GOOGLE_CLIENT_ID", "")
+	t.Setenv("OIDC_ISSUER", m.server.URL+"/")
+	t.Setenv("OIDC_CLIENT_ID", mockClientID)
+	t.Setenv("OIDC_CLIENT_SECRET", mockClientSecret)
+	t.Setenv("OAUTH_REDIRECT_BASE_URL", mockRedirectBase+"/")
+	// Callback berhenti di cek domain sebelum menyentuh database
+	t.Setenv("OAUTH_ALLOWED_DOMAINS", "corp.example.test")
+	t.Setenv("OAUTH_LINK_BY_EMAIL", "")
+	initOIDCProviders()
+
+	return m
+}
+
+// authorize menjalankan StartOIDCLogin dan "login" di provider, returns code, state dan flow token
+func (m *mockIdP) authorize(t *testing.T) (string, string, string) {
+	t.Helper()
+
+	authURL, flowToken, err := StartOIDCLogin(OIDCProviderGeneric)
+	if err != nil {
+		t.Fatalf("StartOIDCLogin: %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse authorization URL: %v", err)
+	}
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("client_id") != mockClientID ||
+		q.Get("redirect_uri") != mockRedirectBase+"/auth/oidc/callback" ||
+		q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" ||
+		q.Get("state") == "" || q.Get("nonce") == "" {
+		t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+
+	code := "code-" + q.Get("state")
+	m.codes[code] = q
+	return code, q.Get("state"), flowToken
+}
+
+func TestOIDCDiscoveryAndJWKS(t *testing.T) {
+	m := setupMockOIDC(t)
+	p := oidcProviders[OIDCProviderGeneric]
+
+	disc, err := p.getDiscovery()
+	if err != nil {
+		t.Fatalf("getDiscovery: %v", err)
+	}
+	if p.Issuer != m.server.URL || disc.TokenEndpoint != m.server.URL+"/token" {
+		t.Fatalf("unexpected discovery: issuer %s, token endpoint %s", p.Issuer, disc.TokenEndpoint)
+	}
+
+	keys, err := fetchJWKS(disc.JWKSURI)
+	if err != nil {
+		t.Fatalf("fetchJWKS: %v", err)
+	}
+	key, ok := keys["key-1"].(*rsa.PublicKey)
+	if !ok || key.N.Cmp(m.key.N) != 0 || key.E != m.key.E {
+		t.Fatalf("JWKS key does not match the provider key")
+	}
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	m := setupMockOIDC(t)
+	m.discoveryIssuer = "https://evil.example.test"
+
+	if _, _, err := StartOIDCLogin(OIDCProviderGeneric); !errors.Is(err, ErrOIDCProviderMisconfig) {
+		t.Fatalf("err = %v, want ErrOIDCProviderMisconfig", err)
+	}
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	m := setupMockOIDC(t)
+	p := oidcProviders[OIDCProviderGeneric]
+
+	code, state, flowToken := m.authorize(t)
+	flow := &OIDCFlowClaims{}
+	if _, err := jwt.ParseWithClaims(flowToken, flow, func(*jwt.Token) (interface{}, error) {
+		return derivedKey("oidc-flow"), nil
+	}); err != nil {
+		t.Fatalf("parse flow token: %v", err)
+	}
+
+	idToken, err := p.exchangeCode(code, flow.CodeVerifier)
+	if err != nil {
+		t.Fatalf("exchangeCode: %v", err)
+	}
+	identity, err := p.verifyIDToken(idToken, flow.Nonce)
+	if err != nil {
+		t.Fatalf("verifyIDToken: %v", err)
+	}
+	if identity.Provider != OIDCProviderGeneric || identity.Subject != "subject-1" ||
+		identity.Email != "alice@example.com" || !identity.EmailVerified || identity.Username != "alice" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+
+	// Seluruh callback (state, PKCE, ID token) lolos dan berhenti di cek domain
+	code, state, flowToken = m.authorize(t)
+	if _, _, err := CompleteOIDCLogin(OIDCProviderGeneric, code, state, flowToken); !errors.Is(err, ErrOIDCDomainNotAllowed) {
+		t.Fatalf("err = %v, want ErrOIDCDomainNotAllowed", err)
+	}
+}
+
+func TestOIDCCallbackRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(m *mockIdP, code, state, flowToken *string)
+		want   error
+	}{
+		{"wrong state", func(m *mockIdP, code, state, flowToken *string) {
+			*state = "other-state"
+		}, ErrOIDCInvalidState},
+		{"tampered flow token", func(m *mockIdP, code, state, flowToken *string) {
+			*flowToken += "x"
+		}, ErrOIDCInvalidState},
+		{"wrong PKCE verifier", func(m *mockIdP, code, state, flowToken *string) {
+			// Flow token baru dengan state yang sama tapi verifier lain
+			_, other, _ := StartOIDCLogin(OIDCProviderGeneric)
+			flow := &OIDCFlowClaims{}
+			jwt.ParseWithClaims(other, flow, func(*jwt.Token) (interface{}, error) {
+				return derivedKey("oidc-flow"), nil
+			})
+			flow.State = *state
+			*flowToken, _ = jwt.NewWithClaims(jwt.SigningMethodHS256, flow).SignedString(derivedKey("oidc-flow"))
+		}, ErrOIDCExchangeFailed},
+		{"unknown code", func(m *mockIdP, code, state, flowToken *string) {
+			*code = "unknown"
+		}, ErrOIDCExchangeFailed},
+		{"wrong nonce", func(m *mockIdP, code, state, flowToken *string) {
+			m.claims = func(c jwt.MapClaims) { c["nonce"] = "other-nonce" }
+		}, ErrOIDCInvalidIDToken},
+		{"wrong audience", func(m *mockIdP, code, state, flowToken *string) {
+			m.claims = func(c jwt.MapClaims) { c["aud"] = "other-client" }
+		}, ErrOIDCInvalidIDToken},
+		{"wrong authorized party", func(m *mockIdP, code, state, flowToken *string) {
+			m.claims = func(c jwt.MapClaims) { c["azp"] = "other-client" }
+		}, ErrOIDCInvalidIDToken},
+		{"wrong issuer", func(m *mockIdP, code, state, flowToken *string) {
+			m.claims = func(c jwt.MapClaims) { c["iss"] = "https://evil.example.test" }
+		}, ErrOIDCInvalidIDToken},
+		{"expired", func(m *mockIdP, code, state, flowToken *string) {
+			m.claims = func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }
+		}, ErrOIDCInvalidIDToken},
+		{"missing subject", func(m *mockIdP, code, state, flowToken *string) {
+			m.claims = func(c jwt.MapClaims) { delete(c, "sub") }
+		}, ErrOIDCInvalidIDToken},
+		{"signed with unknown key", func(m *mockIdP, code, state, flowToken *string) {
+			key, _ := rsa.GenerateKey(rand.Reader, 2048)
+			m.signKey = key
+		}, ErrOIDCInvalidIDToken},
+		{"email not verified", func(m *mockIdP, code, state, flowToken *string) {
+			m.claims = func(c jwt.MapClaims) {
+				c["email"] = "alice@corp.example.test"
+				c["email_verified"] = "false"
+			}
+		}, ErrOIDCDomainNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := setupMockOIDC(t)
+			code, state, flowToken := m.authorize(t)
+			tt.mutate(m, &code, &state, &flowToken)
+
+			_, _, err := CompleteOIDCLogin(OIDCProviderGeneric, code, state, flowToken)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCEmailVerifiedClaim(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		verified bool
+	}{
+		{true, true},
+		{"true", true},
+		{false, false},
+		{"false", false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		m := setupMockOIDC(t)
+		p := oidcProviders[OIDCProviderGeneric]
+		m.claims = func(c jwt.MapClaims) {
+			if tt.value == nil {
+				delete(c, "email_verified")
+			} else {
+				c["email_verified"] = tt.value
+			}
+		}
+
+		if _, err := p.getDiscovery(); err != nil {
+			t.Fatalf("getDiscovery: %v", err)
+		}
+		identity, err := p.verifyIDToken(m.idToken("nonce"), "nonce")
+		if err != nil {
+			t.Fatalf("verifyIDToken: %v", err)
+		}
+		if identity.EmailVerified != tt.verified {
+			t.Errorf("email_verified %v: EmailVerified = %v, want %v", tt.value, identity.EmailVerified, tt.verified)
+		}
+	}
+}
+
+func TestEmailDomainAllowed(t *testing.T) {
+	oauthAllowedDomains = []string{"example.com", "example.co.id"}
+	t.Cleanup(func() { oauthAllowedDomains = nil })
+
+	tests := []struct {
+		email    string
+		verified bool
+		allowed  bool
+	}{
+		{"alice@example.com", true, true},
+		{"Alice@EXAMPLE.CO.ID", true, true},
+		{"alice@example.com", false, false},
+		{"alice@sub.example.com", true, false},
+		{"alice@example.com.evil.test", true, false},
+		{"alice@evil.test", true, false},
+		{"example.com", true, false},
+	}
+
+	for _, tt := range tests {
+		identity := &OIDCIdentity{Email: tt.email, EmailVerified: tt.verified}
+		if got := emailDomainAllowed(identity); got != tt.allowed {
+			t.Errorf("emailDomainAllowed(%q, verified=%v) = %v, want %v", tt.email, tt.verified, got, tt.allowed)
+		}
+	}
+}
+
+func TestCheckOIDCEmailLink(t *testing.T) {
+	t.Cleanup(func() { oauthLinkByEmail = false })
+
+	local := &model.User{AuthProvider: "local", Role: "user", IsActive: true}
+	admin := &model.User{AuthProvider: "local", Role: "admin", IsActive: true}
+	disabled := &model.User{AuthProvider: "local", Role: "user", IsActive: false}
+	sso := &model.User{AuthProvider: OIDCProviderGoogle, Role: "user", IsActive: true}
+
+	tests := []struct {
+		name    string
+		enabled bool
+		user    *model.User
+		want    error
+	}{
+		{"linking disabled", false, local, ErrOIDCAccountNotLinked},
+		{"local account", true, local, nil},
+		{"admin account", true, admin, ErrOIDCAdminNotLinked},
+		{"disabled account", true, disabled, ErrOIDCAccountDisabled},
+		{"other SSO provider", true, sso, ErrOIDCAccountLinked},
+	}
+
+	for _, tt := range tests {
+		oauthLinkByEmail = tt.enabled
+		if err := checkOIDCEmailLink(tt.user); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestOAuthLinkByEmailDefaultOff(t *testing.T) {
+	setupMockOIDC(t)
+	if oauthLinkByEmail {
+		t.Fatal("OAUTH_LINK_BY_EMAIL must default to false")
+	}
+}
+
+func TestOIDCProvisionFollowsRegistrationMode(t *testing.T) {
+	mode, provision := registrationMode, oauthAutoProvision
+	t.Cleanup(func() { registrationMode, oauthAutoProvision = mode, provision })
+
+	tests := []struct {
+		mode      string
+		provision bool
+		want      bool
+	}{
+		{RegistrationOpen, true, true},
+		{RegistrationOpen, false, false},
+		{RegistrationInvite, true, false},
+		{RegistrationDisabled, true, false},
+	}
+
+	for _, tt := range tests {
+		registrationMode, oauthAutoProvision = tt.mode, tt.provision
+		if got := oidcCanProvision(); got != tt.want {
+			t.Errorf("mode %s, auto-provision %v: oidcCanProvision() = %v, want %v", tt.mode, tt.provision, got, tt.want)
+		}
+	}
+}
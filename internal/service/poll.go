@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/ws"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// pollCreationMessage returns isi poll dari semua varian PollCreationMessage (V1/V2/V3/V5/V6)
+func pollCreationMessage(msg *waE2E.Message) *waE2E.PollCreationMessage {
+	switch {
+	case msg == nil:
+		return nil
+	case msg.PollCreationMessage != nil:
+		return msg.PollCreationMessage
+	case msg.PollCreationMessageV2 != nil:
+		return msg.PollCreationMessageV2
+	case msg.PollCreationMessageV3 != nil:
+		return msg.PollCreationMessageV3
+	case msg.PollCreationMessageV5 != nil:
+		return msg.PollCreationMessageV5
+	case msg.PollCreationMessageV6 != nil:
+		return msg.PollCreationMessageV6
+	}
+	return nil
+}
+
+// savePollCreation mencatat poll (masuk maupun keluar) supaya vote-nya bisa di-tally
+func savePollCreation(instanceID string, chat, creator types.JID, messageID string, msg *waE2E.Message) {
+	poll := pollCreationMessage(msg)
+	if poll == nil {
+		return
+	}
+
+	options := make([]string, 0, len(poll.GetOptions()))
+	for _, opt := range poll.GetOptions() {
+		options = append(options, opt.GetOptionName())
+	}
+
+	creatorJID := creator.ToNonAD().String()
+	err := model.SavePoll(&model.Poll{
+		InstanceID:      instanceID,
+		MessageID:       messageID,
+		ChatJID:         chat.ToNonAD().String(),
+		CreatorJID:      sql.NullString{String: creatorJID, Valid: !creator.IsEmpty()},
+		Question:        poll.GetName(),
+		Options:         options,
+		SelectableCount: int(poll.GetSelectableOptionsCount()),
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to store poll %s for instance %s: %v", messageID, instanceID, err)
+	}
+}
+
+// handlePollVote men-decrypt vote (PollUpdateMessage), menyimpan vote terakhir voter,
+// lalu mem-publish event poll_vote ke WebSocket + webhook.
+func handlePollVote(instanceID string, v *events.Message) {
+	pollID := v.Message.GetPollUpdateMessage().GetPollCreationMessageKey().GetID()
+
+	poll, err := model.GetPoll(instanceID, pollID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("⚠️ Failed to load poll %s for instance %s: %v", pollID, instanceID, err)
+		}
+		return
+	}
+
+	session, err := GetSession(instanceID)
+	if err != nil || session.Client == nil {
+		return
+	}
+
+	vote, err := session.Client.DecryptPollVote(context.Background(), v)
+	if err != nil {
+		log.Printf("⚠️ Failed to decrypt poll vote for poll %s: %v", pollID, err)
+		return
+	}
+
+	// Opsi yang dipilih dikirim sebagai sha256 dari nama opsi
+	hashes := make(map[[32]byte]string, len(poll.Options))
+	for _, name := range poll.Options {
+		hashes[sha256.Sum256([]byte(name))] = name
+	}
+
+	selected := []string{}
+	for _, h := range vote.GetSelectedOptions() {
+		var key [32]byte
+		copy(key[:], h)
+		if name, ok := hashes[key]; ok {
+			selected = append(selected, name)
+		}
+	}
+
+	voter := v.Info.Sender.ToNonAD().String()
+	if err := model.UpsertPollVote(instanceID, pollID, voter, selected, v.Info.Timestamp); err != nil {
+		log.Printf("⚠️ %v", err)
+		return
+	}
+
+	if Realtime != nil {
+		Realtime.Publish(ws.WsEvent{
+			Event:     ws.EventPollVote,
+			Timestamp: time.Now().UTC(),
+			Data: ws.PollVoteData{
+				InstanceID:      instanceID,
+				PollMessageID:   pollID,
+				ChatJID:         poll.ChatJID,
+				Voter:           voter,
+				SelectedOptions: selected,
+				Timestamp:       v.Info.Timestamp.UTC(),
+			},
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"gowa-yourself/internal/model"
+	warmingModel "gowa-yourself/internal/model/warming"
+	"gowa-yourself/internal/ws"
+)
+
+// LoadRealtimeAccess memuat instance (user_instances) dan warming room milik user,
+// dipakai Hub untuk memfilter event WebSocket user non-admin
+func LoadRealtimeAccess(userID int64) (*ws.ClientAccess, error) {
+	instanceIDs, err := model.GetUserInstances(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roomIDs, err := warmingModel.GetOwnedWarmingRoomIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	access := &ws.ClientAccess{
+		InstanceIDs: make(map[string]bool, len(instanceIDs)),
+		RoomIDs:     make(map[string]bool, len(roomIDs)),
+	}
+	for _, id := range instanceIDs {
+		access.InstanceIDs[id] = true
+	}
+	for _, id := range roomIDs {
+		access.RoomIDs[id] = true
+	}
+	return access, nil
+}
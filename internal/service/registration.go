@@ -0,0 +1,109 @@
+package service
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+
+	"gowa-yourself/internal/model"
+)
+
+// Mode POST /register (env REGISTRATION_MODE)
+const (
+	RegistrationOpen     = "open"     // siapa saja bisa daftar, undangan opsional
+	RegistrationInvite   = "invite"   // wajib token undangan
+	RegistrationDisabled = "disabled" // hanya admin yang membuat user
+)
+
+// Token undangan: 32 byte acak (hex), hanya hash SHA-256 yang disimpan
+const invitationTokenBytes = 32
+
+var (
+	ErrRegistrationDisabled = errors.New("registration is disabled")
+	ErrInvitationRequired   = errors.New("an invitation token is required to register")
+	ErrInvalidInvitation    = errors.New("invitation is invalid, used, revoked or expired")
+	ErrInvitationEmail      = errors.New("this invitation was issued for a different email")
+)
+
+// RegistrationMode returns the active registration mode
+func RegistrationMode() string {
+	return registrationMode
+}
+
+// CreateInvitation mengisi TokenHash lalu menyimpan undangan; returns token plaintext (hanya ditampilkan sekali)
+func CreateInvitation(inv *model.UserInvitation) (string, error) {
+	token, err := randomHex(invitationTokenBytes)
+	if err != nil {
+		return "", err
+	}
+
+	inv.TokenHash = hashInvitationToken(token)
+	if err := model.CreateUserInvitation(inv); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// RegisterUserWithInvitation mendaftarkan user sesuai REGISTRATION_MODE. Jika token undangan diberikan,
+// role user mengikuti undangan dan undangan ditandai terpakai. Returns undangan yang dipakai (nil jika tanpa undangan).
+func RegisterUserWithInvitation(req model.CreateUserRequest, token string) (*model.User, *model.UserInvitation, error) {
+	token = strings.TrimSpace(token)
+
+	switch registrationMode {
+	case RegistrationDisabled:
+		return nil, nil, ErrRegistrationDisabled
+	case RegistrationInvite:
+		if token == "" {
+			return nil, nil, ErrInvitationRequired
+		}
+	}
+
+	if token == "" {
+		req.Role = "user"
+		user, err := RegisterUser(req)
+		return user, nil, err
+	}
+
+	inv, err := model.GetUserInvitationByTokenHash(hashInvitationToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrInvalidInvitation
+		}
+		return nil, nil, err
+	}
+	if inv.Email.Valid && !strings.EqualFold(inv.Email.String, strings.TrimSpace(req.Email)) {
+		return nil, nil, ErrInvitationEmail
+	}
+
+	// Klaim dulu supaya token yang sama tidak bisa dipakai dua kali secara bersamaan
+	if err := model.ClaimUserInvitation(inv.ID); err != nil {
+		if errors.Is(err, model.ErrInvitationUnavailable) {
+			return nil, nil, ErrInvalidInvitation
+		}
+		return nil, nil, err
+	}
+
+	req.Role = inv.Role
+	user, err := RegisterUser(req)
+	if err != nil {
+		if rerr := model.ReleaseUserInvitation(inv.ID); rerr != nil {
+			log.Printf("⚠️ Failed to release invitation %d: %v", inv.ID, rerr)
+		}
+		return nil, nil, err
+	}
+
+	if err := model.SetUserInvitationUsedBy(inv.ID, user.ID); err != nil {
+		log.Printf("⚠️ Failed to record user of invitation %d: %v", inv.ID, err)
+	}
+
+	return user, inv, nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+)
+
+// revokeJobMaxAge: WhatsApp hanya mengizinkan "delete for everyone" sekitar dua hari setelah
+// pesan dikirim, jadi item yang instance-nya tidak pernah online di node mana pun dianggap gagal.
+const revokeJobMaxAge = 48 * time.Hour
+
+// revokeJobDelay returns jeda antar revoke di satu node (REVOKE_JOB_DELAY_MS, default 1500ms)
+func revokeJobDelay() time.Duration {
+	return time.Duration(helper.GetEnvAsInt("REVOKE_JOB_DELAY_MS", 1500)) * time.Millisecond
+}
+
+// ProcessRevokeJobItems me-revoke item pending milik instance lokal satu per satu dengan jeda
+// REVOKE_JOB_DELAY_MS. Item milik instance di replica lain diproses oleh revoke worker replica tersebut.
+// Returns jumlah item yang diproses.
+func ProcessRevokeJobItems(limit int) (int, error) {
+	if err := model.ExpireRevokeJobItems(revokeJobMaxAge); err != nil {
+		log.Printf("revoke job: %v", err)
+	}
+
+	delay := revokeJobDelay()
+	// Lease harus lebih lama dari waktu memproses satu batch secara berurutan
+	lease := time.Duration(limit)*(delay+webhookTimeout) + time.Minute
+
+	items, err := model.ClaimRevokeJobItems(LocalInstanceIDs(), limit, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for i, item := range items {
+		// Instance pindah node di tengah batch: biarkan pending, lease habis lalu diambil node pemilik baru
+		if !IsLocalInstance(item.InstanceID) {
+			continue
+		}
+		if i > 0 {
+			time.Sleep(delay)
+		}
+
+		errMsg := ""
+		if _, err := RevokeMessage(item.InstanceID, item.WAMessageID); err != nil && !errors.Is(err, ErrMessageRevoked) {
+			errMsg = err.Error()
+		}
+		if err := model.FinishRevokeJobItem(item.ID, errMsg); err != nil {
+			log.Printf("revoke job %d: %v", item.JobID, err)
+			continue
+		}
+		processed++
+	}
+
+	return processed, nil
+}
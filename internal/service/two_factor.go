@@ -0,0 +1,254 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Recovery code: 10 kode sekali pakai, format xxxxx-xxxxx (hex), disimpan sebagai SHA-256
+const (
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 5
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotSetUp       = errors.New("two-factor setup has not been started")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrTwoFactorRequired       = errors.New("two-factor authentication is required for this account")
+	ErrInvalidChallenge        = errors.New("invalid or expired two-factor challenge")
+	ErrTwoFactorEnrollment     = errors.New("two-factor enrollment is required, please login again")
+)
+
+// TwoFactorSetup berisi data untuk authenticator app (secret hanya ditampilkan saat setup)
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCode     string `json:"qr_code"` // data:image/png;base64,...
+}
+
+// ChallengeClaims adalah token login tahap pertama (password benar, kode 2FA belum).
+// Ditandatangani dengan key turunan sehingga tidak bisa dipakai sebagai access token.
+type ChallengeClaims struct {
+	UserID int64 `json:"user_id"`
+	Enroll bool  `json:"enroll,omitempty"` // true = user wajib setup 2FA dulu (kebijakan admin)
+	jwt.RegisteredClaims
+}
+
+// TwoFactorRequired returns true jika kebijakan mewajibkan 2FA untuk role user ini
+func TwoFactorRequired(user *model.User) (bool, error) {
+	if user.Role != "admin" {
+		return false, nil
+	}
+
+	policy, err := model.GetSecurityPolicy()
+	if err != nil {
+		return false, err
+	}
+	return policy.RequireTwoFactorForAdmins, nil
+}
+
+// TwoFactorStep menentukan apakah login butuh langkah kedua, dan apakah user harus setup 2FA dulu
+func TwoFactorStep(user *model.User) (needed bool, enroll bool, err error) {
+	if user.TwoFactorEnabled {
+		return true, false, nil
+	}
+
+	required, err := TwoFactorRequired(user)
+	if err != nil {
+		return false, false, err
+	}
+	return required, required, nil
+}
+
+// SetupTwoFactor membuat secret pending baru; 2FA baru aktif setelah EnableTwoFactor
+func SetupTwoFactor(user *model.User) (*TwoFactorSetup, error) {
+	secret, err := helper.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := model.SetPendingTOTPSecret(user.ID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	uri := helper.TOTPURI(totpIssuer, user.Username, secret)
+	qr, err := helper.TOTPQRCode(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{Secret: secret, OTPAuthURI: uri, QRCode: qr}, nil
+}
+
+// EnableTwoFactor memverifikasi kode pertama dari secret pending, mengaktifkan 2FA
+// dan mengembalikan recovery code (hanya ditampilkan sekali)
+func EnableTwoFactor(userID int64, code string) ([]string, error) {
+	totp, err := model.GetUserTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if !totp.Secret.Valid {
+		return nil, ErrTwoFactorNotSetUp
+	}
+
+	if err := consumeTOTP(userID, totp.Secret.String, code); err != nil {
+		return nil, err
+	}
+
+	if err := model.EnableTOTP(userID); err != nil {
+		return nil, err
+	}
+
+	return RegenerateRecoveryCodes(userID)
+}
+
+// DisableTwoFactor menghapus secret dan recovery code
+func DisableTwoFactor(userID int64) error {
+	return model.DisableTOTP(userID)
+}
+
+// VerifyTwoFactorCode menerima kode TOTP atau recovery code; returns metode yang dipakai ("totp" / "recovery_code")
+func VerifyTwoFactorCode(userID int64, code string) (string, error) {
+	totp, err := model.GetUserTOTP(userID)
+	if err != nil {
+		return "", err
+	}
+	if !totp.Enabled || !totp.Secret.Valid {
+		return "", ErrTwoFactorNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == helper.TOTPDigits {
+		if err := consumeTOTP(userID, totp.Secret.String, code); err != nil {
+			return "", err
+		}
+		return "totp", nil
+	}
+
+	used, err := model.UseRecoveryCode(userID, hashRecoveryCode(code))
+	if err != nil {
+		return "", err
+	}
+	if !used {
+		return "", ErrInvalidTwoFactorCode
+	}
+	return "recovery_code", nil
+}
+
+// RegenerateRecoveryCodes mengganti semua recovery code; returns kode plaintext
+func RegenerateRecoveryCodes(userID int64) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := randomHex(recoveryCodeBytes)
+		if err != nil {
+			return nil, err
+		}
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	if err := model.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// GenerateTwoFactorChallenge membuat challenge token setelah password benar
+func GenerateTwoFactorChallenge(user *model.User, enroll bool) (string, time.Time, error) {
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(twoFactorChallengeExpiry)
+	claims := &ChallengeClaims{
+		UserID: user.ID,
+		Enroll: enroll,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(challengeKey())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ValidateTwoFactorChallenge validates a challenge token and returns its claims
+func ValidateTwoFactorChallenge(tokenString string) (*ChallengeClaims, error) {
+	claims := &ChallengeClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return challengeKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil, ErrInvalidChallenge
+	}
+	return claims, nil
+}
+
+// ConsumeTwoFactorChallenge menandai challenge token sudah dipakai setelah kode 2FA benar,
+// supaya token yang sama tidak bisa ditukar lagi dengan kode berikutnya
+func ConsumeTwoFactorChallenge(claims *ChallengeClaims) error {
+	fresh, err := model.ConsumeTwoFactorChallenge(claims.ID, claims.UserID, claims.ExpiresAt.Time)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidChallenge
+	}
+	return nil
+}
+
+// consumeTOTP memvalidasi kode dan mencatat step-nya supaya kode yang sama tidak bisa dipakai dua kali
+func consumeTOTP(userID int64, secret, code string) error {
+	step, ok := helper.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	fresh, err := model.ConsumeTOTPStep(userID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// challengeKey diturunkan dari JWT_SECRET, berbeda dari key access token
+func challengeKey() []byte {
+	return derivedKey("2fa-challenge")
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+	if len(normalized) == recoveryCodeBytes*2 {
+		normalized = normalized[:5] + "-" + normalized[5:]
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
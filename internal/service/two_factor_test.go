@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gowa-yourself/internal/model"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestHashRecoveryCodeNormalization(t *testing.T) {
+	want := hashRecoveryCode("abcde-12345")
+
+	same := []string{
+		"ABCDE-12345",
+		"abcde12345",
+		" abcde-12345 ",
+		"abcde 12345",
+		"AbCdE 123 45",
+	}
+	for _, code := range same {
+		if got := hashRecoveryCode(code); got != want {
+			t.Errorf("hashRecoveryCode(%q) differs from abcde-12345", code)
+		}
+	}
+
+	different := []string{
+		"abcde-12346",
+		"abcd-12345",
+		"abcde_12345",
+		"",
+	}
+	for _, code := range different {
+		if got := hashRecoveryCode(code); got == want {
+			t.Errorf("hashRecoveryCode(%q) matches abcde-12345", code)
+		}
+	}
+}
+
+func TestTwoFactorChallengeHasNonce(t *testing.T) {
+	InitAuthConfig("test-secret")
+	user := &model.User{ID: 42}
+
+	first, _, err := GenerateTwoFactorChallenge(user, false)
+	if err != nil {
+		t.Fatalf("GenerateTwoFactorChallenge: %v", err)
+	}
+	second, _, err := GenerateTwoFactorChallenge(user, false)
+	if err != nil {
+		t.Fatalf("GenerateTwoFactorChallenge: %v", err)
+	}
+
+	a, err := ValidateTwoFactorChallenge(first)
+	if err != nil {
+		t.Fatalf("ValidateTwoFactorChallenge: %v", err)
+	}
+	b, err := ValidateTwoFactorChallenge(second)
+	if err != nil {
+		t.Fatalf("ValidateTwoFactorChallenge: %v", err)
+	}
+
+	if a.ID == "" || a.ID == b.ID {
+		t.Fatalf("challenge jti must be unique, got %q and %q", a.ID, b.ID)
+	}
+	if a.UserID != 42 {
+		t.Fatalf("UserID = %d, want 42", a.UserID)
+	}
+}
+
+func TestTwoFactorChallengeRejectsTokenWithoutNonce(t *testing.T) {
+	InitAuthConfig("test-secret")
+
+	claims := &ChallengeClaims{
+		UserID: 42,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(challengeKey())
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	if _, err := ValidateTwoFactorChallenge(token); !errors.Is(err, ErrInvalidChallenge) {
+		t.Fatalf("err = %v, want ErrInvalidChallenge", err)
+	}
+}
+
+func TestTwoFactorChallengeRejectsAccessTokenKey(t *testing.T) {
+	InitAuthConfig("test-secret")
+
+	claims := &ChallengeClaims{
+		UserID: 42,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "nonce",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+	// Ditandatangani dengan JWT_SECRET langsung (key access token), bukan key turunan
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	if _, err := ValidateTwoFactorChallenge(token); !errors.Is(err, ErrInvalidChallenge) {
+		t.Fatalf("err = %v, want ErrInvalidChallenge", err)
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Panjang password sementara yang dibuat saat admin reset password tanpa mengisi password
+const temporaryPasswordBytes = 9
+
+var (
+	ErrLastActiveAdmin       = errors.New("cannot remove the last active admin")
+	ErrCannotImpersonate     = errors.New("this user cannot be impersonated")
+	ErrPasswordResetNotLocal = errors.New("password can only be reset for local accounts")
+)
+
+// IsValidRole mengecek role user yang dikenal (sama dengan constraint chk_role)
+func IsValidRole(role string) bool {
+	return role == "admin" || role == "user" || role == "viewer"
+}
+
+// EnsureNotLastAdmin menolak perubahan yang membuat tidak ada admin aktif tersisa
+// (nonaktifkan atau turunkan role admin terakhir)
+func EnsureNotLastAdmin(user *model.User) error {
+	if user.Role != "admin" || !user.IsActive {
+		return nil
+	}
+
+	count, err := model.CountActiveAdmins()
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastActiveAdmin
+	}
+	return nil
+}
+
+// ResetUserPassword memasang password sementara (dibuat acak jika kosong), mewajibkan user
+// menggantinya saat login berikutnya dan mencabut semua sesi. Returns password sementara.
+func ResetUserPassword(user *model.User, password string) (string, error) {
+	if user.AuthProvider != "local" {
+		return "", ErrPasswordResetNotLocal
+	}
+
+	if password == "" {
+		generated, err := randomHex(temporaryPasswordBytes)
+		if err != nil {
+			return "", err
+		}
+		password = generated
+	}
+
+	hash, err := helper.HashPassword(password)
+	if err != nil {
+		return "", err
+	}
+
+	if err := model.SetTemporaryPassword(user.ID, hash); err != nil {
+		return "", err
+	}
+	if err := RevokeAllUserSessions(user.ID); err != nil {
+		return "", err
+	}
+
+	return password, nil
+}
+
+// GenerateImpersonationToken membuat access token berumur pendek atas nama target untuk admin
+// (support). Tidak ada refresh token; claim impersonator dipakai middleware untuk audit.
+func GenerateImpersonationToken(target *model.User, admin *Claims) (string, time.Time, error) {
+	if target.ID == admin.UserID || target.Role == "admin" || !target.IsActive {
+		return "", time.Time{}, ErrCannotImpersonate
+	}
+
+	expiresAt := time.Now().Add(impersonationExpiry)
+	claims := &Claims{
+		UserID:               target.ID,
+		Username:             target.Username,
+		Role:                 target.Role,
+		ImpersonatorID:       admin.UserID,
+		ImpersonatorUsername: admin.Username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
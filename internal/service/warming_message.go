@@ -149,10 +149,12 @@ func sendWarmingMessageInternal(senderSession *model.Session, recipientJID types
 		Conversation: &message,
 	}
 
-	_, err := senderSession.Client.SendMessage(ctx, recipientJID, msg)
+	resp, err := senderSession.Client.SendMessage(ctx, recipientJID, msg)
 	if err != nil {
 		return false, fmt.Sprintf("failed to send message: %v", err)
 	}
 
+	RecordOutgoingMessage(senderSession.ID, recipientJID, msg, resp)
+
 	return true, ""
 }
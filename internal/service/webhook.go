@@ -1,49 +1,83 @@
 package service
 
 import (
-	"bytes"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
 	"log"
-	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/ws"
 )
 
-// ✅ FIX: Tambahkan struct untuk webhook config dengan TTL
-type WebhookConfig struct {
+// WebhookEndpoint adalah satu tujuan webhook untuk sebuah instance: webhook_url lama di tabel
+// instances (WebhookID = 0) atau endpoint dari tabel webhooks (scope instance / circle).
+type WebhookEndpoint struct {
+	WebhookID int64
 	URL       string
 	Secret    string
-	ExpiresAt time.Time // ← Tambahkan expiry time
+	Events    []string // Event yang di-subscribe, "*" = semua
+	Headers   map[string]string
 }
 
-// ✅ FIX: Cache untuk webhook config (menghindari N+1 query)
+// Subscribes mengecek apakah endpoint subscribe ke event tertentu ("*" berarti semua event)
+func (e *WebhookEndpoint) Subscribes(event string) bool {
+	for _, ev := range e.Events {
+		if ev == "*" || ev == event {
+			return true
+		}
+	}
+	return false
+}
+
+// instanceWebhooks adalah isi cache per instance
+type instanceWebhooks struct {
+	Endpoints []WebhookEndpoint
+	ExpiresAt time.Time
+}
+
+// ParseWebhookEvents memecah kolom webhook_events / webhooks.events (comma separated)
+func ParseWebhookEvents(raw string) []string {
+	var events []string
+	for _, e := range strings.Split(raw, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// ✅ FIX: Cache untuk webhook endpoint (menghindari N+1 query)
 var (
-	webhookCache      = make(map[string]*WebhookConfig)
+	webhookCache      = make(map[string]*instanceWebhooks)
 	webhookCacheMutex sync.RWMutex
 	webhookCacheTTL   = 5 * time.Minute // Cache valid selama 5 menit
+
+	// Cluster: invalidasi diteruskan ke node lain lewat realtime relay. Tanpa relay cache dimatikan,
+	// supaya replica lain tidak mengirim ke endpoint / secret yang sudah dihapus.
+	webhookCacheBroadcast func(instanceID string)
+	webhookCacheDisabled  bool
 )
 
+// WebhookCacheControl adalah nama pesan kontrol relay untuk invalidasi cache webhook antar node
+const WebhookCacheControl = "webhook_cache_invalidate"
+
 type WebhookPayload struct {
 	Event     string      `json:"event"`
 	Timestamp time.Time   `json:"timestamp"`
 	Data      interface{} `json:"data"`
 }
 
-// ✅ FIX: Function untuk get webhook config dengan caching + TTL
-func GetWebhookConfig(instanceID string) (*WebhookConfig, error) {
+// GetWebhookEndpoints returns semua endpoint aktif untuk instance (dengan caching + TTL)
+func GetWebhookEndpoints(instanceID string) ([]WebhookEndpoint, error) {
 	// Cek cache dulu
 	webhookCacheMutex.RLock()
-	config, exists := webhookCache[instanceID]
+	cached, exists := webhookCache[instanceID]
+	disabled := webhookCacheDisabled
 	webhookCacheMutex.RUnlock()
 
-	// ✅ IMPROVEMENT: Cek apakah cache masih valid (belum expired)
-	if exists && config != nil && time.Now().Before(config.ExpiresAt) {
-		return config, nil
+	if !disabled && exists && cached != nil && time.Now().Before(cached.ExpiresAt) {
+		return cached.Endpoints, nil
 	}
 
 	// Cache miss atau expired - load dari DB
@@ -52,73 +86,126 @@ func GetWebhookConfig(instanceID string) (*WebhookConfig, error) {
 		return nil, err
 	}
 
-	// Buat config object dengan expiry time
-	config = &WebhookConfig{
-		URL:       inst.WebhookURL.String,
-		Secret:    inst.WebhookSecret.String,
-		ExpiresAt: time.Now().Add(webhookCacheTTL), // ← Set expiry
+	var endpoints []WebhookEndpoint
+
+	// Webhook lama di tabel instances (webhook-setconfig). Tanpa daftar event hanya incoming_message.
+	if inst.WebhookURL.String != "" {
+		events := ParseWebhookEvents(inst.WebhookEvents.String)
+		if len(events) == 0 {
+			events = []string{ws.EventIncomingMessage}
+		}
+		endpoints = append(endpoints, WebhookEndpoint{
+			URL:    inst.WebhookURL.String,
+			Secret: inst.WebhookSecret.String,
+			Events: events,
+		})
+	}
+
+	// Endpoint dari tabel webhooks (scope instance atau circle). Tanpa filter = semua event.
+	webhooks, err := model.GetMatchingWebhooks(instanceID, inst.Circle)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range webhooks {
+		events := ParseWebhookEvents(w.Events.String)
+		if len(events) == 0 {
+			events = []string{"*"}
+		}
+		endpoints = append(endpoints, WebhookEndpoint{
+			WebhookID: w.ID,
+			URL:       w.URL,
+			Secret:    w.Secret.String,
+			Events:    events,
+			Headers:   w.HeaderMap(),
+		})
 	}
 
 	// Simpan ke cache
 	webhookCacheMutex.Lock()
-	webhookCache[instanceID] = config
+	if !webhookCacheDisabled {
+		webhookCache[instanceID] = &instanceWebhooks{
+			Endpoints: endpoints,
+			ExpiresAt: time.Now().Add(webhookCacheTTL),
+		}
+	}
 	webhookCacheMutex.Unlock()
 
-	log.Printf("✅ Webhook config cached for instance: %s (expires in %v)", instanceID, webhookCacheTTL)
-	return config, nil
+	return endpoints, nil
 }
 
 // ✅ FIX: Function untuk invalidate cache (dipanggil saat webhook config diupdate)
 func InvalidateWebhookCache(instanceID string) {
-	webhookCacheMutex.Lock()
-	delete(webhookCache, instanceID)
-	webhookCacheMutex.Unlock()
-	log.Printf("🗑️ Webhook cache invalidated for instance: %s", instanceID)
+	ApplyWebhookCacheInvalidation(instanceID)
+	broadcastWebhookCacheInvalidation(instanceID)
 }
 
-// ✅ FIX: Refactored function - sekarang pakai cache
-func SendIncomingMessageWebhook(instanceID string, data map[string]interface{}) {
-	// Get webhook config dari cache (bukan DB!)
-	config, err := GetWebhookConfig(instanceID)
-	if err != nil || config.URL == "" {
-		return
-	}
+// InvalidateAllWebhookCache mengosongkan cache semua instance
+// (dipanggil saat endpoint di tabel webhooks berubah, karena scope circle bisa kena banyak instance)
+func InvalidateAllWebhookCache() {
+	ApplyWebhookCacheInvalidation("")
+	broadcastWebhookCacheInvalidation("")
+}
 
-	payload := WebhookPayload{
-		Event:     "incoming_message",
-		Timestamp: time.Now().UTC(),
-		Data:      data,
+// ApplyWebhookCacheInvalidation mengosongkan cache lokal satu instance ("" = semua instance).
+// Juga dipanggil untuk pesan kontrol WebhookCacheControl dari node lain.
+func ApplyWebhookCacheInvalidation(instanceID string) {
+	webhookCacheMutex.Lock()
+	if instanceID == "" {
+		webhookCache = make(map[string]*instanceWebhooks)
+	} else {
+		delete(webhookCache, instanceID)
 	}
+	webhookCacheMutex.Unlock()
 
-	body, err := json.Marshal(payload)
-	if err != nil {
-		log.Printf("webhook: marshal error: %v", err)
-		return
+	if instanceID == "" {
+		log.Println("🗑️ Webhook cache invalidated for all instances")
+	} else {
+		log.Printf("🗑️ Webhook cache invalidated for instance: %s", instanceID)
 	}
+}
 
-	req, err := http.NewRequest("POST", config.URL, bytes.NewReader(body))
-	if err != nil {
-		log.Printf("webhook: new request error: %v", err)
-		return
+// SetWebhookCacheBroadcast mengatur pengiriman invalidasi cache ke node lain (realtime relay)
+func SetWebhookCacheBroadcast(broadcast func(instanceID string)) {
+	webhookCacheMutex.Lock()
+	webhookCacheBroadcast = broadcast
+	webhookCacheMutex.Unlock()
+}
+
+// DisableWebhookCache mematikan cache endpoint (mode cluster tanpa realtime relay)
+func DisableWebhookCache() {
+	webhookCacheMutex.Lock()
+	webhookCacheDisabled = true
+	webhookCache = make(map[string]*instanceWebhooks)
+	webhookCacheMutex.Unlock()
+}
+
+func broadcastWebhookCacheInvalidation(instanceID string) {
+	webhookCacheMutex.RLock()
+	broadcast := webhookCacheBroadcast
+	webhookCacheMutex.RUnlock()
+
+	if broadcast != nil {
+		broadcast(instanceID)
 	}
-	req.Header.Set("Content-Type", "application/json")
+}
 
-	// If webhook_secret is set, add HMAC signature header
-	if config.Secret != "" {
-		mac := hmac.New(sha256.New, []byte(config.Secret))
-		mac.Write(body)
-		signature := hex.EncodeToString(mac.Sum(nil))
+// ✅ FIX: Refactored function - sekarang pakai cache
+func SendIncomingMessageWebhook(instanceID string, data map[string]interface{}) {
+	SendInstanceWebhook(instanceID, ws.EventIncomingMessage, data)
+}
 
-		req.Header.Set("X-SUDEVWA-Signature", signature)
+// SendInstanceWebhook mengirim event ke semua endpoint instance yang subscribe ke event tersebut.
+// Delivery disimpan di webhook_deliveries dulu, jadi kalau receiver sedang down akan di-retry.
+func SendInstanceWebhook(instanceID string, event string, data interface{}) {
+	// Get endpoint dari cache (bukan DB!)
+	endpoints, err := GetWebhookEndpoints(instanceID)
+	if err != nil {
+		return
 	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	go func() {
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Printf("webhook: send error: %v", err)
-			return
+	for _, endpoint := range endpoints {
+		if endpoint.Subscribes(event) {
+			EnqueueWebhook(instanceID, event, endpoint, data)
 		}
-		_ = resp.Body.Close()
-	}()
+	}
 }
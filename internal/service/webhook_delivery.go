@@ -0,0 +1,203 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+)
+
+// webhookTimeout adalah batas waktu satu POST webhook
+const webhookTimeout = 10 * time.Second
+
+// webhookLease adalah jeda sebelum dispatcher boleh mengambil delivery yang sedang dikirim,
+// supaya percobaan pertama (langsung) dan dispatcher (node lain) tidak mengirim dua kali.
+// Harus jauh lebih besar dari webhookTimeout: satu batch dikirim paralel, jadi selesai dalam ~1 timeout.
+const webhookLease = 6 * webhookTimeout
+
+var webhookHTTPClient = &http.Client{Timeout: webhookTimeout}
+
+// SignWebhookPayload menghasilkan HMAC SHA256 (hex) untuk header X-SUDEVWA-Signature
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookMaxAttempts returns the maximum number of attempts before a delivery becomes dead
+func WebhookMaxAttempts() int {
+	return helper.GetEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8)
+}
+
+// webhookBackoff menghitung jeda retry (exponential): base * 2^(attempt-1), dibatasi WEBHOOK_RETRY_MAX_SECONDS
+func webhookBackoff(attempt int) time.Duration {
+	base := helper.GetEnvAsInt("WEBHOOK_RETRY_BASE_SECONDS", 10)
+	maxDelay := helper.GetEnvAsInt("WEBHOOK_RETRY_MAX_SECONDS", 3600)
+
+	delay := base
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return time.Duration(delay) * time.Second
+}
+
+// EnqueueWebhook menyimpan delivery ke webhook_deliveries lalu langsung mencoba mengirimnya.
+// Jika gagal, dispatcher (StartWebhookWorker) akan me-retry dengan exponential backoff.
+func EnqueueWebhook(instanceID string, event string, endpoint WebhookEndpoint, data interface{}) {
+	payload := WebhookPayload{
+		Event:     event,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("webhook: marshal error: %v", err)
+		return
+	}
+
+	delivery := &model.WebhookDelivery{
+		InstanceID:    sql.NullString{String: instanceID, Valid: instanceID != ""},
+		WebhookID:     sql.NullInt64{Int64: endpoint.WebhookID, Valid: endpoint.WebhookID > 0},
+		Event:         event,
+		URL:           endpoint.URL,
+		Payload:       string(body),
+		MaxAttempts:   WebhookMaxAttempts(),
+		NextAttemptAt: time.Now().Add(webhookLease),
+	}
+	if endpoint.Secret != "" {
+		delivery.Signature = sql.NullString{String: SignWebhookPayload(endpoint.Secret, body), Valid: true}
+	}
+	if len(endpoint.Headers) > 0 {
+		if headers, err := json.Marshal(endpoint.Headers); err == nil {
+			delivery.Headers = sql.NullString{String: string(headers), Valid: true}
+		}
+	}
+
+	if err := model.CreateWebhookDelivery(delivery); err != nil {
+		log.Printf("webhook: failed to persist delivery for instance %s: %v", instanceID, err)
+		return
+	}
+
+	go AttemptWebhookDelivery(delivery)
+}
+
+// AttemptWebhookDelivery melakukan satu kali POST dan mencatat hasilnya
+func AttemptWebhookDelivery(d *model.WebhookDelivery) {
+	statusCode, err := postWebhook(d)
+	if err == nil {
+		if err := model.MarkWebhookDeliverySuccess(d.ID, statusCode); err != nil {
+			log.Printf("webhook: %v", err)
+		}
+		return
+	}
+
+	nextAttemptAt := time.Now().Add(webhookBackoff(d.Attempts + 1))
+	status, markErr := model.MarkWebhookDeliveryFailed(d.ID, statusCode, err.Error(), nextAttemptAt)
+	if markErr != nil {
+		log.Printf("webhook: %v", markErr)
+		return
+	}
+
+	if status == model.WebhookDeliveryDead {
+		log.Printf("☠️ Webhook delivery %d (%s) moved to dead-letter after %d attempts: %v", d.ID, d.Event, d.Attempts+1, err)
+	} else {
+		log.Printf("⚠️ Webhook delivery %d (%s) failed (attempt %d), retry at %s: %v",
+			d.ID, d.Event, d.Attempts+1, nextAttemptAt.Format(time.RFC3339), err)
+	}
+}
+
+// postWebhook mengirim payload; status 2xx dianggap sukses
+func postWebhook(d *model.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest("POST", d.URL, bytes.NewReader([]byte(d.Payload)))
+	if err != nil {
+		return 0, fmt.Errorf("new request error: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Custom headers dari endpoint (diset dulu supaya header sistem di bawah tidak bisa ditimpa)
+	if d.Headers.Valid && d.Headers.String != "" {
+		var headers map[string]string
+		if err := json.Unmarshal([]byte(d.Headers.String), &headers); err == nil {
+			for k, v := range headers {
+				req.Header.Set(k, v)
+			}
+		}
+	}
+
+	req.Header.Set("X-SUDEVWA-Delivery", strconv.FormatInt(d.ID, 10))
+	req.Header.Set("X-SUDEVWA-Event", d.Event)
+
+	// If webhook_secret is set, add HMAC signature header
+	if d.Signature.Valid && d.Signature.String != "" {
+		req.Header.Set("X-SUDEVWA-Signature", d.Signature.String)
+	}
+
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("send error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("receiver returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return resp.StatusCode, nil
+}
+
+// ProcessDueWebhookDeliveries mengambil delivery yang jatuh tempo dan mengirim ulang.
+// Semua delivery dalam batch dikirim paralel supaya selesai sebelum lease habis; jika dikirim
+// berurutan (50 x timeout 10s) node lain bisa mengambil ulang delivery yang masih dikirim.
+// Returns jumlah delivery yang diproses.
+func ProcessDueWebhookDeliveries(limit int) (int, error) {
+	deliveries, err := model.ClaimDueWebhookDeliveries(limit, webhookLease)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(d *model.WebhookDelivery) {
+			defer wg.Done()
+			AttemptWebhookDelivery(d)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// ReplayWebhookDelivery mengirim ulang delivery (termasuk yang sudah success atau dead)
+func ReplayWebhookDelivery(id int64) (*model.WebhookDelivery, error) {
+	if err := model.ResetWebhookDeliveryForReplay(id, time.Now().Add(webhookLease)); err != nil {
+		return nil, err
+	}
+
+	delivery, err := model.GetWebhookDeliveryByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	AttemptWebhookDelivery(delivery)
+
+	return model.GetWebhookDeliveryByID(id)
+}
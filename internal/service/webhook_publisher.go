@@ -0,0 +1,36 @@
+package service
+
+import (
+	"gowa-yourself/config"
+	"gowa-yourself/internal/ws"
+)
+
+// WebhookPublisher membungkus RealtimePublisher (hub WebSocket) supaya setiap Publish
+// juga diteruskan ke webhook instance yang subscribe ke event tersebut.
+type WebhookPublisher struct {
+	ws.RealtimePublisher
+}
+
+// NewWebhookPublisher membuat publisher yang mengirim ke WebSocket dan webhook sekaligus
+func NewWebhookPublisher(inner ws.RealtimePublisher) *WebhookPublisher {
+	return &WebhookPublisher{RealtimePublisher: inner}
+}
+
+// Publish mengirim event ke semua client WebSocket, lalu ke webhook instance terkait
+func (p *WebhookPublisher) Publish(event ws.WsEvent) {
+	p.RealtimePublisher.Publish(event)
+
+	if !config.EnableWebhook {
+		return
+	}
+
+	for _, instanceID := range eventInstanceIDs(event.Data) {
+		go SendInstanceWebhook(instanceID, event.Event, event.Data)
+	}
+}
+
+// eventInstanceIDs mencari instance pemilik event dari payload (instance_id, atau
+// sender/receiver_instance_id untuk event warming). Payload bisa struct maupun map.
+func eventInstanceIDs(data interface{}) []string {
+	return ws.ScopeOf(data).InstanceIDs
+}
@@ -251,11 +251,13 @@ func eventHandler(instanceID string) func(evt interface{}) {
 			sessionsLock.Lock()
 			delete(sessions, instanceID)
 			sessionsLock.Unlock()
+			releaseInstanceLease(instanceID)
 
 			fmt.Println("✓ Session cleanup completed for:", instanceID)
 
 		case *events.StreamReplaced:
 			fmt.Println("⚠ Stream replaced! Instance:", instanceID)
+			handleStreamReplaced(instanceID)
 
 		case *events.Disconnected:
 			loggingOutLock.RLock()
@@ -280,8 +282,41 @@ func eventHandler(instanceID string) func(evt interface{}) {
 				}
 			}
 
+		// Delivery / read receipt untuk pesan keluar
+		case *events.Receipt:
+			handleReceipt(instanceID, v)
+
+		// Perubahan peserta / info grup dan instance masuk ke grup baru
+		case *events.GroupInfo:
+			handleGroupInfo(instanceID, v)
+
+		case *events.JoinedGroup:
+			handleJoinedGroup(instanceID, v)
+
+		// Simpan pesan dari history sync ke inbox
+		case *events.HistorySync:
+			saveHistorySync(instanceID, v)
+
 		//Handle incoming messages
 		case *events.Message:
+			// Vote poll bukan pesan chat: cukup update tally dan publish poll_vote
+			if v.Message.GetPollUpdateMessage() != nil {
+				handlePollVote(instanceID, v)
+				return
+			}
+
+			// Simpan semua pesan (termasuk pesan lama & echo dari HP sendiri) ke inbox
+			senderNumber := ResolveSenderNumber(instanceID, v.Info.Sender)
+			saveMessageEvent(instanceID, v, senderNumber)
+
+			// Download media masuk (image, video, audio, document, sticker) di background supaya bisa
+			// diambil lagi lewat /api/media/:instanceId/:messageId. Termasuk pesan yang masuk saat offline.
+			content := ExtractMessageContent(v.Message)
+			var mediaQueued bool
+			if !v.Info.IsFromMe && content.Downloadable != nil {
+				mediaQueued = QueueIncomingMedia(instanceID, v.Info.ID, content)
+			}
+
 			msgTime := v.Info.Timestamp
 
 			// Filter pesan lama (History Sync)
@@ -314,6 +349,11 @@ func eventHandler(instanceID string) func(evt interface{}) {
 				messageText = v.Message.GetVideoMessage().GetCaption()
 			}
 
+			// Handle document caption
+			if messageText == "" && v.Message.DocumentMessage != nil {
+				messageText = v.Message.GetDocumentMessage().GetCaption()
+			}
+
 			fmt.Printf("📨 Received message from %s: %s\n", v.Info.Sender, messageText)
 
 			// Debug logging for sender investigation
@@ -321,27 +361,17 @@ func eventHandler(instanceID string) func(evt interface{}) {
 			fmt.Printf("🔍 DEBUG - User: %s, Server: %s\n", v.Info.Sender.User, v.Info.Sender.Server)
 			fmt.Printf("🔍 DEBUG - IsGroup: %v, IsFromMe: %v\n", v.Info.IsGroup, v.Info.IsFromMe)
 
-			senderNumber := v.Info.Sender.User
-
-			// If message from linked device (@lid), resolve to real phone number
+			// If message from linked device (@lid), senderNumber sudah di-resolve ke nomor asli (jika bisa)
 			if v.Info.Sender.Server == "lid" {
-				session, err := GetSession(instanceID)
-				if err == nil && session.Client != nil {
-					ctx := context.Background()
-
-					// Convert LID to Phone Number using whatsmeow's LID store
-					phoneJID, err := session.Client.Store.LIDs.GetPNForLID(ctx, v.Info.Sender)
-					if err == nil && phoneJID.User != "" {
-						senderNumber = phoneJID.User
-						log.Printf("✅ Resolved LID %s to phone number: %s", v.Info.Sender.User, senderNumber)
-					} else {
-						log.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-						log.Printf("⚠️ HUMAN_VS_BOT: Could not resolve LID to phone number")
-						log.Printf("👤 Contact Name: %s", v.Info.PushName)
-						log.Printf("🔑 LID (Use this for whitelisting): %s", v.Info.Sender.User)
-						log.Printf("💡 To enable auto-reply, set whitelisted_number = '%s'", v.Info.Sender.User)
-						log.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-					}
+				if senderNumber != v.Info.Sender.User {
+					log.Printf("✅ Resolved LID %s to phone number: %s", v.Info.Sender.User, senderNumber)
+				} else {
+					log.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+					log.Printf("⚠️ HUMAN_VS_BOT: Could not resolve LID to phone number")
+					log.Printf("👤 Contact Name: %s", v.Info.PushName)
+					log.Printf("🔑 LID (Use this for whitelisting): %s", v.Info.Sender.User)
+					log.Printf("💡 To enable auto-reply, set whitelisted_number = '%s'", v.Info.Sender.User)
+					log.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 				}
 			}
 
@@ -360,6 +390,10 @@ func eventHandler(instanceID string) func(evt interface{}) {
 				"message_id":  v.Info.ID,
 				"push_name":   v.Info.PushName,
 			}
+			payload["message_type"] = content.Type
+			for k, val := range mediaPayload(instanceID, v.Info.ID, content, mediaQueued) {
+				payload[k] = val
+			}
 
 			// Broadcast ke WebSocket (jika diaktifkan)
 			if config.EnableWebsocketIncomingMessage && Realtime != nil {
@@ -418,47 +452,63 @@ func LoadAllDevices() error {
 			time.Sleep(time.Duration(delaySeconds) * time.Second)
 		}
 
-		// 2) Buat client WhatsMeow dan attach event handler dengan instanceID yang benar
-		client := whatsmeow.NewClient(device, nil)
-		client.AddEventHandler(eventHandler(instanceID))
-
-		if err := client.Connect(); err != nil {
+		if err := connectDevice(device, instanceID); err != nil {
 			fmt.Printf("Failed to connect device %s: %v\n", jid, err)
 			continue
 		}
+	}
 
-		// 3) Simpan ke sessions map dengan key instanceID yang konsisten
-		sessionsLock.Lock()
-		sessions[instanceID] = &model.Session{
-			ID:          instanceID,
-			JID:         jid,
-			Client:      client,
-			IsConnected: client.IsConnected(),
-		}
-		sessionsLock.Unlock()
+	return nil
+}
 
-		// 4) Update status di DB bahwa instance ini berhasil re-connect
-		//    (kalau client.IsConnected() == true)
-		if client.IsConnected() {
-			phoneNumber := helper.ExtractPhoneFromJID(jid) // mis. "6285148107612"
-
-			if err := model.UpdateInstanceOnConnected(
-				instanceID,
-				jid,
-				phoneNumber,
-				"", // platform sementara kosong
-			); err != nil {
-				fmt.Printf("Warning: failed to update instance on reconnect %s: %v\n", instanceID, err)
-			}
-		}
+// connectDevice membuat client WhatsMeow untuk device tersimpan, connect, lalu menyimpannya ke sessions.
+// Dipakai saat boot (LoadAllDevices) dan saat replica mengambil alih instance (cluster).
+func connectDevice(device *store.Device, instanceID string) error {
+	jid := device.ID.String()
+
+	// 1) Buat client WhatsMeow dan attach event handler dengan instanceID yang benar
+	client := whatsmeow.NewClient(device, nil)
+	client.AddEventHandler(eventHandler(instanceID))
+
+	if err := client.Connect(); err != nil {
+		return err
+	}
+
+	// 2) Simpan ke sessions map dengan key instanceID yang konsisten
+	sessionsLock.Lock()
+	sessions[instanceID] = &model.Session{
+		ID:          instanceID,
+		JID:         jid,
+		Client:      client,
+		IsConnected: client.IsConnected(),
+	}
+	sessionsLock.Unlock()
 
-		fmt.Printf("✓ Loaded and connected: %s (instance: %s)\n", jid, instanceID)
+	// 3) Update status di DB bahwa instance ini berhasil re-connect
+	//    (kalau client.IsConnected() == true)
+	if client.IsConnected() {
+		phoneNumber := helper.ExtractPhoneFromJID(jid) // mis. "6285148107612"
+
+		if err := model.UpdateInstanceOnConnected(
+			instanceID,
+			jid,
+			phoneNumber,
+			"", // platform sementara kosong
+		); err != nil {
+			fmt.Printf("Warning: failed to update instance on reconnect %s: %v\n", instanceID, err)
+		}
 	}
 
+	fmt.Printf("✓ Loaded and connected: %s (instance: %s)\n", jid, instanceID)
 	return nil
 }
 
 func CreateSession(instanceID string) (*model.Session, error) {
+	// Mode cluster: instance hanya boleh dibuat di replica yang memegang lease-nya
+	if err := acquireInstanceLease(instanceID); err != nil {
+		return nil, err
+	}
+
 	sessionsLock.Lock()
 	defer sessionsLock.Unlock()
 
@@ -551,6 +601,7 @@ func DeleteSession(instanceID string) error {
 	// Hapus dari map sessions (memory)
 	delete(sessions, instanceID)
 	sessionsLock.Unlock()
+	releaseInstanceLease(instanceID)
 
 	// ✅ FIX: Stop heartbeat goroutine sebelum logout
 	if session.HeartbeatCancel != nil {
@@ -641,10 +692,13 @@ func DeleteInstance(instanceID string) error {
 // Hapus session whatsmeow
 func DeleteSessionFromMemory(instanceID string) {
 	sessionsLock.Lock()
-	defer sessionsLock.Unlock()
+	_, ok := sessions[instanceID]
+	delete(sessions, instanceID)
+	sessionsLock.Unlock()
 
-	if _, ok := sessions[instanceID]; ok {
-		delete(sessions, instanceID)
+	// Lease dilepas setelah unlock: query DB tidak boleh menahan sessionsLock
+	if ok {
+		releaseInstanceLease(instanceID)
 		fmt.Println("Session removed from memory:", instanceID)
 	}
 }
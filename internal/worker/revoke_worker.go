@@ -0,0 +1,32 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/service"
+)
+
+// StartRevokeWorker runs bulk revoke jobs (POST /api/blast-outbox/revoke) in background.
+// Setiap node hanya me-revoke pesan milik instance yang session-nya ada di node tersebut.
+func StartRevokeWorker() {
+	log.Println("🗑️ Revoke Job Worker started")
+
+	interval := helper.GetEnvAsInt("REVOKE_JOB_INTERVAL_SECONDS", 5)
+	batchSize := helper.GetEnvAsInt("REVOKE_JOB_BATCH_SIZE", 20)
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		processed, err := service.ProcessRevokeJobItems(batchSize)
+		if err != nil {
+			log.Printf("❌ Revoke worker error: %v", err)
+			continue
+		}
+		if processed > 0 {
+			log.Printf("🗑️ Revoke worker processed %d messages", processed)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+)
+
+// Retention webhook_deliveries: sweep tiap jam, hapus per batch supaya tidak mengunci tabel lama
+const (
+	webhookRetentionInterval  = time.Hour
+	webhookRetentionBatchSize = 5000
+)
+
+// StartWebhookWorker runs the durable webhook dispatcher in background.
+// Mengirim ulang delivery yang gagal (exponential backoff) dan delivery yang di-enqueue oleh outbox worker.
+// Delivery yang sukses dihapus setelah WEBHOOK_DELIVERY_RETENTION_DAYS (default 7, 0 = simpan selamanya).
+func StartWebhookWorker() {
+	log.Println("📮 Webhook Delivery Worker started")
+
+	interval := helper.GetEnvAsInt("WEBHOOK_DISPATCH_INTERVAL_SECONDS", 5)
+	batchSize := helper.GetEnvAsInt("WEBHOOK_DISPATCH_BATCH_SIZE", 50)
+	retentionDays := helper.GetEnvAsInt("WEBHOOK_DELIVERY_RETENTION_DAYS", 7)
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+
+	var lastPrune time.Time
+	for range ticker.C {
+		if retentionDays > 0 && time.Since(lastPrune) >= webhookRetentionInterval {
+			lastPrune = time.Now()
+			pruneWebhookDeliveries(retentionDays)
+		}
+
+		processed, err := service.ProcessDueWebhookDeliveries(batchSize)
+		if err != nil {
+			log.Printf("❌ Webhook worker error: %v", err)
+			continue
+		}
+		if processed > 0 {
+			log.Printf("📮 Webhook worker processed %d deliveries", processed)
+		}
+	}
+}
+
+// pruneWebhookDeliveries menghapus delivery sukses yang lebih tua dari retentionDays
+func pruneWebhookDeliveries(retentionDays int) {
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+
+	var total int64
+	for {
+		deleted, err := model.DeleteDeliveredWebhookDeliveries(cutoff, webhookRetentionBatchSize)
+		if err != nil {
+			log.Printf("❌ Webhook retention error: %v", err)
+			break
+		}
+		total += deleted
+		if deleted < webhookRetentionBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Printf("🧹 Webhook retention removed %d delivered webhooks older than %d days", total, retentionDays)
+	}
+}
@@ -0,0 +1,178 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Interval refresh hak akses client (instance / room baru yang di-assign ikut terlihat tanpa reconnect)
+const accessRefreshInterval = time.Minute
+
+// EventScope adalah instance dan warming room yang terkait sebuah event, diambil dari payload.
+type EventScope struct {
+	InstanceIDs []string
+	RoomID      string
+	Application string // application outbox (message_status), dipakai untuk topic outbox:<app>
+	AdminOnly   bool   // event keamanan (lihat IsAdminOnlyEvent), diisi Hub dari nama event
+}
+
+// ScopeOf mencari instance (instance_id, sender/receiver_instance_id), room_id dan application dari payload.
+// Payload bisa struct maupun map.
+func ScopeOf(data interface{}) EventScope {
+	var scope EventScope
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return scope
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return scope
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range []string{"instance_id", "sender_instance_id", "receiver_instance_id"} {
+		if id, ok := fields[key].(string); ok && id != "" && !seen[id] {
+			seen[id] = true
+			scope.InstanceIDs = append(scope.InstanceIDs, id)
+		}
+	}
+	if roomID, ok := fields["room_id"].(string); ok {
+		scope.RoomID = roomID
+	}
+	if application, ok := fields["application"].(string); ok {
+		scope.Application = application
+	}
+
+	return scope
+}
+
+// ClientAccess adalah instance dan warming room yang boleh dilihat user non-admin
+type ClientAccess struct {
+	InstanceIDs map[string]bool
+	RoomIDs     map[string]bool
+}
+
+// AccessLoader memuat hak akses user (diisi dari service, supaya ws tidak tergantung ke model)
+type AccessLoader func(userID int64) (*ClientAccess, error)
+
+// SetAccessLoader mengatur loader hak akses client, wajib diset sebelum Run
+func (h *Hub) SetAccessLoader(loader AccessLoader) {
+	h.accessLoader = loader
+}
+
+// LoadAccess memuat hak akses client sekarang (dipanggil handler sebelum Register)
+func (h *Hub) LoadAccess(client *Client) error {
+	if client.IsAdmin() || h.accessLoader == nil {
+		return nil
+	}
+
+	access, err := h.accessLoader(client.UserID)
+	if err != nil {
+		return err
+	}
+	client.setAccess(access)
+	return nil
+}
+
+// refreshAccessLoop memperbarui hak akses semua client non-admin secara berkala
+func (h *Hub) refreshAccessLoop() {
+	ticker := time.NewTicker(accessRefreshInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.mu.RLock()
+		clients := make([]*Client, 0, len(h.clients))
+		for client := range h.clients {
+			if !client.IsAdmin() {
+				clients = append(clients, client)
+			}
+		}
+		h.mu.RUnlock()
+
+		// Satu query per user, bukan per koneksi
+		byUser := make(map[int64]*ClientAccess)
+		for _, client := range clients {
+			access, ok := byUser[client.UserID]
+			if !ok {
+				var err error
+				if access, err = h.accessLoader(client.UserID); err != nil {
+					log.Printf("ws: failed to refresh access for user %d: %v", client.UserID, err)
+					continue
+				}
+				byUser[client.UserID] = access
+			}
+			client.setAccess(access)
+		}
+	}
+}
+
+// IsAdmin true jika client login sebagai admin (menerima semua event)
+func (c *Client) IsAdmin() bool {
+	return c.Role == "admin"
+}
+
+// RestrictInstances membatasi client ke instance tertentu di atas hak akses user
+// (mis. allow-list API key). Dipanggil sebelum Register.
+func (c *Client) RestrictInstances(instanceIDs []string) {
+	c.instanceFilter = make(map[string]bool, len(instanceIDs))
+	for _, id := range instanceIDs {
+		c.instanceFilter[id] = true
+	}
+}
+
+// instanceAllowed true jika salah satu instance lolos filter RestrictInstances
+func (c *Client) instanceAllowed(instanceIDs ...string) bool {
+	if c.instanceFilter == nil {
+		return true
+	}
+	for _, id := range instanceIDs {
+		if c.instanceFilter[id] {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) setAccess(access *ClientAccess) {
+	c.accessMu.Lock()
+	c.access = access
+	c.accessMu.Unlock()
+}
+
+// CanReceive mengecek apakah event dengan scope tersebut boleh dikirim ke client.
+// Event warming hanya untuk pemilik room; event instance untuk user yang punya akses ke salah satu instance;
+// event tanpa instance / room (sistem) dikirim ke semua client.
+func (c *Client) CanReceive(scope EventScope) bool {
+	if scope.AdminOnly {
+		return c.IsAdmin()
+	}
+	if c.instanceFilter != nil && len(scope.InstanceIDs) > 0 && !c.instanceAllowed(scope.InstanceIDs...) {
+		return false
+	}
+	if c.IsAdmin() {
+		return true
+	}
+	if scope.RoomID == "" && len(scope.InstanceIDs) == 0 {
+		return true
+	}
+
+	c.accessMu.RLock()
+	access := c.access
+	c.accessMu.RUnlock()
+
+	if access == nil {
+		return false
+	}
+	if scope.RoomID != "" {
+		return access.RoomIDs[scope.RoomID]
+	}
+	for _, id := range scope.InstanceIDs {
+		if access.InstanceIDs[id] {
+			return true
+		}
+	}
+	return false
+}
@@ -15,15 +15,74 @@ const (
 	// Kalau nanti mau dipakai:
 	// EventQRScanned = "QR_SCANNED"
 
+	EventPairCodeGenerated = "PAIR_CODE_GENERATED" // Linking code 8 karakter siap dimasukkan di HP
+	EventPairCodeExpired   = "PAIR_CODE_EXPIRED"   // Linking code tidak dipakai sampai timeout
+	EventPairCodeSuccess   = "PAIR_CODE_SUCCESS"   // Pairing via linking code berhasil
+
 	EventWarmingMessage = "warming_message" // Warming system message
+
+	EventMessageStatus = "message_status" // Delivery/read receipt untuk pesan keluar
+
+	EventIncomingMessage = "incoming_message" // Pesan masuk (dikirim per instance via BroadcastToInstance)
+
+	EventPollVote = "poll_vote" // Vote masuk untuk poll yang tercatat di tabel polls
+
+	EventGroupParticipantsChanged = "GROUP_PARTICIPANTS_CHANGED" // Peserta join / leave / promote / demote
+	EventGroupUpdated             = "GROUP_UPDATED"              // Subject, deskripsi, setting grup berubah
+	EventGroupJoined              = "GROUP_JOINED"               // Instance ditambahkan / join ke grup baru
+
+	EventSecurityAlert = "SECURITY_ALERT" // Username / IP dikunci karena terlalu banyak gagal login (hanya admin)
 )
 
+// SubscribableEvents adalah daftar event yang bisa dipilih untuk webhook instance.
+var SubscribableEvents = []string{
+	EventIncomingMessage,
+	EventMessageStatus,
+	EventPollVote,
+	EventGroupParticipantsChanged,
+	EventGroupUpdated,
+	EventGroupJoined,
+	EventInstanceStatusChanged,
+	EventInstanceError,
+	EventQRGenerated,
+	EventQRExpired,
+	EventQRSuccess,
+	EventQRTimeout,
+	EventQRCancelled,
+	EventPairCodeGenerated,
+	EventPairCodeExpired,
+	EventPairCodeSuccess,
+	EventWarmingMessage,
+}
+
+// adminOnlyEvents hanya dikirim ke client admin, walaupun tidak terkait instance / room
+var adminOnlyEvents = map[string]bool{
+	EventSecurityAlert: true,
+}
+
+// IsAdminOnlyEvent mengecek apakah event hanya boleh diterima admin
+func IsAdminOnlyEvent(event string) bool {
+	return adminOnlyEvents[event]
+}
+
+// IsSubscribableEvent mengecek apakah nama event ada di SubscribableEvents.
+func IsSubscribableEvent(event string) bool {
+	for _, e := range SubscribableEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
 // WsEvent adalah envelope umum setiap pesan yang dikirim via WebSocket.
 // FE cukup switch berdasarkan field Event, lalu cast Data ke bentuk yang sesuai.
 type WsEvent struct {
-	Event     string      `json:"event"`     // Nama event, salah satu dari konstanta di atas
-	Timestamp time.Time   `json:"timestamp"` // Waktu event dibuat (UTC)
-	Data      interface{} `json:"data"`      // Payload spesifik event
+	ID        string      `json:"id,omitempty"`  // ID unik event, dipakai dedup antar node (relay)
+	Event     string      `json:"event"`         // Nama event, salah satu dari konstanta di atas
+	Timestamp time.Time   `json:"timestamp"`     // Waktu event dibuat (UTC)
+	Data      interface{} `json:"data"`          // Payload spesifik event
+	Seq       uint64      `json:"seq,omitempty"` // Nomor urut per node, dipakai client untuk resume (lihat subscription.go)
 }
 
 // =====================
@@ -45,6 +104,14 @@ type QRExpiredData struct {
 	PhoneNumber string `json:"phone_number,omitempty"`
 }
 
+// PairCodeGeneratedData dikirim ketika linking code untuk login via nomor telepon berhasil dibuat.
+type PairCodeGeneratedData struct {
+	InstanceID  string    `json:"instance_id"`
+	PhoneNumber string    `json:"phone_number"`
+	PairCode    string    `json:"pair_code"`  // format "XXXX-XXXX" seperti yang ditampilkan WhatsApp
+	ExpiresAt   time.Time `json:"expires_at"` // batas waktu memasukkan code di HP
+}
+
 // InstanceStatusChangedData dikirim ketika status koneksi instance berubah,
 // misalnya akibat events.Connected, events.Disconnected, events.LoggedOut.
 type InstanceStatusChangedData struct {
@@ -79,3 +146,79 @@ type WarmingMessageData struct {
 	ErrorMessage       string    `json:"error_message,omitempty"`
 	Timestamp          time.Time `json:"timestamp"`
 }
+
+// MessageStatusData dikirim ketika status pesan keluar berubah
+// (server_ack -> delivered -> read -> played, atau failed).
+// Untuk grup (Group=true) Status adalah status peserta Recipient, bukan status seluruh grup.
+type MessageStatusData struct {
+	InstanceID  string    `json:"instance_id"`
+	MessageID   string    `json:"message_id"`
+	ChatJID     string    `json:"chat_jid"`
+	Status      string    `json:"status"`
+	Recipient   string    `json:"recipient,omitempty"`   // JID yang mengirim receipt
+	Group       bool      `json:"group,omitempty"`       // Receipt satu peserta grup
+	Application string    `json:"application,omitempty"` // Application outbox asal pesan (jika ada)
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// PollVoteData dikirim ketika ada vote baru / perubahan vote pada poll.
+// SelectedOptions kosong berarti voter membatalkan pilihannya.
+type PollVoteData struct {
+	InstanceID      string    `json:"instance_id"`
+	PollMessageID   string    `json:"poll_message_id"`
+	ChatJID         string    `json:"chat_jid"`
+	Voter           string    `json:"voter"`
+	SelectedOptions []string  `json:"selected_options"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// GroupParticipantsChangedData dikirim ketika peserta grup berubah.
+// Action: "join", "leave", "promote" atau "demote".
+type GroupParticipantsChangedData struct {
+	InstanceID   string    `json:"instance_id"`
+	GroupJID     string    `json:"group_jid"`
+	Action       string    `json:"action"`
+	Participants []string  `json:"participants"`
+	Actor        string    `json:"actor,omitempty"`  // admin yang melakukan perubahan (kosong = peserta sendiri / via link)
+	Reason       string    `json:"reason,omitempty"` // "invite" kalau join lewat invite link
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// GroupUpdatedData dikirim ketika info / setting grup berubah. Hanya field yang berubah yang diisi.
+type GroupUpdatedData struct {
+	InstanceID           string    `json:"instance_id"`
+	GroupJID             string    `json:"group_jid"`
+	Actor                string    `json:"actor,omitempty"`
+	Subject              *string   `json:"subject,omitempty"`
+	Description          *string   `json:"description,omitempty"`
+	Announce             *bool     `json:"announce,omitempty"`
+	Locked               *bool     `json:"locked,omitempty"`
+	EphemeralTimer       *uint32   `json:"ephemeral_timer,omitempty"` // detik, 0 = disappearing messages off
+	JoinApprovalRequired *bool     `json:"join_approval_required,omitempty"`
+	InviteLinkChanged    bool      `json:"invite_link_changed,omitempty"`
+	Deleted              bool      `json:"deleted,omitempty"`
+	Timestamp            time.Time `json:"timestamp"`
+}
+
+// GroupJoinedData dikirim ketika instance ditambahkan ke grup, membuat grup, atau join lewat invite link.
+type GroupJoinedData struct {
+	InstanceID       string    `json:"instance_id"`
+	GroupJID         string    `json:"group_jid"`
+	Name             string    `json:"name"`
+	Actor            string    `json:"actor,omitempty"`  // yang menambahkan instance ke grup
+	Reason           string    `json:"reason,omitempty"` // "invite" kalau join lewat invite link
+	Type             string    `json:"type,omitempty"`   // "new" kalau grup baru dibuat
+	ParticipantCount int       `json:"participant_count"`
+	Timestamp        time.Time `json:"timestamp"`
+}
+
+// SecurityAlertData dikirim ke admin ketika username / IP dikunci karena brute force
+type SecurityAlertData struct {
+	Type        string    `json:"type"`  // "lockout"
+	Scope       string    `json:"scope"` // username, login_ip, refresh_ip atau api_key_ip
+	Key         string    `json:"key"`   // username atau IP
+	Failures    int       `json:"failures"`
+	IPAddress   string    `json:"ip_address,omitempty"` // IP request terakhir yang gagal
+	LockedUntil time.Time `json:"locked_until"`
+	Timestamp   time.Time `json:"timestamp"`
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -18,10 +19,21 @@ type Client struct {
 	// Goroutine write akan membaca dari sini dan mengirim ke conn.
 	send chan WsEvent
 
-	// (Opsional) informasi identitas untuk filtering ke depan,
-	// misalnya UserID / TenantID / daftar InstanceID yang di-subscribe.
-	// Untuk versi awal bisa dikosongkan dulu.
-	// UserID string
+	// Identitas user dari token, dipakai untuk filter event Publish
+	UserID int64
+	Role   string
+
+	accessMu sync.RWMutex
+	access   *ClientAccess // instance & room yang boleh dilihat (non-admin)
+
+	instanceFilter map[string]bool // batasan tambahan (allow-list API key), nil = tidak dibatasi
+
+	// Topic yang di-subscribe client (lihat subscription.go). Hanya disentuh goroutine Hub.Run.
+	topics map[string]bool
+
+	// Seq terakhir saat client di-register, dan resume yang diminta sebelum Register (SSE)
+	joinedSeq  uint64
+	resumeFrom *uint64
 
 	InstanceID string
 }
@@ -35,11 +47,21 @@ type Hub struct {
 	register   chan *Client
 	unregister chan *Client
 
-	// Broadcast adalah channel event yang akan dikirim ke semua client.
-	broadcast chan WsEvent
+	// Broadcast adalah channel event yang akan dikirim ke client (semua, atau listener instance tertentu).
+	broadcast chan hubMessage
+
+	// Perintah subscribe / unsubscribe / resume dari client
+	requests chan clientRequest
+
+	// Nomor urut event terakhir dan buffer event terakhir untuk resume
+	seq     uint64
+	history *eventRing
 
 	// Mutex kalau nanti butuh akses synchronous ke clients dari luar Run().
 	mu sync.RWMutex
+
+	// Loader hak akses user non-admin (lihat access.go)
+	accessLoader AccessLoader
 }
 
 // NewHub membuat instance Hub baru.
@@ -48,7 +70,9 @@ func NewHub() *Hub {
 		clients:    make(map[*Client]bool),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
-		broadcast:  make(chan WsEvent, 256), // buffer kecil untuk mencegah blocking
+		broadcast:  make(chan hubMessage, 256), // buffer kecil untuk mencegah blocking
+		requests:   make(chan clientRequest, 64),
+		history:    newEventRing(replayBufferSize),
 	}
 }
 
@@ -56,40 +80,80 @@ func NewHub() *Hub {
 // Loop ini akan:
 // - menerima client baru (register)
 // - menghapus client yang disconnect (unregister)
-// - mengirim event ke semua client (broadcast)
+// - memberi nomor urut, menyimpan, lalu mengirim event ke client (broadcast)
+// - menjalankan perintah subscribe / resume dari client
 func (h *Hub) Run() {
+	if h.accessLoader != nil {
+		go h.refreshAccessLoop()
+	}
+
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
 			h.mu.Unlock()
+			client.joinedSeq = h.seq
+
+			// Beri tahu seq terakhir supaya client tahu dari mana harus resume nanti
+			if h.send(client, ackEvent(AckData{Action: "connected", Topics: client.topicList(), Seq: h.seq})) && client.resumeFrom != nil {
+				h.replay(client, *client.resumeFrom)
+			}
 
 		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
+			h.drop(client)
+
+		case msg := <-h.broadcast:
+			h.seq++
+			msg.event.Seq = h.seq
+			msg.scope = ScopeOf(msg.event.Data)
+			msg.scope.AdminOnly = IsAdminOnlyEvent(msg.event.Event)
+			if msg.instanceID != "" && !containsString(msg.scope.InstanceIDs, msg.instanceID) {
+				msg.scope.InstanceIDs = append(msg.scope.InstanceIDs, msg.instanceID)
 			}
-			h.mu.Unlock()
+			h.history.add(msg)
 
-		case event := <-h.broadcast:
-			h.mu.RLock()
+			// Run adalah satu-satunya penulis map clients, jadi iterasi di sini tidak perlu lock
 			for client := range h.clients {
-				select {
-				case client.send <- event:
-					// sukses kirim ke buffer client
-				default:
-					// kalau buffer penuh, anggap client bermasalah dan putuskan
-					close(client.send)
-					delete(h.clients, client)
+				// User non-admin hanya menerima event instance / warming room miliknya,
+				// dan client yang subscribe topic hanya menerima event topic tersebut
+				if client.wants(msg) {
+					h.send(client, msg.event)
 				}
 			}
-			h.mu.RUnlock()
+
+		case req := <-h.requests:
+			if h.clients[req.client] {
+				h.handleRequest(req)
+			}
 		}
 	}
 }
 
+// send mengirim event ke buffer client tanpa memblok Run.
+// Kalau buffer penuh, anggap client bermasalah dan putuskan.
+func (h *Hub) send(client *Client, event WsEvent) bool {
+	select {
+	case client.send <- event:
+		return true
+	default:
+		log.Printf("⚠️ ws: client buffer full (user %d, instance %s), disconnecting", client.UserID, client.InstanceID)
+		h.drop(client)
+		return false
+	}
+}
+
+// drop menghapus client dari hub dan menutup channel send-nya (goroutine write akan berhenti)
+func (h *Hub) drop(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.send)
+	}
+}
+
 // Register digunakan oleh handler WS saat koneksi baru dibuat.
 func (h *Hub) Register(client *Client) {
 	h.register <- client
@@ -103,11 +167,18 @@ func (h *Hub) Unregister(client *Client) {
 // Publish mengimplementasikan RealtimePublisher.
 // Service lain cukup memanggil ini untuk mengirim event ke semua client.
 func (h *Hub) Publish(event WsEvent) {
-	// Pastikan timestamp terisi kalau belum diset.
+	h.broadcast <- hubMessage{event: stampEvent(event)}
+}
+
+// stampEvent mengisi ID dan timestamp event kalau belum diset.
+func stampEvent(event WsEvent) WsEvent {
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now().UTC()
 	}
-	h.broadcast <- event
+	return event
 }
 
 // RealtimePublisher adalah interface yang akan dipegang oleh service lain
@@ -119,7 +190,6 @@ type RealtimePublisher interface {
 
 // NewClient membuat objek Client baru dari koneksi Gorilla WebSocket.
 // Fungsi ini tidak menjalankan goroutine read/write; itu tugas handler WS.
-// NewClient membuat objek Client baru dari koneksi Gorilla WebSocket.
 func NewClient(hub *Hub, conn *websocket.Conn) *Client {
 	return &Client{
 		hub:        hub,
@@ -129,6 +199,21 @@ func NewClient(hub *Hub, conn *websocket.Conn) *Client {
 	}
 }
 
+// NewStreamClient membuat Client tanpa koneksi WebSocket (mis. Server-Sent Events).
+// Pemanggil membaca event dari Events() dan wajib memanggil Hub.Unregister saat selesai.
+func NewStreamClient(hub *Hub) *Client {
+	return &Client{
+		hub:  hub,
+		send: make(chan WsEvent, 256),
+	}
+}
+
+// Events adalah channel event untuk client tanpa WritePump; ditutup ketika client di-unregister
+// atau diputus karena buffer penuh.
+func (c *Client) Events() <-chan WsEvent {
+	return c.send
+}
+
 // WritePump adalah loop yang mengirim event dari channel send ke koneksi WS.
 // Biasanya dipanggil sebagai goroutine dari handler /ws.
 func (c *Client) WritePump() {
@@ -176,17 +261,15 @@ func (c *Client) WritePump() {
 	}
 }
 
-// ReadPump opsional: loop baca dari client.
-// Untuk versi awal, kamu bisa hanya consume dan buang,
-// atau pakai untuk menerima perintah subscribe dsb.
-// Kalau belum butuh, bisa dibuat minimal / dikosongkan.
+// ReadPump membaca perintah dari client (subscribe / unsubscribe / resume / ping, lihat subscription.go)
+// dan meneruskannya ke Hub.Run.
 func (c *Client) ReadPump() {
 	defer func() {
 		c.hub.Unregister(c)
 		_ = c.conn.Close()
 	}()
 
-	c.conn.SetReadLimit(512)
+	c.conn.SetReadLimit(maxClientMessageSize)
 
 	_ = c.conn.SetReadDeadline(time.Now().Add(15 * time.Minute))
 	c.conn.SetPongHandler(func(string) error {
@@ -195,36 +278,35 @@ func (c *Client) ReadPump() {
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, payload, err := c.conn.ReadMessage()
 		if err != nil {
 			log.Printf("ws read error: %v", err)
 			break
 		}
+
+		// Pesan dari client juga dianggap tanda koneksi masih hidup
+		_ = c.conn.SetReadDeadline(time.Now().Add(15 * time.Minute))
+
+		req := clientRequest{client: c}
+		if err := json.Unmarshal(payload, &req.msg); err != nil {
+			req.err = "invalid JSON message"
+		}
+		c.hub.requests <- req
 	}
 }
 
 // BroadcastToInstance kirim message ke client yang listen instance tertentu
 func (h *Hub) BroadcastToInstance(instanceID string, data map[string]interface{}) {
-	event := WsEvent{
+	h.SendToInstance(instanceID, WsEvent{
 		Event:     "incoming_message",
 		Timestamp: time.Now(),
 		Data:      data,
-	}
+	})
+}
 
-	// ✅ FIX: Tambahkan RLock untuk prevent race condition
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-
-	for client := range h.clients {
-		if client.InstanceID == instanceID {
-			select {
-			case client.send <- event:
-				// Sukses kirim event ke client
-			default:
-				// ✅ FIX: Jangan delete di sini untuk avoid modifying map during iteration
-				// Biarkan Hub.Run() yang handle cleanup client yang bermasalah
-				log.Printf("⚠️ Client buffer full for instance: %s", instanceID)
-			}
-		}
-	}
+// SendToInstance kirim event apa adanya ke client yang listen instance tertentu
+// (dipakai BroadcastToInstance dan relay antar node supaya ID event tetap sama).
+// Event tetap lewat Run supaya dapat nomor urut dan bisa di-replay.
+func (h *Hub) SendToInstance(instanceID string, event WsEvent) {
+	h.broadcast <- hubMessage{instanceID: instanceID, event: stampEvent(event)}
 }
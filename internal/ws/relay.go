@@ -0,0 +1,201 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// Relay meneruskan payload realtime antar proses API (mis. Postgres LISTEN/NOTIFY atau Redis pub/sub).
+// Relay tidak tahu isi payload; encode / decode dilakukan RelayPublisher.
+type Relay interface {
+	// Publish mengirim payload ke semua node (termasuk node pengirim sendiri)
+	Publish(payload []byte) error
+	// Subscribe mulai menerima payload dari node lain; handler dipanggil dari goroutine relay
+	Subscribe(handler func(payload []byte)) error
+	Close() error
+}
+
+// RelayMessage adalah envelope yang dikirim lewat Relay.
+type RelayMessage struct {
+	Origin     string  `json:"origin"`                // node pengirim
+	InstanceID string  `json:"instance_id,omitempty"` // diisi untuk BroadcastToInstance (listener per instance)
+	Event      WsEvent `json:"event"`
+	Control    string  `json:"control,omitempty"`     // pesan kontrol antar node (bukan event WS), mis. invalidasi cache
+	ControlArg string  `json:"control_arg,omitempty"` // argumen pesan kontrol
+}
+
+// label untuk log: nama event, atau nama pesan kontrol
+func (m RelayMessage) label() string {
+	if m.Control != "" {
+		return m.Control
+	}
+	return m.Event.Event
+}
+
+// Berapa lama ID event diingat untuk dedup, dan ukuran antrian kirim ke relay
+const (
+	relaySeenTTL   = 5 * time.Minute
+	relayQueueSize = 1024
+)
+
+// RelayPublisher mengimplementasikan RealtimePublisher: event dikirim ke client lokal (Hub)
+// lalu diteruskan ke node lain lewat Relay. Event dari node lain hanya dikirim ke client lokal,
+// de-duplicated berdasarkan WsEvent.ID.
+type RelayPublisher struct {
+	hub    *Hub
+	relay  Relay
+	origin string
+	queue  chan RelayMessage
+
+	seenMu sync.Mutex
+	seen   map[string]time.Time
+
+	controlMu sync.RWMutex
+	controls  map[string]func(arg string)
+}
+
+// NewRelayPublisher membuat publisher lokal + remote. origin adalah ID node ini.
+func NewRelayPublisher(hub *Hub, relay Relay, origin string) *RelayPublisher {
+	return &RelayPublisher{
+		hub:      hub,
+		relay:    relay,
+		origin:   origin,
+		queue:    make(chan RelayMessage, relayQueueSize),
+		seen:     make(map[string]time.Time),
+		controls: make(map[string]func(arg string)),
+	}
+}
+
+// OnControl mendaftarkan handler untuk pesan kontrol dari node lain
+func (p *RelayPublisher) OnControl(name string, handler func(arg string)) {
+	p.controlMu.Lock()
+	p.controls[name] = handler
+	p.controlMu.Unlock()
+}
+
+// PublishControl mengirim pesan kontrol ke node lain (node ini sendiri tidak menerimanya)
+func (p *RelayPublisher) PublishControl(name, arg string) {
+	p.enqueue(RelayMessage{Origin: p.origin, Control: name, ControlArg: arg})
+}
+
+// Start mulai subscribe ke relay dan menjalankan goroutine pengirim.
+func (p *RelayPublisher) Start() error {
+	if err := p.relay.Subscribe(p.deliver); err != nil {
+		return err
+	}
+
+	go p.sendLoop()
+	go p.pruneLoop()
+	return nil
+}
+
+// Publish mengirim event ke semua client lokal lalu ke node lain.
+func (p *RelayPublisher) Publish(event WsEvent) {
+	event = stampEvent(event)
+	p.markSeen(event.ID)
+	p.hub.Publish(event)
+	p.enqueue(RelayMessage{Origin: p.origin, Event: event})
+}
+
+// BroadcastToInstance mengirim incoming_message ke listener instance di semua node.
+func (p *RelayPublisher) BroadcastToInstance(instanceID string, data map[string]interface{}) {
+	event := stampEvent(WsEvent{
+		Event: "incoming_message",
+		Data:  data,
+	})
+	p.markSeen(event.ID)
+	p.hub.SendToInstance(instanceID, event)
+	p.enqueue(RelayMessage{Origin: p.origin, InstanceID: instanceID, Event: event})
+}
+
+// enqueue tidak boleh memblok pemanggil (event handler whatsmeow), jadi antrian penuh = event di-drop
+func (p *RelayPublisher) enqueue(msg RelayMessage) {
+	select {
+	case p.queue <- msg:
+	default:
+		log.Printf("⚠️ Realtime relay queue full, dropping %s event %s", msg.label(), msg.Event.ID)
+	}
+}
+
+func (p *RelayPublisher) sendLoop() {
+	for msg := range p.queue {
+		payload, err := json.Marshal(msg)
+		if err != nil {
+			log.Printf("⚠️ Realtime relay: failed to marshal %s event: %v", msg.label(), err)
+			continue
+		}
+		if err := p.relay.Publish(payload); err != nil {
+			log.Printf("⚠️ Realtime relay: failed to publish %s event: %v", msg.label(), err)
+		}
+	}
+}
+
+// deliver dipanggil relay untuk setiap payload yang diterima
+func (p *RelayPublisher) deliver(payload []byte) {
+	var msg RelayMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		log.Printf("⚠️ Realtime relay: invalid payload: %v", err)
+		return
+	}
+
+	if msg.Control != "" {
+		p.deliverControl(msg)
+		return
+	}
+
+	// Event dari node sendiri sudah dikirim ke client lokal saat Publish
+	if msg.Origin == p.origin || msg.Event.ID == "" || !p.markSeen(msg.Event.ID) {
+		return
+	}
+
+	if msg.InstanceID != "" {
+		p.hub.SendToInstance(msg.InstanceID, msg.Event)
+		return
+	}
+	p.hub.Publish(msg.Event)
+}
+
+// deliverControl menjalankan handler pesan kontrol dari node lain
+func (p *RelayPublisher) deliverControl(msg RelayMessage) {
+	if msg.Origin == p.origin {
+		return
+	}
+
+	p.controlMu.RLock()
+	handler := p.controls[msg.Control]
+	p.controlMu.RUnlock()
+
+	if handler != nil {
+		handler(msg.ControlArg)
+	}
+}
+
+// markSeen mencatat ID event, returns false jika ID sudah pernah dilihat
+func (p *RelayPublisher) markSeen(id string) bool {
+	p.seenMu.Lock()
+	defer p.seenMu.Unlock()
+
+	if _, ok := p.seen[id]; ok {
+		return false
+	}
+	p.seen[id] = time.Now()
+	return true
+}
+
+func (p *RelayPublisher) pruneLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := time.Now().Add(-relaySeenTTL)
+		p.seenMu.Lock()
+		for id, at := range p.seen {
+			if at.Before(cutoff) {
+				delete(p.seen, id)
+			}
+		}
+		p.seenMu.Unlock()
+	}
+}
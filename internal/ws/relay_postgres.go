@@ -0,0 +1,133 @@
+package ws
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// NOTIFY payload dibatasi 8000 byte oleh Postgres. Payload yang lebih besar (mis. incoming_message
+// dengan teks panjang) disimpan di realtime_relay_payloads dan yang dikirim hanya referensinya.
+const (
+	pgNotifyMaxPayload = 7000
+	pgPayloadRefPrefix = "@"
+	pgPayloadRetention = 10 * time.Minute
+)
+
+// PostgresRelay adalah Relay berbasis LISTEN/NOTIFY di APP DB.
+type PostgresRelay struct {
+	db       *sql.DB
+	dsn      string
+	channel  string
+	listener *pq.Listener
+}
+
+// NewPostgresRelay membuat relay Postgres. db dipakai untuk NOTIFY, dsn untuk koneksi LISTEN khusus.
+func NewPostgresRelay(db *sql.DB, dsn, channel string) *PostgresRelay {
+	return &PostgresRelay{db: db, dsn: dsn, channel: channel}
+}
+
+// Publish mengirim payload lewat pg_notify
+func (r *PostgresRelay) Publish(payload []byte) error {
+	message := string(payload)
+
+	if len(payload) > pgNotifyMaxPayload {
+		var id int64
+		err := r.db.QueryRow(`INSERT INTO realtime_relay_payloads (payload) VALUES ($1) RETURNING id`, message).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to store large relay payload: %w", err)
+		}
+		message = pgPayloadRefPrefix + strconv.FormatInt(id, 10)
+	}
+
+	if _, err := r.db.Exec(`SELECT pg_notify($1, $2)`, r.channel, message); err != nil {
+		return fmt.Errorf("pg_notify failed: %w", err)
+	}
+	return nil
+}
+
+// Subscribe membuka koneksi LISTEN (dengan auto reconnect dari lib/pq) dan memanggil handler per notifikasi
+func (r *PostgresRelay) Subscribe(handler func(payload []byte)) error {
+	r.listener = pq.NewListener(r.dsn, 2*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			log.Printf("⚠️ Realtime relay (postgres): listener disconnected: %v", err)
+		case pq.ListenerEventReconnected:
+			log.Println("✅ Realtime relay (postgres): listener reconnected")
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Printf("⚠️ Realtime relay (postgres): reconnect failed: %v", err)
+		}
+	})
+
+	if err := r.listener.Listen(r.channel); err != nil {
+		return fmt.Errorf("failed to LISTEN %s: %w", r.channel, err)
+	}
+
+	go func() {
+		for n := range r.listener.Notify {
+			// nil = koneksi baru saja reconnect, notifikasi selama putus tidak bisa diambil lagi
+			if n == nil {
+				continue
+			}
+
+			payload, err := r.resolve(n.Extra)
+			if err != nil {
+				log.Printf("⚠️ Realtime relay (postgres): %v", err)
+				continue
+			}
+			handler(payload)
+		}
+	}()
+
+	go r.cleanupLoop()
+
+	log.Printf("✅ Realtime relay (postgres) listening on channel %s", r.channel)
+	return nil
+}
+
+// Close menutup koneksi LISTEN
+func (r *PostgresRelay) Close() error {
+	if r.listener == nil {
+		return nil
+	}
+	return r.listener.Close()
+}
+
+// resolve mengambil payload besar dari tabel jika notifikasi hanya berisi referensi
+func (r *PostgresRelay) resolve(extra string) ([]byte, error) {
+	if !strings.HasPrefix(extra, pgPayloadRefPrefix) {
+		return []byte(extra), nil
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(extra, pgPayloadRefPrefix), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload reference %q", extra)
+	}
+
+	var payload string
+	if err := r.db.QueryRow(`SELECT payload FROM realtime_relay_payloads WHERE id = $1`, id).Scan(&payload); err != nil {
+		return nil, fmt.Errorf("failed to load relay payload %d: %w", id, err)
+	}
+	return []byte(payload), nil
+}
+
+// cleanupLoop menghapus payload besar yang sudah lewat masa simpan (semua node sudah membacanya)
+func (r *PostgresRelay) cleanupLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		_, err := r.db.Exec(
+			`DELETE FROM realtime_relay_payloads WHERE created_at < NOW() - make_interval(secs => $1)`,
+			pgPayloadRetention.Seconds(),
+		)
+		if err != nil {
+			log.Printf("⚠️ Realtime relay (postgres): failed to clean payloads: %v", err)
+		}
+	}
+}
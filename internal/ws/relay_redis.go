@@ -0,0 +1,296 @@
+package ws
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisRelay adalah Relay berbasis Redis pub/sub. Protokol RESP yang dipakai hanya
+// AUTH / SELECT / PUBLISH / SUBSCRIBE, jadi cukup diimplementasikan langsung tanpa library.
+type RedisRelay struct {
+	addr     string
+	host     string
+	username string
+	password string
+	db       int
+	useTLS   bool
+	channel  string
+
+	pubMu   sync.Mutex
+	pubConn *redisConn
+
+	closed chan struct{}
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewRedisRelay membuat relay dari URL redis://[user:password@]host:port[/db] (rediss:// untuk TLS)
+func NewRedisRelay(redisURL, channel string) (*RedisRelay, error) {
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("invalid REDIS_URL scheme %q, use redis:// or rediss://", u.Scheme)
+	}
+
+	relay := &RedisRelay{
+		addr:    u.Host,
+		host:    u.Hostname(),
+		useTLS:  u.Scheme == "rediss",
+		channel: channel,
+		closed:  make(chan struct{}),
+	}
+	if u.Port() == "" {
+		relay.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		relay.username = u.User.Username()
+		relay.password, _ = u.User.Password()
+	}
+	if path := strings.TrimPrefix(u.Path, "/"); path != "" {
+		if relay.db, err = strconv.Atoi(path); err != nil {
+			return nil, fmt.Errorf("invalid REDIS_URL database %q", path)
+		}
+	}
+
+	return relay, nil
+}
+
+// Publish mengirim payload dengan PUBLISH (koneksi dipakai ulang, reconnect sekali jika putus)
+func (r *RedisRelay) Publish(payload []byte) error {
+	r.pubMu.Lock()
+	defer r.pubMu.Unlock()
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if r.pubConn == nil {
+			conn, err := r.dial()
+			if err != nil {
+				return err
+			}
+			r.pubConn = conn
+		}
+
+		_, err := r.pubConn.do("PUBLISH", r.channel, string(payload))
+		if err == nil {
+			return nil
+		}
+
+		_ = r.pubConn.conn.Close()
+		r.pubConn = nil
+		if attempt == 1 {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe menjalankan loop SUBSCRIBE dengan reconnect otomatis
+func (r *RedisRelay) Subscribe(handler func(payload []byte)) error {
+	// Cek koneksi sekali di awal supaya konfigurasi salah langsung ketahuan saat startup
+	conn, err := r.dial()
+	if err != nil {
+		return err
+	}
+	_ = conn.conn.Close()
+
+	go func() {
+		backoff := time.Second
+		for {
+			select {
+			case <-r.closed:
+				return
+			default:
+			}
+
+			if err := r.subscribeOnce(handler); err != nil {
+				log.Printf("⚠️ Realtime relay (redis): subscription lost: %v (retry in %v)", err, backoff)
+			}
+
+			select {
+			case <-r.closed:
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+		}
+	}()
+
+	log.Printf("✅ Realtime relay (redis) subscribed to channel %s", r.channel)
+	return nil
+}
+
+func (r *RedisRelay) subscribeOnce(handler func(payload []byte)) error {
+	conn, err := r.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.conn.Close()
+
+	if err := conn.write("SUBSCRIBE", r.channel); err != nil {
+		return err
+	}
+
+	for {
+		reply, err := conn.read()
+		if err != nil {
+			return err
+		}
+
+		// Push message: ["message", channel, payload]
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 3 {
+			continue
+		}
+		if kind, _ := parts[0].(string); kind != "message" {
+			continue
+		}
+		if payload, ok := parts[2].(string); ok {
+			handler([]byte(payload))
+		}
+	}
+}
+
+// Close menghentikan loop subscribe dan menutup koneksi publish
+func (r *RedisRelay) Close() error {
+	select {
+	case <-r.closed:
+	default:
+		close(r.closed)
+	}
+
+	r.pubMu.Lock()
+	defer r.pubMu.Unlock()
+	if r.pubConn != nil {
+		err := r.pubConn.conn.Close()
+		r.pubConn = nil
+		return err
+	}
+	return nil
+}
+
+// dial membuka koneksi lalu AUTH / SELECT sesuai URL
+func (r *RedisRelay) dial() (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if r.useTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", r.addr, &tls.Config{ServerName: r.host})
+	} else {
+		conn, err = dialer.Dial("tcp", r.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis %s: %w", r.addr, err)
+	}
+
+	rc := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+
+	if r.password != "" {
+		args := []string{"AUTH", r.password}
+		if r.username != "" {
+			args = []string{"AUTH", r.username, r.password}
+		}
+		if _, err := rc.do(args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis AUTH failed: %w", err)
+		}
+	}
+	if r.db != 0 {
+		if _, err := rc.do("SELECT", strconv.Itoa(r.db)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis SELECT failed: %w", err)
+		}
+	}
+
+	return rc, nil
+}
+
+// do mengirim satu command dan membaca satu reply
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	if err := c.write(args...); err != nil {
+		return nil, err
+	}
+	_ = c.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	defer c.conn.SetReadDeadline(time.Time{})
+	return c.read()
+}
+
+// write encode command sebagai RESP array of bulk strings
+func (c *redisConn) write(args ...string) error {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		b.WriteString("$" + strconv.Itoa(len(a)) + "\r\n" + a + "\r\n")
+	}
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := io.WriteString(c.conn, b.String())
+	return err
+}
+
+// read decode satu reply RESP (simple string, error, integer, bulk string, array)
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, errors.New("redis: " + line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,302 @@
+package ws
+
+import (
+	"sort"
+	"strings"
+)
+
+// Protokol pesan dari client (JSON text frame):
+//
+//	{"action": "subscribe", "topics": ["instance:<id>", "warming:<roomId>", "outbox:<application>"]}
+//	{"action": "unsubscribe", "topics": ["instance:<id>"]}
+//	{"action": "resume", "seq": 1234}
+//	{"action": "ping"}
+//
+// Setiap perintah dibalas WS_ACK (atau WS_ERROR). Tanpa subscription, client menerima semua event
+// yang boleh dilihatnya (perilaku lama). Setiap event punya seq yang naik terus per node; client cukup
+// menyimpan seq terakhir lalu mengirim resume setelah reconnect untuk menerima event yang terlewat.
+const (
+	EventWsAck   = "WS_ACK"
+	EventWsError = "WS_ERROR"
+
+	TopicInstancePrefix = "instance:"
+	TopicWarmingPrefix  = "warming:"
+	TopicOutboxPrefix   = "outbox:"
+)
+
+const (
+	replayBufferSize     = 1000 // jumlah event terakhir yang disimpan untuk resume
+	maxClientMessageSize = 4096
+	maxClientTopics      = 100
+)
+
+// hubMessage adalah event yang diproses Hub.Run. instanceID diisi untuk SendToInstance
+// (incoming_message hanya untuk listener instance tsb).
+type hubMessage struct {
+	instanceID string
+	event      WsEvent
+	scope      EventScope
+}
+
+// clientMessage adalah perintah yang dikirim client lewat WebSocket
+type clientMessage struct {
+	Action string   `json:"action"`
+	Topics []string `json:"topics,omitempty"`
+	Seq    uint64   `json:"seq,omitempty"`
+}
+
+type clientRequest struct {
+	client *Client
+	msg    clientMessage
+	err    string
+}
+
+// AckData adalah payload WS_ACK
+type AckData struct {
+	Action    string   `json:"action"`
+	Topics    []string `json:"topics,omitempty"`     // subscription aktif setelah perintah dijalankan
+	Rejected  []string `json:"rejected,omitempty"`   // topic tidak valid / tidak punya akses
+	Seq       uint64   `json:"seq"`                  // seq terakhir yang sudah dikirim ke client
+	Replayed  int      `json:"replayed,omitempty"`   // jumlah event yang dikirim ulang (resume)
+	Gap       bool     `json:"gap,omitempty"`        // sebagian event sudah keluar dari buffer, client perlu sync ulang
+	OldestSeq uint64   `json:"oldest_seq,omitempty"` // seq tertua yang masih ada di buffer
+	More      bool     `json:"more,omitempty"`       // replay terpotong, kirim resume lagi dari Seq
+}
+
+// ErrorData adalah payload WS_ERROR
+type ErrorData struct {
+	Action  string `json:"action,omitempty"`
+	Message string `json:"message"`
+}
+
+func ackEvent(data AckData) WsEvent {
+	return stampEvent(WsEvent{Event: EventWsAck, Data: data})
+}
+
+func errorEvent(action, message string) WsEvent {
+	return stampEvent(WsEvent{Event: EventWsError, Data: ErrorData{Action: action, Message: message}})
+}
+
+// handleRequest menjalankan perintah client, dipanggil dari Run
+func (h *Hub) handleRequest(req clientRequest) {
+	client := req.client
+	if req.err != "" {
+		h.send(client, errorEvent("", req.err))
+		return
+	}
+
+	switch req.msg.Action {
+	case "subscribe":
+		rejected := client.addTopics(req.msg.Topics)
+		h.send(client, ackEvent(AckData{Action: "subscribe", Topics: client.topicList(), Rejected: rejected, Seq: h.seq}))
+
+	case "unsubscribe":
+		for _, topic := range req.msg.Topics {
+			if topic, ok := normalizeTopic(topic); ok {
+				delete(client.topics, topic)
+			}
+		}
+		h.send(client, ackEvent(AckData{Action: "unsubscribe", Topics: client.topicList(), Seq: h.seq}))
+
+	case "resume":
+		h.replay(client, req.msg.Seq)
+
+	case "ping":
+		h.send(client, ackEvent(AckData{Action: "ping", Seq: h.seq}))
+
+	default:
+		h.send(client, errorEvent(req.msg.Action, "unknown action, use subscribe, unsubscribe, resume or ping"))
+	}
+}
+
+// replay mengirim ulang event dengan seq > from yang relevan untuk client. Event setelah client
+// terhubung (joinedSeq) sudah dikirim live, jadi tidak diulang. Jumlah event dibatasi sisa buffer
+// client supaya client tidak diputus; sisanya diminta lewat resume berikutnya.
+func (h *Hub) replay(client *Client, from uint64) {
+	ack := AckData{Action: "resume", Seq: h.seq}
+
+	events, oldest := h.history.since(from)
+	ack.OldestSeq = oldest
+	// from > seq berarti node restart (seq mulai dari awal) atau client pindah node
+	if from > h.seq || (oldest > 0 && from+1 < oldest) {
+		ack.Gap = true
+		events, _ = h.history.since(0)
+	}
+
+	room := cap(client.send) - len(client.send) - 1 // sisakan satu slot untuk ack
+	for _, msg := range events {
+		if msg.event.Seq > client.joinedSeq {
+			break
+		}
+		if !client.wants(msg) {
+			continue
+		}
+		if ack.Replayed >= room {
+			ack.More = true
+			ack.Seq = msg.event.Seq - 1
+			break
+		}
+		if !h.send(client, msg.event) {
+			return
+		}
+		ack.Replayed++
+	}
+
+	h.send(client, ackEvent(ack))
+}
+
+// wants mengecek hak akses dan subscription client untuk sebuah event
+func (c *Client) wants(msg hubMessage) bool {
+	if !c.CanReceive(msg.scope) {
+		return false
+	}
+
+	// incoming_message hanya untuk listener instance tsb atau client yang subscribe instance:<id>
+	if msg.instanceID != "" {
+		return c.InstanceID == msg.instanceID || c.topics[TopicInstancePrefix+msg.instanceID]
+	}
+
+	if len(c.topics) == 0 {
+		return true
+	}
+	for _, id := range msg.scope.InstanceIDs {
+		if c.topics[TopicInstancePrefix+id] {
+			return true
+		}
+	}
+	if msg.scope.RoomID != "" && c.topics[TopicWarmingPrefix+msg.scope.RoomID] {
+		return true
+	}
+	if msg.scope.Application != "" && c.topics[TopicOutboxPrefix+strings.ToLower(msg.scope.Application)] {
+		return true
+	}
+	return false
+}
+
+// addTopics menambah subscription client, returns topic yang ditolak.
+// Dipanggil dari Run, atau sebelum Register (lihat SetTopics).
+func (c *Client) addTopics(topics []string) []string {
+	var rejected []string
+	for _, topic := range topics {
+		topic, ok := c.canSubscribe(topic)
+		if !ok || (!c.topics[topic] && len(c.topics) >= maxClientTopics) {
+			rejected = append(rejected, topic)
+			continue
+		}
+		if c.topics == nil {
+			c.topics = make(map[string]bool)
+		}
+		c.topics[topic] = true
+	}
+	return rejected
+}
+
+// SetTopics mengatur subscription awal client sebelum Register (mis. stream SSE dengan ?topics=).
+// Hak akses harus sudah dimuat (LoadAccess). Returns topic yang ditolak.
+func (c *Client) SetTopics(topics []string) []string {
+	return c.addTopics(topics)
+}
+
+// ResumeFrom meminta Hub mengirim ulang event setelah seq tersebut begitu client di-Register
+// (dipakai stream SSE dengan Last-Event-ID, supaya tidak ada event yang terlewat di antaranya).
+func (c *Client) ResumeFrom(seq uint64) {
+	c.resumeFrom = &seq
+}
+
+// canSubscribe memvalidasi topic dan hak akses client (non-admin hanya instance / room miliknya)
+func (c *Client) canSubscribe(topic string) (string, bool) {
+	topic, ok := normalizeTopic(topic)
+	if ok && strings.HasPrefix(topic, TopicInstancePrefix) && !c.instanceAllowed(strings.TrimPrefix(topic, TopicInstancePrefix)) {
+		return topic, false
+	}
+	if !ok || c.IsAdmin() {
+		return topic, ok
+	}
+
+	c.accessMu.RLock()
+	access := c.access
+	c.accessMu.RUnlock()
+
+	switch {
+	case strings.HasPrefix(topic, TopicInstancePrefix):
+		return topic, access != nil && access.InstanceIDs[strings.TrimPrefix(topic, TopicInstancePrefix)]
+	case strings.HasPrefix(topic, TopicWarmingPrefix):
+		return topic, access != nil && access.RoomIDs[strings.TrimPrefix(topic, TopicWarmingPrefix)]
+	default:
+		// outbox:<app> tetap difilter CanReceive per event (instance pengirim)
+		return topic, true
+	}
+}
+
+func (c *Client) topicList() []string {
+	topics := make([]string, 0, len(c.topics))
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// normalizeTopic memvalidasi format <jenis>:<id>; nama application outbox tidak case-sensitive
+func normalizeTopic(topic string) (string, bool) {
+	topic = strings.TrimSpace(topic)
+	for _, prefix := range []string{TopicInstancePrefix, TopicWarmingPrefix, TopicOutboxPrefix} {
+		if len(topic) > len(prefix) && strings.EqualFold(topic[:len(prefix)], prefix) {
+			value := strings.TrimSpace(topic[len(prefix):])
+			if value == "" {
+				return topic, false
+			}
+			if prefix == TopicOutboxPrefix {
+				value = strings.ToLower(value)
+			}
+			return prefix + value, true
+		}
+	}
+	return topic, false
+}
+
+func containsString(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// eventRing menyimpan N event terakhir (urut seq) untuk resume
+type eventRing struct {
+	items []hubMessage
+	start int
+	size  int
+}
+
+func newEventRing(capacity int) *eventRing {
+	return &eventRing{items: make([]hubMessage, capacity)}
+}
+
+func (r *eventRing) add(msg hubMessage) {
+	if r.size < len(r.items) {
+		r.items[(r.start+r.size)%len(r.items)] = msg
+		r.size++
+		return
+	}
+	r.items[r.start] = msg
+	r.start = (r.start + 1) % len(r.items)
+}
+
+// since mengembalikan event dengan seq > from, beserta seq tertua di buffer (0 jika kosong)
+func (r *eventRing) since(from uint64) ([]hubMessage, uint64) {
+	if r.size == 0 {
+		return nil, 0
+	}
+
+	var out []hubMessage
+	for i := 0; i < r.size; i++ {
+		msg := r.items[(r.start+i)%len(r.items)]
+		if msg.event.Seq > from {
+			out = append(out, msg)
+		}
+	}
+	return out, r.items[r.start].event.Seq
+}
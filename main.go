@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"log"
+	"math/rand"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
@@ -15,6 +17,7 @@ import (
 	warmingHandler "gowa-yourself/internal/handler/warming"
 	"gowa-yourself/internal/helper"
 	customMiddleware "gowa-yourself/internal/middleware"
+	"gowa-yourself/internal/model"
 	"gowa-yourself/internal/service"
 	"gowa-yourself/internal/worker"
 
@@ -113,6 +116,28 @@ func main() {
 		config.AIDefaultMaxTokens = 150
 	}
 
+	// Cluster (lebih dari satu replica API). Default mati: satu node memegang semua instance.
+	config.ClusterEnabled = os.Getenv("CLUSTER_ENABLED") == "true"
+	config.ClusterNodeID = os.Getenv("CLUSTER_NODE_ID")
+	if config.ClusterNodeID == "" {
+		hostname, _ := os.Hostname()
+		config.ClusterNodeID = fmt.Sprintf("%s-%04x", hostname, rand.Intn(0xffff))
+	}
+	config.ClusterAdvertiseURL = strings.TrimRight(os.Getenv("CLUSTER_ADVERTISE_URL"), "/")
+	config.ClusterLeaseTTL = time.Duration(helper.GetEnvAsInt("CLUSTER_LEASE_TTL_SECONDS", 30)) * time.Second
+	config.ClusterHeartbeatInterval = time.Duration(helper.GetEnvAsInt("CLUSTER_HEARTBEAT_SECONDS", 10)) * time.Second
+	if config.ClusterHeartbeatInterval <= 0 || config.ClusterHeartbeatInterval >= config.ClusterLeaseTTL {
+		config.ClusterHeartbeatInterval = config.ClusterLeaseTTL / 3
+	}
+	config.ClusterForwardMode = strings.ToLower(os.Getenv("CLUSTER_FORWARD_MODE"))
+	if config.ClusterForwardMode != "redirect" {
+		config.ClusterForwardMode = "proxy"
+	}
+	config.ClusterSecret = os.Getenv("CLUSTER_SECRET")
+	if config.ClusterEnabled && config.ClusterAdvertiseURL == "" {
+		log.Println("CLUSTER_ADVERTISE_URL is not set, other nodes cannot forward requests to this node")
+	}
+
 	log.Printf("feature flags -> websocket_incoming_msg: %v, webhook: %v, warming_auto_reply: %v, ai_enabled: %v",
 		config.EnableWebsocketIncomingMessage, config.EnableWebhook, config.WarmingAutoReplyEnabled, config.AIEnabled)
 
@@ -125,6 +150,11 @@ func main() {
 	// Initialize authentication service (for new user management)
 	service.InitAuthConfig(jwtSecret)
 
+	// Storage backend untuk file upload & media (local / s3)
+	if err := helper.InitStorage(); err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
 	// **************************
 	// main proses.
 	//***************************
@@ -134,20 +164,52 @@ func main() {
 	helper.InitCustomSchema()
 
 	// Load all existing devices from database
-	log.Println("Loading existing devices...")
-	err := service.LoadAllDevices()
-	if err != nil {
-		log.Printf("Warning: Failed to load devices: %v", err)
+	// Mode cluster: device dibagi per shard lewat lease, diambil bertahap oleh loop cluster
+	if config.ClusterEnabled {
+		log.Println("Cluster mode enabled, devices will be claimed by lease...")
+		service.StartCluster()
+	} else {
+		log.Println("Loading existing devices...")
+		err := service.LoadAllDevices()
+		if err != nil {
+			log.Printf("Warning: Failed to load devices: %v", err)
+		}
 	}
 
 	// Inisialisasi WebSocket Hub
 	hub := ws.NewHub()
+	hub.SetAccessLoader(service.LoadRealtimeAccess)
 	go hub.Run()
 
-	service.Realtime = hub
+	// Relay antar proses: event yang dibuat replica lain ikut dikirim ke client WS di node ini
+	var realtime ws.RealtimePublisher = hub
+	relayStarted := false
+	if relay := newRealtimeRelay(appDbURL); relay != nil {
+		publisher := ws.NewRelayPublisher(hub, relay, config.ClusterNodeID)
+		publisher.OnControl(service.WebhookCacheControl, service.ApplyWebhookCacheInvalidation)
+		if err := publisher.Start(); err != nil {
+			log.Printf("⚠️ Warning: Realtime relay disabled: %v", err)
+		} else {
+			realtime = publisher
+			relayStarted = true
+			// Perubahan endpoint webhook ikut mengosongkan cache di node lain
+			service.SetWebhookCacheBroadcast(func(instanceID string) {
+				publisher.PublishControl(service.WebhookCacheControl, instanceID)
+			})
+		}
+	}
+	if config.ClusterEnabled && !relayStarted {
+		log.Println("⚠️ Warning: Cluster mode without realtime relay, webhook endpoint cache disabled")
+		service.DisableWebhookCache()
+	}
+
+	service.Realtime = service.NewWebhookPublisher(realtime)
 
 	// Setup Echo
 	e := echo.New()
+	// IP client (rate limit, brute-force, audit log) dari koneksi langsung, atau X-Forwarded-For
+	// hanya jika request datang dari proxy di TRUSTED_PROXIES
+	e.IPExtractor = newIPExtractor()
 	// e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 
@@ -160,6 +222,7 @@ func main() {
 	for i, o := range allowOrigins {
 		allowOrigins[i] = strings.TrimSpace(o)
 	}
+	handler.SetWebSocketAllowedOrigins(allowOrigins)
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: allowOrigins,
 		AllowMethods: []string{
@@ -176,6 +239,7 @@ func main() {
 			echo.HeaderAccept,
 			echo.HeaderXRequestedWith,
 			echo.HeaderAuthorization,
+			customMiddleware.APIKeyHeader,
 		},
 		AllowCredentials: true,
 	}))
@@ -203,15 +267,23 @@ func main() {
 	// =====================================================
 
 	// New user authentication endpoints
+	e.GET("/register", handler.GetRegistrationMode) // mode: open, invite atau disabled (REGISTRATION_MODE)
 	e.POST("/register", handler.Register)
 	e.POST("/login", handler.LoginUser)
+	e.POST("/login/2fa", handler.LoginTwoFactor)            // tahap kedua: challenge_token + kode TOTP / recovery code
+	e.POST("/login/2fa/setup", handler.LoginTwoFactorSetup) // setup paksa saat kebijakan mewajibkan 2FA
 	e.POST("/refresh", handler.RefreshToken)
 
-	// Static file serving for uploaded files
-	e.Static("/uploads", "./uploads")
+	// Single sign-on (OIDC authorization code + PKCE): google dan/atau oidc (Keycloak dkk)
+	e.GET("/auth/providers", handler.GetOAuthProviders)
+	e.GET("/auth/:provider/login", handler.OAuthLogin)
+	e.GET("/auth/:provider/callback", handler.OAuthCallback)
+
+	// File dari storage (avatar, logo, media masuk) hanya lewat signed URL yang expired
+	e.GET("/files/*", handler.ServeStorageFile)
 
 	// WebSocket and health check
-	e.GET("/ws", handler.WebSocketHandler(hub)) //listen socket gorilla
+	e.GET("/ws", handler.WebSocketHandler(hub)) //listen socket gorilla (token via ?token= / Sec-WebSocket-Protocol)
 	e.GET("/", func(c echo.Context) error {     // Health check
 		return c.JSON(200, map[string]interface{}{
 			"success": true,
@@ -221,7 +293,9 @@ func main() {
 	})
 
 	// Daftar group route yang butuh JWT
-	api := e.Group("/api", customMiddleware.JWTAuthMiddleware())
+	// RestrictViewer: role viewer hanya boleh request baca (lihat role_middleware.go)
+	// ForwardToInstanceOwner: di mode cluster, request instance milik replica lain diteruskan ke sana
+	api := e.Group("/api", customMiddleware.JWTAuthMiddleware(), customMiddleware.RestrictViewer(), customMiddleware.ForwardToInstanceOwner())
 
 	e.HTTPErrorHandler = func(err error, c echo.Context) {
 		code := http.StatusInternalServerError
@@ -260,11 +334,52 @@ func main() {
 	// File upload
 	api.POST("/me/avatar", handler.UploadAvatar)
 
+	// Two-factor authentication (TOTP)
+	api.GET("/me/2fa", handler.GetTwoFactorStatus)
+	api.POST("/me/2fa/setup", handler.SetupTwoFactor)
+	api.POST("/me/2fa/enable", handler.EnableTwoFactor)
+	api.POST("/me/2fa/disable", handler.DisableTwoFactor)
+	api.POST("/me/2fa/recovery-codes", handler.RegenerateRecoveryCodes)
+
+	// API keys untuk akses machine-to-machine (header X-API-Key), tidak bisa dikelola lewat API key
+	api.GET("/api-keys", handler.GetAPIKeys)
+	api.POST("/api-keys", handler.CreateAPIKey)
+	api.DELETE("/api-keys/:id", handler.RevokeAPIKey)
+
+	// =====================================================
+	// USER MANAGEMENT ROUTES (Admin Only)
+	// =====================================================
+	api.GET("/users", handler.ListUsers, customMiddleware.RequireAdmin)
+	api.POST("/users", handler.AdminCreateUser, customMiddleware.RequireAdmin)
+	api.GET("/users/:id", handler.GetUser, customMiddleware.RequireAdmin)
+	api.PUT("/users/:id/role", handler.UpdateUserRole, customMiddleware.RequireAdmin)
+	api.POST("/users/:id/deactivate", handler.DeactivateUser, customMiddleware.RequireAdmin)
+	api.POST("/users/:id/reactivate", handler.ReactivateUser, customMiddleware.RequireAdmin)
+	api.POST("/users/:id/reset-password", handler.ResetUserPassword, customMiddleware.RequireAdmin)
+	api.GET("/users/:id/sessions", handler.GetUserSessions, customMiddleware.RequireAdmin)
+	api.DELETE("/users/:id/sessions", handler.RevokeUserSessions, customMiddleware.RequireAdmin)
+	api.DELETE("/users/:id/sessions/:sessionId", handler.RevokeUserSession, customMiddleware.RequireAdmin)
+	api.POST("/users/:id/impersonate", handler.ImpersonateUser, customMiddleware.RequireAdmin) // token support berumur pendek, diaudit
+	api.DELETE("/users/:id/2fa", handler.ResetUserTwoFactor, customMiddleware.RequireAdmin)
+	api.POST("/users/:id/unlock", handler.UnlockUser, customMiddleware.RequireAdmin)
+
+	// Undangan registrasi (REGISTRATION_MODE=invite)
+	api.GET("/invitations", handler.GetInvitations, customMiddleware.RequireAdmin)
+	api.POST("/invitations", handler.CreateInvitation, customMiddleware.RequireAdmin)
+	api.DELETE("/invitations/:id", handler.RevokeInvitation, customMiddleware.RequireAdmin)
+
 	// =====================================================
 	// SYSTEM IDENTITY ROUTES (Admin Only)
 	// =====================================================
 	api.GET("/system/identity", handler.GetSystemIdentityHandler)                                 // Publicly accessible via API token
 	api.POST("/system/identity", handler.UpdateSystemIdentityFull, customMiddleware.RequireAdmin) // Unified: Text + Logos (Admin Only)
+	api.GET("/system/security-policy", handler.GetSecurityPolicy, customMiddleware.RequireAdmin)
+	api.PUT("/system/security-policy", handler.UpdateSecurityPolicy, customMiddleware.RequireAdmin)
+	api.GET("/security/lockouts", handler.GetLoginLockouts, customMiddleware.RequireAdmin)
+	api.DELETE("/security/lockouts", handler.ClearLoginLockout, customMiddleware.RequireAdmin)
+
+	// Cluster status (Admin Only)
+	api.GET("/cluster", handler.GetClusterStatus, customMiddleware.RequireAdmin)
 
 	// =====================================================
 	// WHATSAPP INSTANCE ROUTES (JWT required)
@@ -272,11 +387,12 @@ func main() {
 
 	// Routes
 	api.POST("/login", handler.Login)
-	api.GET("/qr/:instanceId", handler.GetQR, customMiddleware.RequireInstanceAccess())
-	api.GET("/status/:instanceId", handler.GetStatus, customMiddleware.RequireInstanceAccess())
-	api.POST("/logout/:instanceId", handler.Logout, customMiddleware.RequireInstanceAccess())
-	api.DELETE("/instances/:instanceId", handler.DeleteInstance, customMiddleware.RequireInstanceAccess())
-	api.DELETE("/qr-cancel/:instanceId", handler.CancelQR, customMiddleware.RequireInstanceAccess())
+	api.GET("/qr/:instanceId", handler.GetQR, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.POST("/pair-code/:instanceId", handler.PairCode, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.GET("/status/:instanceId", handler.GetStatus, customMiddleware.RequireInstanceAccess(model.PermissionRead))
+	api.POST("/logout/:instanceId", handler.Logout, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.DELETE("/instances/:instanceId", handler.DeleteInstance, customMiddleware.RequireInstanceAccess(model.PermissionOwner))
+	api.DELETE("/qr-cancel/:instanceId", handler.CancelQR, customMiddleware.RequireInstanceAccess(model.PermissionManage))
 
 	// Get all instances (requires authentication, filtered by user role)
 	api.GET("/instances", handler.GetAllInstances) // JWT already applied to 'api' group
@@ -285,10 +401,21 @@ func main() {
 	api.GET("/timeline", handler.GetGlobalTimeline)
 
 	// update instance fields (used, keterangan)
-	api.PATCH("/instances/:instanceId", handler.UpdateInstanceFields, customMiddleware.RequireInstanceAccess())
+	api.PATCH("/instances/:instanceId", handler.UpdateInstanceFields, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+
+	// Akses user per instance (permission level read/send/manage/owner, admin only)
+	api.GET("/instances/:instanceId/users", handler.GetInstanceUsers, customMiddleware.RequireAdmin)
+	api.PUT("/instances/:instanceId/users/:userId", handler.AssignInstanceUser, customMiddleware.RequireAdmin)
+	api.DELETE("/instances/:instanceId/users/:userId", handler.RevokeInstanceUser, customMiddleware.RequireAdmin)
+
+	// Default country (normalisasi nomor) per circle
+	api.GET("/circles/settings", handler.GetCircleSettings)
+	api.PUT("/circles/:circle/settings", handler.UpdateCircleSetting, customMiddleware.RequireAdmin)
+	api.DELETE("/circles/:circle/settings", handler.DeleteCircleSetting, customMiddleware.RequireAdmin)
+	api.GET("/phone/countries", handler.GetPhoneCountries)
 
 	// Timeline route
-	api.GET("/instances/:instanceId/timeline", handler.GetInstanceTimeline, customMiddleware.RequireInstanceAccess())
+	api.GET("/instances/:instanceId/timeline", handler.GetInstanceTimeline, customMiddleware.RequireInstanceAccess(model.PermissionRead))
 
 	// Attendance routes
 	api.POST("/attendance", handler.CreateAttendance)
@@ -297,60 +424,148 @@ func main() {
 	api.DELETE("/attendance/:id", handler.DeleteAttendance)
 
 	// Message routes by instance id
-	api.POST("/send/:instanceId", handler.SendMessage, customMiddleware.RequireInstanceAccess())
-	api.POST("/check/:instanceId", handler.CheckNumber, customMiddleware.RequireInstanceAccess())
+	api.POST("/send/:instanceId", handler.SendMessage, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/check/:instanceId", handler.CheckNumber, customMiddleware.RequireInstanceAccess(model.PermissionSend))
 
 	// Contact routes
-	api.GET("/contacts/:instanceId", handler.GetContactList, customMiddleware.RequireInstanceAccess())
-	api.GET("/contacts/:instanceId/export", handler.ExportContacts, customMiddleware.RequireInstanceAccess())
-	api.GET("/contacts/:instanceId/:jid", handler.GetContactDetail, customMiddleware.RequireInstanceAccess())
-	api.GET("/contacts/:instanceId/:jid/mutual-groups", handler.GetMutualGroups, customMiddleware.RequireInstanceAccess())
+	api.GET("/contacts/:instanceId", handler.GetContactList, customMiddleware.RequireInstanceAccess(model.PermissionRead))
+	api.GET("/contacts/:instanceId/export", handler.ExportContacts, customMiddleware.RequireInstanceAccess(model.PermissionRead))
+	api.GET("/contacts/:instanceId/:jid", handler.GetContactDetail, customMiddleware.RequireInstanceAccess(model.PermissionRead))
+	api.GET("/contacts/:instanceId/:jid/mutual-groups", handler.GetMutualGroups, customMiddleware.RequireInstanceAccess(model.PermissionRead))
+
+	// Chat / inbox routes (persistent message history)
+	api.GET("/chats/:instanceId", handler.GetChats, customMiddleware.RequireInstanceAccess(model.PermissionRead))
+	api.GET("/chats/:instanceId/:jid/messages", handler.GetChatMessages, customMiddleware.RequireInstanceAccess(model.PermissionRead))
+	api.GET("/messages/:instanceId/:messageId/status", handler.GetMessageStatus, customMiddleware.RequireInstanceAccess(model.PermissionRead))
+	api.PATCH("/messages/:instanceId/:messageId", handler.EditMessage, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.DELETE("/messages/:instanceId/:messageId", handler.RevokeMessage, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/messages/:instanceId/:messageId/pin", handler.PinMessage, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.DELETE("/messages/:instanceId/:messageId/pin", handler.UnpinMessage, customMiddleware.RequireInstanceAccess(model.PermissionSend))
 
 	// Media routes by instance id
-	api.POST("/send/:instanceId/media", handler.SendMediaFile, customMiddleware.RequireInstanceAccess())
-	api.POST("/send/:instanceId/media-url", handler.SendMediaURL, customMiddleware.RequireInstanceAccess())
+	api.POST("/send/:instanceId/media", handler.SendMediaFile, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/send/:instanceId/media-url", handler.SendMediaURL, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+
+	// Rich message routes (location, contact, poll, reaction, reply, audio)
+	api.POST("/send/:instanceId/location", handler.SendLocation, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/send/:instanceId/contact", handler.SendContact, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/send/:instanceId/poll", handler.SendPoll, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/send/:instanceId/reaction", handler.SendReaction, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/send/:instanceId/reply", handler.SendReply, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/send/:instanceId/audio", handler.SendAudio, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.GET("/polls/:instanceId/:messageId", handler.GetPollResults, customMiddleware.RequireInstanceAccess(model.PermissionRead))
 
 	//Message by phone number (requires phone number access)
-	api.POST("/by-number/:phoneNumber", handler.SendMessageByNumber, customMiddleware.RequirePhoneNumberAccess())
-	api.POST("/by-number/:phoneNumber/media-url", handler.SendMediaURLByNumber, customMiddleware.RequirePhoneNumberAccess())
-	api.POST("/by-number/:phoneNumber/media-file", handler.SendMediaFileByNumber, customMiddleware.RequirePhoneNumberAccess())
+	api.POST("/by-number/:phoneNumber", handler.SendMessageByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/by-number/:phoneNumber/media-url", handler.SendMediaURLByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/by-number/:phoneNumber/location", handler.SendLocationByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/by-number/:phoneNumber/contact", handler.SendContactByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/by-number/:phoneNumber/poll", handler.SendPollByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/by-number/:phoneNumber/reaction", handler.SendReactionByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/by-number/:phoneNumber/reply", handler.SendReplyByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/by-number/:phoneNumber/audio", handler.SendAudioByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/by-number/:phoneNumber/media-file", handler.SendMediaFileByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
 
 	// Group routes
-	api.GET("/groups/:instanceId", handler.GetGroups, customMiddleware.RequireInstanceAccess())
-	api.POST("/send-group/:instanceId", handler.SendGroupMessage, customMiddleware.RequireInstanceAccess())
-	api.POST("/send-group/:instanceId/media", handler.SendGroupMedia, customMiddleware.RequireInstanceAccess())
-	api.POST("/send-group/:instanceId/media-url", handler.SendGroupMediaURL, customMiddleware.RequireInstanceAccess())
+	api.GET("/groups/:instanceId", handler.GetGroups, customMiddleware.RequireInstanceAccess(model.PermissionRead))
+	api.POST("/send-group/:instanceId", handler.SendGroupMessage, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/send-group/:instanceId/media", handler.SendGroupMedia, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/send-group/:instanceId/media-url", handler.SendGroupMediaURL, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/send-group/:instanceId/location", handler.SendGroupLocation, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/send-group/:instanceId/contact", handler.SendGroupContact, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/send-group/:instanceId/poll", handler.SendGroupPoll, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/send-group/:instanceId/reaction", handler.SendGroupReaction, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/send-group/:instanceId/reply", handler.SendGroupReply, customMiddleware.RequireInstanceAccess(model.PermissionSend))
+	api.POST("/send-group/:instanceId/audio", handler.SendGroupAudio, customMiddleware.RequireInstanceAccess(model.PermissionSend))
 
 	//Group by phone number (requires phone number access)
-	api.GET("/groups/by-number/:phoneNumber", handler.GetGroupsByNumber, customMiddleware.RequirePhoneNumberAccess())
-	api.POST("/send-group/by-number/:phoneNumber", handler.SendGroupMessageByNumber, customMiddleware.RequirePhoneNumberAccess())
-	api.POST("/send-group/by-number/:phoneNumber/media", handler.SendGroupMediaByNumber, customMiddleware.RequirePhoneNumberAccess())
-	api.POST("/send-group/by-number/:phoneNumber/media-url", handler.SendGroupMediaURLByNumber, customMiddleware.RequirePhoneNumberAccess())
+	api.GET("/groups/by-number/:phoneNumber", handler.GetGroupsByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionRead))
+
+	// Group administration routes
+	api.POST("/groups/:instanceId", handler.CreateGroup, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.POST("/groups/:instanceId/join", handler.JoinGroupWithLink, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.GET("/groups/:instanceId/:groupJid", handler.GetGroupInfo, customMiddleware.RequireInstanceAccess(model.PermissionRead))
+	api.POST("/groups/:instanceId/:groupJid/participants", handler.UpdateGroupParticipants, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.PUT("/groups/:instanceId/:groupJid/subject", handler.SetGroupSubject, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.PUT("/groups/:instanceId/:groupJid/description", handler.SetGroupDescription, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.PUT("/groups/:instanceId/:groupJid/photo", handler.SetGroupPhoto, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.PUT("/groups/:instanceId/:groupJid/settings", handler.SetGroupSettings, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.GET("/groups/:instanceId/:groupJid/invite-link", handler.GetGroupInviteLink, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.POST("/groups/:instanceId/:groupJid/invite-link/revoke", handler.RevokeGroupInviteLink, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.GET("/groups/:instanceId/:groupJid/requests", handler.GetGroupJoinRequests, customMiddleware.RequireInstanceAccess(model.PermissionRead))
+	api.POST("/groups/:instanceId/:groupJid/requests", handler.UpdateGroupJoinRequests, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.GET("/groups/:instanceId/:groupJid/events", handler.GetGroupEvents, customMiddleware.RequireInstanceAccess(model.PermissionRead))
+
+	api.POST("/groups/by-number/:phoneNumber", handler.CreateGroupByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionManage))
+	api.POST("/groups/by-number/:phoneNumber/join", handler.JoinGroupWithLinkByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionManage))
+	api.GET("/groups/by-number/:phoneNumber/:groupJid", handler.GetGroupInfoByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionRead))
+	api.POST("/groups/by-number/:phoneNumber/:groupJid/participants", handler.UpdateGroupParticipantsByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionManage))
+	api.PUT("/groups/by-number/:phoneNumber/:groupJid/subject", handler.SetGroupSubjectByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionManage))
+	api.PUT("/groups/by-number/:phoneNumber/:groupJid/description", handler.SetGroupDescriptionByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionManage))
+	api.PUT("/groups/by-number/:phoneNumber/:groupJid/photo", handler.SetGroupPhotoByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionManage))
+	api.PUT("/groups/by-number/:phoneNumber/:groupJid/settings", handler.SetGroupSettingsByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionManage))
+	api.GET("/groups/by-number/:phoneNumber/:groupJid/invite-link", handler.GetGroupInviteLinkByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionManage))
+	api.POST("/groups/by-number/:phoneNumber/:groupJid/invite-link/revoke", handler.RevokeGroupInviteLinkByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionManage))
+	api.GET("/groups/by-number/:phoneNumber/:groupJid/requests", handler.GetGroupJoinRequestsByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionRead))
+	api.POST("/groups/by-number/:phoneNumber/:groupJid/requests", handler.UpdateGroupJoinRequestsByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionManage))
+	api.POST("/send-group/by-number/:phoneNumber", handler.SendGroupMessageByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/send-group/by-number/:phoneNumber/media", handler.SendGroupMediaByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/send-group/by-number/:phoneNumber/media-url", handler.SendGroupMediaURLByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/send-group/by-number/:phoneNumber/location", handler.SendGroupLocationByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/send-group/by-number/:phoneNumber/contact", handler.SendGroupContactByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/send-group/by-number/:phoneNumber/poll", handler.SendGroupPollByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/send-group/by-number/:phoneNumber/reaction", handler.SendGroupReactionByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/send-group/by-number/:phoneNumber/reply", handler.SendGroupReplyByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+	api.POST("/send-group/by-number/:phoneNumber/audio", handler.SendGroupAudioByNumber, customMiddleware.RequirePhoneNumberAccess(model.PermissionSend))
+
+	// Media dari pesan masuk
+	api.GET("/media/:instanceId/:messageId", handler.GetMessageMedia, customMiddleware.RequireInstanceAccess(model.PermissionRead))
 
 	//get info akun
-	api.GET("/info-device/:instanceId", handler.GetDeviceInfo, customMiddleware.RequireInstanceAccess())
+	api.GET("/info-device/:instanceId", handler.GetDeviceInfo, customMiddleware.RequireInstanceAccess(model.PermissionRead))
 
 	//----------------------------
 	// WEBSOCKET DAN WEBHOOK
 	//----------------------------
 	//dapatkan pesan masuk, pakai ws
-	api.GET("/listen/:instanceId", handler.ListenMessages(hub), customMiddleware.RequireInstanceAccess())
+	api.GET("/listen/:instanceId", handler.ListenMessages(hub), customMiddleware.RequireInstanceAccess(model.PermissionRead))
+	//alternatif ws lewat Server-Sent Events (untuk proxy yang memblok upgrade WebSocket)
+	api.GET("/events/stream", handler.EventStream(hub))
+	api.GET("/events/stream/:instanceId", handler.EventStream(hub), customMiddleware.RequireInstanceAccess(model.PermissionRead))
 	//webhook
-	api.POST("/instances/:instanceId/webhook-setconfig", handler.SetWebhookConfig, customMiddleware.RequireInstanceAccess())
+	api.POST("/instances/:instanceId/webhook-setconfig", handler.SetWebhookConfig, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.GET("/instances/:instanceId/webhook-deliveries", handler.GetWebhookDeliveries, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	api.POST("/instances/:instanceId/webhook-deliveries/:deliveryId/replay", handler.ReplayWebhookDelivery, customMiddleware.RequireInstanceAccess(model.PermissionManage))
+	//webhook endpoints (banyak endpoint per instance / per circle)
+	webhooks := api.Group("/webhooks")
+	webhooks.GET("", handler.GetWebhooks)
+	webhooks.POST("", handler.CreateWebhook)
+	webhooks.GET("/:id", handler.GetWebhook)
+	webhooks.PUT("/:id", handler.UpdateWebhook)
+	webhooks.DELETE("/:id", handler.DeleteWebhook)
 
 	//----------------------------
 	// WORKER BLAST OUTBOX
 	//----------------------------
+	// Hak akses blast outbox & warming:
+	//   - GET (list / detail / logs): semua role (read-only)
+	//   - POST/PUT/PATCH/DELETE: canSend (admin / user; viewer sudah ditolak RestrictViewer)
+	//   - configs create / update / toggle aktif: send ke semua instance di circle (CheckCircleSendAccess)
+	//   - revoke: hanya pesan dari instance dengan akses send (+ allow-list API key)
+	//   - warming rooms create / update / delete / status / restart: send ke instance pengirim & penerima
+	canSend := customMiddleware.RequireRole("admin", "user")
 	blastOutbox := api.Group("/blast-outbox")
 	blastOutbox.GET("/configs", handler.GetWorkerConfigs)
-	blastOutbox.POST("/configs", handler.CreateWorkerConfig)
+	blastOutbox.POST("/configs", handler.CreateWorkerConfig, canSend)
 	blastOutbox.GET("/configs/:id", handler.GetWorkerConfig)
-	blastOutbox.PUT("/configs/:id", handler.UpdateWorkerConfig)
-	blastOutbox.DELETE("/configs/:id", handler.DeleteWorkerConfig)
-	blastOutbox.POST("/configs/:id/toggle", handler.ToggleWorkerConfig)
-	blastOutbox.POST("/queue", handler.CreateOutboxQueue)
-	blastOutbox.POST("/queue/bulk-status", handler.BulkUpdateOutboxStatusHandler)
-	blastOutbox.POST("/import-excel", handler.ImportOutboxExcel)
+	blastOutbox.PUT("/configs/:id", handler.UpdateWorkerConfig, canSend)
+	blastOutbox.DELETE("/configs/:id", handler.DeleteWorkerConfig, canSend)
+	blastOutbox.POST("/configs/:id/toggle", handler.ToggleWorkerConfig, canSend)
+	blastOutbox.POST("/queue", handler.CreateOutboxQueue, canSend)
+	blastOutbox.POST("/queue/bulk-status", handler.BulkUpdateOutboxStatusHandler, canSend)
+	blastOutbox.POST("/revoke", handler.BulkRevokeOutbox, canSend)
+	blastOutbox.GET("/revoke/:jobId", handler.GetBulkRevokeJob)
+	blastOutbox.POST("/import-excel", handler.ImportOutboxExcel, canSend)
 	blastOutbox.GET("/template-excel", handler.DownloadOutboxExcelTemplate)
 	blastOutbox.GET("/queue", handler.GetOutboxQueue)
 	blastOutbox.GET("/queue/:id", handler.GetOutboxByID)
@@ -363,37 +578,37 @@ func main() {
 	// WARMING SYSTEM
 	//----------------------------
 	warming := api.Group("/warming")
-	warming.POST("/scripts", warmingHandler.CreateWarmingScript)
+	warming.POST("/scripts", warmingHandler.CreateWarmingScript, canSend)
 	warming.GET("/scripts", warmingHandler.GetAllWarmingScripts)
 	warming.GET("/scripts/:id", warmingHandler.GetWarmingScriptByID)
-	warming.PUT("/scripts/:id", warmingHandler.UpdateWarmingScript)
-	warming.DELETE("/scripts/:id", warmingHandler.DeleteWarmingScript)
+	warming.PUT("/scripts/:id", warmingHandler.UpdateWarmingScript, canSend)
+	warming.DELETE("/scripts/:id", warmingHandler.DeleteWarmingScript, canSend)
 
 	// Script Lines (Dialog/Naskah)
 	// IMPORTANT: Specific routes must come BEFORE parameterized routes to avoid conflicts
-	warming.POST("/scripts/:scriptId/lines/generate", warmingHandler.GenerateWarmingScriptLines)
-	warming.PUT("/scripts/:scriptId/lines/reorder", warmingHandler.ReorderWarmingScriptLines)
-	warming.POST("/scripts/:scriptId/lines", warmingHandler.CreateWarmingScriptLine)
+	warming.POST("/scripts/:scriptId/lines/generate", warmingHandler.GenerateWarmingScriptLines, canSend)
+	warming.PUT("/scripts/:scriptId/lines/reorder", warmingHandler.ReorderWarmingScriptLines, canSend)
+	warming.POST("/scripts/:scriptId/lines", warmingHandler.CreateWarmingScriptLine, canSend)
 	warming.GET("/scripts/:scriptId/lines", warmingHandler.GetAllWarmingScriptLines)
 	warming.GET("/scripts/:scriptId/lines/:id", warmingHandler.GetWarmingScriptLineByID)
-	warming.PUT("/scripts/:scriptId/lines/:id", warmingHandler.UpdateWarmingScriptLine)
-	warming.DELETE("/scripts/:scriptId/lines/:id", warmingHandler.DeleteWarmingScriptLine)
+	warming.PUT("/scripts/:scriptId/lines/:id", warmingHandler.UpdateWarmingScriptLine, canSend)
+	warming.DELETE("/scripts/:scriptId/lines/:id", warmingHandler.DeleteWarmingScriptLine, canSend)
 
 	// Templates (Manage Conversation Templates)
-	warming.POST("/templates", warmingHandler.CreateWarmingTemplate)
+	warming.POST("/templates", warmingHandler.CreateWarmingTemplate, canSend)
 	warming.GET("/templates", warmingHandler.GetAllWarmingTemplates)
 	warming.GET("/templates/:id", warmingHandler.GetWarmingTemplateByID)
-	warming.PUT("/templates/:id", warmingHandler.UpdateWarmingTemplate)
-	warming.DELETE("/templates/:id", warmingHandler.DeleteWarmingTemplate)
+	warming.PUT("/templates/:id", warmingHandler.UpdateWarmingTemplate, canSend)
+	warming.DELETE("/templates/:id", warmingHandler.DeleteWarmingTemplate, canSend)
 
 	// Rooms (Execution Management)
-	warming.POST("/rooms", warmingHandler.CreateWarmingRoom)
+	warming.POST("/rooms", warmingHandler.CreateWarmingRoom, canSend)
 	warming.GET("/rooms", warmingHandler.GetAllWarmingRooms)
 	warming.GET("/rooms/:id", warmingHandler.GetWarmingRoomByID)
-	warming.PUT("/rooms/:id", warmingHandler.UpdateWarmingRoom)
-	warming.DELETE("/rooms/:id", warmingHandler.DeleteWarmingRoom)
-	warming.PATCH("/rooms/:id/status", warmingHandler.UpdateRoomStatus)
-	warming.POST("/rooms/:id/restart", warmingHandler.RestartWarmingRoom)
+	warming.PUT("/rooms/:id", warmingHandler.UpdateWarmingRoom, canSend)
+	warming.DELETE("/rooms/:id", warmingHandler.DeleteWarmingRoom, canSend)
+	warming.PATCH("/rooms/:id/status", warmingHandler.UpdateRoomStatus, canSend)
+	warming.POST("/rooms/:id/restart", warmingHandler.RestartWarmingRoom, canSend)
 
 	// Logs (Execution History - Read Only)
 	warming.GET("/logs", warmingHandler.GetAllWarmingLogs)
@@ -407,11 +622,17 @@ func main() {
 	// Start warming worker if enabled
 	if os.Getenv("WARMING_WORKER_ENABLED") == "true" {
 		log.Println("🚀 Starting Warming Worker...")
-		go worker.StartWarmingWorker(hub)
+		go worker.StartWarmingWorker(service.Realtime)
 	} else {
 		log.Println("⏸️  Warming Worker disabled (set WARMING_WORKER_ENABLED=true to enable)")
 	}
 
+	// Durable webhook delivery (retry + dead-letter), selalu jalan
+	go worker.StartWebhookWorker()
+
+	// Bulk revoke blast outbox (bertahap, per node pemilik instance), selalu jalan
+	go worker.StartRevokeWorker()
+
 	baseURL := os.Getenv("BASEURL")
 	if baseURL == "" {
 		log.Fatal("BASEURL is not set")
@@ -424,3 +645,71 @@ func main() {
 	log.Fatal(e.Start(":" + port))
 
 }
+
+// newIPExtractor membaca TRUSTED_PROXIES (IP / CIDR, pisahkan dengan koma). Kosong = tanpa proxy,
+// header X-Forwarded-For / X-Real-IP dari client diabaikan.
+func newIPExtractor() echo.IPExtractor {
+	var options []echo.TrustOption
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Printf("⚠️ Warning: Invalid TRUSTED_PROXIES entry %q ignored", entry)
+			continue
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+
+	if len(options) == 0 {
+		log.Println("Client IP: direct connection (TRUSTED_PROXIES is not set)")
+		return echo.ExtractIPDirect()
+	}
+
+	log.Printf("Client IP: X-Forwarded-For from %d trusted proxy range(s)", len(options))
+
+	// Default echo mempercayai loopback / link-local / jaringan privat, matikan agar hanya
+	// proxy yang terdaftar yang dipercaya
+	options = append(options, echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false))
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// newRealtimeRelay membuat relay WebSocket antar node sesuai REALTIME_RELAY
+// (postgres | redis | none). Default postgres jika CLUSTER_ENABLED, selain itu none.
+func newRealtimeRelay(appDbURL string) ws.Relay {
+	driver := strings.ToLower(os.Getenv("REALTIME_RELAY"))
+	if driver == "" && config.ClusterEnabled {
+		driver = "postgres"
+	}
+
+	channel := os.Getenv("REALTIME_RELAY_CHANNEL")
+	if channel == "" {
+		channel = "sudevwa_realtime"
+	}
+
+	switch driver {
+	case "postgres":
+		return ws.NewPostgresRelay(database.AppDB, appDbURL, channel)
+	case "redis":
+		relay, err := ws.NewRedisRelay(os.Getenv("REDIS_URL"), channel)
+		if err != nil {
+			log.Printf("⚠️ Warning: Realtime relay disabled: %v", err)
+			return nil
+		}
+		return relay
+	case "", "none":
+		return nil
+	default:
+		log.Printf("⚠️ Warning: Unknown REALTIME_RELAY %q, relay disabled", driver)
+		return nil
+	}
+}